@@ -0,0 +1,648 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"x-network/internal/dbus"
+
+	gobus "github.com/godbus/dbus/v5"
+	"golang.org/x/sys/unix"
+)
+
+// Client exit codes
+const (
+	exitOK       = 0
+	exitFailed   = 1 // The daemon refused or the operation failed
+	exitUsage    = 2 // Bad subcommand or arguments
+	exitNoDaemon = 3 // Bus or daemon unreachable
+)
+
+// How long the client waits for the signal that ends an operation
+const (
+	clientScanTimeout    = 30 * time.Second
+	clientConnectTimeout = 60 * time.Second
+
+	// errorGrace catches an Error signal emitted just before a method reply
+	errorGrace = 300 * time.Millisecond
+)
+
+// errUsage marks a bad invocation (exit code 2)
+var errUsage = errors.New("usage")
+
+// clientCommand is one subcommand of the client mode
+type clientCommand struct {
+	usage string
+	run   func(c *client, args []string) error
+}
+
+var clientCommands = map[string]clientCommand{
+	"status":     {"status [--json]", cmdStatus},
+	"scan":       {"scan [--json]", cmdScan},
+	"connect":    {"connect [-p] [--security psk|open|8021x] [--hidden] [--json] <ssid>", cmdConnect},
+	"disconnect": {"disconnect [--json]", cmdDisconnect},
+	"forget":     {"forget [--json] <ssid>", cmdForget},
+	"hotspot":    {"hotspot start [-p] [--band 2.4|5] [--json] <ssid> | hotspot stop [--json]", cmdHotspot},
+	"usage":      {"usage [--json]", cmdUsage},
+}
+
+// client is a connection to the daemon on the configured bus
+type client struct {
+	conn    *gobus.Conn
+	obj     gobus.BusObject
+	signals chan *gobus.Signal
+	json    bool
+}
+
+// runClient runs a client subcommand and returns the process exit code
+func runClient(args []string) int {
+	cmd, ok := clientCommands[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "x-network: unknown command %q\n", args[0])
+		clientUsage()
+		return exitUsage
+	}
+
+	var conn *gobus.Conn
+	var err error
+	if *busType == "system" {
+		conn, err = gobus.SystemBus()
+	} else {
+		conn, err = gobus.SessionBus()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "x-network: cannot connect to %s bus: %v\n", *busType, err)
+		return exitNoDaemon
+	}
+	defer conn.Close()
+
+	c := &client{
+		conn:    conn,
+		obj:     conn.Object(*serviceName, dbus.ObjectPath),
+		signals: make(chan *gobus.Signal, 64),
+	}
+	// Subscribe before calling anything so no completion signal is missed
+	if err := conn.AddMatchSignal(gobus.WithMatchSender(*serviceName), gobus.WithMatchObjectPath(dbus.ObjectPath)); err != nil {
+		fmt.Fprintf(os.Stderr, "x-network: %v\n", err)
+		return exitNoDaemon
+	}
+	conn.Signal(c.signals)
+
+	err = cmd.run(c, args[1:])
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, errUsage):
+		fmt.Fprintf(os.Stderr, "usage: x-network %s\n", cmd.usage)
+		return exitUsage
+	case isNoDaemon(err):
+		fmt.Fprintf(os.Stderr, "x-network: daemon not running on the %s bus\n", *busType)
+		return exitNoDaemon
+	default:
+		fmt.Fprintf(os.Stderr, "x-network: %v\n", err)
+		return exitFailed
+	}
+}
+
+// clientUsage lists the subcommands
+func clientUsage() {
+	fmt.Fprintln(os.Stderr, "commands:")
+	for _, name := range []string{"status", "scan", "connect", "disconnect", "forget", "hotspot", "usage"} {
+		fmt.Fprintf(os.Stderr, "  x-network [-bus session|system] %s\n", clientCommands[name].usage)
+	}
+}
+
+// isNoDaemon reports whether err means nobody owns the service name
+func isNoDaemon(err error) bool {
+	var dbusErr gobus.Error
+	if errors.As(err, &dbusErr) {
+		return dbusErr.Name == "org.freedesktop.DBus.Error.ServiceUnknown" ||
+			dbusErr.Name == "org.freedesktop.DBus.Error.NameHasNoOwner"
+	}
+	return false
+}
+
+// flags returns a subcommand flag set with the shared --json flag
+func (c *client) flags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.BoolVar(&c.json, "json", false, "Print JSON")
+	return fs
+}
+
+// parse parses args, letting flags follow positional arguments
+func parse(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, errUsage
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// call invokes a daemon method and stores its outputs
+func (c *client) call(method string, ret interface{}, args ...interface{}) error {
+	call := c.obj.Call(dbus.Interface+"."+method, 0, args...)
+	if call.Err != nil {
+		return callError(call.Err)
+	}
+	if ret == nil {
+		return nil
+	}
+	return call.Store(ret)
+}
+
+// callError strips the D-Bus error wrapping down to the daemon's message
+func callError(err error) error {
+	var dbusErr gobus.Error
+	if errors.As(err, &dbusErr) && len(dbusErr.Body) > 0 && !isNoDaemon(err) {
+		if msg, ok := dbusErr.Body[0].(string); ok {
+			return errors.New(msg)
+		}
+	}
+	return err
+}
+
+// properties reads every property of the daemon
+func (c *client) properties() (map[string]gobus.Variant, error) {
+	var props map[string]gobus.Variant
+	err := c.obj.Call("org.freedesktop.DBus.Properties.GetAll", 0, dbus.Interface).Store(&props)
+	if err != nil {
+		return nil, callError(err)
+	}
+	return props, nil
+}
+
+// wait reads signals until match returns done or the timeout passes
+// A nil match error with done=true ends the wait successfully
+func (c *client) wait(timeout time.Duration, match func(sig *gobus.Signal) (bool, error)) error {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	for {
+		select {
+		case sig, ok := <-c.signals:
+			if !ok {
+				return errors.New("lost the bus connection")
+			}
+			if done, err := match(sig); done || err != nil {
+				return err
+			}
+		case <-deadline.C:
+			return errTimeout
+		}
+	}
+}
+
+var errTimeout = errors.New("timed out")
+
+// opError matches the daemon's Error(op, message, code) signal for op
+func opError(sig *gobus.Signal, op string) error {
+	if sig.Name != dbus.Interface+".Error" || len(sig.Body) < 2 {
+		return nil
+	}
+	if sigOp, _ := sig.Body[0].(string); sigOp != op {
+		return nil
+	}
+	msg, _ := sig.Body[1].(string)
+	return fmt.Errorf("%s failed: %s", strings.ToLower(op), msg)
+}
+
+// callAndCheck calls a method whose failure is only reported through the
+// Error signal, waiting briefly for that signal after the reply
+func (c *client) callAndCheck(method string, ret interface{}, args ...interface{}) error {
+	if err := c.call(method, ret, args...); err != nil {
+		return err
+	}
+	err := c.wait(errorGrace, func(sig *gobus.Signal) (bool, error) {
+		return false, opError(sig, method)
+	})
+	if errors.Is(err, errTimeout) {
+		return nil
+	}
+	return err
+}
+
+// output prints v as JSON with --json, otherwise runs the text printer
+func (c *client) output(v interface{}, text func()) error {
+	if c.json {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+	text()
+	return nil
+}
+
+// prop returns a property value, or the zero value of T when absent
+func prop[T any](props map[string]gobus.Variant, name string) T {
+	var zero T
+	v, ok := props[name]
+	if !ok {
+		return zero
+	}
+	t, ok := v.Value().(T)
+	if !ok {
+		return zero
+	}
+	return t
+}
+
+// clientStatus is the status subcommand's JSON
+type clientStatus struct {
+	WifiEnabled    bool   `json:"wifi_enabled"`
+	State          string `json:"state"`
+	SSID           string `json:"ssid,omitempty"`
+	Signal         uint8  `json:"signal"`
+	IPAddress      string `json:"ip_address,omitempty"`
+	Gateway        string `json:"gateway,omitempty"`
+	Connectivity   string `json:"connectivity"`
+	HasInternet    bool   `json:"has_internet"`
+	ConnectionType string `json:"connection_type,omitempty"`
+	HotspotActive  bool   `json:"hotspot_active"`
+	HotspotSSID    string `json:"hotspot_ssid,omitempty"`
+	UsbTethering   bool   `json:"usb_tethering"`
+	AirplaneMode   bool   `json:"airplane_mode"`
+	LastError      string `json:"last_error,omitempty"`
+}
+
+func cmdStatus(c *client, args []string) error {
+	fs := c.flags("status")
+	if rest, err := parse(fs, args); err != nil || len(rest) > 0 {
+		return errUsage
+	}
+	props, err := c.properties()
+	if err != nil {
+		return err
+	}
+
+	st := clientStatus{
+		WifiEnabled:    prop[bool](props, "WifiEnabled"),
+		State:          prop[string](props, "ConnectionState"),
+		SSID:           prop[string](props, "ActiveSSID"),
+		Signal:         prop[uint8](props, "SignalStrength"),
+		IPAddress:      prop[string](props, "IpAddress"),
+		Gateway:        prop[string](props, "Gateway"),
+		Connectivity:   prop[string](props, "Connectivity"),
+		HasInternet:    prop[bool](props, "HasInternet"),
+		ConnectionType: prop[string](props, "ConnectionType"),
+		HotspotActive:  prop[bool](props, "HotspotActive"),
+		HotspotSSID:    prop[string](props, "HotspotSSID"),
+		UsbTethering:   prop[bool](props, "UsbTetheringConnected"),
+		AirplaneMode:   prop[bool](props, "AirplaneMode"),
+		LastError:      prop[string](props, "LastError"),
+	}
+	return c.output(st, func() {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "WiFi:\t%s\n", onOff(st.WifiEnabled))
+		fmt.Fprintf(w, "State:\t%s\n", st.State)
+		if st.SSID != "" {
+			fmt.Fprintf(w, "Network:\t%s (%d%%)\n", st.SSID, st.Signal)
+		}
+		if st.ConnectionType != "" {
+			fmt.Fprintf(w, "Connection:\t%s\n", st.ConnectionType)
+		}
+		if st.IPAddress != "" {
+			fmt.Fprintf(w, "Address:\t%s\n", st.IPAddress)
+		}
+		if st.Gateway != "" {
+			fmt.Fprintf(w, "Gateway:\t%s\n", st.Gateway)
+		}
+		fmt.Fprintf(w, "Connectivity:\t%s\n", st.Connectivity)
+		fmt.Fprintf(w, "Internet:\t%s\n", yesNo(st.HasInternet))
+		if st.HotspotActive {
+			fmt.Fprintf(w, "Hotspot:\t%s\n", st.HotspotSSID)
+		}
+		if st.UsbTethering {
+			fmt.Fprintf(w, "USB tethering:\tconnected\n")
+		}
+		if st.AirplaneMode {
+			fmt.Fprintf(w, "Airplane mode:\ton\n")
+		}
+		if st.LastError != "" {
+			fmt.Fprintf(w, "Last error:\t%s\n", st.LastError)
+		}
+		w.Flush()
+	})
+}
+
+// clientNetwork is one scan result in the scan subcommand's JSON
+type clientNetwork struct {
+	SSID      string `json:"ssid"`
+	Security  string `json:"security"`
+	Signal    uint8  `json:"signal"`
+	Connected bool   `json:"connected"`
+	Frequency uint32 `json:"frequency"`
+}
+
+func cmdScan(c *client, args []string) error {
+	fs := c.flags("scan")
+	if rest, err := parse(fs, args); err != nil || len(rest) > 0 {
+		return errUsage
+	}
+	if err := c.call("Scan", nil); err != nil {
+		return err
+	}
+	err := c.wait(clientScanTimeout, func(sig *gobus.Signal) (bool, error) {
+		if err := opError(sig, "Scan"); err != nil {
+			return true, err
+		}
+		return sig.Name == dbus.Interface+".ScanCompleted", nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var raw []dbus.NetworkDBus
+	v, err := c.obj.GetProperty(dbus.Interface + ".Networks")
+	if err != nil {
+		return callError(err)
+	}
+	if err := v.Store(&raw); err != nil {
+		return err
+	}
+	networks := make([]clientNetwork, len(raw))
+	for i, n := range raw {
+		networks[i] = clientNetwork{n.SSID, n.Security, n.Signal, n.Connected, n.Frequency}
+	}
+	return c.output(networks, func() {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "\tSSID\tSECURITY\tSIGNAL")
+		for _, n := range raw {
+			mark := ""
+			if n.Connected {
+				mark = "*"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d%%\n", mark, n.SSID, n.SecurityLabel, n.Signal)
+		}
+		w.Flush()
+	})
+}
+
+// clientResult is the JSON of subcommands that only succeed or fail
+type clientResult struct {
+	Success bool   `json:"success"`
+	SSID    string `json:"ssid,omitempty"`
+}
+
+func cmdConnect(c *client, args []string) error {
+	fs := c.flags("connect")
+	askPassword := fs.Bool("p", false, "Prompt for the password")
+	security := fs.String("security", "", "Security type (psk, open, 8021x)")
+	hidden := fs.Bool("hidden", false, "The network does not broadcast its SSID")
+	rest, err := parse(fs, args)
+	if err != nil || len(rest) != 1 {
+		return errUsage
+	}
+	ssid := rest[0]
+
+	params := map[string]gobus.Variant{"ssid": gobus.MakeVariant(ssid)}
+	if *security != "" {
+		params["security"] = gobus.MakeVariant(*security)
+	}
+	if *hidden {
+		params["hidden"] = gobus.MakeVariant(true)
+	}
+	if *askPassword {
+		password, err := readPassword(fmt.Sprintf("Password for %s: ", ssid))
+		if err != nil {
+			return err
+		}
+		params["password"] = gobus.MakeVariant(password)
+	}
+
+	var ok bool
+	if err := c.call("Connect", &ok, params); err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("connect to %s refused", ssid)
+	}
+	err = c.wait(clientConnectTimeout, func(sig *gobus.Signal) (bool, error) {
+		if err := opError(sig, "Connect"); err != nil {
+			return true, err
+		}
+		switch connectionState(sig) {
+		case "connected":
+			return true, nil
+		case "failed":
+			return true, fmt.Errorf("connect to %s failed", ssid)
+		}
+		return false, nil
+	})
+	if err != nil {
+		return err
+	}
+	return c.output(clientResult{true, ssid}, func() {
+		fmt.Printf("Connected to %s\n", ssid)
+	})
+}
+
+// connectionState returns ConnectionState from a PropertiesChanged signal ("" if absent)
+func connectionState(sig *gobus.Signal) string {
+	if sig.Name != "org.freedesktop.DBus.Properties.PropertiesChanged" || len(sig.Body) < 2 {
+		return ""
+	}
+	changed, _ := sig.Body[1].(map[string]gobus.Variant)
+	state, _ := changed["ConnectionState"].Value().(string)
+	return state
+}
+
+func cmdDisconnect(c *client, args []string) error {
+	fs := c.flags("disconnect")
+	if rest, err := parse(fs, args); err != nil || len(rest) > 0 {
+		return errUsage
+	}
+	if err := c.callAndCheck("Disconnect", nil); err != nil {
+		return err
+	}
+	return c.output(clientResult{Success: true}, func() {
+		fmt.Println("Disconnected")
+	})
+}
+
+func cmdForget(c *client, args []string) error {
+	fs := c.flags("forget")
+	rest, err := parse(fs, args)
+	if err != nil || len(rest) != 1 {
+		return errUsage
+	}
+	ssid := rest[0]
+
+	var ok bool
+	if err := c.callAndCheck("Forget", &ok, ssid); err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%s is not a saved network", ssid)
+	}
+	return c.output(clientResult{true, ssid}, func() {
+		fmt.Printf("Forgot %s\n", ssid)
+	})
+}
+
+func cmdHotspot(c *client, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	switch args[0] {
+	case "start":
+		fs := c.flags("hotspot start")
+		askPassword := fs.Bool("p", false, "Prompt for the password")
+		band := fs.String("band", "", "Band: 2.4 or 5")
+		rest, err := parse(fs, args[1:])
+		if err != nil || len(rest) != 1 {
+			return errUsage
+		}
+		ssid := rest[0]
+
+		params := map[string]gobus.Variant{"ssid": gobus.MakeVariant(ssid)}
+		if *band != "" {
+			params["band"] = gobus.MakeVariant(*band)
+		}
+		if *askPassword {
+			password, err := readPassword(fmt.Sprintf("Password for hotspot %s: ", ssid))
+			if err != nil {
+				return err
+			}
+			params["password"] = gobus.MakeVariant(string(password))
+		} else {
+			params["security"] = gobus.MakeVariant("open")
+		}
+
+		var ok bool
+		if err := c.callAndCheck("StartHotspotWithParams", &ok, params); err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("hotspot %s failed to start", ssid)
+		}
+		return c.output(clientResult{true, ssid}, func() {
+			fmt.Printf("Hotspot %s started\n", ssid)
+		})
+	case "stop":
+		fs := c.flags("hotspot stop")
+		if rest, err := parse(fs, args[1:]); err != nil || len(rest) > 0 {
+			return errUsage
+		}
+		if err := c.callAndCheck("StopHotspot", nil); err != nil {
+			return err
+		}
+		return c.output(clientResult{Success: true}, func() {
+			fmt.Println("Hotspot stopped")
+		})
+	}
+	return errUsage
+}
+
+// clientUsageStats is the usage subcommand's JSON
+type clientUsageStats struct {
+	Interface      string `json:"interface,omitempty"`
+	RxBytesPerSec  uint64 `json:"rx_bytes_per_sec"`
+	TxBytesPerSec  uint64 `json:"tx_bytes_per_sec"`
+	ConnectedSince int64  `json:"connected_since,omitempty"` // Unix seconds
+}
+
+func cmdUsage(c *client, args []string) error {
+	fs := c.flags("usage")
+	if rest, err := parse(fs, args); err != nil || len(rest) > 0 {
+		return errUsage
+	}
+	props, err := c.properties()
+	if err != nil {
+		return err
+	}
+
+	u := clientUsageStats{
+		Interface:      prop[string](props, "PrimaryInterface"),
+		RxBytesPerSec:  prop[uint64](props, "TrafficIn"),
+		TxBytesPerSec:  prop[uint64](props, "TrafficOut"),
+		ConnectedSince: prop[int64](props, "ConnectedSince"),
+	}
+	return c.output(u, func() {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		if u.Interface != "" {
+			fmt.Fprintf(w, "Interface:\t%s\n", u.Interface)
+		}
+		fmt.Fprintf(w, "Download:\t%s/s\n", humanBytes(u.RxBytesPerSec))
+		fmt.Fprintf(w, "Upload:\t%s/s\n", humanBytes(u.TxBytesPerSec))
+		if u.ConnectedSince > 0 {
+			up := time.Since(time.Unix(u.ConnectedSince, 0)).Truncate(time.Second)
+			fmt.Fprintf(w, "Connected for:\t%s\n", up)
+		}
+		w.Flush()
+	})
+}
+
+// readPassword prompts on the terminal with echo turned off
+// When stdin is not a terminal the first line is read, so scripts can pipe it
+func readPassword(prompt string) ([]byte, error) {
+	fd := int(os.Stdin.Fd())
+	termios, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil {
+		line, err := bufio.NewReader(os.Stdin).ReadBytes('\n')
+		if err != nil && len(line) == 0 {
+			return nil, fmt.Errorf("read password: %w", err)
+		}
+		return bytes.TrimRight(line, "\r\n"), nil
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	noEcho := *termios
+	noEcho.Lflag &^= unix.ECHO
+	noEcho.Lflag |= unix.ICANON | unix.ISIG
+	if err := unix.IoctlSetTermios(fd, unix.TCSETS, &noEcho); err != nil {
+		return nil, fmt.Errorf("disable echo: %w", err)
+	}
+	defer func() {
+		unix.IoctlSetTermios(fd, unix.TCSETS, termios)
+		fmt.Fprintln(os.Stderr)
+	}()
+
+	line, err := bufio.NewReader(os.Stdin).ReadBytes('\n')
+	if err != nil && len(line) == 0 {
+		return nil, fmt.Errorf("read password: %w", err)
+	}
+	return bytes.TrimRight(line, "\r\n"), nil
+}
+
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// humanBytes formats a byte count with a binary unit
+func humanBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
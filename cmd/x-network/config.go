@@ -0,0 +1,92 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"sync"
+
+	"x-network/internal/config"
+	"x-network/internal/connectivity"
+	"x-network/internal/iwd"
+	"x-network/internal/priority"
+	"x-network/internal/quality"
+)
+
+// liveConfig is the running config; ReloadConfig and SIGHUP replace it
+type liveConfig struct {
+	mu  sync.Mutex
+	cfg config.Config
+
+	iwd      *iwd.Client // nil without IWD
+	priority *priority.Engine
+	quality  *quality.Monitor // nil unless quality.enabled
+	portal   *connectivity.Portal
+	publicIP *connectivity.PublicIPResolver
+}
+
+// loadConfig reads the config files (or -config) and applies explicit flags
+func loadConfig() (config.Config, error) {
+	cfg, err := config.Load(config.Paths(*configPath), *configPath != "")
+	if err != nil {
+		return cfg, err
+	}
+	return cfg, cfg.ApplyFlags(flag.CommandLine)
+}
+
+// Get returns a copy of the running config
+func (l *liveConfig) Get() config.Config {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.cfg
+}
+
+// apply pushes the runtime-changeable keys to the modules
+func (l *liveConfig) apply(cfg *config.Config) {
+	if l.iwd != nil {
+		l.iwd.SetScanTimeout(cfg.WiFi.ScanTimeout)
+		l.iwd.SetConnectAttempts(cfg.WiFi.ConnectAttempts)
+		l.iwd.SetMergeBSS(cfg.WiFi.MergeBSS)
+		l.iwd.SetSignalSmoothing(cfg.WiFi.SignalAlpha)
+		l.iwd.SetSignalHysteresis(cfg.WiFi.SignalDeltaPct, cfg.WiFi.SignalDeltaDBm)
+		l.iwd.SetUsbFallbackPolicy(cfg.USB.Sticky, cfg.USB.ReleaseAfter)
+	}
+	if order, err := priority.ParseOrder(cfg.Routing.LinkPriority); err == nil { // Validated on load
+		l.priority.SetOrder(order)
+	}
+	if err := l.portal.Load(cfg.Portal.Endpoints); err != nil {
+		log.Printf("Warning: portal endpoint config ignored: %v", err)
+	}
+	if err := l.publicIP.SetEndpoints(config.List(cfg.PublicIP.Endpoints)); err != nil {
+		log.Printf("Warning: public IP endpoints ignored: %v", err)
+	}
+	if l.quality != nil {
+		l.quality.SetThresholds(qualityThresholds(cfg))
+	}
+}
+
+// qualityThresholds returns the link quality thresholds of cfg
+func qualityThresholds(cfg *config.Config) quality.Thresholds {
+	return quality.Thresholds{Latency: cfg.Quality.LatencyThreshold, LossPercent: cfg.Quality.LossThreshold}
+}
+
+// Reload re-reads the config and applies what can change at runtime
+// Keys that need a restart are reported and keep their running value, so
+// they are reported again by every reload until the daemon restarts
+func (l *liveConfig) Reload() (reloaded, restart []string, err error) {
+	next, err := loadConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	reloaded, restart = config.Diff(&l.cfg, &next)
+	if len(reloaded) > 0 {
+		running := l.cfg
+		config.CopyReloadable(&running, &next)
+		l.apply(&running)
+		l.cfg = running
+	}
+	log.Printf("Config reloaded: applied %v, restart required for %v", reloaded, restart)
+	return reloaded, restart, nil
+}
@@ -5,85 +5,314 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	"x-network/internal/bluez"
+	"x-network/internal/config"
+	"x-network/internal/connectivity"
 	"x-network/internal/dbus"
+	"x-network/internal/dhcp"
+	"x-network/internal/dns"
+	"x-network/internal/hooks"
+	"x-network/internal/hotspot"
+	"x-network/internal/ipconfig"
 	"x-network/internal/iwd"
 	"x-network/internal/netlink"
+	"x-network/internal/priority"
+	"x-network/internal/quality"
+	"x-network/internal/rfkill"
+	"x-network/internal/settings"
+	"x-network/internal/snapshot"
 	"x-network/internal/state"
 	"x-network/internal/traffic"
 
 	gobus "github.com/godbus/dbus/v5"
 )
 
+// version is set at build time: go build -ldflags "-X main.version=1.2.3"
+var version = "dev"
+
 var (
-	busType = flag.String("bus", "session", "D-Bus bus type: session or system")
-	debug   = flag.Bool("debug", false, "Enable debug logging")
+	busType     = flag.String("bus", "session", "D-Bus bus type: session or system")
+	serviceName = flag.String("service-name", dbus.ServiceName, "D-Bus name to own (or, for client commands, to talk to)")
+	debug       = flag.Bool("debug", false, "Enable debug logging")
+	configPath  = flag.String("config", "", "Config file to read instead of /etc/x-network/config and ~/.config/x-network/config")
+
+	// Overrides for config file keys, read back by Config.ApplyFlags
+	_ = flag.Bool("secret-service", false, "Keep WiFi passphrases in the Secret Service keyring")
+	_ = flag.String("secret-service-bus", "", "Address of the session bus holding the keyring (\"\" = this process's session bus)")
+	_ = flag.String("up-script", "", "Script run when a connection comes up")
+	_ = flag.String("down-script", "", "Script run when the connection goes down")
+	_ = flag.Bool("usb-soft-fallback", false, "Prefer USB tethering while WiFi is connected without internet")
+	_ = flag.String("hooks-dir", hooks.DefaultDir(), "Directory of executables run on connection events (\"\" disables)")
+	_ = flag.Duration("hook-timeout", hooks.DefaultHookTimeout, "Upper bound for each hook run")
+	_ = flag.String("hotspot-subnet", hotspot.DefaultSubnet, "Hotspot address and client subnet (CIDR)")
+	_ = flag.Bool("merge-bss", true, "Show one scan entry per SSID and security instead of one per access point")
+	_ = flag.Float64("signal-alpha", iwd.DefaultSignalAlpha, "Signal smoothing weight of each new RSSI sample (1 disables smoothing)")
+	_ = flag.Int("signal-delta", iwd.DefaultSignalDeltaPercent, "Publish signal changes of at least this many percentage points")
+	_ = flag.Int("signal-delta-dbm", iwd.DefaultSignalDeltaDBm, "Also publish signal changes of more than this many dBm")
+	_ = flag.String("link-priority", strings.Join(priority.DefaultOrder, ","), "Default route preference by link type, best first")
+	_ = flag.Bool("usb-sticky", false, "Keep USB tethering primary after WiFi recovers from a USB fallback")
+	_ = flag.Duration("usb-release-after", 0, "Release the USB fallback lease once WiFi has been back this long (0 keeps it)")
+	_ = flag.Bool("bt-fallback", true, "Connect Bluetooth tethering when WiFi drops and no USB tethering is available")
+	_ = flag.Int("connect-attempts", iwd.DefaultConnectAttempts, "Max WiFi connect attempts on transient failures (1 disables retry)")
+	_ = flag.String("dhcp-client", "auto", "Fallback DHCP client when the native one lacks permissions: auto, dhcpcd or dhclient")
+	_ = flag.Duration("shutdown-timeout", 5*time.Second, "Upper bound for the cleanup on SIGTERM/SIGINT")
+	_ = flag.Bool("release-usb-on-exit", false, "Release the USB tethering lease on shutdown")
+	_ = flag.String("ignore-interfaces", strings.Join(netlink.DefaultIgnorePatterns, ","), "Interfaces never tracked (comma-separated globs)")
+	_ = flag.String("include-interfaces", "", "Interfaces tracked even when an ignore pattern matches (comma-separated globs)")
+	_ = flag.Bool("link-quality", false, "Probe gateway and internet latency and packet loss while connected")
+
+	polkitPolicy = flag.Bool("polkit-policy", false, "Print the polkit .policy file for the system-bus actions and exit")
 )
 
 func main() {
+	startedAt := time.Now()
 	flag.Parse()
 
+	// Positional arguments select client mode (x-network status, scan, ...)
+	if flag.NArg() > 0 {
+		os.Exit(runClient(flag.Args()))
+	}
+
+	if *polkitPolicy {
+		os.Stdout.Write(dbus.PolkitPolicy())
+		return
+	}
+
 	if *debug {
 		log.SetFlags(log.LstdFlags | log.Lshortfile)
 	}
 
-	log.Println("x-network daemon starting...")
+	log.Printf("x-network daemon %s starting...", version)
+
+	cfg, err := loadConfig()
+	if err != nil {
+		log.Fatalf("Config: %v", err)
+	}
+	live := &liveConfig{cfg: cfg}
+
+	// Container/VM links stay out of interface tracking (validated on load)
+	ifaceFilter, err := netlink.NewInterfaceFilter(config.List(cfg.Interfaces.Ignore), config.List(cfg.Interfaces.Include))
+	if err != nil {
+		log.Fatalf("Config: %v", err)
+	}
+
+	// Captive portal probes and public IP endpoints (both reloadable)
+	portal, err := connectivity.NewPortal(cfg.Portal.Endpoints)
+	if err != nil {
+		log.Printf("Warning: portal endpoint config ignored: %v", err)
+	}
+	publicIP, err := connectivity.NewPublicIPResolver(config.List(cfg.PublicIP.Endpoints))
+	if err != nil {
+		log.Printf("Warning: public IP endpoints ignored: %v", err)
+	}
+	live.portal, live.publicIP = portal, publicIP
 
 	// Initialize state manager
 	stateMgr := state.NewManager()
 
-	// Mark as startup - will trigger weather fetch on first network connection
+	// Mark as startup - runs the address-acquired hooks on first network connection
 	stateMgr.Update(func(st *state.State) {
 		st.IsStartup = true
+		st.StartupTimestamp = time.Now()
+	})
+
+	// Persisted D-Bus toggles - applied before any watcher reacts to links
+	settingsStore, err := settings.NewStore(settings.DefaultPath())
+	if err != nil {
+		log.Printf("Warning: settings unreadable, using defaults: %v", err)
+	}
+	stateMgr.Update(func(st *state.State) {
+		set := settingsStore.Get()
+		st.UsbAutoConnect = set.UsbAutoConnectOr(cfg.USB.AutoConnect)
+		st.ScanMode, st.ScanDwellMs = set.ScanMode, set.ScanDwellMs // Validated when set
 	})
 
+	// Up/down scripts and hooks.d follow state transitions
+	hookRunner := hooks.NewRunner(cfg.General.UpScript, cfg.General.DownScript)
+	hookRunner.SetDir(cfg.General.HooksDir, cfg.General.HookTimeout)
+	hookRunner.Attach(stateMgr)
+	defer hookRunner.Close()
+
+	// Undo hotspot NAT/addressing left by a previous run that didn't stop cleanly
+	hotspot.CleanupStale()
+
+	// Initialize DHCP manager (native client, dhcpcd/dhclient fallback)
+	dhcpMgr := dhcp.NewManager(stateMgr)
+	defer dhcpMgr.Close()
+	if ext, err := dhcp.ExternalByName(cfg.General.DHCPClient); err != nil {
+		log.Fatalf("Invalid DHCP client: %v", err)
+	} else {
+		dhcpMgr.SetExternal(ext)
+		if ext == nil {
+			log.Println("Warning: neither dhcpcd nor dhclient found, DHCP needs CAP_NET_RAW")
+		}
+	}
+
+	// Per-SSID/interface IP profiles (static or DHCP)
+	ipStore, err := ipconfig.NewStore(ipconfig.DefaultPath())
+	if err != nil {
+		log.Printf("Warning: IP config profiles unreadable, using DHCP everywhere: %v", err)
+	}
+	ipcfg := ipconfig.NewManager(ipStore, dhcpMgr)
+
 	// Initialize IWD client
-	iwdClient, err := iwd.NewClient(stateMgr)
+	iwdClient, err := iwd.NewClient(stateMgr, ipcfg, portal)
 	if err != nil {
 		log.Printf("Warning: IWD not available: %v", err)
 		// Continue without WiFi support
 	} else {
 		defer iwdClient.Close()
+		live.iwd = iwdClient
+		iwdClient.SetNetworkPriorities(settingsStore.Get().NetworkPriority)
+		iwdClient.SetBlockedNetworks(settingsStore.Get().BlockedNetworks)
+		if err := iwdClient.SetHotspotSubnet(cfg.Hotspot.Subnet); err != nil {
+			log.Printf("Warning: %v, using %s", err, hotspot.DefaultSubnet)
+		}
+		if cfg.General.SecretService {
+			if provider, err := iwd.NewSecretServiceProvider(cfg.General.SecretBus); err != nil {
+				log.Printf("Warning: Secret Service unavailable, using IWD credential storage: %v", err)
+			} else {
+				iwdClient.SetCredentialProvider(provider)
+				log.Println("Using Secret Service for WiFi credentials")
+			}
+		}
 		log.Println("IWD client connected")
 	}
 
+	// Initialize Bluetooth tethering client (optional)
+	btClient, err := bluez.NewClient(stateMgr, ipcfg)
+	if err != nil {
+		log.Printf("Warning: Bluetooth tethering not available: %v", err)
+		btClient = nil
+	} else {
+		defer btClient.Close()
+		log.Println("BlueZ client started")
+	}
+
+	if iwdClient != nil && btClient != nil && cfg.Bluetooth.Fallback {
+		iwdClient.SetBluetoothFallback(func() error {
+			d, err := btClient.Lookup("")
+			if err != nil {
+				return err
+			}
+			return btClient.Connect(d)
+		})
+	}
+
+	// Session context of the previous run (connected-since, captive result,
+	// connection history). Reconciled once the netlink watcher's initial fetch
+	// has set the connection type, before it can fire the startup hook
+	snapStore := snapshot.NewStore(snapshot.DefaultPath(), stateMgr, iwdClient)
+	restoreSession := func() {
+		snapStore.Restore()
+		snapStore.Attach()
+	}
+
 	// Initialize netlink watcher
-	nlWatcher, err := netlink.NewWatcher(stateMgr)
+	nlWatcher, err := netlink.NewWatcher(stateMgr, ipcfg, ifaceFilter)
 	if err != nil {
 		log.Printf("Warning: Netlink watcher failed: %v", err)
+		restoreSession()
 	} else {
 		defer nlWatcher.Close()
+		nlWatcher.SetConnectHook(func(reason, ssid, iface, ip string) error {
+			return hookRunner.Run(hooks.EventAddressAcquired, hooks.Info{Reason: reason, SSID: ssid, Iface: iface, IP: ip})
+		})
+		nlWatcher.SetSyncHook(restoreSession)
 		go nlWatcher.Run()
 		log.Println("Netlink watcher started")
 	}
 
+	// Initialize DNS resolver watcher
+	dnsWatcher, err := dns.NewWatcher(stateMgr)
+	if err != nil {
+		log.Printf("Warning: DNS watcher failed: %v", err)
+	} else {
+		defer dnsWatcher.Close()
+		go dnsWatcher.Run()
+		log.Println("DNS watcher started")
+	}
+
+	// Initialize rfkill watcher (reads the current block state before D-Bus is up)
+	rfkillWatcher, err := rfkill.NewWatcher(stateMgr)
+	if err != nil {
+		log.Printf("Warning: rfkill watcher failed: %v", err)
+	} else {
+		defer rfkillWatcher.Close()
+		go rfkillWatcher.Run()
+		log.Println("rfkill watcher started")
+	}
+
+	// Initialize link priority engine (one link owns the default route)
+	priorityEngine := priority.NewEngine(stateMgr)
+	live.priority = priorityEngine
+
+	// Runtime-changeable settings: IWD tuning, link order, portal endpoints
+	live.apply(&cfg)
+
+	go priorityEngine.Run()
+	defer priorityEngine.Close()
+	log.Println("Link priority engine started")
+
+	// Initialize connectivity checker (probes over the default route interface)
+	connChecker := connectivity.NewChecker(stateMgr, portal)
+	connChecker.SetSoftFallback(cfg.USB.SoftFallback)
+	go connChecker.Run()
+	defer connChecker.Close()
+	log.Println("Connectivity checker started")
+
 	// Initialize traffic monitor
-	trafficMon := traffic.NewMonitor(stateMgr)
+	trafficMon := traffic.NewMonitor(stateMgr, cfg.Traffic.Interval, ifaceFilter)
 	go trafficMon.Run()
 	defer trafficMon.Stop()
 	log.Println("Traffic monitor started")
 
+	// Initialize link quality probes (gateway and internet latency, loss)
+	if cfg.Quality.Enabled {
+		qualityMon := quality.NewMonitor(stateMgr, cfg.Quality.Interval, cfg.Quality.Window, cfg.Quality.Target)
+		qualityMon.SetThresholds(qualityThresholds(&cfg))
+		live.quality = qualityMon
+		go qualityMon.Run()
+		defer qualityMon.Stop()
+		log.Println("Link quality monitor started")
+	}
+
 	// Initialize D-Bus service
-	dbusService, err := dbus.NewService(*busType, stateMgr, iwdClient)
+	dbusService, err := dbus.NewService(*busType, *serviceName, stateMgr, iwdClient, btClient, ipcfg, settingsStore,
+		portal, publicIP, ifaceFilter)
 	if err != nil {
 		log.Fatalf("Failed to start D-Bus service: %v", err)
 	}
 	defer dbusService.Close()
-	log.Printf("D-Bus service registered on %s bus", *busType)
+	dbusService.SetConfigReloader(live.Reload)
+	dbusService.SetBuildInfo(version, startedAt)
+	log.Printf("D-Bus service %s registered on %s bus", *serviceName, *busType)
 
-	// Watch for system resume to trigger weather refresh and accelerate reconnect
+	// Watch for system resume to re-arm the on-connect hook and accelerate reconnect
 	go watchSystemResume(stateMgr, iwdClient)
 	log.Println("System resume watcher started")
 
 	// Wait for signals
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 
 	log.Println("x-network daemon ready")
-	<-sigChan
+	for sig := range sigChan {
+		if sig == syscall.SIGHUP {
+			if _, _, err := live.Reload(); err != nil {
+				log.Printf("Config reload failed, keeping the running config: %v", err)
+			}
+			continue
+		}
+		break
+	}
 	log.Println("Shutting down...")
+	cfg = live.Get()
+	shutdown(cfg.General.ShutdownTimeout, stateMgr, iwdClient, ipcfg, dbusService, snapStore, cfg.USB.ReleaseOnExit)
 }
 
 // watchSystemResume listens for PrepareForSleep D-Bus signal from logind
@@ -119,7 +348,7 @@ func watchSystemResume(stateMgr *state.Manager, iwdClient *iwd.Client) {
 				stateMgr.Update(func(st *state.State) {
 					st.WasResumed = true
 					st.ResumeTimestamp = time.Now()
-					st.WeatherTriggered = false // Reset dedup flag
+					st.ConnectHookTriggered = false // Reset dedup flag
 				})
 
 				// Trigger iwd scan to accelerate reconnection
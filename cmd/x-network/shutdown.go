@@ -0,0 +1,57 @@
+package main
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"x-network/internal/dbus"
+	"x-network/internal/ipconfig"
+	"x-network/internal/iwd"
+	"x-network/internal/snapshot"
+	"x-network/internal/state"
+)
+
+// shutdown tears down what outlives the process if left alone: the IWD agent
+// registration, a running hotspot and (with releaseUsb) the USB tethering
+// lease. The state snapshot is saved first, and clients get a final Shutdown
+// signal before the bus connection closes.
+// Bounded by timeout; a step that hangs exits the process instead
+func shutdown(timeout time.Duration, stateMgr *state.Manager, iwdClient *iwd.Client, ipcfg *ipconfig.Manager, svc *dbus.Service, snap *snapshot.Store, releaseUsb bool) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		st := stateMgr.Get()
+
+		// Saved while the connection is still described as it was
+		snap.Close()
+
+		if iwdClient != nil {
+			if st.HotspotActive {
+				log.Printf("Shutdown: stopping hotspot %s", st.HotspotSSID)
+				if err := iwdClient.StopHotspot(); err != nil {
+					log.Printf("Shutdown: failed to stop hotspot: %v", err)
+				}
+			}
+			// Unregisters the agent; the deferred Close is then a no-op
+			iwdClient.Close()
+		}
+
+		if releaseUsb && st.UsbTetheringConnected && st.UsbInterfaceName != "" {
+			log.Printf("Shutdown: releasing USB lease on %s", st.UsbInterfaceName)
+			if err := ipcfg.Release(st.UsbInterfaceName); err != nil {
+				log.Printf("Shutdown: failed to release %s: %v", st.UsbInterfaceName, err)
+			}
+		}
+
+		svc.EmitSignal("Shutdown")
+	}()
+
+	select {
+	case <-done:
+		log.Println("Shutdown complete")
+	case <-time.After(timeout):
+		log.Printf("Shutdown did not finish within %s, exiting", timeout)
+		os.Exit(1)
+	}
+}
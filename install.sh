@@ -9,7 +9,8 @@ cd "$(dirname "$0")"
 
 # Build
 echo "→ Building..."
-go build -ldflags="-s -w" -o x-network ./cmd/x-network
+VERSION=$(git describe --tags --always --dirty 2>/dev/null || echo dev)
+go build -ldflags="-s -w -X main.version=$VERSION" -o x-network ./cmd/x-network
 
 # Install binary
 echo "→ Installing binary..."
@@ -23,6 +24,11 @@ sudo mkdir -p /usr/share/dbus-1/services
 sudo cp configs/org.xshell.Network.conf /etc/dbus-1/session.d/
 sudo cp configs/org.xshell.Network.service /usr/share/dbus-1/services/
 
+# Install polkit actions (privileged D-Bus methods are checked against these)
+echo "→ Installing polkit policy..."
+sudo mkdir -p /usr/share/polkit-1/actions
+sudo cp configs/org.xshell.network.policy /usr/share/polkit-1/actions/
+
 # Create user systemd service (optional)
 mkdir -p ~/.config/systemd/user
 cat > ~/.config/systemd/user/x-network.service << 'EOF'
@@ -0,0 +1,287 @@
+package bluez
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"x-network/internal/ipconfig"
+	"x-network/internal/state"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	Service       = "org.bluez"
+	DeviceIface   = "org.bluez.Device1"
+	NetworkIface  = "org.bluez.Network1"
+	objectManager = "org.freedesktop.DBus.ObjectManager"
+
+	// NAPUUID is the PAN Network Access Point profile a tethering phone offers
+	NAPUUID = "00001116-0000-1000-8000-00805f9b34fb"
+
+	// linkTimeout bounds the wait for the bnep interface after Network1.Connect
+	linkTimeout = 10 * time.Second
+)
+
+var (
+	ErrDeviceNotFound = errors.New("no paired device offering Bluetooth tethering")
+	ErrNotConnected   = errors.New("Bluetooth tethering not connected")
+)
+
+// Device is a paired device exposing the NAP profile
+type Device struct {
+	Path      dbus.ObjectPath
+	Address   string
+	Name      string
+	Connected bool // Network1.Connected (PAN link up)
+}
+
+// Client drives Bluetooth PAN tethering through BlueZ
+// The bnep interface itself is tracked by the netlink watcher (BtInterfaceName)
+type Client struct {
+	conn     *dbus.Conn
+	stateMgr *state.Manager
+	ipcfg    *ipconfig.Manager
+
+	mu     sync.Mutex
+	device dbus.ObjectPath // Device we connected, "" when none
+
+	signals chan *dbus.Signal
+	stopCh  chan struct{}
+}
+
+// NewClient connects to BlueZ on a private system bus connection and publishes
+// BtTetheringAvailable
+func NewClient(stateMgr *state.Manager, ipcfg *ipconfig.Manager) (*Client, error) {
+	conn, err := dbus.ConnectSystemBus()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to system bus: %w", err)
+	}
+
+	c := &Client{
+		conn:     conn,
+		stateMgr: stateMgr,
+		ipcfg:    ipcfg,
+		signals:  make(chan *dbus.Signal, 16),
+		stopCh:   make(chan struct{}),
+	}
+
+	// Pairing, unpairing and PAN connects all change BlueZ objects
+	for _, rule := range []string{
+		"type='signal',sender='" + Service + "',interface='" + objectManager + "'",
+		"type='signal',sender='" + Service + "',interface='org.freedesktop.DBus.Properties',member='PropertiesChanged'",
+	} {
+		if err := conn.BusObject().Call("org.freedesktop.DBus.AddMatch", 0, rule).Err; err != nil {
+			log.Printf("Bluetooth: AddMatch failed: %v", err)
+		}
+	}
+	conn.Signal(c.signals)
+
+	c.refresh()
+	go c.watch()
+
+	return c, nil
+}
+
+// Close stops watching BlueZ
+func (c *Client) Close() {
+	close(c.stopCh)
+	c.conn.Close()
+}
+
+// watch refreshes availability on BlueZ object changes
+func (c *Client) watch() {
+	for {
+		select {
+		case <-c.stopCh:
+			return
+		case sig, ok := <-c.signals:
+			if !ok {
+				return
+			}
+			if !strings.HasPrefix(string(sig.Path), "/org/bluez") && sig.Path != "/" {
+				continue
+			}
+			c.refresh()
+			c.handleDeviceChange(sig)
+		}
+	}
+}
+
+// handleDeviceChange clears the connection when our device drops the PAN link
+func (c *Client) handleDeviceChange(sig *dbus.Signal) {
+	c.mu.Lock()
+	device := c.device
+	c.mu.Unlock()
+
+	if device == "" || sig.Path != device || sig.Name != "org.freedesktop.DBus.Properties.PropertiesChanged" || len(sig.Body) < 2 {
+		return
+	}
+	if iface, _ := sig.Body[0].(string); iface != NetworkIface {
+		return
+	}
+	changed, _ := sig.Body[1].(map[string]dbus.Variant)
+	if v, ok := changed["Connected"]; ok {
+		if connected, _ := v.Value().(bool); !connected {
+			log.Printf("Bluetooth tethering link to %s dropped", device)
+			c.clear()
+		}
+	}
+}
+
+// refresh publishes whether any paired device offers tethering
+func (c *Client) refresh() {
+	devices, err := c.Devices()
+	available := err == nil && len(devices) > 0
+	if c.stateMgr.Get().BtTetheringAvailable == available {
+		return
+	}
+	c.stateMgr.Update(func(st *state.State) {
+		st.BtTetheringAvailable = available
+	})
+}
+
+// Devices lists paired devices exposing the NAP profile
+func (c *Client) Devices() ([]Device, error) {
+	var objects map[dbus.ObjectPath]map[string]map[string]dbus.Variant
+	err := c.conn.Object(Service, "/").Call(objectManager+".GetManagedObjects", 0).Store(&objects)
+	if err != nil {
+		return nil, err
+	}
+
+	var devices []Device
+	for path, ifaces := range objects {
+		props, ok := ifaces[DeviceIface]
+		if !ok {
+			continue
+		}
+		if _, hasNetwork := ifaces[NetworkIface]; !hasNetwork {
+			continue
+		}
+		if paired, _ := props["Paired"].Value().(bool); !paired {
+			continue
+		}
+		if !hasUUID(props, NAPUUID) {
+			continue
+		}
+
+		d := Device{Path: path}
+		d.Address, _ = props["Address"].Value().(string)
+		d.Name, _ = props["Alias"].Value().(string)
+		if d.Name == "" {
+			d.Name = d.Address
+		}
+		d.Connected, _ = ifaces[NetworkIface]["Connected"].Value().(bool)
+		devices = append(devices, d)
+	}
+	return devices, nil
+}
+
+// hasUUID reports whether a Device1 property map advertises uuid
+func hasUUID(props map[string]dbus.Variant, uuid string) bool {
+	uuids, _ := props["UUIDs"].Value().([]string)
+	for _, u := range uuids {
+		if strings.EqualFold(u, uuid) {
+			return true
+		}
+	}
+	return false
+}
+
+// Lookup matches device against the NAP devices by address, object path or name
+// "" picks the only NAP device
+func (c *Client) Lookup(device string) (Device, error) {
+	devices, err := c.Devices()
+	if err != nil {
+		return Device{}, err
+	}
+	if device == "" && len(devices) == 1 {
+		return devices[0], nil
+	}
+	for _, d := range devices {
+		if strings.EqualFold(d.Address, device) || string(d.Path) == device || d.Name == device {
+			return d, nil
+		}
+	}
+	return Device{}, ErrDeviceNotFound
+}
+
+// Connect joins the NAP of d, waits for its bnep interface and runs DHCP
+func (c *Client) Connect(d Device) error {
+	var iface string
+	if !d.Connected {
+		err := c.conn.Object(Service, d.Path).Call(NetworkIface+".Connect", 0, "nap").Store(&iface)
+		if err != nil {
+			return fmt.Errorf("bluetooth PAN connect failed: %w", err)
+		}
+	} else {
+		v, err := c.conn.Object(Service, d.Path).GetProperty(NetworkIface + ".Interface")
+		if err != nil {
+			return err
+		}
+		iface, _ = v.Value().(string)
+	}
+	log.Printf("Bluetooth tethering: %s connected as %s", d.Name, iface)
+
+	c.mu.Lock()
+	c.device = d.Path
+	c.mu.Unlock()
+	c.stateMgr.Update(func(st *state.State) {
+		st.BtDeviceName = d.Name
+	})
+
+	if err := c.waitForLink(iface); err != nil {
+		return err
+	}
+	return c.ipcfg.Start(iface)
+}
+
+// waitForLink waits until the netlink watcher reports iface
+func (c *Client) waitForLink(iface string) error {
+	deadline := time.Now().Add(linkTimeout)
+	for time.Now().Before(deadline) {
+		st := c.stateMgr.Get()
+		if st.BtInterfaceName == iface && st.BtInterfaceIndex != 0 {
+			return nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return fmt.Errorf("bluetooth interface %s did not appear", iface)
+}
+
+// Disconnect releases the lease and drops the PAN link
+func (c *Client) Disconnect() error {
+	c.mu.Lock()
+	device := c.device
+	c.mu.Unlock()
+
+	if device == "" {
+		return ErrNotConnected
+	}
+
+	if iface := c.stateMgr.Get().BtInterfaceName; iface != "" {
+		c.ipcfg.Release(iface) // Ignore error - interface might already be gone
+	}
+	err := c.conn.Object(Service, device).Call(NetworkIface+".Disconnect", 0).Err
+	c.clear()
+	return err
+}
+
+// clear forgets the connected device
+func (c *Client) clear() {
+	c.mu.Lock()
+	c.device = ""
+	c.mu.Unlock()
+
+	c.stateMgr.Update(func(st *state.State) {
+		st.BtTetheringConnected = false
+		st.BtDeviceName = ""
+		if st.ConnectionType == "bluetooth" {
+			st.ConnectionType = ""
+		}
+	})
+}
@@ -0,0 +1,433 @@
+package config
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"x-network/internal/connectivity"
+	"x-network/internal/hooks"
+	"x-network/internal/hotspot"
+	"x-network/internal/iwd"
+	"x-network/internal/netlink"
+	"x-network/internal/priority"
+	"x-network/internal/quality"
+	"x-network/internal/traffic"
+)
+
+// SystemPath is read first; the user file overrides it key by key
+const SystemPath = "/etc/x-network/config"
+
+// Config holds the daemon settings read from the INI config files
+// Command-line flags override whatever the files say
+type Config struct {
+	General struct {
+		UpScript        string
+		DownScript      string
+		HooksDir        string
+		HookTimeout     time.Duration
+		SecretService   bool
+		SecretBus       string // Session bus holding the keyring ("" = the daemon's own)
+		DHCPClient      string
+		ShutdownTimeout time.Duration
+	}
+	WiFi struct {
+		ScanTimeout     time.Duration
+		MergeBSS        bool
+		SignalAlpha     float64
+		SignalDeltaPct  int
+		SignalDeltaDBm  int
+		ConnectAttempts int
+	}
+	USB struct {
+		AutoConnect   bool // Default until SetUsbAutoConnect is called over D-Bus
+		SoftFallback  bool
+		Sticky        bool
+		ReleaseAfter  time.Duration
+		ReleaseOnExit bool
+	}
+	Bluetooth struct {
+		Fallback bool
+	}
+	Routing struct {
+		LinkPriority string
+	}
+	Hotspot struct {
+		Subnet string
+	}
+	Portal struct {
+		Endpoints string // Path of the probe endpoint JSON
+	}
+	PublicIP struct {
+		Endpoints string // Comma-separated https URLs, see connectivity.PublicIPResolver
+	}
+	Traffic struct {
+		Interval time.Duration
+	}
+	Quality struct {
+		Enabled          bool
+		Interval         time.Duration
+		Window           int
+		Target           string
+		LatencyThreshold time.Duration
+		LossThreshold    int // Percent
+	}
+	Interfaces struct {
+		Ignore  string // Comma-separated globs, see netlink.InterfaceFilter
+		Include string
+	}
+}
+
+// Default returns the settings the daemon uses without a config file
+func Default() Config {
+	var c Config
+	c.General.HooksDir = hooks.DefaultDir()
+	c.General.HookTimeout = hooks.DefaultHookTimeout
+	c.General.DHCPClient = "auto"
+	c.General.ShutdownTimeout = 5 * time.Second
+	c.WiFi.ScanTimeout = iwd.DefaultScanTimeout
+	c.WiFi.MergeBSS = true
+	c.WiFi.SignalAlpha = iwd.DefaultSignalAlpha
+	c.WiFi.SignalDeltaPct = iwd.DefaultSignalDeltaPercent
+	c.WiFi.SignalDeltaDBm = iwd.DefaultSignalDeltaDBm
+	c.WiFi.ConnectAttempts = iwd.DefaultConnectAttempts
+	c.USB.AutoConnect = true
+	c.Bluetooth.Fallback = true
+	c.Routing.LinkPriority = strings.Join(priority.DefaultOrder, ",")
+	c.Hotspot.Subnet = hotspot.DefaultSubnet
+	c.Portal.Endpoints = connectivity.PortalConfigPath()
+	c.PublicIP.Endpoints = strings.Join(connectivity.DefaultPublicIPEndpoints, ",")
+	c.Traffic.Interval = traffic.DefaultInterval
+	c.Quality.Interval = quality.DefaultInterval
+	c.Quality.Window = quality.DefaultWindow
+	c.Quality.Target = quality.DefaultTarget
+	c.Quality.LatencyThreshold = quality.DefaultLatencyThreshold
+	c.Quality.LossThreshold = quality.DefaultLossThreshold
+	c.Interfaces.Ignore = strings.Join(netlink.DefaultIgnorePatterns, ",")
+	return c
+}
+
+// key is one "section.name" config entry
+type key struct {
+	name   string
+	flag   string                      // Command-line flag overriding it ("" = none)
+	reload bool                        // Applied by ReloadConfig without a restart
+	field  func(c *Config) interface{} // Pointer to the field
+	check  func(c *Config) error       // Extra validation (nil = type check only)
+}
+
+var keys = []key{
+	{"general.up_script", "up-script", false, func(c *Config) interface{} { return &c.General.UpScript }, nil},
+	{"general.down_script", "down-script", false, func(c *Config) interface{} { return &c.General.DownScript }, nil},
+	{"general.hooks_dir", "hooks-dir", false, func(c *Config) interface{} { return &c.General.HooksDir }, nil},
+	{"general.hook_timeout", "hook-timeout", false, func(c *Config) interface{} { return &c.General.HookTimeout }, positive(func(c *Config) time.Duration { return c.General.HookTimeout })},
+	{"general.secret_service", "secret-service", false, func(c *Config) interface{} { return &c.General.SecretService }, nil},
+	{"general.secret_service_bus", "secret-service-bus", false, func(c *Config) interface{} { return &c.General.SecretBus }, nil},
+	{"general.dhcp_client", "dhcp-client", false, func(c *Config) interface{} { return &c.General.DHCPClient }, func(c *Config) error {
+		switch c.General.DHCPClient {
+		case "auto", "dhcpcd", "dhclient":
+			return nil
+		}
+		return errors.New("want auto, dhcpcd or dhclient")
+	}},
+	{"general.shutdown_timeout", "shutdown-timeout", true, func(c *Config) interface{} { return &c.General.ShutdownTimeout }, positive(func(c *Config) time.Duration { return c.General.ShutdownTimeout })},
+
+	{"wifi.scan_timeout", "", true, func(c *Config) interface{} { return &c.WiFi.ScanTimeout }, positive(func(c *Config) time.Duration { return c.WiFi.ScanTimeout })},
+	{"wifi.merge_bss", "merge-bss", true, func(c *Config) interface{} { return &c.WiFi.MergeBSS }, nil},
+	{"wifi.signal_alpha", "signal-alpha", true, func(c *Config) interface{} { return &c.WiFi.SignalAlpha }, func(c *Config) error {
+		if c.WiFi.SignalAlpha <= 0 || c.WiFi.SignalAlpha > 1 {
+			return errors.New("must be in (0, 1]")
+		}
+		return nil
+	}},
+	{"wifi.signal_delta", "signal-delta", true, func(c *Config) interface{} { return &c.WiFi.SignalDeltaPct }, nonNegative(func(c *Config) int { return c.WiFi.SignalDeltaPct })},
+	{"wifi.signal_delta_dbm", "signal-delta-dbm", true, func(c *Config) interface{} { return &c.WiFi.SignalDeltaDBm }, nonNegative(func(c *Config) int { return c.WiFi.SignalDeltaDBm })},
+	{"wifi.connect_attempts", "connect-attempts", true, func(c *Config) interface{} { return &c.WiFi.ConnectAttempts }, func(c *Config) error {
+		if c.WiFi.ConnectAttempts < 1 {
+			return errors.New("must be at least 1")
+		}
+		return nil
+	}},
+
+	{"usb.autoconnect", "", false, func(c *Config) interface{} { return &c.USB.AutoConnect }, nil},
+	{"usb.soft_fallback", "usb-soft-fallback", false, func(c *Config) interface{} { return &c.USB.SoftFallback }, nil},
+	{"usb.sticky", "usb-sticky", true, func(c *Config) interface{} { return &c.USB.Sticky }, nil},
+	{"usb.release_after", "usb-release-after", true, func(c *Config) interface{} { return &c.USB.ReleaseAfter }, nonNegative(func(c *Config) int { return int(c.USB.ReleaseAfter) })},
+	{"usb.release_on_exit", "release-usb-on-exit", true, func(c *Config) interface{} { return &c.USB.ReleaseOnExit }, nil},
+
+	{"bluetooth.fallback", "bt-fallback", false, func(c *Config) interface{} { return &c.Bluetooth.Fallback }, nil},
+
+	{"routing.link_priority", "link-priority", true, func(c *Config) interface{} { return &c.Routing.LinkPriority }, func(c *Config) error {
+		_, err := priority.ParseOrder(c.Routing.LinkPriority)
+		return err
+	}},
+
+	{"hotspot.subnet", "hotspot-subnet", false, func(c *Config) interface{} { return &c.Hotspot.Subnet }, func(c *Config) error {
+		_, _, err := net.ParseCIDR(c.Hotspot.Subnet)
+		return err
+	}},
+
+	{"portal.endpoints", "", true, func(c *Config) interface{} { return &c.Portal.Endpoints }, nil},
+
+	{"publicip.endpoints", "", true, func(c *Config) interface{} { return &c.PublicIP.Endpoints }, func(c *Config) error {
+		return connectivity.ValidatePublicIPEndpoints(List(c.PublicIP.Endpoints))
+	}},
+
+	{"traffic.interval", "", false, func(c *Config) interface{} { return &c.Traffic.Interval }, positive(func(c *Config) time.Duration { return c.Traffic.Interval })},
+
+	{"quality.enabled", "link-quality", false, func(c *Config) interface{} { return &c.Quality.Enabled }, nil},
+	{"quality.interval", "", false, func(c *Config) interface{} { return &c.Quality.Interval }, positive(func(c *Config) time.Duration { return c.Quality.Interval })},
+	{"quality.window", "", false, func(c *Config) interface{} { return &c.Quality.Window }, func(c *Config) error {
+		if c.Quality.Window < 1 {
+			return errors.New("must be at least 1")
+		}
+		return nil
+	}},
+	{"quality.target", "", false, func(c *Config) interface{} { return &c.Quality.Target }, func(c *Config) error {
+		if net.ParseIP(c.Quality.Target).To4() == nil {
+			return errors.New("want an IPv4 address")
+		}
+		return nil
+	}},
+	{"quality.latency_threshold", "", true, func(c *Config) interface{} { return &c.Quality.LatencyThreshold }, positive(func(c *Config) time.Duration { return c.Quality.LatencyThreshold })},
+	{"quality.loss_threshold", "", true, func(c *Config) interface{} { return &c.Quality.LossThreshold }, func(c *Config) error {
+		if c.Quality.LossThreshold < 1 || c.Quality.LossThreshold > 100 {
+			return errors.New("must be between 1 and 100")
+		}
+		return nil
+	}},
+
+	{"interfaces.ignore", "ignore-interfaces", false, func(c *Config) interface{} { return &c.Interfaces.Ignore }, globs(func(c *Config) string { return c.Interfaces.Ignore })},
+	{"interfaces.include", "include-interfaces", false, func(c *Config) interface{} { return &c.Interfaces.Include }, globs(func(c *Config) string { return c.Interfaces.Include })},
+}
+
+// List splits a comma-separated value, dropping empty entries
+func List(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// globs rejects malformed shell patterns in a comma-separated list
+func globs(get func(c *Config) string) func(c *Config) error {
+	return func(c *Config) error {
+		for _, p := range List(get(c)) {
+			if _, err := filepath.Match(p, ""); err != nil {
+				return fmt.Errorf("bad pattern %q", p)
+			}
+		}
+		return nil
+	}
+}
+
+// positive rejects zero and negative durations
+func positive(get func(c *Config) time.Duration) func(c *Config) error {
+	return func(c *Config) error {
+		if get(c) <= 0 {
+			return errors.New("must be positive")
+		}
+		return nil
+	}
+}
+
+// nonNegative rejects negative values
+func nonNegative(get func(c *Config) int) func(c *Config) error {
+	return func(c *Config) error {
+		if get(c) < 0 {
+			return errors.New("must not be negative")
+		}
+		return nil
+	}
+}
+
+// lookup finds a key by its "section.name"
+func lookup(name string) (key, bool) {
+	for _, k := range keys {
+		if k.name == name {
+			return k, true
+		}
+	}
+	return key{}, false
+}
+
+// Set parses value into the key name ("section.name")
+// The error names the key, so callers only need to add where it came from
+func (c *Config) Set(name, value string) error {
+	k, ok := lookup(name)
+	if !ok {
+		return fmt.Errorf("unknown key %s", name)
+	}
+
+	var err error
+	switch p := k.field(c).(type) {
+	case *string:
+		*p = value
+	case *bool:
+		*p, err = strconv.ParseBool(value)
+	case *int:
+		*p, err = strconv.Atoi(value)
+	case *float64:
+		*p, err = strconv.ParseFloat(value, 64)
+	case *time.Duration:
+		*p, err = time.ParseDuration(value)
+	}
+	if err == nil && k.check != nil {
+		err = k.check(c)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: invalid value %q: %v", name, value, err)
+	}
+	return nil
+}
+
+// value returns a key's current value as text (for comparing configs)
+func (c *Config) value(k key) string {
+	switch p := k.field(c).(type) {
+	case *string:
+		return *p
+	case *bool:
+		return strconv.FormatBool(*p)
+	case *int:
+		return strconv.Itoa(*p)
+	case *float64:
+		return strconv.FormatFloat(*p, 'g', -1, 64)
+	case *time.Duration:
+		return p.String()
+	}
+	return ""
+}
+
+// Paths returns the files Load reads, lowest precedence first: the system
+// file, then the user's. An explicit path (-config) replaces both
+func Paths(explicit string) []string {
+	if explicit != "" {
+		return []string{explicit}
+	}
+	paths := []string{SystemPath}
+	if dir, err := os.UserConfigDir(); err == nil {
+		paths = append(paths, filepath.Join(dir, "x-network", "config"))
+	}
+	return paths
+}
+
+// Load applies each file in paths over the defaults, later files winning
+// Missing files are skipped unless named explicitly (required)
+func Load(paths []string, required bool) (Config, error) {
+	c := Default()
+	for _, path := range paths {
+		if err := c.loadFile(path); err != nil {
+			if errors.Is(err, os.ErrNotExist) && !required {
+				continue
+			}
+			return Default(), err
+		}
+	}
+	return c, nil
+}
+
+// loadFile parses one INI file: [section] headers, key = value lines,
+// # and ; comments. Values may be quoted
+func (c *Config) loadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	section := ""
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || text[0] == '#' || text[0] == ';' {
+			continue
+		}
+		if strings.HasPrefix(text, "[") && strings.HasSuffix(text, "]") {
+			section = strings.ToLower(strings.TrimSpace(text[1 : len(text)-1]))
+			continue
+		}
+
+		name, value, ok := strings.Cut(text, "=")
+		if !ok {
+			return fmt.Errorf("%s:%d: expected key = value", path, line)
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		if section != "" {
+			name = section + "." + name
+		}
+		value = strings.TrimSpace(value)
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+		if err := c.Set(name, value); err != nil {
+			return fmt.Errorf("%s:%d: %v", path, line, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// ApplyFlags overrides keys with the flags set explicitly on fs
+func (c *Config) ApplyFlags(fs *flag.FlagSet) error {
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		for _, k := range keys {
+			if k.flag == f.Name && err == nil {
+				if e := c.Set(k.name, f.Value.String()); e != nil {
+					err = fmt.Errorf("-%s: %v", f.Name, e)
+				}
+			}
+		}
+	})
+	return err
+}
+
+// CopyReloadable copies the keys applied at runtime from src into dst;
+// keys that need a restart keep dst's value
+func CopyReloadable(dst, src *Config) {
+	for _, k := range keys {
+		if !k.reload {
+			continue
+		}
+		switch p := k.field(dst).(type) {
+		case *string:
+			*p = *k.field(src).(*string)
+		case *bool:
+			*p = *k.field(src).(*bool)
+		case *int:
+			*p = *k.field(src).(*int)
+		case *float64:
+			*p = *k.field(src).(*float64)
+		case *time.Duration:
+			*p = *k.field(src).(*time.Duration)
+		}
+	}
+}
+
+// Diff compares two configs and splits the changed keys into those applied
+// at runtime and those that need a restart, both sorted
+func Diff(old, new *Config) (reloaded, restart []string) {
+	for _, k := range keys {
+		if old.value(k) == new.value(k) {
+			continue
+		}
+		if k.reload {
+			reloaded = append(reloaded, k.name)
+		} else {
+			restart = append(restart, k.name)
+		}
+	}
+	sort.Strings(reloaded)
+	sort.Strings(restart)
+	return reloaded, restart
+}
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCopyReloadable(t *testing.T) {
+	running := Default()
+	next := Default()
+	next.WiFi.ScanTimeout = 30 * time.Second // Reloadable
+	next.WiFi.MergeBSS = !running.WiFi.MergeBSS
+	next.Hotspot.Subnet = "10.99.0.1/24" // Needs a restart
+	next.General.HooksDir = "/tmp/hooks.d"
+
+	reloaded, restart := Diff(&running, &next)
+	if want := []string{"wifi.merge_bss", "wifi.scan_timeout"}; !reflect.DeepEqual(reloaded, want) {
+		t.Errorf("reloaded = %v, want %v", reloaded, want)
+	}
+	if want := []string{"general.hooks_dir", "hotspot.subnet"}; !reflect.DeepEqual(restart, want) {
+		t.Errorf("restart = %v, want %v", restart, want)
+	}
+
+	CopyReloadable(&running, &next)
+	if running.WiFi.ScanTimeout != next.WiFi.ScanTimeout || running.WiFi.MergeBSS != next.WiFi.MergeBSS {
+		t.Error("reloadable keys not copied")
+	}
+	if running.Hotspot.Subnet == next.Hotspot.Subnet || running.General.HooksDir == next.General.HooksDir {
+		t.Error("restart-only keys replaced")
+	}
+
+	// The next reload still reports the keys waiting for a restart
+	reloaded, restart = Diff(&running, &next)
+	if len(reloaded) != 0 || len(restart) != 2 {
+		t.Errorf("second reload: reloaded %v, restart %v; want only the 2 restart keys", reloaded, restart)
+	}
+}
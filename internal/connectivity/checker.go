@@ -0,0 +1,358 @@
+package connectivity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"x-network/internal/state"
+
+	"github.com/jsimonetti/rtnetlink"
+	"github.com/mdlayher/netlink"
+)
+
+const probeTimeout = 5 * time.Second
+
+// Re-check schedule after each check; the last step repeats
+var backoffSchedule = []time.Duration{30 * time.Second, 2 * time.Minute, 10 * time.Minute}
+
+// Checker periodically verifies internet reachability over the default route
+type Checker struct {
+	stateMgr *state.Manager
+	portal   *Portal
+	trigger  chan struct{}
+	stopCh   chan struct{}
+
+	// Soft USB fallback (opt-in); the switch is flipped by config reloads,
+	// the route is only touched from the Run goroutine
+	softFallback atomic.Bool
+	fallback     *fallbackRoute // Installed USB preference route (nil = none)
+
+	keyMu   sync.Mutex
+	lastKey string // Link state at the last trigger (see observe)
+}
+
+// NewChecker creates a connectivity checker probing with portal
+func NewChecker(stateMgr *state.Manager, portal *Portal) *Checker {
+	c := &Checker{
+		stateMgr: stateMgr,
+		portal:   portal,
+		trigger:  make(chan struct{}, 1),
+		stopCh:   make(chan struct{}),
+	}
+	stateMgr.AddListener(c.observe)
+	return c
+}
+
+// observe re-checks when a link connects or drops, or its address, gateway
+// or lease changes (a DHCP renew moves the lease expiry)
+func (c *Checker) observe(st *state.State) {
+	key := checkKey(st)
+
+	c.keyMu.Lock()
+	changed := key != c.lastKey
+	c.lastKey = key
+	c.keyMu.Unlock()
+
+	if changed {
+		// Give DHCP/DNS a moment to settle before probing
+		time.AfterFunc(2*time.Second, c.Trigger)
+	}
+}
+
+// checkKey covers the state a connectivity result depends on
+func checkKey(st *state.State) string {
+	return fmt.Sprint(st.ConnectionState, st.UsbTetheringConnected, st.BtTetheringConnected,
+		st.EthernetCablePlugged, st.IpAddress, st.Gateway, st.DhcpLeaseExpiry.UnixNano())
+}
+
+// Trigger requests an immediate re-check (non-blocking)
+func (c *Checker) Trigger() {
+	select {
+	case c.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Close stops the checker
+func (c *Checker) Close() {
+	close(c.stopCh)
+}
+
+// Run checks now, then on the backoff schedule and whenever routes change
+func (c *Checker) Run() {
+	go c.watchRoutes()
+
+	step := 0
+	for {
+		changed := c.Check()
+		if changed {
+			step = 0 // Result moved - look again soon
+		}
+
+		timer := time.NewTimer(backoffSchedule[step])
+		select {
+		case <-c.stopCh:
+			timer.Stop()
+			c.revertFallback()
+			return
+		case <-c.trigger:
+			timer.Stop()
+			step = 0
+		case <-timer.C:
+			if step < len(backoffSchedule)-1 {
+				step++
+			}
+		}
+	}
+}
+
+// Check probes every interface with a default route and stores the results
+// The preferred route's result drives Connectivity and HasInternet; reports
+// whether either changed
+func (c *Checker) Check() bool {
+	st := c.stateMgr.Get()
+
+	ifaces, err := defaultRouteInterfaces()
+	perIface := make(map[string]string, len(ifaces))
+	for _, name := range ifaces {
+		perIface[name] = ""
+	}
+
+	var iface, result, portalURL, endpoint, stage string
+	internet := false
+	switch {
+	case err != nil || len(ifaces) == 0:
+		// No default route - an address alone is only local reachability
+		// (the same "limited" Detect reports when no probe is answered)
+		result = state.ConnectivityNone
+		if st.IpAddress != "" {
+			result = state.ConnectivityLimited
+		}
+	default:
+		iface = ifaces[0]
+		r := c.portal.Detect(iface)
+		result, portalURL, endpoint, stage = r.Connectivity, r.URL, r.Endpoint, r.Stage
+		perIface[iface] = r.Connectivity
+		for _, name := range ifaces[1:] {
+			perIface[name] = c.portal.Detect(name).Connectivity
+		}
+		internet = HasInternet(iface)
+	}
+
+	c.evaluateFallback(st, perIface)
+
+	perChanged := !sameConnectivity(perIface, st.InterfaceConnectivity)
+	internetChanged := internet != st.HasInternet
+	if internetChanged {
+		log.Printf("Internet check via %s: %v -> %v", iface, st.HasInternet, internet)
+	}
+	if result == st.Connectivity && (result != state.ConnectivityPortal || portalURL == st.CaptivePortalURL) {
+		if perChanged || internetChanged {
+			c.stateMgr.Update(func(st *state.State) {
+				st.InterfaceConnectivity = perIface
+				st.HasInternet = internet
+			})
+		}
+		return internetChanged
+	}
+
+	log.Printf("Connectivity check via %s: %s -> %s (per interface: %v)", iface, st.Connectivity, result, perIface)
+	c.stateMgr.Update(func(st *state.State) {
+		st.Connectivity = result
+		st.InterfaceConnectivity = perIface
+		st.HasInternet = internet
+		detected := result == state.ConnectivityPortal
+		if detected != st.CaptivePortalDetected || portalURL != st.CaptivePortalURL {
+			st.SetCaptiveResult(detected, portalURL, endpoint, stage)
+		}
+	})
+	return true
+}
+
+// sameConnectivity compares two per-interface result maps
+func sameConnectivity(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if w, ok := b[k]; !ok || w != v {
+			return false
+		}
+	}
+	return true
+}
+
+// boundDialer returns a dialer pinned to iface so a probe can't leak out over
+// another link; an empty iface follows the routing table
+// SO_BINDTODEVICE needs CAP_NET_RAW - without it the interface's source address is used
+func boundDialer(iface string) *net.Dialer {
+	if iface == "" {
+		return &net.Dialer{Timeout: probeTimeout}
+	}
+
+	d := &net.Dialer{Timeout: probeTimeout}
+	if src := sourceAddress(iface); src != nil {
+		d.LocalAddr = &net.TCPAddr{IP: src}
+	}
+	bindDialer(d, iface)
+
+	// Name lookups go over iface too, or a portal's DNS answer could come from
+	// another link. A loopback stub (systemd-resolved, dnsmasq) can't be
+	// reached through iface: queries to it stay unbound and the stub picks
+	// the upstream link itself
+	d.Resolver = &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			host, _, _ := net.SplitHostPort(address)
+			server := net.ParseIP(host)
+			dns := &net.Dialer{Timeout: probeTimeout}
+			if server != nil && server.IsLoopback() {
+				return dns.DialContext(ctx, network, address)
+			}
+			if src := sourceAddress(iface); src != nil && server != nil && server.To4() != nil {
+				if strings.HasPrefix(network, "udp") {
+					dns.LocalAddr = &net.UDPAddr{IP: src}
+				} else {
+					dns.LocalAddr = &net.TCPAddr{IP: src}
+				}
+			}
+			return bindDialer(dns, iface).DialContext(ctx, network, address)
+		},
+	}
+	return d
+}
+
+// bindDialer pins d to iface with SO_BINDTODEVICE; without the capability
+// d.LocalAddr (if set) is the fallback
+func bindDialer(d *net.Dialer, iface string) *net.Dialer {
+	d.Control = func(network, address string, rc syscall.RawConn) error {
+		var sockErr error
+		err := rc.Control(func(fd uintptr) {
+			sockErr = syscall.BindToDevice(int(fd), iface)
+		})
+		if err != nil {
+			return err
+		}
+		if sockErr == syscall.EPERM && d.LocalAddr != nil {
+			return nil // Fall back to source-address binding
+		}
+		return sockErr
+	}
+	return d
+}
+
+// sourceAddress returns the first IPv4 address on iface
+func sourceAddress(iface string) net.IP {
+	link, err := net.InterfaceByName(iface)
+	if err != nil {
+		return nil
+	}
+	addrs, err := link.Addrs()
+	if err != nil {
+		return nil
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.To4() != nil {
+			return ipNet.IP
+		}
+	}
+	return nil
+}
+
+// DefaultRouteInterface returns the interface of the best IPv4 default route
+func DefaultRouteInterface() (string, error) {
+	ifaces, err := defaultRouteInterfaces()
+	if err != nil {
+		return "", err
+	}
+	return ifaces[0], nil
+}
+
+// defaultRouteInterfaces returns every interface with an IPv4 default route,
+// best (lowest metric) first
+func defaultRouteInterfaces() ([]string, error) {
+	conn, err := rtnetlink.Dial(nil)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	routes, err := conn.Route.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var defaults []rtnetlink.RouteMessage
+	for _, r := range routes {
+		if r.Family != syscall.AF_INET || r.DstLength != 0 || r.Attributes.Gateway == nil {
+			continue
+		}
+		if r.Table != syscall.RT_TABLE_MAIN && r.Attributes.Table != syscall.RT_TABLE_MAIN {
+			continue
+		}
+		defaults = append(defaults, r)
+	}
+	sort.SliceStable(defaults, func(i, j int) bool {
+		return defaults[i].Attributes.Priority < defaults[j].Attributes.Priority
+	})
+
+	var names []string
+	seen := make(map[uint32]bool)
+	for _, r := range defaults {
+		if seen[r.Attributes.OutIface] {
+			continue
+		}
+		seen[r.Attributes.OutIface] = true
+		if link, err := net.InterfaceByIndex(int(r.Attributes.OutIface)); err == nil {
+			names = append(names, link.Name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, errors.New("no default route")
+	}
+	return names, nil
+}
+
+// watchRoutes triggers a re-check on every IPv4 route change
+func (c *Checker) watchRoutes() {
+	conn, err := netlink.Dial(syscall.NETLINK_ROUTE, &netlink.Config{
+		Groups: 0x40, // RTMGRP_IPV4_ROUTE
+	})
+	if err != nil {
+		log.Printf("Connectivity: cannot watch routes: %v", err)
+		return
+	}
+	go func() {
+		<-c.stopCh
+		conn.Close()
+	}()
+
+	for {
+		msgs, err := conn.Receive()
+		if err != nil {
+			select {
+			case <-c.stopCh:
+				return
+			default:
+			}
+			log.Printf("Connectivity: route watch error: %v", err)
+			return
+		}
+		for _, msg := range msgs {
+			if msg.Header.Type == syscall.RTM_NEWROUTE || msg.Header.Type == syscall.RTM_DELROUTE {
+				// Give DHCP/DNS a moment to settle before probing
+				time.AfterFunc(2*time.Second, c.Trigger)
+				break
+			}
+		}
+	}
+}
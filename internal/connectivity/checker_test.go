@@ -0,0 +1,43 @@
+package connectivity
+
+import (
+	"testing"
+	"time"
+
+	"x-network/internal/state"
+)
+
+func TestCheckKeyTriggers(t *testing.T) {
+	base := state.State{
+		ConnectionState: state.StateConnected,
+		IpAddress:       "192.168.1.10",
+		Gateway:         "192.168.1.1",
+		DhcpLeaseExpiry: time.Unix(1000, 0),
+	}
+	tests := []struct {
+		name   string
+		change func(st *state.State)
+	}{
+		{"connect", func(st *state.State) { st.ConnectionState = state.StateObtaining }},
+		{"new address", func(st *state.State) { st.IpAddress = "192.168.1.11" }},
+		{"new gateway", func(st *state.State) { st.Gateway = "192.168.1.254" }},
+		{"lease renewed", func(st *state.State) { st.DhcpLeaseExpiry = time.Unix(2000, 0) }},
+		{"cable", func(st *state.State) { st.EthernetCablePlugged = true }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := base
+			tt.change(&st)
+			if checkKey(&st) == checkKey(&base) {
+				t.Errorf("%s doesn't trigger a check", tt.name)
+			}
+		})
+	}
+
+	// Unrelated updates must not cause probes
+	st := base
+	st.SignalStrength = 40
+	if checkKey(&st) != checkKey(&base) {
+		t.Error("signal change triggers a check")
+	}
+}
@@ -0,0 +1,152 @@
+package connectivity
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"syscall"
+
+	"x-network/internal/netlink"
+	"x-network/internal/state"
+
+	"github.com/jsimonetti/rtnetlink"
+)
+
+// fallbackRoute is the extra USB default route installed by the soft fallback
+type fallbackRoute struct {
+	index  uint32
+	gw     net.IP
+	metric uint32
+}
+
+// SetSoftFallback enables preferring USB tethering while WiFi is connected
+// but fails the internet check; the switch reverts when WiFi recovers.
+// Safe to call while Run is active: disabling reverts an installed route
+// on the next check
+func (c *Checker) SetSoftFallback(enabled bool) {
+	if c.softFallback.Swap(enabled) != enabled && !enabled {
+		c.Trigger()
+	}
+}
+
+// evaluateFallback switches the default route to USB or back after a check
+func (c *Checker) evaluateFallback(st state.State, perIface map[string]string) {
+	if !c.softFallback.Load() {
+		if c.fallback != nil {
+			c.revertFallback() // Disabled by a config reload
+			c.Trigger()
+		}
+		return
+	}
+
+	wifiResult, wifiProbed := perIface[st.InterfaceName]
+	want := st.ConnectionState == state.StateConnected &&
+		st.UsbTetheringConnected &&
+		wifiProbed && wifiResult != state.ConnectivityFull &&
+		perIface[st.UsbInterfaceName] == state.ConnectivityFull
+
+	switch {
+	case want && c.fallback == nil:
+		route, err := installFallbackRoute(st.InterfaceName, st.UsbInterfaceName)
+		if err != nil {
+			log.Printf("Soft fallback: cannot prefer %s: %v", st.UsbInterfaceName, err)
+			return
+		}
+		c.fallback = route
+		log.Printf("Soft fallback: WiFi %s has %s connectivity, preferring USB %s (metric %d)",
+			st.InterfaceName, wifiResult, st.UsbInterfaceName, route.metric)
+		c.stateMgr.Update(func(st *state.State) {
+			st.UsbFallbackActive = true
+		})
+		c.Trigger() // Re-evaluate Connectivity over the new default route
+
+	case !want && c.fallback != nil:
+		c.revertFallback()
+		c.Trigger()
+	}
+}
+
+// revertFallback removes the USB preference route, if installed
+func (c *Checker) revertFallback() {
+	if c.fallback == nil {
+		return
+	}
+	route := c.fallback
+	c.fallback = nil
+
+	conn, err := rtnetlink.Dial(nil)
+	if err == nil {
+		err = netlink.DelDefaultRoute(conn, route.index, route.gw, route.metric)
+		conn.Close()
+	}
+	// ESRCH: the kernel already dropped it with the USB link
+	if err != nil && !errors.Is(err, syscall.ESRCH) {
+		log.Printf("Soft fallback: failed to remove USB route: %v", err)
+	} else {
+		log.Printf("Soft fallback: reverted to WiFi")
+	}
+	c.stateMgr.Update(func(st *state.State) {
+		st.UsbFallbackActive = false
+	})
+}
+
+// installFallbackRoute adds a USB default route with a lower metric than WiFi's
+// The DHCP-installed routes are left untouched so reverting is a single delete
+func installFallbackRoute(wifiName, usbName string) (*fallbackRoute, error) {
+	wifi, err := net.InterfaceByName(wifiName)
+	if err != nil {
+		return nil, err
+	}
+	usb, err := net.InterfaceByName(usbName)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := rtnetlink.Dial(nil)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	routes, err := conn.Route.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var wifiMetric, usbMetric uint32
+	var usbGw net.IP
+	wifiFound := false
+	for _, r := range routes {
+		if r.Family != syscall.AF_INET || r.DstLength != 0 || r.Attributes.Gateway == nil {
+			continue
+		}
+		switch r.Attributes.OutIface {
+		case uint32(wifi.Index):
+			if !wifiFound || r.Attributes.Priority < wifiMetric {
+				wifiMetric = r.Attributes.Priority
+				wifiFound = true
+			}
+		case uint32(usb.Index):
+			if usbGw == nil || r.Attributes.Priority < usbMetric {
+				usbMetric = r.Attributes.Priority
+				usbGw = r.Attributes.Gateway
+			}
+		}
+	}
+	if usbGw == nil {
+		return nil, fmt.Errorf("no default route via %s", usbName)
+	}
+	if !wifiFound || usbMetric < wifiMetric {
+		return nil, errors.New("USB is already preferred")
+	}
+	if wifiMetric == 0 {
+		return nil, fmt.Errorf("WiFi default route has metric 0, nothing lower to use")
+	}
+
+	route := &fallbackRoute{index: uint32(usb.Index), gw: usbGw, metric: wifiMetric - 1}
+	if err := netlink.ReplaceDefaultRoute(conn, route.index, route.gw, route.metric); err != nil {
+		return nil, err
+	}
+	return route, nil
+}
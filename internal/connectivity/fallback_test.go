@@ -0,0 +1,57 @@
+package connectivity
+
+import (
+	"net"
+	"sync"
+	"testing"
+
+	"x-network/internal/state"
+)
+
+func TestSetSoftFallbackConcurrent(t *testing.T) {
+	c := NewChecker(state.NewManager(), nil)
+	// Disconnected: evaluateFallback never wants the USB route
+	st := state.State{ConnectionState: state.StateDisconnected, InterfaceName: "wlan0", UsbInterfaceName: "usb0"}
+	perIface := map[string]string{"wlan0": state.ConnectivityLimited, "usb0": state.ConnectivityFull}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			c.SetSoftFallback(i%2 == 0) // Config reloads
+		}
+	}()
+	for i := 0; i < 1000; i++ {
+		c.evaluateFallback(st, perIface) // Run goroutine
+	}
+	wg.Wait()
+
+	if c.fallback != nil {
+		t.Fatal("fallback route installed while disconnected")
+	}
+}
+
+func TestDisableSoftFallbackReverts(t *testing.T) {
+	mgr := state.NewManager()
+	mgr.Update(func(st *state.State) { st.UsbFallbackActive = true })
+	c := NewChecker(mgr, nil)
+	c.SetSoftFallback(true)
+	// A route nothing matches: the delete fails with ESRCH and is ignored
+	c.fallback = &fallbackRoute{gw: net.IPv4(192, 0, 2, 1), metric: 65000}
+
+	c.SetSoftFallback(false)
+	select {
+	case <-c.trigger:
+	default:
+		t.Error("disabling didn't trigger a check")
+	}
+	c.evaluateFallback(mgr.Get(), nil)
+
+	if c.fallback != nil {
+		t.Error("fallback route kept after disabling")
+	}
+	if mgr.Get().UsbFallbackActive {
+		t.Error("UsbFallbackActive still set after disabling")
+	}
+}
@@ -0,0 +1,33 @@
+package connectivity
+
+import (
+	"io"
+	"net/http"
+)
+
+// InternetProbeURL answers 204 with an empty body when the internet is reachable
+// Kept separate from the portal endpoints so HasInternet means exactly one thing
+const InternetProbeURL = "http://connectivitycheck.gstatic.com/generate_204"
+
+// HasInternet fetches InternetProbeURL over iface ("" = routing table) and
+// reports whether the real 204 came back; portals, redirects and timeouts fail
+func HasInternet(iface string) bool {
+	client := &http.Client{
+		Timeout: probeTimeout,
+		Transport: &http.Transport{
+			DialContext:       boundDialer(iface).DialContext,
+			DisableKeepAlives: true,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	resp, err := client.Get(InternetProbeURL)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return resp.StatusCode == http.StatusNoContent && len(body) == 0
+}
@@ -0,0 +1,254 @@
+package connectivity
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"x-network/internal/state"
+)
+
+// Probe stages reported with a portal detection
+const (
+	StageHTTP  = "http"  // Plain HTTP answer was redirected or rewritten
+	StageHTTPS = "https" // HTTP looked fine but the validation URL failed its certificate check or was redirected
+)
+
+// Endpoint is a plain-HTTP probe with a known answer
+type Endpoint struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"status"`         // Expected status
+	Body       string `json:"body,omitempty"` // Expected body substring ("" = don't check)
+}
+
+// PortalConfig is the probe configuration (persisted as JSON)
+type PortalConfig struct {
+	Endpoints []Endpoint `json:"endpoints"`
+	HTTPSURL  string     `json:"https_url"` // Certificate must validate for "full" ("" = skip stage)
+}
+
+// DefaultPortalConfig mirrors what browsers use for portal detection
+var DefaultPortalConfig = PortalConfig{
+	Endpoints: []Endpoint{
+		{URL: "http://detectportal.firefox.com/success.txt", StatusCode: 200, Body: "success"},
+		{URL: "http://www.gstatic.com/generate_204", StatusCode: 204},
+		{URL: "http://captive.apple.com/hotspot-detect.html", StatusCode: 200, Body: "Success"},
+	},
+	HTTPSURL: "https://www.gstatic.com/generate_204",
+}
+
+// Portal holds the probe configuration and runs captive portal detection
+type Portal struct {
+	mu   sync.RWMutex
+	cfg  PortalConfig
+	path string // Where Set persists the configuration ("" = not persisted)
+}
+
+// NewPortal creates a detector with the endpoints stored at path (see Load)
+// On a load error the returned detector is still usable, with the defaults
+func NewPortal(path string) (*Portal, error) {
+	p := &Portal{cfg: DefaultPortalConfig}
+	return p, p.Load(path)
+}
+
+// Result is the outcome of a portal/connectivity probe
+type Result struct {
+	Connectivity string // state.Connectivity* value
+	URL          string // Portal login URL (redirect target or probed URL)
+	Endpoint     string // Endpoint that triggered the verdict
+	Stage        string // StageHTTP or StageHTTPS when a portal was detected
+}
+
+// Detected reports whether the result indicates a captive portal
+func (r Result) Detected() bool {
+	return r.Connectivity == state.ConnectivityPortal
+}
+
+// PortalConfigPath returns ~/.config/x-network/portal.json
+func PortalConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = "/etc"
+	}
+	return filepath.Join(dir, "x-network", "portal.json")
+}
+
+// Load reads endpoints from path and persists later changes there
+// A missing file (or "" path) keeps the current configuration
+func (p *Portal) Load(path string) error {
+	p.mu.Lock()
+	p.path = path
+	p.mu.Unlock()
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var cfg PortalConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.cfg = cfg
+	p.mu.Unlock()
+	return nil
+}
+
+// Set validates, applies and persists a new probe configuration
+func (p *Portal) Set(cfg PortalConfig) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.cfg = cfg
+	path := p.path
+	p.mu.Unlock()
+
+	if path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Config returns the active probe configuration
+func (p *Portal) Config() PortalConfig {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.cfg
+}
+
+// Validate checks URLs and expected status codes
+func (c *PortalConfig) Validate() error {
+	if len(c.Endpoints) == 0 {
+		return errors.New("at least one endpoint required")
+	}
+	for i := range c.Endpoints {
+		ep := &c.Endpoints[i]
+		u, err := url.Parse(ep.URL)
+		if err != nil || u.Scheme != "http" || u.Host == "" {
+			return fmt.Errorf("endpoint must be a plain http URL: %q", ep.URL)
+		}
+		if ep.StatusCode == 0 {
+			ep.StatusCode = 200
+		}
+		if ep.StatusCode < 100 || ep.StatusCode > 599 {
+			return fmt.Errorf("invalid status %d for %s", ep.StatusCode, ep.URL)
+		}
+	}
+	if c.HTTPSURL != "" {
+		if u, err := url.Parse(c.HTTPSURL); err != nil || u.Scheme != "https" || u.Host == "" {
+			return fmt.Errorf("validation URL must be https: %q", c.HTTPSURL)
+		}
+	}
+	return nil
+}
+
+// Detect runs the HTTP stage, then the HTTPS validation stage, over iface
+// ("" = whatever the routing table picks)
+// HTTP: matching answer passes, redirect or wrong answer is a portal, no answer is "limited"
+// HTTPS: a certificate that doesn't validate, a redirect or an unexpected
+// status after a clean HTTP answer means interception; no answer is "limited"
+func (p *Portal) Detect(iface string) Result {
+	dialer := boundDialer(iface)
+	client := &http.Client{
+		Timeout: probeTimeout,
+		Transport: &http.Transport{
+			DialContext:       dialer.DialContext,
+			DisableKeepAlives: true,
+			TLSClientConfig:   &tls.Config{}, // System roots, full verification
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	return detect(client, p.Config())
+}
+
+// detect runs both stages with client, which must not follow redirects
+func detect(client *http.Client, cfg PortalConfig) Result {
+	answered := ""
+	for _, ep := range cfg.Endpoints {
+		resp, err := client.Get(ep.URL)
+		if err != nil {
+			continue
+		}
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		resp.Body.Close()
+
+		if resp.StatusCode >= 300 && resp.StatusCode < 400 {
+			return Result{state.ConnectivityPortal, resp.Header.Get("Location"), ep.URL, StageHTTP}
+		}
+		if resp.StatusCode != ep.StatusCode || (ep.Body != "" && !strings.Contains(string(body), ep.Body)) {
+			// Answered, but not with the known response - intercepted
+			return Result{state.ConnectivityPortal, ep.URL, ep.URL, StageHTTP}
+		}
+		answered = ep.URL
+		break
+	}
+	if answered == "" {
+		return Result{Connectivity: state.ConnectivityLimited}
+	}
+
+	if cfg.HTTPSURL != "" {
+		resp, err := client.Get(cfg.HTTPSURL)
+		if err != nil {
+			log.Printf("Connectivity: HTTPS validation via %s failed: %v", cfg.HTTPSURL, err)
+			if isCertificateError(err) {
+				return Result{state.ConnectivityPortal, answered, cfg.HTTPSURL, StageHTTPS}
+			}
+			// Timeout, reset, DNS: the validation host is out of reach, not intercepted
+			return Result{Connectivity: state.ConnectivityLimited, Endpoint: answered}
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode >= 300 && resp.StatusCode < 400 {
+			return Result{state.ConnectivityPortal, resp.Header.Get("Location"), cfg.HTTPSURL, StageHTTPS}
+		}
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			log.Printf("Connectivity: HTTPS validation via %s answered %d", cfg.HTTPSURL, resp.StatusCode)
+			return Result{state.ConnectivityPortal, answered, cfg.HTTPSURL, StageHTTPS}
+		}
+	}
+
+	return Result{Connectivity: state.ConnectivityFull, Endpoint: answered}
+}
+
+// isCertificateError reports whether err is a failed certificate check, the
+// sign of a middlebox answering for the validation host
+func isCertificateError(err error) bool {
+	var verifyErr *tls.CertificateVerificationError
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	return errors.As(err, &verifyErr) || errors.As(err, &authorityErr) ||
+		errors.As(err, &hostnameErr) || errors.As(err, &invalidErr)
+}
@@ -0,0 +1,112 @@
+package connectivity
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"x-network/internal/state"
+)
+
+// probeClient returns a non-redirecting client trusting roots (nil = system roots)
+func probeClient(roots *x509.CertPool) *http.Client {
+	return &http.Client{
+		Timeout: 2 * time.Second,
+		Transport: &http.Transport{
+			DisableKeepAlives: true,
+			TLSClientConfig:   &tls.Config{RootCAs: roots},
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// closedURL returns a URL nothing listens on
+func closedURL(t *testing.T, scheme string) string {
+	t.Helper()
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	return scheme + "://" + srv.Listener.Addr().String() + "/"
+}
+
+func TestDetect(t *testing.T) {
+	probe := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer probe.Close()
+
+	validation := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/redirect":
+			http.Redirect(w, r, "http://portal.example/login", http.StatusFound)
+		case "/forbidden":
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer validation.Close()
+	trusted := x509.NewCertPool()
+	trusted.AddCert(validation.Certificate())
+
+	cfg := func(httpsURL string) PortalConfig {
+		return PortalConfig{
+			Endpoints: []Endpoint{{URL: probe.URL, StatusCode: http.StatusNoContent}},
+			HTTPSURL:  httpsURL,
+		}
+	}
+	tests := []struct {
+		name      string
+		roots     *x509.CertPool
+		cfg       PortalConfig
+		want      string
+		wantStage string
+	}{
+		{"validated", trusted, cfg(validation.URL), state.ConnectivityFull, ""},
+		{"https stage skipped", nil, cfg(""), state.ConnectivityFull, ""},
+		{"untrusted certificate", x509.NewCertPool(), cfg(validation.URL), state.ConnectivityPortal, StageHTTPS},
+		{"https redirected", trusted, cfg(validation.URL + "/redirect"), state.ConnectivityPortal, StageHTTPS},
+		{"https unexpected status", trusted, cfg(validation.URL + "/forbidden"), state.ConnectivityPortal, StageHTTPS},
+		{"https unreachable", trusted, cfg(closedURL(t, "https")), state.ConnectivityLimited, ""},
+		{"http unreachable", trusted, PortalConfig{Endpoints: []Endpoint{{URL: closedURL(t, "http"), StatusCode: 204}}}, state.ConnectivityLimited, ""},
+		{"http rewritten", trusted, PortalConfig{Endpoints: []Endpoint{{URL: probe.URL, StatusCode: 200}}}, state.ConnectivityPortal, StageHTTP},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := detect(probeClient(tt.roots), tt.cfg)
+			if got.Connectivity != tt.want || got.Stage != tt.wantStage {
+				t.Fatalf("detect() = %+v, want %s (stage %q)", got, tt.want, tt.wantStage)
+			}
+		})
+	}
+}
+
+func TestPortalPersistsConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "portal.json")
+	p, err := NewPortal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := p.Config(); got.HTTPSURL != DefaultPortalConfig.HTTPSURL {
+		t.Fatalf("missing file: config = %+v, want the defaults", got)
+	}
+
+	cfg := PortalConfig{Endpoints: []Endpoint{{URL: "http://probe.example/", StatusCode: 204}}}
+	if err := p.Set(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	// A second instance (a restart) reads what the first one saved
+	again, err := NewPortal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := again.Config(); len(got.Endpoints) != 1 || got.Endpoints[0].URL != "http://probe.example/" || got.HTTPSURL != "" {
+		t.Errorf("reloaded config = %+v, want %+v", got, cfg)
+	}
+}
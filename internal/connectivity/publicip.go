@@ -0,0 +1,205 @@
+package connectivity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultPublicIPEndpoints answer a plain-text address over HTTPS; the
+// first one is IPv4-only, the fallback also answers over IPv6
+var DefaultPublicIPEndpoints = []string{"https://api.ipify.org", "https://icanhazip.com"}
+
+// publicIPTimeout bounds one address family across all endpoints
+const publicIPTimeout = 8 * time.Second
+
+// ErrPublicIPTimeout is returned when no endpoint answered in time
+var ErrPublicIPTimeout = errors.New("public IP lookup timed out")
+
+// PublicIPResolver looks up the public addresses through its endpoints
+type PublicIPResolver struct {
+	mu        sync.RWMutex
+	endpoints []string
+}
+
+// NewPublicIPResolver creates a resolver querying endpoints (see SetEndpoints)
+func NewPublicIPResolver(endpoints []string) (*PublicIPResolver, error) {
+	r := &PublicIPResolver{endpoints: DefaultPublicIPEndpoints}
+	return r, r.SetEndpoints(endpoints)
+}
+
+// PublicIP is the result of one lookup and where it was obtained
+type PublicIP struct {
+	IPv4      string // "" when no endpoint answered over IPv4
+	IPv6      string
+	Interface string
+	SSID      string
+	At        time.Time
+}
+
+// ValidatePublicIPEndpoints rejects anything but absolute https URLs
+func ValidatePublicIPEndpoints(endpoints []string) error {
+	if len(endpoints) == 0 {
+		return errors.New("no public IP endpoints")
+	}
+	for _, e := range endpoints {
+		u, err := url.Parse(e)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			return fmt.Errorf("public IP endpoint %q is not an https URL", e)
+		}
+	}
+	return nil
+}
+
+// SetEndpoints replaces the endpoints queried by Lookup; invalid ones keep
+// the current list
+func (r *PublicIPResolver) SetEndpoints(endpoints []string) error {
+	if err := ValidatePublicIPEndpoints(endpoints); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.endpoints = append([]string(nil), endpoints...)
+	r.mu.Unlock()
+	return nil
+}
+
+// Lookup asks the endpoints for the public addresses seen from iface,
+// once over IPv4 and once over IPv6. Only fails when both families fail;
+// ErrPublicIPTimeout if any endpoint timed out
+func (r *PublicIPResolver) Lookup(iface string) (PublicIP, error) {
+	r.mu.RLock()
+	endpoints := r.endpoints
+	r.mu.RUnlock()
+
+	result := PublicIP{Interface: iface}
+	var wg sync.WaitGroup
+	var err4, err6 error
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		result.IPv4, err4 = lookupFamily(iface, false, endpoints)
+	}()
+	go func() {
+		defer wg.Done()
+		result.IPv6, err6 = lookupFamily(iface, true, endpoints)
+	}()
+	wg.Wait()
+
+	result.At = time.Now()
+	if result.IPv4 != "" || result.IPv6 != "" {
+		return result, nil
+	}
+	if errors.Is(err4, ErrPublicIPTimeout) || errors.Is(err6, ErrPublicIPTimeout) {
+		return result, ErrPublicIPTimeout
+	}
+	return result, fmt.Errorf("public IP lookup failed: %v", err4)
+}
+
+// lookupFamily tries the endpoints in order over one address family
+func lookupFamily(iface string, v6 bool, endpoints []string) (string, error) {
+	dialer := publicIPDialer(iface, v6)
+	network := "tcp4"
+	if v6 {
+		network = "tcp6"
+	}
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, addr string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, addr)
+			},
+			DisableKeepAlives: true,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), publicIPTimeout)
+	defer cancel()
+
+	var lastErr error
+	for _, endpoint := range endpoints {
+		ip, err := fetchPublicIP(ctx, client, endpoint)
+		if err == nil && (ip.To4() != nil) != v6 {
+			return ip.String(), nil
+		}
+		if err == nil {
+			err = fmt.Errorf("%s answered %s over the wrong family", endpoint, ip)
+		}
+		if ctx.Err() != nil || isTimeout(err) {
+			return "", ErrPublicIPTimeout
+		}
+		lastErr = err
+	}
+	return "", lastErr
+}
+
+// fetchPublicIP reads one plain-text address from endpoint
+func fetchPublicIP(ctx context.Context, client *http.Client, endpoint string) (net.IP, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: HTTP %d", endpoint, resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 128))
+	if err != nil {
+		return nil, err
+	}
+	ip := net.ParseIP(strings.TrimSpace(string(body)))
+	if ip == nil {
+		return nil, fmt.Errorf("%s: no address in reply", endpoint)
+	}
+	return ip, nil
+}
+
+// publicIPDialer binds to iface like boundDialer, with a source address of
+// the requested family for the fallback
+func publicIPDialer(iface string, v6 bool) *net.Dialer {
+	d := boundDialer(iface)
+	if iface == "" || !v6 {
+		return d
+	}
+	d.LocalAddr = nil
+	if src := sourceAddress6(iface); src != nil {
+		d.LocalAddr = &net.TCPAddr{IP: src}
+	}
+	return d
+}
+
+// sourceAddress6 returns the first global IPv6 address on iface
+func sourceAddress6(iface string) net.IP {
+	link, err := net.InterfaceByName(iface)
+	if err != nil {
+		return nil
+	}
+	addrs, err := link.Addrs()
+	if err != nil {
+		return nil
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.To4() == nil && ipNet.IP.IsGlobalUnicast() {
+			return ipNet.IP
+		}
+	}
+	return nil
+}
+
+// isTimeout reports whether err is a network timeout
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -1,71 +1,79 @@
 package dbus
 
 import (
-	"io"
-	"net/http"
+	"errors"
+	"log"
 	"os/exec"
 	"strings"
 	"time"
+
+	"x-network/internal/iwd"
+	"x-network/internal/state"
+
+	"github.com/godbus/dbus/v5"
 )
 
-// setRfkill sets airplane mode via rfkill
-func setRfkill(block bool) error {
-	action := "unblock"
-	if block {
-		action = "block"
+// errorCode maps an operation error to a stable code for the Error signal
+// Conditions shared by all operations are detected here; otherwise fallback is used
+func errorCode(err error, fallback string) string {
+	var dbusErr dbus.Error
+	if errors.As(err, &dbusErr) {
+		switch dbusErr.Name {
+		case "org.freedesktop.DBus.Error.ServiceUnknown",
+			"org.freedesktop.DBus.Error.NameHasNoOwner",
+			iwd.IWDService + ".NotAvailable":
+			return state.ErrCodeIWDUnavailable
+		case iwd.IWDService + ".NotFound":
+			return state.ErrCodeNetworkNotFound
+		case iwd.IWDService + ".Timeout":
+			return state.ErrCodeTimeout
+		case iwd.IWDService + ".Busy":
+			return state.ErrCodeBusy
+		case iwd.IWDService + ".InProgress":
+			return state.ErrCodeInProgress
+		case iwd.IWDService + ".NotSupported":
+			return state.ErrCodeNotSupported
+		case iwd.IWDService + ".NotConnected":
+			return state.ErrCodeNotConnected
+		case iwd.IWDService + ".Aborted":
+			return state.ErrCodeAborted
+		}
 	}
-	cmd := exec.Command("rfkill", action, "all")
-	return cmd.Run()
-}
 
-// checkCaptivePortal checks for captive portal by HTTP probe
-func checkCaptivePortal() (detected bool, url string) {
-	// Use common captive portal detection endpoints
-	endpoints := []string{
-		"http://detectportal.firefox.com/success.txt",
-		"http://www.gstatic.com/generate_204",
-		"http://captive.apple.com/hotspot-detect.html",
+	switch {
+	case errors.Is(err, iwd.ErrNotReady):
+		return state.ErrCodeInitializing
+	case errors.Is(err, iwd.ErrIWDUnavailable):
+		return state.ErrCodeIWDUnavailable
 	}
 
-	client := &http.Client{
-		Timeout: 5 * time.Second,
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			// Capture redirect URL
-			url = req.URL.String()
-			return http.ErrUseLastResponse
-		},
+	msg := err.Error()
+	switch {
+	case strings.HasPrefix(msg, "network not found"), strings.HasPrefix(msg, "known network not found"):
+		return state.ErrCodeNetworkNotFound
+	case strings.HasPrefix(msg, "no WiFi station found"):
+		return state.ErrCodeIWDUnavailable
 	}
+	return fallback
+}
 
-	for _, endpoint := range endpoints {
-		resp, err := client.Get(endpoint)
-		if err != nil {
-			continue
-		}
-		defer resp.Body.Close()
-
-		// Check for redirect (captive portal)
-		if resp.StatusCode == 302 || resp.StatusCode == 301 {
-			return true, url
-		}
-
-		// Check content for Firefox endpoint
-		if strings.Contains(endpoint, "firefox") {
-			body, _ := io.ReadAll(resp.Body)
-			if !strings.Contains(string(body), "success") {
-				return true, endpoint
-			}
-		}
-
-		// Check for 204 (Google endpoint)
-		if strings.Contains(endpoint, "generate_204") && resp.StatusCode != 204 {
-			return true, endpoint
-		}
+// iwdReadyWait is how long a method call waits for IWD to finish initializing
+const iwdReadyWait = 3 * time.Second
 
-		// Got expected response - no captive portal
-		return false, ""
+// requireIWD returns a D-Bus error unless the IWD client can take calls
+// While IWD (re)starts, calls wait briefly and then fail with the retryable
+// Interface+".Error.Initializing" instead of a cryptic path error
+func (s *Service) requireIWD() *dbus.Error {
+	if s.iwd == nil {
+		return dbus.NewError(Interface+".Error", []interface{}{"IWD not available"})
 	}
-
-	return false, ""
+	switch err := s.iwd.WaitReady(iwdReadyWait); {
+	case errors.Is(err, iwd.ErrNotReady):
+		return dbus.NewError(Interface+".Error.Initializing", []interface{}{err.Error()})
+	case err != nil:
+		return dbus.NewError(Interface+".Error", []interface{}{err.Error()})
+	}
+	return nil
 }
 
 // openURL opens a URL in the default browser
@@ -79,3 +87,85 @@ func openURL(url string) error {
 	}
 	return nil
 }
+
+// Re-check cadence after the portal page was opened
+const (
+	captiveRecheckInterval = 10 * time.Second
+	captiveRecheckTimeout  = 5 * time.Minute
+)
+
+// recheckCaptivePortal probes until the portal lets us through, then clears the flag
+func (s *Service) recheckCaptivePortal() {
+	deadline := time.Now().Add(captiveRecheckTimeout)
+	for time.Now().Before(deadline) {
+		time.Sleep(captiveRecheckInterval)
+
+		st := s.stateMgr.Get()
+		if !st.CaptivePortalDetected {
+			return // Cleared elsewhere (disconnect or connectivity check)
+		}
+
+		if result := s.portal.Detect(""); !result.Detected() {
+			log.Printf("Captive portal sign-in complete")
+			s.stateMgr.Update(func(st *state.State) {
+				st.SetCaptiveResult(false, "", result.Endpoint, "")
+			})
+			return
+		}
+	}
+	log.Printf("Captive portal still present after %s, giving up re-checks", captiveRecheckTimeout)
+}
+
+// Retry cadence for powering WiFi back on after airplane mode: IWD needs a
+// moment to notice the rfkill unblock before Powered can be written
+const (
+	wifiRestoreInterval = 500 * time.Millisecond
+	wifiRestoreAttempts = 10
+)
+
+// restoreWifiPower powers the WiFi device back on after airplane mode
+func (s *Service) restoreWifiPower() {
+	if s.iwd == nil {
+		return
+	}
+	var err error
+	for i := 0; i < wifiRestoreAttempts; i++ {
+		time.Sleep(wifiRestoreInterval)
+		if s.iwd.WaitReady(iwdReadyWait) != nil {
+			continue
+		}
+		if err = s.iwd.SetWifiEnabled(true); err == nil {
+			log.Printf("WiFi powered back on after airplane mode")
+			s.stateMgr.Update(func(st *state.State) {
+				st.WifiEnabled = true
+			})
+			s.EmitSignal("WifiStateChanged", true)
+			return
+		}
+	}
+	log.Printf("Failed to power WiFi back on after airplane mode: %v", err)
+	if err != nil {
+		s.EmitSignal("Error", "SetAirplaneMode", err.Error(), errorCode(err, state.ErrCodeFailed))
+	}
+}
+
+// uintParam reads a non-negative integer from any D-Bus integer type
+func uintParam(v dbus.Variant) (uint32, bool) {
+	switch n := v.Value().(type) {
+	case uint8:
+		return uint32(n), true
+	case uint16:
+		return uint32(n), true
+	case uint32:
+		return n, true
+	case uint64:
+		return uint32(n), n <= 1<<32-1
+	case int16:
+		return uint32(n), n >= 0
+	case int32:
+		return uint32(n), n >= 0
+	case int64:
+		return uint32(n), n >= 0 && n <= 1<<32-1
+	}
+	return 0, false
+}
@@ -1,8 +1,23 @@
 package dbus
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
-	"os/exec"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"x-network/internal/bluez"
+	"x-network/internal/connectivity"
+	"x-network/internal/dns"
+	"x-network/internal/ipconfig"
+	"x-network/internal/iwd"
+	"x-network/internal/netlink"
+	"x-network/internal/rfkill"
+	"x-network/internal/settings"
 	"x-network/internal/state"
 
 	"github.com/godbus/dbus/v5"
@@ -11,14 +26,24 @@ import (
 // D-Bus method implementations
 
 // EnableWifi enables or disables WiFi
-func (s *Service) EnableWifi(enabled bool) (bool, *dbus.Error) {
-	if s.iwd == nil {
-		return false, dbus.NewError(Interface+".Error", []interface{}{"IWD not available"})
+func (s *Service) EnableWifi(sender dbus.Sender, enabled bool) (bool, *dbus.Error) {
+	if err := s.authorize(sender, "EnableWifi"); err != nil {
+		return false, err
+	}
+	if err := s.requireIWD(); err != nil {
+		return false, err
+	}
+
+	// IWD's Powered write fails opaquely on a hard-blocked radio
+	if enabled && s.stateMgr.Get().WifiBlocked.Hard {
+		msg := "WiFi is disabled by a hardware switch"
+		s.EmitSignal("Error", "EnableWifi", msg, state.ErrCodeHardBlocked)
+		return false, dbus.NewError(Interface+".Error.HardBlocked", []interface{}{msg})
 	}
 
 	err := s.iwd.SetWifiEnabled(enabled)
 	if err != nil {
-		s.EmitSignal("Error", "EnableWifi", err.Error())
+		s.EmitSignal("Error", "EnableWifi", err.Error(), errorCode(err, state.ErrCodeFailed))
 		return false, nil
 	}
 
@@ -31,8 +56,8 @@ func (s *Service) EnableWifi(enabled bool) (bool, *dbus.Error) {
 
 // Scan triggers a WiFi network scan
 func (s *Service) Scan() *dbus.Error {
-	if s.iwd == nil {
-		return dbus.NewError(Interface+".Error", []interface{}{"IWD not available"})
+	if err := s.requireIWD(); err != nil {
+		return err
 	}
 
 	// Set WifiScanning=true immediately
@@ -43,41 +68,65 @@ func (s *Service) Scan() *dbus.Error {
 	go func() {
 		networks, err := s.iwd.Scan()
 
-		// Set WifiScanning=false when scan completes (regardless of success)
+		// Set WifiScanning=false when scan completes (regardless of success); a
+		// cancelled scan clears it itself once the radio is done
+		cancelled := errors.Is(err, iwd.ErrScanCancelled)
 		s.stateMgr.Update(func(st *state.State) {
-			st.WifiScanning = false
+			if !cancelled {
+				st.WifiScanning = false
+			}
 			if networks != nil {
 				st.Networks = networks
 			}
 		})
 
-		if err != nil {
-			s.EmitSignal("Error", "Scan", err.Error())
+		if err != nil && !cancelled {
+			s.EmitSignal("Error", "Scan", err.Error(), errorCode(err, state.ErrCodeScanFailed))
 		}
 	}()
 
 	return nil
 }
 
+// CancelScan aborts the wait for a running scan; Networks keeps the partial results
+// WifiScanning (and ScanCompleted) follow once the radio has actually stopped
+func (s *Service) CancelScan() (bool, *dbus.Error) {
+	if err := s.requireIWD(); err != nil {
+		return false, err
+	}
+	return s.iwd.CancelScan(), nil
+}
+
 // Connect connects to a network with parameters
-func (s *Service) Connect(params map[string]dbus.Variant) (bool, *dbus.Error) {
+func (s *Service) Connect(sender dbus.Sender, params map[string]dbus.Variant) (bool, *dbus.Error) {
+	if err := s.authorize(sender, "Connect"); err != nil {
+		return false, err
+	}
 	log.Printf("Connect called with %d params", len(params))
 
-	if s.iwd == nil {
-		return false, dbus.NewError(Interface+".Error", []interface{}{"IWD not available"})
+	if err := s.requireIWD(); err != nil {
+		return false, err
 	}
 
 	// Extract parameters
 	ssid := ""
-	password := ""
+	var password []byte
 	security := "psk"
 	hidden := false
+	force := false
+	bssid := ""
 
 	if v, ok := params["ssid"]; ok {
 		ssid = v.Value().(string)
 	}
 	if v, ok := params["password"]; ok {
-		password = v.Value().(string)
+		// Accept "ay" so clients can avoid immutable strings entirely
+		switch p := v.Value().(type) {
+		case []byte:
+			password = p
+		case string:
+			password = []byte(p)
+		}
 	}
 	if v, ok := params["security"]; ok {
 		security = v.Value().(string)
@@ -85,27 +134,58 @@ func (s *Service) Connect(params map[string]dbus.Variant) (bool, *dbus.Error) {
 	if v, ok := params["hidden"]; ok {
 		hidden = v.Value().(bool)
 	}
+	if v, ok := params["force"]; ok {
+		force, _ = v.Value().(bool)
+	}
+	if v, ok := params["bssid"]; ok {
+		bssid, _ = v.Value().(string)
+		if _, err := net.ParseMAC(bssid); bssid != "" && err != nil {
+			return false, dbus.NewError(Interface+".Error", []interface{}{"invalid bssid: " + bssid})
+		}
+	}
 
 	if ssid == "" {
 		return false, dbus.NewError(Interface+".Error", []interface{}{"SSID required"})
 	}
+	if !force && s.iwd.IsBlocked(ssid) {
+		return false, blockedError(ssid)
+	}
 
 	s.stateMgr.Update(func(st *state.State) {
 		st.ConnectionState = state.StateConnecting
 		st.ActiveSSID = ssid
 		st.LastError = "" // Clear previous error on new attempt
+		st.LastErrorCode = ""
 	})
-	s.EmitSignal("ConnectionChanged", "connecting", ssid, uint8(0))
+	s.EmitSignal("ConnectionChanged", "connecting", ssid, uint8(0), "")
 
 	go func() {
-		err := s.iwd.Connect(ssid, password, security, hidden)
+		err := s.iwd.Connect(ssid, password, security, hidden, bssid)
+		for i := range password {
+			password[i] = 0
+		}
+		if errors.Is(err, iwd.ErrBSSIDMismatch) {
+			// Connected, just not where asked - report without failing the link
+			code, msg := iwd.ClassifyConnectError(err)
+			s.stateMgr.Update(func(st *state.State) {
+				st.LastError = msg
+				st.LastErrorCode = code
+			})
+			s.EmitSignal("Error", "Connect", err.Error(), code)
+			return
+		}
 		if err != nil {
+			code, msg := iwd.ClassifyConnectError(err)
 			s.stateMgr.Update(func(st *state.State) {
 				st.ConnectionState = state.StateFailed
-				st.LastError = err.Error() // Set error for UI to display
+				// Keep a specific reason the station handler already derived
+				if st.LastErrorCode == "" || st.LastErrorCode == state.ErrCodeUnknown || st.LastErrorCode == state.ErrCodeConnectFailed {
+					st.LastError = msg // Set error for UI to display
+					st.LastErrorCode = code
+				}
 			})
-			s.EmitSignal("Error", "Connect", err.Error())
-			s.EmitSignal("ConnectionChanged", "failed", ssid, uint8(0))
+			s.EmitSignal("Error", "Connect", msg, code)
+			s.EmitSignal("ConnectionChanged", "failed", ssid, uint8(0), code)
 		}
 		// Success state will be set by IWD signal handlers
 	}()
@@ -114,24 +194,38 @@ func (s *Service) Connect(params map[string]dbus.Variant) (bool, *dbus.Error) {
 }
 
 // ConnectSaved connects to a saved network
-func (s *Service) ConnectSaved(ssid string) (bool, *dbus.Error) {
-	if s.iwd == nil {
-		return false, dbus.NewError(Interface+".Error", []interface{}{"IWD not available"})
+func (s *Service) ConnectSaved(sender dbus.Sender, ssid string) (bool, *dbus.Error) {
+	if err := s.authorize(sender, "ConnectSaved"); err != nil {
+		return false, err
+	}
+	if err := s.requireIWD(); err != nil {
+		return false, err
+	}
+	if s.iwd.IsBlocked(ssid) {
+		return false, blockedError(ssid)
 	}
 
 	s.stateMgr.Update(func(st *state.State) {
 		st.ConnectionState = state.StateConnecting
 		st.ActiveSSID = ssid
+		st.LastError = ""
+		st.LastErrorCode = ""
 	})
-	s.EmitSignal("ConnectionChanged", "connecting", ssid, uint8(0))
+	s.EmitSignal("ConnectionChanged", "connecting", ssid, uint8(0), "")
 
 	go func() {
 		err := s.iwd.ConnectSaved(ssid)
 		if err != nil {
+			code, msg := iwd.ClassifyConnectError(err)
 			s.stateMgr.Update(func(st *state.State) {
 				st.ConnectionState = state.StateFailed
+				if st.LastErrorCode == "" || st.LastErrorCode == state.ErrCodeUnknown || st.LastErrorCode == state.ErrCodeConnectFailed {
+					st.LastError = msg
+					st.LastErrorCode = code
+				}
 			})
-			s.EmitSignal("Error", "ConnectSaved", err.Error())
+			s.EmitSignal("Error", "ConnectSaved", msg, code)
+			s.EmitSignal("ConnectionChanged", "failed", ssid, uint8(0), code)
 		}
 	}()
 
@@ -139,9 +233,12 @@ func (s *Service) ConnectSaved(ssid string) (bool, *dbus.Error) {
 }
 
 // Disconnect disconnects from current network
-func (s *Service) Disconnect() *dbus.Error {
-	if s.iwd == nil {
-		return dbus.NewError(Interface+".Error", []interface{}{"IWD not available"})
+func (s *Service) Disconnect(sender dbus.Sender) *dbus.Error {
+	if err := s.authorize(sender, "Disconnect"); err != nil {
+		return err
+	}
+	if err := s.requireIWD(); err != nil {
+		return err
 	}
 
 	st := s.stateMgr.Get()
@@ -149,30 +246,37 @@ func (s *Service) Disconnect() *dbus.Error {
 
 	err := s.iwd.Disconnect()
 	if err != nil {
-		s.EmitSignal("Error", "Disconnect", err.Error())
+		s.EmitSignal("Error", "Disconnect", err.Error(), errorCode(err, state.ErrCodeFailed))
 		return nil
 	}
 
 	s.stateMgr.Update(func(st *state.State) {
+		if st.ConnectionState == state.StateConnected || st.ConnectionState == state.StateObtaining {
+			st.DisconnectReason = state.DisconnectReasonLocal
+		}
 		st.ConnectionState = state.StateDisconnected
 		st.ActiveSSID = ""
 		st.SignalRSSI = 0
 		st.SignalStrength = 0
+		st.SignalBars = 0
 	})
-	s.EmitSignal("ConnectionChanged", "disconnected", ssid, uint8(0))
+	s.EmitSignal("ConnectionChanged", "disconnected", ssid, uint8(0), "")
 
 	return nil
 }
 
 // Forget forgets a saved network
-func (s *Service) Forget(ssid string) (bool, *dbus.Error) {
-	if s.iwd == nil {
-		return false, dbus.NewError(Interface+".Error", []interface{}{"IWD not available"})
+func (s *Service) Forget(sender dbus.Sender, ssid string) (bool, *dbus.Error) {
+	if err := s.authorize(sender, "Forget"); err != nil {
+		return false, err
+	}
+	if err := s.requireIWD(); err != nil {
+		return false, err
 	}
 
 	err := s.iwd.Forget(ssid)
 	if err != nil {
-		s.EmitSignal("Error", "Forget", err.Error())
+		s.EmitSignal("Error", "Forget", err.Error(), errorCode(err, state.ErrCodeFailed))
 		return false, nil
 	}
 
@@ -183,100 +287,357 @@ func (s *Service) Forget(ssid string) (bool, *dbus.Error) {
 }
 
 // SetAutoConnect enables/disables auto-connect for a network
-func (s *Service) SetAutoConnect(ssid string, enabled bool) (bool, *dbus.Error) {
-	if s.iwd == nil {
-		return false, dbus.NewError(Interface+".Error", []interface{}{"IWD not available"})
+func (s *Service) SetAutoConnect(sender dbus.Sender, ssid string, enabled bool) (bool, *dbus.Error) {
+	if err := s.authorize(sender, "SetAutoConnect"); err != nil {
+		return false, err
+	}
+	if err := s.requireIWD(); err != nil {
+		return false, err
+	}
+	if enabled && s.iwd.IsBlocked(ssid) {
+		return false, blockedError(ssid)
 	}
 
 	err := s.iwd.SetAutoConnect(ssid, enabled)
 	if err != nil {
-		s.EmitSignal("Error", "SetAutoConnect", err.Error())
+		s.EmitSignal("Error", "SetAutoConnect", err.Error(), errorCode(err, state.ErrCodeFailed))
 		return false, nil
 	}
+	s.iwd.RefreshKnownNetworks()
 
 	return true, nil
 }
 
-// StartHotspot starts WiFi hotspot
-func (s *Service) StartHotspot(ssid, password string) (bool, *dbus.Error) {
-	if s.iwd == nil {
-		return false, dbus.NewError(Interface+".Error", []interface{}{"IWD not available"})
+// BlacklistNetwork blocks or unblocks ssid: never auto-joined (AutoConnect is
+// forced off while blocked) and refused by ConnectSaved and Connect unless
+// Connect gets force=true. The list is persisted
+func (s *Service) BlacklistNetwork(sender dbus.Sender, ssid string, blocked bool) (bool, *dbus.Error) {
+	if err := s.authorize(sender, "BlacklistNetwork"); err != nil {
+		return false, err
+	}
+	if err := s.requireIWD(); err != nil {
+		return false, err
 	}
 
-	err := s.iwd.StartHotspot(ssid, password)
-	if err != nil {
-		s.EmitSignal("Error", "StartHotspot", err.Error())
+	if err := s.iwd.BlockNetwork(ssid, blocked); err != nil {
+		s.EmitSignal("Error", "BlacklistNetwork", err.Error(), errorCode(err, state.ErrCodeFailed))
 		return false, nil
 	}
+	if s.settings != nil {
+		list := s.iwd.BlockedNetworks()
+		err := s.settings.Update(func(set *settings.Settings) {
+			set.BlockedNetworks = list
+		})
+		if err != nil {
+			log.Printf("Failed to save blacklist: %v", err)
+			return false, dbus.NewError(Interface+".Error", []interface{}{err.Error()})
+		}
+	}
+	return true, nil
+}
+
+// blockedError is the reply for a connect to a blacklisted network
+func blockedError(ssid string) *dbus.Error {
+	return dbus.NewError(Interface+".Error.Blocked", []interface{}{"network is blacklisted: " + ssid})
+}
+
+// SetNetworkPriority ranks a saved network (higher first in SavedNetworks and
+// GetKnownNetworks); the value is persisted by the daemon since IWD has no priority
+func (s *Service) SetNetworkPriority(sender dbus.Sender, ssid string, priority int32) (bool, *dbus.Error) {
+	if err := s.authorize(sender, "SetNetworkPriority"); err != nil {
+		return false, err
+	}
+	if err := s.requireIWD(); err != nil {
+		return false, err
+	}
 
-	s.stateMgr.Update(func(st *state.State) {
-		st.HotspotActive = true
-		st.HotspotSSID = ssid
-	})
+	if err := s.iwd.SetNetworkPriority(ssid, priority); err != nil {
+		return false, dbus.NewError(Interface+".Error.NotFound", []interface{}{err.Error()})
+	}
+	if s.settings != nil {
+		err := s.settings.Update(func(set *settings.Settings) {
+			if set.NetworkPriority == nil {
+				set.NetworkPriority = make(map[string]int32)
+			}
+			if priority == 0 {
+				delete(set.NetworkPriority, ssid)
+			} else {
+				set.NetworkPriority[ssid] = priority
+			}
+		})
+		if err != nil {
+			log.Printf("Failed to save priority of %s: %v", ssid, err)
+			return false, dbus.NewError(Interface+".Error", []interface{}{err.Error()})
+		}
+	}
+	return true, nil
+}
+
+// ApplyPolicy reconciles saved networks with a JSON policy file and returns
+// the SSIDs it added, updated and removed; re-applying an unchanged policy is a no-op
+func (s *Service) ApplyPolicy(sender dbus.Sender, path string) ([]string, []string, []string, *dbus.Error) {
+	if err := s.authorize(sender, "ApplyPolicy"); err != nil {
+		return nil, nil, nil, err
+	}
+	if err := s.requireIWD(); err != nil {
+		return nil, nil, nil, err
+	}
+
+	policy, err := iwd.LoadPolicy(path)
+	if err != nil {
+		return nil, nil, nil, dbus.NewError(Interface+".Error", []interface{}{err.Error()})
+	}
 
+	res, err := s.iwd.ApplyPolicy(policy)
+	if err != nil {
+		log.Printf("ApplyPolicy %s: %v", path, err)
+		s.EmitSignal("Error", "ApplyPolicy", err.Error(), state.ErrCodeFailed)
+	}
+	if s.settings != nil {
+		priorities, blocked := s.iwd.NetworkPriorities(), s.iwd.BlockedNetworks()
+		err := s.settings.Update(func(set *settings.Settings) {
+			set.NetworkPriority = priorities
+			set.BlockedNetworks = blocked
+		})
+		if err != nil {
+			log.Printf("Failed to save policy priorities and blacklist: %v", err)
+			return nil, nil, nil, dbus.NewError(Interface+".Error", []interface{}{err.Error()})
+		}
+	}
+	return nonNil(res.Added), nonNil(res.Updated), nonNil(res.Removed), nil
+}
+
+// StartHotspot starts a WPA2 WiFi hotspot with IWD's defaults
+// Kept with its original (ss) signature; StartHotspotWithParams takes options
+func (s *Service) StartHotspot(sender dbus.Sender, ssid, password string) (bool, *dbus.Error) {
+	if err := s.authorize(sender, "StartHotspot"); err != nil {
+		return false, err
+	}
+	if err := s.requireIWD(); err != nil {
+		return false, err
+	}
+	return s.startHotspot(ssid, password, iwd.HotspotSecurityWPA2, iwd.HotspotOptions{})
+}
+
+// StartHotspotWithParams starts WiFi hotspot with parameters
+// Keys: ssid, password, security ("wpa2", "open"), band ("2.4", "5"),
+// channel, hidden, timeout-minutes (stop after that long without clients)
+// Invalid input fails with Error.InvalidArgument / Error.NotSupported
+func (s *Service) StartHotspotWithParams(sender dbus.Sender, params map[string]dbus.Variant) (bool, *dbus.Error) {
+	if err := s.authorize(sender, "StartHotspotWithParams"); err != nil {
+		return false, err
+	}
+	if err := s.requireIWD(); err != nil {
+		return false, err
+	}
+
+	var ssid, password, security string
+	var opts iwd.HotspotOptions
+	var badType []string
+	for key, v := range params {
+		ok := true
+		switch key {
+		case "ssid":
+			ssid, ok = v.Value().(string)
+		case "password":
+			password, ok = v.Value().(string)
+		case "security":
+			security, ok = v.Value().(string)
+		case "band":
+			opts.Band, ok = v.Value().(string)
+		case "channel":
+			var ch uint32
+			ch, ok = uintParam(v)
+			opts.Channel = uint16(ch)
+		case "hidden":
+			opts.Hidden, ok = v.Value().(bool)
+		case "timeout-minutes":
+			var minutes uint32
+			minutes, ok = uintParam(v)
+			opts.Timeout = time.Duration(minutes) * time.Minute
+		default:
+			badType = append(badType, "unknown key "+key)
+		}
+		if !ok {
+			badType = append(badType, "wrong type for "+key)
+		}
+	}
+	if len(badType) > 0 {
+		sort.Strings(badType)
+		return false, dbus.NewError(Interface+".Error.InvalidArgument", []interface{}{strings.Join(badType, ", ")})
+	}
+	return s.startHotspot(ssid, password, security, opts)
+}
+
+// startHotspot starts the AP and maps validation errors to D-Bus errors
+func (s *Service) startHotspot(ssid, password, security string, opts iwd.HotspotOptions) (bool, *dbus.Error) {
+	err := s.iwd.StartHotspot(ssid, password, security, opts)
+	switch {
+	case errors.Is(err, iwd.ErrInvalidHotspot):
+		return false, dbus.NewError(Interface+".Error.InvalidArgument", []interface{}{err.Error()})
+	case errors.Is(err, iwd.ErrHotspotUnsupported):
+		return false, dbus.NewError(Interface+".Error.NotSupported", []interface{}{err.Error()})
+	}
+	if err != nil {
+		s.stateMgr.Update(func(st *state.State) {
+			st.SetHotspotState(false, ssid, state.HotspotReasonFailed)
+		})
+		s.EmitSignal("Error", "StartHotspot", err.Error(), errorCode(err, state.ErrCodeHotspotFailed))
+		return false, nil
+	}
+
+	// HotspotActive follows AccessPoint.Started once IWD reports it
 	return true, nil
 }
 
 // StopHotspot stops WiFi hotspot
-func (s *Service) StopHotspot() *dbus.Error {
-	if s.iwd == nil {
-		return dbus.NewError(Interface+".Error", []interface{}{"IWD not available"})
+func (s *Service) StopHotspot(sender dbus.Sender) *dbus.Error {
+	if err := s.authorize(sender, "StopHotspot"); err != nil {
+		return err
+	}
+	if err := s.requireIWD(); err != nil {
+		return err
 	}
 
 	err := s.iwd.StopHotspot()
 	if err != nil {
-		s.EmitSignal("Error", "StopHotspot", err.Error())
+		s.EmitSignal("Error", "StopHotspot", err.Error(), errorCode(err, state.ErrCodeHotspotFailed))
 		return nil
 	}
 
-	s.stateMgr.Update(func(st *state.State) {
-		st.HotspotActive = false
-		st.HotspotSSID = ""
-	})
-
+	// HotspotActive clears when IWD reports Started=false
 	return nil
 }
 
-// SetAirplaneMode enables/disables airplane mode
-func (s *Service) SetAirplaneMode(enabled bool) (bool, *dbus.Error) {
-	err := setRfkill(enabled)
-	if err != nil {
-		s.EmitSignal("Error", "SetAirplaneMode", err.Error())
-		return false, nil
+// SetAirplaneMode soft-blocks/unblocks every radio (SetRfkill("all", enabled))
+// WiFi's power state is remembered on the way in and restored on the way out,
+// so WiFi the user had switched off stays off
+func (s *Service) SetAirplaneMode(sender dbus.Sender, enabled bool) (bool, *dbus.Error) {
+	if err := s.authorize(sender, "SetAirplaneMode"); err != nil {
+		return false, err
+	}
+	st := s.stateMgr.Get()
+	if enabled && !st.AirplaneMode {
+		s.airplaneMu.Lock()
+		s.airplaneSaved = true
+		s.wifiBefore = st.WifiEnabled
+		s.airplaneMu.Unlock()
 	}
 
-	s.stateMgr.Update(func(st *state.State) {
-		st.AirplaneMode = enabled
-	})
+	ok, err := s.setRfkill("SetAirplaneMode", "all", enabled)
+	if !ok || enabled {
+		return ok, err
+	}
 
+	s.airplaneMu.Lock()
+	restore := s.airplaneSaved && s.wifiBefore
+	s.airplaneSaved = false
+	s.airplaneMu.Unlock()
+	if restore {
+		go s.restoreWifiPower()
+	}
 	return true, nil
 }
 
-// CheckCaptivePortal checks for captive portal
+// SetRfkill soft-blocks/unblocks one radio kind: "wifi", "bluetooth" or "all"
+// AirplaneMode and the *Blocked properties follow the rfkill watcher, which
+// sees the change (and any hardware switch) a moment later
+func (s *Service) SetRfkill(sender dbus.Sender, kind string, blocked bool) (bool, *dbus.Error) {
+	if err := s.authorize(sender, "SetRfkill"); err != nil {
+		return false, err
+	}
+	return s.setRfkill("SetRfkill", kind, blocked)
+}
+
+// SetRadioBlocked is SetRfkill under its older name (also accepts "wlan")
+func (s *Service) SetRadioBlocked(sender dbus.Sender, radio string, blocked bool) (bool, *dbus.Error) {
+	if err := s.authorize(sender, "SetRadioBlocked"); err != nil {
+		return false, err
+	}
+	return s.setRfkill("SetRadioBlocked", radio, blocked)
+}
+
+// setRfkill applies a soft block, reporting failures as op
+func (s *Service) setRfkill(op, kind string, blocked bool) (bool, *dbus.Error) {
+	typ, err := rfkill.ParseType(kind)
+	if err != nil {
+		return false, dbus.NewError(Interface+".Error.InvalidArgument", []interface{}{err.Error()})
+	}
+	if err := rfkill.SetBlocked(typ, blocked); err != nil {
+		s.EmitSignal("Error", op, err.Error(), errorCode(err, state.ErrCodeRfkillFailed))
+		return false, nil
+	}
+	return true, nil
+}
+
+// CheckCaptivePortal probes for a captive portal over the default route
 func (s *Service) CheckCaptivePortal() (bool, *dbus.Error) {
-	detected, url := checkCaptivePortal()
+	return s.checkCaptivePortal("")
+}
 
+// CheckCaptivePortalOn probes for a captive portal over iface ("" = default route)
+// The result lands in InterfaceConnectivity; only the default-route interface
+// drives CaptivePortalDetected
+func (s *Service) CheckCaptivePortalOn(sender dbus.Sender, iface string) (bool, *dbus.Error) {
+	if err := s.authorize(sender, "CheckCaptivePortalOn"); err != nil {
+		return false, err
+	}
+	return s.checkCaptivePortal(iface)
+}
+
+// checkCaptivePortal runs the probe for CheckCaptivePortal(On)
+func (s *Service) checkCaptivePortal(iface string) (bool, *dbus.Error) {
+	primary, _ := connectivity.DefaultRouteInterface()
+	if iface == "" {
+		iface = primary
+	}
+	if iface != "" {
+		if _, err := net.InterfaceByName(iface); err != nil {
+			return false, dbus.NewError(Interface+".Error", []interface{}{"unknown interface: " + iface})
+		}
+	}
+
+	result := s.portal.Detect(iface)
+	detected := result.Detected()
+
+	// CaptivePortalStatus is emitted from the state change
 	s.stateMgr.Update(func(st *state.State) {
-		st.CaptivePortalDetected = detected
-		st.CaptivePortalURL = url
+		if iface != "" {
+			perIface := make(map[string]string, len(st.InterfaceConnectivity)+1)
+			for k, v := range st.InterfaceConnectivity {
+				perIface[k] = v
+			}
+			perIface[iface] = result.Connectivity
+			st.InterfaceConnectivity = perIface
+		}
+		if iface == primary {
+			st.SetCaptiveResult(detected, result.URL, result.Endpoint, result.Stage)
+		}
 	})
-	s.EmitSignal("CaptivePortalStatus", detected, url)
 
 	return detected, nil
 }
 
 // OpenCaptivePortal opens captive portal URL in browser
-func (s *Service) OpenCaptivePortal() *dbus.Error {
+// Then re-checks until the user has signed in (or we give up)
+func (s *Service) OpenCaptivePortal(sender dbus.Sender) *dbus.Error {
+	if err := s.authorize(sender, "OpenCaptivePortal"); err != nil {
+		return err
+	}
 	st := s.stateMgr.Get()
 	if st.CaptivePortalURL != "" {
 		openURL(st.CaptivePortalURL)
+		go s.recheckCaptivePortal()
 	}
 	return nil
 }
 
 // RequestUsbNetwork requests DHCP on USB tethering interface
 // This doesn't "enable" tethering (phone controls that) - just requests network
-func (s *Service) RequestUsbNetwork() (bool, *dbus.Error) {
+func (s *Service) RequestUsbNetwork(sender dbus.Sender) (bool, *dbus.Error) {
+	if err := s.authorize(sender, "RequestUsbNetwork"); err != nil {
+		return false, err
+	}
 	st := s.stateMgr.Get()
 
 	if !st.UsbInterfaceDetected {
@@ -295,10 +656,17 @@ func (s *Service) RequestUsbNetwork() (bool, *dbus.Error) {
 	go func() {
 		iface := st.UsbInterfaceName
 		log.Printf("Requesting USB network on %s", iface)
-		cmd := exec.Command("dhcpcd", "-4", "-q", iface)
-		if err := cmd.Run(); err != nil {
+		// With auto-connect off nothing has brought the link up yet
+		if !st.UsbAutoConnect {
+			if err := netlink.BringUpInterface(s.stateMgr, iface); err != nil {
+				log.Printf("Failed to bring up %s: %v", iface, err)
+				s.EmitSignal("Error", "RequestUsbNetwork", err.Error(), state.ErrCodeFailed)
+				return
+			}
+		}
+		if err := s.ipcfg.Start(iface); err != nil {
 			log.Printf("DHCP request failed on %s: %v", iface, err)
-			s.EmitSignal("Error", "RequestUsbNetwork", err.Error())
+			s.EmitSignal("Error", "RequestUsbNetwork", err.Error(), errorCode(err, state.ErrCodeDHCPFailed))
 		}
 		// Success handled by netlink RTM_NEWADDR event
 	}()
@@ -307,19 +675,21 @@ func (s *Service) RequestUsbNetwork() (bool, *dbus.Error) {
 }
 
 // ReleaseUsbNetwork releases DHCP lease on USB tethering interface
-func (s *Service) ReleaseUsbNetwork() *dbus.Error {
+func (s *Service) ReleaseUsbNetwork(sender dbus.Sender) *dbus.Error {
+	if err := s.authorize(sender, "ReleaseUsbNetwork"); err != nil {
+		return err
+	}
 	st := s.stateMgr.Get()
 
 	if st.UsbInterfaceName == "" {
 		return nil // Nothing to release
 	}
 
-	// Release DHCP lease
+	// Release DHCP lease (sends DHCPRELEASE when the native client holds it)
 	go func() {
 		iface := st.UsbInterfaceName
 		log.Printf("Releasing USB network on %s", iface)
-		cmd := exec.Command("dhcpcd", "-k", iface)
-		cmd.Run() // Ignore error - interface might already be gone
+		s.ipcfg.Release(iface) // Ignore error - interface might already be gone
 
 		s.stateMgr.Update(func(st *state.State) {
 			st.UsbTetheringConnected = false
@@ -328,3 +698,649 @@ func (s *Service) ReleaseUsbNetwork() *dbus.Error {
 
 	return nil
 }
+
+// SetUsbAutoConnect controls whether USB tethering is brought up and given an
+// address automatically when carrier appears (and used as WiFi fallback)
+// Disabled, UsbTetheringAvailable is still tracked and RequestUsbNetwork connects
+func (s *Service) SetUsbAutoConnect(sender dbus.Sender, enabled bool) (bool, *dbus.Error) {
+	if err := s.authorize(sender, "SetUsbAutoConnect"); err != nil {
+		return false, err
+	}
+	if s.settings != nil {
+		err := s.settings.Update(func(set *settings.Settings) {
+			set.UsbAutoConnect = &enabled
+		})
+		if err != nil {
+			log.Printf("Failed to save USB auto-connect setting: %v", err)
+			return false, dbus.NewError(Interface+".Error", []interface{}{err.Error()})
+		}
+	}
+	s.stateMgr.Update(func(st *state.State) {
+		st.UsbAutoConnect = enabled
+	})
+	log.Printf("USB auto-connect set to %v", enabled)
+	return true, nil
+}
+
+// BluetoothDeviceDBus is a paired tethering-capable device (address, name, connected)
+type BluetoothDeviceDBus struct {
+	Address   string
+	Name      string
+	Connected bool
+}
+
+// GetBluetoothDevices lists paired devices offering Bluetooth tethering (NAP)
+func (s *Service) GetBluetoothDevices() ([]BluetoothDeviceDBus, *dbus.Error) {
+	if s.bt == nil {
+		return []BluetoothDeviceDBus{}, nil
+	}
+	devices, err := s.bt.Devices()
+	if err != nil {
+		return nil, dbus.NewError(Interface+".Error", []interface{}{err.Error()})
+	}
+	result := make([]BluetoothDeviceDBus, len(devices))
+	for i, d := range devices {
+		result[i] = BluetoothDeviceDBus{Address: d.Address, Name: d.Name, Connected: d.Connected}
+	}
+	return result, nil
+}
+
+// ConnectBluetoothTethering joins a phone's Bluetooth PAN and runs DHCP on it
+// device is an address, name or BlueZ object path ("" = the only paired NAP device)
+func (s *Service) ConnectBluetoothTethering(sender dbus.Sender, device string) (bool, *dbus.Error) {
+	if err := s.authorize(sender, "ConnectBluetoothTethering"); err != nil {
+		return false, err
+	}
+	if s.bt == nil {
+		return false, dbus.NewError(Interface+".Error", []interface{}{"Bluetooth not available"})
+	}
+	if s.stateMgr.Get().BtTetheringConnected {
+		return true, nil // Already connected
+	}
+
+	d, err := s.bt.Lookup(device)
+	if err != nil {
+		return false, dbus.NewError(Interface+".Error", []interface{}{err.Error()})
+	}
+
+	// Connect and DHCP asynchronously; success handled by netlink RTM_NEWADDR
+	go func() {
+		log.Printf("Requesting Bluetooth tethering via %s", d.Name)
+		if err := s.bt.Connect(d); err != nil {
+			log.Printf("Bluetooth tethering failed: %v", err)
+			s.EmitSignal("Error", "ConnectBluetoothTethering", err.Error(), errorCode(err, state.ErrCodeFailed))
+		}
+	}()
+
+	return true, nil
+}
+
+// DisconnectBluetoothTethering releases the lease and drops the PAN link
+func (s *Service) DisconnectBluetoothTethering(sender dbus.Sender) *dbus.Error {
+	if err := s.authorize(sender, "DisconnectBluetoothTethering"); err != nil {
+		return err
+	}
+	if s.bt == nil {
+		return nil
+	}
+	go func() {
+		if err := s.bt.Disconnect(); err != nil && !errors.Is(err, bluez.ErrNotConnected) {
+			log.Printf("Bluetooth tethering disconnect failed: %v", err)
+		}
+	}()
+	return nil
+}
+
+// SetPrimaryConnection pins iface as the default-route owner until cleared with ""
+// The pin only takes effect while iface has a default route
+func (s *Service) SetPrimaryConnection(sender dbus.Sender, iface string) (bool, *dbus.Error) {
+	if err := s.authorize(sender, "SetPrimaryConnection"); err != nil {
+		return false, err
+	}
+	if iface != "" {
+		if _, err := net.InterfaceByName(iface); err != nil {
+			return false, dbus.NewError(Interface+".Error.InvalidArgument", []interface{}{"unknown interface: " + iface})
+		}
+		// The VPN owns its own routes
+		if iface == s.stateMgr.Get().VpnInterface {
+			return false, dbus.NewError(Interface+".Error.InvalidArgument", []interface{}{"cannot pin VPN interface: " + iface})
+		}
+	}
+	s.stateMgr.Update(func(st *state.State) {
+		st.PrimaryPinned = iface
+	})
+	return true, nil
+}
+
+// SetIPConfig stores the IP profile for an SSID or interface name
+// config keys: method ("dhcp"|"static"), address, prefix, gateway, dns
+// An empty config or method "dhcp" clears the profile (DHCP on next connect)
+func (s *Service) SetIPConfig(sender dbus.Sender, target string, config map[string]dbus.Variant) (bool, *dbus.Error) {
+	if err := s.authorize(sender, "SetIPConfig"); err != nil {
+		return false, err
+	}
+	profile, err := ipProfileFromConfig(config)
+	if err != nil {
+		return false, dbus.NewError(Interface+".Error.InvalidArgument", []interface{}{err.Error()})
+	}
+
+	if err := s.ipcfg.SetProfile(target, profile); err != nil {
+		return false, dbus.NewError(Interface+".Error", []interface{}{err.Error()})
+	}
+
+	log.Printf("IP config for %s set to %s", target, profile.Method)
+	return true, nil
+}
+
+// ipProfileFromConfig reads a SetIPConfig dict; a key of the wrong type (or a
+// prefix that doesn't fit) is an error rather than silently ignored
+func ipProfileFromConfig(config map[string]dbus.Variant) (ipconfig.Profile, error) {
+	profile := ipconfig.Profile{Method: ipconfig.MethodDHCP}
+	for key, v := range config {
+		var ok bool
+		switch key {
+		case "method":
+			profile.Method, ok = v.Value().(string)
+		case "address":
+			profile.Address, ok = v.Value().(string)
+		case "gateway":
+			profile.Gateway, ok = v.Value().(string)
+		case "dns":
+			profile.DNS, ok = v.Value().([]string)
+		case "prefix":
+			var n int64
+			switch x := v.Value().(type) {
+			case uint8:
+				n, ok = int64(x), true
+			case uint32:
+				n, ok = int64(x), true
+			case int32:
+				n, ok = int64(x), true
+			}
+			if ok && (n < 1 || n > 32) {
+				return profile, fmt.Errorf("invalid prefix length: %d", n)
+			}
+			profile.PrefixLen = uint8(n)
+		default:
+			return profile, fmt.Errorf("unknown key %q", key)
+		}
+		if !ok {
+			return profile, fmt.Errorf("%s: unexpected type %s", key, v.Signature())
+		}
+	}
+	return profile, nil
+}
+
+// SetStaticIP configures iface with a static IPv4 address and applies it now
+// cidr is "address/prefix"; gateway may be empty for an on-link-only setup
+func (s *Service) SetStaticIP(sender dbus.Sender, iface, cidr, gateway string, dns []string) (bool, *dbus.Error) {
+	if err := s.authorize(sender, "SetStaticIP"); err != nil {
+		return false, err
+	}
+	if iface == "" {
+		return false, dbus.NewError(Interface+".Error", []interface{}{"interface required"})
+	}
+	profile, err := ipconfig.StaticProfile(cidr, gateway, dns)
+	if err != nil {
+		return false, dbus.NewError(Interface+".Error", []interface{}{err.Error()})
+	}
+
+	if err := s.ipcfg.SetStatic(iface, profile); err != nil {
+		s.EmitSignal("Error", "SetStaticIP", err.Error(), state.ErrCodeFailed)
+		return false, dbus.NewError(Interface+".Error", []interface{}{err.Error()})
+	}
+
+	// The watcher also sees RTM_NEWADDR; update now so readers don't see the old lease
+	s.stateMgr.Update(func(st *state.State) {
+		if st.InterfaceName == iface || st.UsbInterfaceName == iface {
+			st.IpAddress = profile.Address
+			st.IpPrefixLen = profile.PrefixLen
+			st.Gateway = profile.Gateway
+		}
+	})
+	log.Printf("Static IP %s/%d (gw %q) set on %s", profile.Address, profile.PrefixLen, gateway, iface)
+	return true, nil
+}
+
+// SetDHCP reverts iface to DHCP, removing a static address set by SetStaticIP
+func (s *Service) SetDHCP(sender dbus.Sender, iface string) (bool, *dbus.Error) {
+	if err := s.authorize(sender, "SetDHCP"); err != nil {
+		return false, err
+	}
+	if iface == "" {
+		return false, dbus.NewError(Interface+".Error", []interface{}{"interface required"})
+	}
+
+	go func() {
+		// DHCP blocks until a lease is installed
+		if err := s.ipcfg.SetDHCP(iface); err != nil {
+			log.Printf("SetDHCP on %s failed: %v", iface, err)
+			s.EmitSignal("Error", "SetDHCP", err.Error(), state.ErrCodeDHCPFailed)
+		}
+	}()
+	return true, nil
+}
+
+// GetIPConfig returns the stored IP profile for an SSID or interface name
+func (s *Service) GetIPConfig(target string) (map[string]dbus.Variant, *dbus.Error) {
+	profile := s.ipcfg.Profile(target)
+
+	config := map[string]dbus.Variant{
+		"method": dbus.MakeVariant(profile.Method),
+	}
+	if profile.Method == ipconfig.MethodStatic {
+		config["address"] = dbus.MakeVariant(profile.Address)
+		config["prefix"] = dbus.MakeVariant(uint32(profile.PrefixLen))
+		config["gateway"] = dbus.MakeVariant(profile.Gateway)
+		config["dns"] = dbus.MakeVariant(nonNil(profile.DNS))
+	}
+	return config, nil
+}
+
+// SetPortalEndpoints replaces the captive portal probe configuration
+// endpoints: (url, expected status, expected body substring); httpsURL must
+// present a valid certificate before connectivity is "full" ("" skips that stage)
+func (s *Service) SetPortalEndpoints(sender dbus.Sender, endpoints []PortalEndpointDBus, httpsURL string) (bool, *dbus.Error) {
+	if err := s.authorize(sender, "SetPortalEndpoints"); err != nil {
+		return false, err
+	}
+	cfg := connectivity.PortalConfig{HTTPSURL: httpsURL}
+	for _, ep := range endpoints {
+		cfg.Endpoints = append(cfg.Endpoints, connectivity.Endpoint{
+			URL:        ep.URL,
+			StatusCode: int(ep.Status),
+			Body:       ep.Body,
+		})
+	}
+
+	if err := s.portal.Set(cfg); err != nil {
+		return false, dbus.NewError(Interface+".Error", []interface{}{err.Error()})
+	}
+	log.Printf("Portal endpoints set: %d HTTP, https=%q", len(cfg.Endpoints), httpsURL)
+	return true, nil
+}
+
+// PortalEndpointDBus is a probe endpoint on the wire (sus)
+type PortalEndpointDBus struct {
+	URL    string
+	Status uint32
+	Body   string
+}
+
+// GetLinkFlapStats returns carrier up/down transitions per interface over the
+// last 10 minutes; high counts point at a bad cable or marginal WiFi
+func (s *Service) GetLinkFlapStats() (map[string]uint32, *dbus.Error) {
+	return netlink.FlapCounts(s.stateMgr.Get().LinkFlaps, time.Now()), nil
+}
+
+// NeighborDBus is one GetNeighbors entry: (ip, mac, interface, state)
+type NeighborDBus struct {
+	IP        string
+	MAC       string
+	Interface string
+	State     string
+}
+
+// GetNeighbors lists the peers seen on the connected link and the hotspot
+// interface. Links that aren't up contribute nothing, so entries from a
+// previous network never show
+func (s *Service) GetNeighbors() ([]NeighborDBus, *dbus.Error) {
+	st := s.stateMgr.Get()
+	var ifaces []string
+	if st.InterfaceName != "" && (st.IpAddress != "" || st.HotspotActive) {
+		ifaces = append(ifaces, st.InterfaceName)
+	}
+	if st.UsbTetheringConnected && st.UsbInterfaceName != "" {
+		ifaces = append(ifaces, st.UsbInterfaceName)
+	}
+	if st.BtTetheringConnected && st.BtInterfaceName != "" {
+		ifaces = append(ifaces, st.BtInterfaceName)
+	}
+
+	neighbors, err := netlink.ListNeighbors(ifaces)
+	if err != nil {
+		return nil, dbus.NewError(Interface+".Error", []interface{}{err.Error()})
+	}
+	result := make([]NeighborDBus, len(neighbors))
+	for i, n := range neighbors {
+		result[i] = NeighborDBus{n.IP, n.MAC, n.Interface, n.State}
+	}
+	return result, nil
+}
+
+// GetPublicIP looks up the public IPv4 and IPv6 addresses through the primary
+// interface. Never called by the daemon itself; the result is cached until
+// the connection or the default route changes
+func (s *Service) GetPublicIP() (string, string, string, string, int64, *dbus.Error) {
+	st := s.stateMgr.Get()
+	iface := st.PrimaryInterface
+	if iface == "" {
+		iface, _ = connectivity.DefaultRouteInterface()
+	}
+	if iface == "" {
+		return "", "", "", "", 0, dbus.NewError(Interface+".Error.NotConnected", []interface{}{"no default route"})
+	}
+	ssid := ""
+	if st.PrimaryType == "wifi" || (st.PrimaryType == "" && iface == st.InterfaceName) {
+		ssid = st.ActiveSSID
+	}
+	key := publicIPCacheKey(&st, iface, ssid)
+
+	s.publicIPMu.Lock()
+	defer s.publicIPMu.Unlock()
+	if s.publicIPKey != key {
+		result, err := s.ipLookup.Lookup(iface)
+		if errors.Is(err, connectivity.ErrPublicIPTimeout) {
+			return "", "", "", "", 0, dbus.NewError(Interface+".Error.Timeout", []interface{}{err.Error()})
+		}
+		if err != nil {
+			return "", "", "", "", 0, dbus.NewError(Interface+".Error", []interface{}{err.Error()})
+		}
+		result.SSID = ssid
+		s.publicIP, s.publicIPKey = result, key
+	}
+	ip := s.publicIP
+	return ip.IPv4, ip.IPv6, ip.Interface, ip.SSID, ip.At.Unix(), nil
+}
+
+// publicIPCacheKey identifies the connection a public IP was obtained on:
+// a reconnect restarts the session timestamps, a route change moves the
+// primary interface or the gateway
+func publicIPCacheKey(st *state.State, iface, ssid string) string {
+	return fmt.Sprintf("%s|%s|%s|%d|%d", iface, ssid, st.Gateway, st.ConnectedSince.UnixNano(), st.UsbConnectedSince.UnixNano())
+}
+
+// ConnectionAttemptDBus is one GetConnectionHistory entry:
+// (ssid, started unix, duration ms, success, failure code, signal)
+type ConnectionAttemptDBus struct {
+	SSID        string
+	Started     int64
+	DurationMs  uint32
+	Success     bool
+	FailureCode string
+	Signal      uint8
+}
+
+// GetConnectionHistory returns up to limit connection attempts, newest first
+// (0 = the whole in-memory history)
+func (s *Service) GetConnectionHistory(limit uint32) ([]ConnectionAttemptDBus, *dbus.Error) {
+	if err := s.requireIWD(); err != nil {
+		return nil, err
+	}
+	attempts := s.iwd.ConnectionHistory(int(limit))
+	result := make([]ConnectionAttemptDBus, len(attempts))
+	for i, a := range attempts {
+		result[i] = ConnectionAttemptDBus{a.SSID, a.Started.Unix(), uint32(a.Duration.Milliseconds()),
+			a.Success, a.FailureCode, a.Signal}
+	}
+	return result, nil
+}
+
+// GetNetworkStats aggregates the recorded attempts of ssid: attempts,
+// successes, success rate (0-1), average connect time in ms and the time
+// and code of the last failure
+func (s *Service) GetNetworkStats(ssid string) (uint32, uint32, float64, uint32, int64, string, *dbus.Error) {
+	if err := s.requireIWD(); err != nil {
+		return 0, 0, 0, 0, 0, "", err
+	}
+	st := s.iwd.NetworkStats(ssid)
+	return st.Attempts, st.Successes, st.SuccessRate(), uint32(st.AvgConnectTime.Milliseconds()),
+		unixOrZero(st.LastFailure), st.LastFailureCode, nil
+}
+
+// AccessPointDBus is one BSS for GetAccessPoints: (bssid, frequency, signal dBm, connected)
+type AccessPointDBus struct {
+	BSSID     string
+	Frequency uint32
+	Signal    int16
+	Connected bool
+}
+
+// GetAccessPoints lists the access points advertising ssid, strongest first
+func (s *Service) GetAccessPoints(ssid string) ([]AccessPointDBus, *dbus.Error) {
+	if err := s.requireIWD(); err != nil {
+		return nil, err
+	}
+	aps, err := s.iwd.GetAccessPoints(ssid)
+	if err != nil {
+		return nil, dbus.NewError(Interface+".Error", []interface{}{err.Error()})
+	}
+	result := make([]AccessPointDBus, 0, len(aps))
+	for _, ap := range aps {
+		result = append(result, AccessPointDBus{ap.BSSID, ap.Frequency, ap.SignalDBm, ap.Connected})
+	}
+	return result, nil
+}
+
+// ScanSSID probes for one SSID (hidden networks included) and returns whether
+// it answered and its signal in dBm
+func (s *Service) ScanSSID(ssid string) (bool, int16, *dbus.Error) {
+	if err := s.requireIWD(); err != nil {
+		return false, 0, err
+	}
+	found, signal, err := s.iwd.ScanSSID(ssid)
+	if err != nil {
+		return false, 0, dbus.NewError(Interface+".Error", []interface{}{err.Error()})
+	}
+	return found, signal, nil
+}
+
+// Roam reassociates with a stronger access point of the connected network
+// Refuses with Error.NoAlternativeAP / Error.RoamNotWorthwhile when there is
+// nothing to gain and Error.NotSupported outside IWD developer mode; otherwise returns the target BSSID and roams in the background
+func (s *Service) Roam(sender dbus.Sender) (string, *dbus.Error) {
+	if err := s.authorize(sender, "Roam"); err != nil {
+		return "", err
+	}
+	if err := s.requireIWD(); err != nil {
+		return "", err
+	}
+
+	current, target, err := s.iwd.FindRoamTarget()
+	switch {
+	case errors.Is(err, iwd.ErrNoAlternativeAP):
+		return "", dbus.NewError(Interface+".Error.NoAlternativeAP", []interface{}{err.Error()})
+	case errors.Is(err, iwd.ErrRoamNotWorthwhile):
+		return "", dbus.NewError(Interface+".Error.RoamNotWorthwhile", []interface{}{err.Error()})
+	case errors.Is(err, iwd.ErrRoamUnsupported):
+		return "", dbus.NewError(Interface+".Error.NotSupported", []interface{}{err.Error()})
+	case err != nil:
+		return "", dbus.NewError(Interface+".Error", []interface{}{err.Error()})
+	}
+
+	log.Printf("Roam: %s %s (%d dBm) -> %s (%d dBm)",
+		target.SSID, current.BSSID, current.SignalDBm, target.BSSID, target.SignalDBm)
+	s.EmitSignal("ConnectionChanged", "roaming", target.SSID, state.DBmToPercent(target.SignalDBm), "")
+
+	go func() {
+		err := s.iwd.RoamTo(target)
+		if err == nil {
+			return // Connected state comes from the IWD signal handlers
+		}
+		code, msg := iwd.ClassifyConnectError(err)
+		if errors.Is(err, iwd.ErrBSSIDMismatch) || errors.Is(err, iwd.ErrRoamUnsupported) {
+			// Still connected, just not where we wanted
+			s.EmitSignal("Error", "Roam", err.Error(), code)
+			return
+		}
+		s.stateMgr.Update(func(st *state.State) {
+			st.LastError = msg
+			st.LastErrorCode = code
+		})
+		s.EmitSignal("Error", "Roam", msg, code)
+		s.EmitSignal("ConnectionChanged", "failed", target.SSID, uint8(0), code)
+	}()
+
+	return target.BSSID, nil
+}
+
+// GetKnownNetworks returns saved networks with name, security, hidden,
+// autoconnect, last_connected (unix seconds, 0 = never) and priority
+func (s *Service) GetKnownNetworks() ([]map[string]dbus.Variant, *dbus.Error) {
+	known := s.stateMgr.Get().KnownNetworks
+	result := make([]map[string]dbus.Variant, 0, len(known))
+	for _, k := range known {
+		result = append(result, map[string]dbus.Variant{
+			"name":           dbus.MakeVariant(k.SSID),
+			"security":       dbus.MakeVariant(k.Security),
+			"hidden":         dbus.MakeVariant(k.Hidden),
+			"autoconnect":    dbus.MakeVariant(k.AutoConnect),
+			"last_connected": dbus.MakeVariant(unixOrZero(k.LastConnected)),
+			"priority":       dbus.MakeVariant(k.Priority),
+		})
+	}
+	return result, nil
+}
+
+// GetDnsLatency times a lookup against each configured resolver
+// Returns milliseconds per server, -1 for servers that failed or timed out
+func (s *Service) GetDnsLatency() (map[string]int32, *dbus.Error) {
+	servers := s.stateMgr.Get().DnsServers
+	if len(servers) == 0 {
+		return map[string]int32{}, nil
+	}
+	return dns.MeasureLatency(servers, dns.LatencyProbeHost, dns.LatencyTimeout, nil), nil
+}
+
+// GetState returns the whole daemon state as one JSON object so clients can
+// hydrate without a round-trip per property (internal tracking fields omitted)
+func (s *Service) GetState() (string, *dbus.Error) {
+	st := s.stateMgr.Get()
+	data, err := json.Marshal(&st)
+	if err != nil {
+		return "", dbus.NewError(Interface+".Error", []interface{}{err.Error()})
+	}
+	return string(data), nil
+}
+
+// ClearLastError empties LastError and LastErrorCode once the user dismissed
+// the error, so clients re-reading state don't show it again
+func (s *Service) ClearLastError() *dbus.Error {
+	st := s.stateMgr.Get()
+	if st.LastError == "" && st.LastErrorCode == "" {
+		return nil
+	}
+	s.stateMgr.Update(func(st *state.State) {
+		st.LastError = ""
+		st.LastErrorCode = ""
+	})
+	return nil
+}
+
+// SetScanActive enables periodic scanning while a network picker is open
+// Auto-disables after connecting or when no client renews it for a few minutes
+func (s *Service) SetScanActive(sender dbus.Sender, enabled bool) *dbus.Error {
+	if err := s.authorize(sender, "SetScanActive"); err != nil {
+		return err
+	}
+	if err := s.requireIWD(); err != nil {
+		return err
+	}
+	s.setScanActive(enabled)
+	return nil
+}
+
+// SetScanParams sets scan mode ("active"|"passive") and per-channel dwell time in ms
+// Values are validated against the adapter's capabilities and kept in settings.json
+func (s *Service) SetScanParams(sender dbus.Sender, params map[string]dbus.Variant) (bool, *dbus.Error) {
+	if err := s.authorize(sender, "SetScanParams"); err != nil {
+		return false, err
+	}
+	if err := s.requireIWD(); err != nil {
+		return false, err
+	}
+
+	st := s.stateMgr.Get()
+	mode, dwell, err := scanParamsFromConfig(params, st.ScanMode, st.ScanDwellMs)
+	if err != nil {
+		return false, dbus.NewError(Interface+".Error.InvalidArgument", []interface{}{err.Error()})
+	}
+
+	if err := s.iwd.SetScanParams(mode, dwell); err != nil {
+		return false, dbus.NewError(Interface+".Error", []interface{}{err.Error()})
+	}
+	if s.settings != nil {
+		st := s.stateMgr.Get() // Normalized by SetScanParams
+		err := s.settings.Update(func(set *settings.Settings) {
+			set.ScanMode = st.ScanMode
+			set.ScanDwellMs = st.ScanDwellMs
+		})
+		if err != nil {
+			log.Printf("Failed to save scan params: %v", err)
+			return false, dbus.NewError(Interface+".Error", []interface{}{err.Error()})
+		}
+	}
+	return true, nil
+}
+
+// scanParamsFromConfig applies the SetScanParams a{sv} over the current mode
+// and dwell; keys left out keep their value
+func scanParamsFromConfig(params map[string]dbus.Variant, mode string, dwell uint32) (string, uint32, error) {
+	var badType []string
+	for key, v := range params {
+		ok := true
+		switch key {
+		case "mode":
+			mode, ok = v.Value().(string)
+		case "dwell":
+			dwell, ok = uintParam(v)
+		default:
+			badType = append(badType, "unknown key "+key)
+		}
+		if !ok {
+			badType = append(badType, "wrong type for "+key)
+		}
+	}
+	if len(badType) > 0 {
+		sort.Strings(badType)
+		return "", 0, errors.New(strings.Join(badType, ", "))
+	}
+	return mode, dwell, nil
+}
+
+// ReloadConfig re-reads the config file and applies the keys that can change
+// at runtime; keys that need a restart are returned and keep their old value
+func (s *Service) ReloadConfig(sender dbus.Sender) ([]string, []string, *dbus.Error) {
+	if err := s.authorize(sender, "ReloadConfig"); err != nil {
+		return nil, nil, err
+	}
+	if s.reloadConfig == nil {
+		return nil, nil, dbus.NewError(Interface+".Error.NotSupported", []interface{}{"config reload not available"})
+	}
+
+	reloaded, restart, err := s.reloadConfig()
+	if err != nil {
+		log.Printf("ReloadConfig: %v", err)
+		return nil, nil, dbus.NewError(Interface+".Error.InvalidArgument", []interface{}{err.Error()})
+	}
+	return nonNil(reloaded), nonNil(restart), nil
+}
+
+// GetInterfaceFilter returns the active interface ignore patterns and the
+// include list that overrides them
+func (s *Service) GetInterfaceFilter() ([]string, []string, *dbus.Error) {
+	ignore, include := s.filter.Patterns()
+	return nonNil(ignore), nonNil(include), nil
+}
+
+// GetInterfaces lists the network links at call time, one dict per link:
+// name, type, operstate, carrier, ifindex and mac
+func (s *Service) GetInterfaces() ([]map[string]dbus.Variant, *dbus.Error) {
+	ifaces, err := netlink.ListInterfaces(s.filter)
+	if err != nil {
+		return nil, dbus.NewError(Interface+".Error", []interface{}{err.Error()})
+	}
+
+	result := make([]map[string]dbus.Variant, len(ifaces))
+	for i, iface := range ifaces {
+		result[i] = map[string]dbus.Variant{
+			"name":      dbus.MakeVariant(iface.Name),
+			"type":      dbus.MakeVariant(iface.Type),
+			"operstate": dbus.MakeVariant(iface.OperState),
+			"carrier":   dbus.MakeVariant(iface.Carrier),
+			"ifindex":   dbus.MakeVariant(iface.Index),
+			"mac":       dbus.MakeVariant(iface.MAC),
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,96 @@
+package dbus
+
+import (
+	"strings"
+	"testing"
+
+	"x-network/internal/ipconfig"
+	"x-network/internal/state"
+
+	"github.com/godbus/dbus/v5"
+)
+
+func TestIPProfileFromConfig(t *testing.T) {
+	static := func(prefix interface{}) map[string]dbus.Variant {
+		return map[string]dbus.Variant{
+			"method":  dbus.MakeVariant(ipconfig.MethodStatic),
+			"address": dbus.MakeVariant("192.168.1.10"),
+			"prefix":  dbus.MakeVariant(prefix),
+		}
+	}
+	tests := []struct {
+		name    string
+		config  map[string]dbus.Variant
+		prefix  uint8
+		wantErr bool
+	}{
+		{"byte prefix", static(uint8(16)), 16, false},
+		{"uint32 prefix", static(uint32(28)), 28, false},
+		{"int32 prefix", static(int32(8)), 8, false},
+		{"string prefix", static("24"), 0, true},
+		{"int64 prefix", static(int64(24)), 0, true},
+		{"prefix out of range", static(uint32(280)), 0, true},
+		{"zero prefix", static(uint8(0)), 0, true},
+		{"address of wrong type", map[string]dbus.Variant{"address": dbus.MakeVariant(uint32(1))}, 0, true},
+		{"unknown key", map[string]dbus.Variant{"mask": dbus.MakeVariant("255.255.255.0")}, 0, true},
+		{"empty is dhcp", map[string]dbus.Variant{}, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := ipProfileFromConfig(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ipProfileFromConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && p.PrefixLen != tt.prefix {
+				t.Errorf("PrefixLen = %d, want %d", p.PrefixLen, tt.prefix)
+			}
+		})
+	}
+}
+
+func TestGetStateOmitsHotspotPassword(t *testing.T) {
+	s := &Service{stateMgr: state.NewManager()}
+	s.stateMgr.Update(func(st *state.State) {
+		st.HotspotActive = true
+		st.HotspotSSID = "Phone"
+		st.HotspotPassword = "hunter2-secret"
+	})
+
+	data, dbusErr := s.GetState()
+	if dbusErr != nil {
+		t.Fatalf("GetState() = %v", dbusErr)
+	}
+	if strings.Contains(data, "hunter2-secret") || strings.Contains(data, "HotspotPassword") {
+		t.Fatalf("GetState() leaks the hotspot password: %s", data)
+	}
+}
+
+func TestScanParamsFromConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  map[string]dbus.Variant
+		mode    string
+		dwell   uint32
+		wantErr bool
+	}{
+		{"empty keeps current", map[string]dbus.Variant{}, "active", 40, false},
+		{"mode", map[string]dbus.Variant{"mode": dbus.MakeVariant("passive")}, "passive", 40, false},
+		{"uint32 dwell", map[string]dbus.Variant{"dwell": dbus.MakeVariant(uint32(100))}, "active", 100, false},
+		{"int32 dwell", map[string]dbus.Variant{"dwell": dbus.MakeVariant(int32(0))}, "active", 0, false},
+		{"mode of wrong type", map[string]dbus.Variant{"mode": dbus.MakeVariant(uint32(1))}, "", 0, true},
+		{"dwell of wrong type", map[string]dbus.Variant{"dwell": dbus.MakeVariant("100")}, "", 0, true},
+		{"negative dwell", map[string]dbus.Variant{"dwell": dbus.MakeVariant(int32(-1))}, "", 0, true},
+		{"unknown key", map[string]dbus.Variant{"dwel": dbus.MakeVariant(uint32(100))}, "", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mode, dwell, err := scanParamsFromConfig(tt.params, "active", 40)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("scanParamsFromConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (mode != tt.mode || dwell != tt.dwell) {
+				t.Errorf("scanParamsFromConfig() = %q, %d, want %q, %d", mode, dwell, tt.mode, tt.dwell)
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package dbus
+
+import (
+	"encoding/hex"
+	"log"
+	"strings"
+	"sync"
+
+	"x-network/internal/state"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+)
+
+// Per-network objects, mirroring NetworkManager's AccessPoint enumeration
+const (
+	NetworksPath         = ObjectPath + "/Networks"
+	AccessPointInterface = Interface + ".AccessPoint"
+	ObjectManagerIface   = "org.freedesktop.DBus.ObjectManager"
+)
+
+// accessPoint is one visible network exported at NetworksPath/<id>
+type accessPoint struct {
+	svc  *Service
+	path dbus.ObjectPath
+
+	mu  sync.Mutex
+	net state.Network
+}
+
+// networkObjects tracks the exported accessPoint objects
+// The tree is rebuilt from state.Networks, so it always matches the Networks property
+type networkObjects struct {
+	mu      sync.Mutex
+	objects map[dbus.ObjectPath]*accessPoint
+}
+
+// objectManager serves org.freedesktop.DBus.ObjectManager on ObjectPath
+type objectManager struct {
+	svc *Service
+}
+
+// networkPath returns the object path of a network: hex SSID plus security,
+// like IWD's own network paths, so the id is stable across scans
+func networkPath(n state.Network) dbus.ObjectPath {
+	security := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, n.Security)
+	id := hex.EncodeToString([]byte(n.SSID)) + "_" + security
+	return dbus.ObjectPath(NetworksPath + "/" + id)
+}
+
+// apProperties builds the AccessPoint property set of a network
+func apProperties(n state.Network) map[string]dbus.Variant {
+	return map[string]dbus.Variant{
+		"Ssid":      dbus.MakeVariant(n.SSID),
+		"Security":  dbus.MakeVariant(n.Security),
+		"Signal":    dbus.MakeVariant(n.Signal),
+		"Saved":     dbus.MakeVariant(n.Saved),
+		"Connected": dbus.MakeVariant(n.Connected),
+	}
+}
+
+// exportObjectManager exports the ObjectManager on the root object
+func (s *Service) exportObjectManager() error {
+	s.networkObjs.objects = make(map[dbus.ObjectPath]*accessPoint)
+	return s.conn.Export(&objectManager{svc: s}, ObjectPath, ObjectManagerIface)
+}
+
+// GetManagedObjects implements org.freedesktop.DBus.ObjectManager.GetManagedObjects
+func (m *objectManager) GetManagedObjects() (map[dbus.ObjectPath]map[string]map[string]dbus.Variant, *dbus.Error) {
+	objs := &m.svc.networkObjs
+	objs.mu.Lock()
+	defer objs.mu.Unlock()
+
+	result := make(map[dbus.ObjectPath]map[string]map[string]dbus.Variant, len(objs.objects))
+	for path, ap := range objs.objects {
+		ap.mu.Lock()
+		result[path] = map[string]map[string]dbus.Variant{AccessPointInterface: apProperties(ap.net)}
+		ap.mu.Unlock()
+	}
+	return result, nil
+}
+
+// syncNetworkObjects brings the object tree in line with networks: new
+// networks are exported (InterfacesAdded), changed ones emit PropertiesChanged
+// and networks gone from the scan are unexported (InterfacesRemoved)
+func (s *Service) syncNetworkObjects(networks []state.Network) {
+	objs := &s.networkObjs
+	objs.mu.Lock()
+	defer objs.mu.Unlock()
+
+	seen := make(map[dbus.ObjectPath]bool, len(networks))
+	for _, n := range networks {
+		path := networkPath(n)
+		if seen[path] {
+			continue // Unmerged BSS entries - the first (strongest) one wins
+		}
+		seen[path] = true
+
+		ap, ok := objs.objects[path]
+		if !ok {
+			ap = &accessPoint{svc: s, path: path, net: n}
+			if err := s.exportAccessPoint(ap); err != nil {
+				log.Printf("Failed to export network %s: %v", n.SSID, err)
+				continue
+			}
+			objs.objects[path] = ap
+			s.conn.Emit(ObjectPath, ObjectManagerIface+".InterfacesAdded", path,
+				map[string]map[string]dbus.Variant{AccessPointInterface: apProperties(n)})
+			continue
+		}
+
+		ap.mu.Lock()
+		changed := ap.net != n
+		ap.net = n
+		ap.mu.Unlock()
+		if changed {
+			s.conn.Emit(path, "org.freedesktop.DBus.Properties.PropertiesChanged",
+				AccessPointInterface, apProperties(n), []string{})
+		}
+	}
+
+	for path := range objs.objects {
+		if seen[path] {
+			continue
+		}
+		delete(objs.objects, path)
+		s.conn.Export(nil, path, AccessPointInterface)
+		s.conn.Export(nil, path, "org.freedesktop.DBus.Properties")
+		s.conn.Export(nil, path, "org.freedesktop.DBus.Introspectable")
+		s.conn.Emit(ObjectPath, ObjectManagerIface+".InterfacesRemoved", path, []string{AccessPointInterface})
+	}
+}
+
+// exportAccessPoint exports one network object with its properties and introspection
+func (s *Service) exportAccessPoint(ap *accessPoint) error {
+	if err := s.conn.Export(ap, ap.path, AccessPointInterface); err != nil {
+		return err
+	}
+	if err := s.conn.Export(apPropertiesIface{ap}, ap.path, "org.freedesktop.DBus.Properties"); err != nil {
+		return err
+	}
+	node := &introspect.Node{
+		Name: string(ap.path),
+		Interfaces: []introspect.Interface{
+			introspect.IntrospectData,
+			{
+				Name: AccessPointInterface,
+				Methods: []introspect.Method{
+					{Name: "Connect", Args: []introspect.Arg{
+						{Name: "params", Type: "a{sv}", Direction: "in"},
+						{Name: "success", Type: "b", Direction: "out"},
+					}},
+				},
+				Properties: []introspect.Property{
+					{Name: "Ssid", Type: "s", Access: "read"},
+					{Name: "Security", Type: "s", Access: "read"},
+					{Name: "Signal", Type: "y", Access: "read"},
+					{Name: "Saved", Type: "b", Access: "read"},
+					{Name: "Connected", Type: "b", Access: "read"},
+				},
+			},
+		},
+	}
+	return s.conn.Export(introspect.NewIntrospectable(node), ap.path, "org.freedesktop.DBus.Introspectable")
+}
+
+// Connect joins this network; params are those of the root Connect minus
+// ssid/security, which come from the object. Authorized as Connect
+func (ap *accessPoint) Connect(sender dbus.Sender, params map[string]dbus.Variant) (bool, *dbus.Error) {
+	ap.mu.Lock()
+	n := ap.net
+	ap.mu.Unlock()
+
+	full := make(map[string]dbus.Variant, len(params)+2)
+	for k, v := range params {
+		full[k] = v
+	}
+	full["ssid"] = dbus.MakeVariant(n.SSID)
+	full["security"] = dbus.MakeVariant(n.Security)
+	return ap.svc.Connect(sender, full)
+}
+
+// apPropertiesIface serves org.freedesktop.DBus.Properties for an accessPoint
+type apPropertiesIface struct {
+	ap *accessPoint
+}
+
+// Get implements org.freedesktop.DBus.Properties.Get
+func (p apPropertiesIface) Get(iface, propName string) (dbus.Variant, *dbus.Error) {
+	props, err := p.GetAll(iface)
+	if err != nil {
+		return dbus.Variant{}, err
+	}
+	v, ok := props[propName]
+	if !ok {
+		return dbus.Variant{}, dbus.NewError("org.freedesktop.DBus.Error.UnknownProperty", []interface{}{"Unknown property"})
+	}
+	return v, nil
+}
+
+// GetAll implements org.freedesktop.DBus.Properties.GetAll
+func (p apPropertiesIface) GetAll(iface string) (map[string]dbus.Variant, *dbus.Error) {
+	if iface != AccessPointInterface {
+		return nil, dbus.NewError("org.freedesktop.DBus.Error.UnknownInterface", []interface{}{"Unknown interface"})
+	}
+	p.ap.mu.Lock()
+	defer p.ap.mu.Unlock()
+	return apProperties(p.ap.net), nil
+}
+
+// Set implements org.freedesktop.DBus.Properties.Set (read-only, returns error)
+func (p apPropertiesIface) Set(iface, propName string, value dbus.Variant) *dbus.Error {
+	return dbus.NewError("org.freedesktop.DBus.Error.PropertyReadOnly", []interface{}{"Properties are read-only"})
+}
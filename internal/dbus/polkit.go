@@ -0,0 +1,170 @@
+package dbus
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// polkit authority on the system bus
+const (
+	polkitService   = "org.freedesktop.PolicyKit1"
+	polkitPath      = "/org/freedesktop/PolicyKit1/Authority"
+	polkitAuthority = "org.freedesktop.PolicyKit1.Authority"
+
+	// polkitAllowInteraction lets polkit ask the user to authenticate
+	polkitAllowInteraction uint32 = 1
+)
+
+// polkit action IDs guarding privileged methods on the system bus
+const (
+	ActionConnect      = "org.xshell.network.connect"
+	ActionModify       = "org.xshell.network.modify"
+	ActionHotspot      = "org.xshell.network.hotspot"
+	ActionAirplaneMode = "org.xshell.network.airplane-mode"
+)
+
+// Action describes one polkit action for the generated .policy file
+type Action struct {
+	ID            string
+	Description   string
+	Message       string
+	AllowActive   string // Default for the active local session
+	AllowInactive string
+	AllowAny      string
+}
+
+// Actions lists every action the daemon checks
+var Actions = []Action{
+	{ActionConnect, "Connect to and disconnect from networks",
+		"Authentication is required to change the network connection",
+		"yes", "auth_admin", "auth_admin"},
+	{ActionModify, "Change saved networks and network settings",
+		"Authentication is required to change network settings",
+		"auth_admin_keep", "auth_admin", "auth_admin"},
+	{ActionHotspot, "Start and stop a WiFi hotspot",
+		"Authentication is required to share the connection",
+		"auth_admin_keep", "auth_admin", "auth_admin"},
+	{ActionAirplaneMode, "Turn radios on and off",
+		"Authentication is required to change the radio state",
+		"yes", "auth_admin", "auth_admin"},
+}
+
+// methodActions maps privileged methods to the action they need
+// Methods not listed (reads, scans, CheckCaptivePortal over the default route)
+// are open to every caller; AccessPoint.Connect goes through Connect
+var methodActions = map[string]string{
+	"Connect":                      ActionConnect,
+	"ConnectSaved":                 ActionConnect,
+	"Disconnect":                   ActionConnect,
+	"Roam":                         ActionConnect,
+	"ConnectBluetoothTethering":    ActionConnect,
+	"DisconnectBluetoothTethering": ActionConnect,
+	"RequestUsbNetwork":            ActionConnect,
+	"ReleaseUsbNetwork":            ActionConnect,
+	"CheckCaptivePortalOn":         ActionConnect,
+	"OpenCaptivePortal":            ActionConnect,
+
+	"Forget":               ActionModify,
+	"SetAutoConnect":       ActionModify,
+	"SetNetworkPriority":   ActionModify,
+	"BlacklistNetwork":     ActionModify,
+	"SetIPConfig":          ActionModify,
+	"SetStaticIP":          ActionModify,
+	"SetDHCP":              ActionModify,
+	"SetPrimaryConnection": ActionModify,
+	"SetUsbAutoConnect":    ActionModify,
+	"ApplyPolicy":          ActionModify,
+	"SetPortalEndpoints":   ActionModify,
+	"ReloadConfig":         ActionModify,
+	"SetScanActive":        ActionModify,
+	"SetScanParams":        ActionModify,
+
+	"StartHotspot":           ActionHotspot,
+	"StartHotspotWithParams": ActionHotspot,
+	"StopHotspot":            ActionHotspot,
+
+	"EnableWifi":      ActionAirplaneMode,
+	"SetAirplaneMode": ActionAirplaneMode,
+	"SetRfkill":       ActionAirplaneMode,
+	"SetRadioBlocked": ActionAirplaneMode,
+}
+
+// ActionForMethod returns the polkit action guarding method ("" if unguarded)
+func ActionForMethod(method string) string {
+	return methodActions[method]
+}
+
+// polkitSubject is polkit's (sa{sv}) subject
+type polkitSubject struct {
+	Kind    string
+	Details map[string]dbus.Variant
+}
+
+// polkitResult is polkit's (bba{ss}) authorization result
+type polkitResult struct {
+	IsAuthorized bool
+	IsChallenge  bool
+	Details      map[string]string
+}
+
+// authorize checks that sender may call method
+// Only enforced on the system bus; the session bus already belongs to the user
+func (s *Service) authorize(sender dbus.Sender, method string) *dbus.Error {
+	action := ActionForMethod(method)
+	if !s.polkit || action == "" {
+		return nil
+	}
+
+	subject := polkitSubject{
+		Kind:    "system-bus-name",
+		Details: map[string]dbus.Variant{"name": dbus.MakeVariant(string(sender))},
+	}
+	var result polkitResult
+	err := s.conn.Object(polkitService, polkitPath).Call(polkitAuthority+".CheckAuthorization", 0,
+		subject, action, map[string]string{}, polkitAllowInteraction, "").Store(&result)
+	if err != nil {
+		log.Printf("polkit: cannot check %s for %s: %v", action, sender, err)
+		return dbus.NewError("org.freedesktop.DBus.Error.AccessDenied", []interface{}{"authorization check failed: " + err.Error()})
+	}
+	if !result.IsAuthorized {
+		log.Printf("polkit: %s denied %s (%s)", sender, method, action)
+		return dbus.NewError("org.freedesktop.DBus.Error.AccessDenied", []interface{}{"not authorized: " + action})
+	}
+	return nil
+}
+
+// PolkitPolicy renders the .policy file for Actions
+// Install it as /usr/share/polkit-1/actions/org.xshell.network.policy
+func PolkitPolicy() []byte {
+	actions := append([]Action(nil), Actions...)
+	sort.Slice(actions, func(i, j int) bool { return actions[i].ID < actions[j].ID })
+
+	var b bytes.Buffer
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE policyconfig PUBLIC
+ "-//freedesktop//DTD PolicyKit Policy Configuration 1.0//EN"
+ "http://www.freedesktop.org/standards/PolicyKit/1/policyconfig.dtd">
+<policyconfig>
+  <vendor>x-network</vendor>
+  <vendor_url>https://github.com/syndicateF/x-network</vendor_url>
+`)
+	for _, a := range actions {
+		fmt.Fprintf(&b, `
+  <action id="%s">
+    <description>%s</description>
+    <message>%s</message>
+    <defaults>
+      <allow_any>%s</allow_any>
+      <allow_inactive>%s</allow_inactive>
+      <allow_active>%s</allow_active>
+    </defaults>
+  </action>
+`, a.ID, a.Description, a.Message, a.AllowAny, a.AllowInactive, a.AllowActive)
+	}
+	b.WriteString("</policyconfig>\n")
+	return b.Bytes()
+}
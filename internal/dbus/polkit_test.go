@@ -0,0 +1,57 @@
+package dbus
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+func TestActionForMethod(t *testing.T) {
+	tests := []struct {
+		method string
+		want   string
+	}{
+		{"Connect", ActionConnect},
+		{"CheckCaptivePortalOn", ActionConnect},
+		{"OpenCaptivePortal", ActionConnect},
+		{"SetScanActive", ActionModify},
+		{"SetScanParams", ActionModify},
+		{"ApplyPolicy", ActionModify},
+		{"StartHotspot", ActionHotspot},
+		{"StartHotspotWithParams", ActionHotspot},
+		{"SetAirplaneMode", ActionAirplaneMode},
+		{"Scan", ""},
+		{"CheckCaptivePortal", ""},
+		{"GetState", ""},
+	}
+	for _, tt := range tests {
+		if got := ActionForMethod(tt.method); got != tt.want {
+			t.Errorf("ActionForMethod(%q) = %q, want %q", tt.method, got, tt.want)
+		}
+	}
+}
+
+// Every method taking the caller is guarded, and every guarded method exists
+func TestMethodActionsMatchService(t *testing.T) {
+	senderType := reflect.TypeOf(dbus.Sender(""))
+	svc := reflect.TypeOf(&Service{})
+
+	for i := 0; i < svc.NumMethod(); i++ {
+		m := svc.Method(i)
+		takesSender := m.Type.NumIn() > 1 && m.Type.In(1) == senderType
+		if takesSender && ActionForMethod(m.Name) == "" {
+			t.Errorf("%s takes the sender but has no polkit action", m.Name)
+		}
+	}
+	for method := range methodActions {
+		m, ok := svc.MethodByName(method)
+		if !ok {
+			t.Errorf("methodActions lists %s, which Service doesn't export", method)
+			continue
+		}
+		if m.Type.NumIn() < 2 || m.Type.In(1) != senderType {
+			t.Errorf("%s is guarded but doesn't take the sender to authorize", method)
+		}
+	}
+}
@@ -1,6 +1,10 @@
 package dbus
 
 import (
+	"time"
+
+	"x-network/internal/hotspot"
+	"x-network/internal/iwd"
 	"x-network/internal/state"
 
 	"github.com/godbus/dbus/v5"
@@ -21,24 +25,60 @@ func (s *Service) Get(iface, propName string) (dbus.Variant, *dbus.Error) {
 		return dbus.MakeVariant(st.WifiEnabled), nil
 	case "WifiScanning":
 		return dbus.MakeVariant(st.WifiScanning), nil
+	case "LastScanTime":
+		return dbus.MakeVariant(unixOrZero(st.LastScanTime)), nil
+	case "ScanParams":
+		return dbus.MakeVariant(scanParams(st)), nil
+	case "SecurityDowngraded":
+		return dbus.MakeVariant(st.SecurityDowngraded), nil
+	case "Connectivity":
+		return dbus.MakeVariant(st.Connectivity), nil
+	case "InterfaceConnectivity":
+		return dbus.MakeVariant(nonNilMap(st.InterfaceConnectivity)), nil
+	case "HasInternet":
+		return dbus.MakeVariant(st.HasInternet), nil
+	case "AgentRegistered":
+		return dbus.MakeVariant(st.AgentRegistered), nil
 	case "ConnectionState":
 		return dbus.MakeVariant(string(st.ConnectionState)), nil
+	case "ConnectedSince":
+		return dbus.MakeVariant(unixOrZero(st.ConnectedSince)), nil
+	case "UsbConnectedSince":
+		return dbus.MakeVariant(unixOrZero(st.UsbConnectedSince)), nil
+	case "HotspotSince":
+		return dbus.MakeVariant(unixOrZero(st.HotspotSince)), nil
 	case "ActiveSSID":
 		return dbus.MakeVariant(st.ActiveSSID), nil
 	case "ConnectingSSID":
 		return dbus.MakeVariant(st.ConnectingSSID), nil
 	case "ActiveSecurity":
 		return dbus.MakeVariant(st.ActiveSecurity), nil
+	case "DisconnectReason":
+		return dbus.MakeVariant(st.DisconnectReason), nil
 	case "SignalRSSI":
 		return dbus.MakeVariant(st.SignalRSSI), nil
 	case "SignalStrength":
 		return dbus.MakeVariant(st.SignalStrength), nil
+	case "SignalBars":
+		return dbus.MakeVariant(st.SignalBars), nil
 	case "Frequency":
 		return dbus.MakeVariant(st.Frequency), nil
 	case "IpAddress":
 		return dbus.MakeVariant(st.IpAddress), nil
+	case "IpCidr":
+		return dbus.MakeVariant(state.IPCidr(st.IpAddress, st.IpPrefixLen)), nil
+	case "IpBroadcast":
+		return dbus.MakeVariant(state.IPv4Broadcast(st.IpAddress, st.IpPrefixLen)), nil
+	case "Addresses":
+		return dbus.MakeVariant(addressesToDBus(st.Addresses)), nil
 	case "Gateway":
 		return dbus.MakeVariant(st.Gateway), nil
+	case "GatewayReachable":
+		return dbus.MakeVariant(st.GatewayReachable), nil
+	case "DnsServers":
+		return dbus.MakeVariant(nonNil(st.DnsServers)), nil
+	case "SearchDomains":
+		return dbus.MakeVariant(nonNil(st.SearchDomains)), nil
 	case "MacAddress":
 		return dbus.MakeVariant(st.MacAddress), nil
 	case "InterfaceName":
@@ -47,20 +87,54 @@ func (s *Service) Get(iface, propName string) (dbus.Variant, *dbus.Error) {
 		return dbus.MakeVariant(st.TrafficIn), nil
 	case "TrafficOut":
 		return dbus.MakeVariant(st.TrafficOut), nil
+	case "GatewayLatencyMs":
+		return dbus.MakeVariant(st.GatewayLatencyMs), nil
+	case "InternetLatencyMs":
+		return dbus.MakeVariant(st.InternetLatencyMs), nil
+	case "PacketLossPercent":
+		return dbus.MakeVariant(st.PacketLossPercent), nil
+	case "LinkQuality":
+		return dbus.MakeVariant(st.LinkQuality), nil
 	case "Networks":
 		return dbus.MakeVariant(s.networksToDBus(st.Networks)), nil
 	case "SavedNetworks":
 		return dbus.MakeVariant(st.SavedNetworks), nil
+	case "BlockedNetworks":
+		return dbus.MakeVariant(nonNil(st.BlockedNetworks)), nil
 	case "AirplaneMode":
 		return dbus.MakeVariant(st.AirplaneMode), nil
+	case "WifiBlocked":
+		return dbus.MakeVariant(st.WifiBlocked.String()), nil
+	case "BluetoothBlocked":
+		return dbus.MakeVariant(st.BluetoothBlocked.String()), nil
 	case "CaptivePortalDetected":
 		return dbus.MakeVariant(st.CaptivePortalDetected), nil
 	case "HotspotActive":
 		return dbus.MakeVariant(st.HotspotActive), nil
+	case "HotspotSSID":
+		return dbus.MakeVariant(st.HotspotSSID), nil
+	case "HotspotClients":
+		return dbus.MakeVariant(st.HotspotClients), nil
+	case "HotspotExpiresAt":
+		return dbus.MakeVariant(unixOrZero(st.HotspotExpiresAt)), nil
+	case "HotspotQRString":
+		return dbus.MakeVariant(hotspotQR(&st)), nil
 	case "ConnectionType":
 		return dbus.MakeVariant(st.ConnectionType), nil
+	case "PrimaryInterface":
+		return dbus.MakeVariant(st.PrimaryInterface), nil
+	case "PrimaryPinned":
+		return dbus.MakeVariant(st.PrimaryPinned), nil
 	case "Band":
 		return dbus.MakeVariant(state.FrequencyToBand(st.Frequency)), nil
+	case "Channel":
+		return dbus.MakeVariant(st.Channel), nil
+	case "ChannelWidth":
+		return dbus.MakeVariant(st.ChannelWidth), nil
+	case "WifiGeneration":
+		return dbus.MakeVariant(st.WifiGeneration), nil
+	case "EthernetCablePlugged":
+		return dbus.MakeVariant(st.EthernetCablePlugged), nil
 	// USB Tethering properties
 	case "UsbInterfaceDetected":
 		return dbus.MakeVariant(st.UsbInterfaceDetected), nil
@@ -70,8 +144,37 @@ func (s *Service) Get(iface, propName string) (dbus.Variant, *dbus.Error) {
 		return dbus.MakeVariant(st.UsbTetheringConnected), nil
 	case "UsbInterfaceName":
 		return dbus.MakeVariant(st.UsbInterfaceName), nil
+	case "UsbFallbackActive":
+		return dbus.MakeVariant(st.UsbFallbackActive), nil
+	case "UsbIpAddressV6":
+		return dbus.MakeVariant(st.UsbIpAddressV6), nil
+	case "UsbAutoConnect":
+		return dbus.MakeVariant(st.UsbAutoConnect), nil
+	case "VpnActive":
+		return dbus.MakeVariant(st.VpnActive), nil
+	case "VpnInterface":
+		return dbus.MakeVariant(st.VpnInterface), nil
+	case "BtTetheringAvailable":
+		return dbus.MakeVariant(st.BtTetheringAvailable), nil
+	case "BtTetheringConnected":
+		return dbus.MakeVariant(st.BtTetheringConnected), nil
+	case "BtDeviceName":
+		return dbus.MakeVariant(st.BtDeviceName), nil
+	case "BtInterfaceName":
+		return dbus.MakeVariant(st.BtInterfaceName), nil
+	// DHCP lease properties
+	case "DhcpServer":
+		return dbus.MakeVariant(st.DhcpServer), nil
+	case "DhcpLeaseExpiry":
+		return dbus.MakeVariant(unixOrZero(st.DhcpLeaseExpiry)), nil
 	case "LastError":
 		return dbus.MakeVariant(st.LastError), nil
+	case "LastErrorCode":
+		return dbus.MakeVariant(st.LastErrorCode), nil
+	case "DaemonUptimeSeconds":
+		return dbus.MakeVariant(s.uptimeSeconds()), nil
+	case "Version":
+		return dbus.MakeVariant(s.version), nil
 	default:
 		return dbus.Variant{}, dbus.NewError("org.freedesktop.DBus.Error.UnknownProperty", []interface{}{"Unknown property: " + propName})
 	}
@@ -89,33 +192,86 @@ func (s *Service) GetAll(iface string) (map[string]dbus.Variant, *dbus.Error) {
 		"WifiEnabled":           dbus.MakeVariant(st.WifiEnabled),
 		"WifiScanning":          dbus.MakeVariant(st.WifiScanning),
 		"ConnectionState":       dbus.MakeVariant(string(st.ConnectionState)),
+		"Connectivity":          dbus.MakeVariant(st.Connectivity),
+		"InterfaceConnectivity": dbus.MakeVariant(nonNilMap(st.InterfaceConnectivity)),
+		"HasInternet":           dbus.MakeVariant(st.HasInternet),
+		"SecurityDowngraded":    dbus.MakeVariant(st.SecurityDowngraded),
+		"AgentRegistered":       dbus.MakeVariant(st.AgentRegistered),
+		"ScanParams":            dbus.MakeVariant(scanParams(st)),
+		"LastScanTime":          dbus.MakeVariant(unixOrZero(st.LastScanTime)),
 		"ActiveSSID":            dbus.MakeVariant(st.ActiveSSID),
+		"ConnectedSince":        dbus.MakeVariant(unixOrZero(st.ConnectedSince)),
+		"UsbConnectedSince":     dbus.MakeVariant(unixOrZero(st.UsbConnectedSince)),
+		"HotspotSince":          dbus.MakeVariant(unixOrZero(st.HotspotSince)),
 		"ConnectingSSID":        dbus.MakeVariant(st.ConnectingSSID), // Added - was missing!
 		"ActiveSecurity":        dbus.MakeVariant(st.ActiveSecurity),
+		"DisconnectReason":      dbus.MakeVariant(st.DisconnectReason),
 		"SignalRSSI":            dbus.MakeVariant(st.SignalRSSI),
 		"SignalStrength":        dbus.MakeVariant(st.SignalStrength),
+		"SignalBars":            dbus.MakeVariant(st.SignalBars),
 		"Frequency":             dbus.MakeVariant(st.Frequency),
 		"IpAddress":             dbus.MakeVariant(st.IpAddress),
+		"IpCidr":                dbus.MakeVariant(state.IPCidr(st.IpAddress, st.IpPrefixLen)),
+		"IpBroadcast":           dbus.MakeVariant(state.IPv4Broadcast(st.IpAddress, st.IpPrefixLen)),
+		"Addresses":             dbus.MakeVariant(addressesToDBus(st.Addresses)),
 		"Gateway":               dbus.MakeVariant(st.Gateway),
+		"GatewayReachable":      dbus.MakeVariant(st.GatewayReachable),
+		"DnsServers":            dbus.MakeVariant(nonNil(st.DnsServers)),
+		"SearchDomains":         dbus.MakeVariant(nonNil(st.SearchDomains)),
 		"MacAddress":            dbus.MakeVariant(st.MacAddress),
 		"InterfaceName":         dbus.MakeVariant(st.InterfaceName),
 		"TrafficIn":             dbus.MakeVariant(st.TrafficIn),
 		"TrafficOut":            dbus.MakeVariant(st.TrafficOut),
+		"GatewayLatencyMs":      dbus.MakeVariant(st.GatewayLatencyMs),
+		"InternetLatencyMs":     dbus.MakeVariant(st.InternetLatencyMs),
+		"PacketLossPercent":     dbus.MakeVariant(st.PacketLossPercent),
+		"LinkQuality":           dbus.MakeVariant(st.LinkQuality),
 		"Networks":              dbus.MakeVariant(s.networksToDBus(st.Networks)),
 		"SavedNetworks":         dbus.MakeVariant(st.SavedNetworks),
+		"BlockedNetworks":       dbus.MakeVariant(nonNil(st.BlockedNetworks)),
 		"AirplaneMode":          dbus.MakeVariant(st.AirplaneMode),
+		"WifiBlocked":           dbus.MakeVariant(st.WifiBlocked.String()),
+		"BluetoothBlocked":      dbus.MakeVariant(st.BluetoothBlocked.String()),
 		"CaptivePortalDetected": dbus.MakeVariant(st.CaptivePortalDetected),
 		"HotspotActive":         dbus.MakeVariant(st.HotspotActive),
+		"HotspotSSID":           dbus.MakeVariant(st.HotspotSSID),
+		"HotspotClients":        dbus.MakeVariant(st.HotspotClients),
+		"HotspotExpiresAt":      dbus.MakeVariant(unixOrZero(st.HotspotExpiresAt)),
+		"HotspotQRString":       dbus.MakeVariant(hotspotQR(&st)),
 		"ConnectionType":        dbus.MakeVariant(st.ConnectionType),
+		"PrimaryInterface":      dbus.MakeVariant(st.PrimaryInterface),
+		"PrimaryPinned":         dbus.MakeVariant(st.PrimaryPinned),
 		"Band":                  dbus.MakeVariant(state.FrequencyToBand(st.Frequency)),
+		"Channel":               dbus.MakeVariant(st.Channel),
+		"ChannelWidth":          dbus.MakeVariant(st.ChannelWidth),
+		"WifiGeneration":        dbus.MakeVariant(st.WifiGeneration),
+		"EthernetCablePlugged":  dbus.MakeVariant(st.EthernetCablePlugged),
 		// USB Tethering properties
 		"UsbInterfaceDetected":  dbus.MakeVariant(st.UsbInterfaceDetected),
 		"UsbTetheringAvailable": dbus.MakeVariant(st.UsbTetheringAvailable),
 		"UsbTetheringConnected": dbus.MakeVariant(st.UsbTetheringConnected),
 		"UsbInterfaceName":      dbus.MakeVariant(st.UsbInterfaceName),
+		"UsbFallbackActive":     dbus.MakeVariant(st.UsbFallbackActive),
+		"UsbIpAddressV6":        dbus.MakeVariant(st.UsbIpAddressV6),
+		"UsbAutoConnect":        dbus.MakeVariant(st.UsbAutoConnect),
+		"VpnActive":             dbus.MakeVariant(st.VpnActive),
+		"VpnInterface":          dbus.MakeVariant(st.VpnInterface),
+		"BtTetheringAvailable":  dbus.MakeVariant(st.BtTetheringAvailable),
+		"BtTetheringConnected":  dbus.MakeVariant(st.BtTetheringConnected),
+		"BtDeviceName":          dbus.MakeVariant(st.BtDeviceName),
+		"BtInterfaceName":       dbus.MakeVariant(st.BtInterfaceName),
+
+		// DHCP lease properties
+		"DhcpServer":      dbus.MakeVariant(st.DhcpServer),
+		"DhcpLeaseExpiry": dbus.MakeVariant(unixOrZero(st.DhcpLeaseExpiry)),
 
 		// Error reporting
-		"LastError": dbus.MakeVariant(st.LastError),
+		"LastError":     dbus.MakeVariant(st.LastError),
+		"LastErrorCode": dbus.MakeVariant(st.LastErrorCode),
+
+		// Daemon build and uptime (not part of PropertiesChanged)
+		"DaemonUptimeSeconds": dbus.MakeVariant(s.uptimeSeconds()),
+		"Version":             dbus.MakeVariant(s.version),
 	}, nil
 }
 
@@ -124,13 +280,61 @@ func (s *Service) Set(iface, propName string, value dbus.Variant) *dbus.Error {
 	return dbus.NewError("org.freedesktop.DBus.Error.PropertyReadOnly", []interface{}{"Properties are read-only"})
 }
 
+// unixOrZero converts a timestamp to unix seconds, 0 for the zero time
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+// hotspotQR returns the join QR payload for the running hotspot ("" if none)
+func hotspotQR(st *state.State) string {
+	if !st.HotspotActive || st.HotspotSecurity == "" {
+		return "" // Not running, or started outside the daemon (password unknown)
+	}
+	return hotspot.QRString(st.HotspotSSID, st.HotspotPassword, st.HotspotSecurity)
+}
+
+// nonNil returns an empty slice for nil so D-Bus always gets a typed array
+func nonNil(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
+
+// nonNilMap returns an empty map instead of nil
+func nonNilMap(m map[string]string) map[string]string {
+	if m == nil {
+		return map[string]string{}
+	}
+	return m
+}
+
 // NetworkDBus represents a network for D-Bus
 type NetworkDBus struct {
-	SSID      string
-	Security  string
-	Signal    uint8
-	Connected bool
-	Frequency uint32
+	SSID          string
+	Security      string
+	Signal        uint8
+	Connected     bool
+	Frequency     uint32
+	SecurityLabel string
+}
+
+// AddressDBus is one entry of the Addresses property: (cidr, family)
+type AddressDBus struct {
+	CIDR   string
+	Family string
+}
+
+// addressesToDBus converts interface addresses to D-Bus format
+func addressesToDBus(addrs []state.Address) []AddressDBus {
+	result := make([]AddressDBus, len(addrs))
+	for i, a := range addrs {
+		result[i] = AddressDBus{a.CIDR, a.Family}
+	}
+	return result
 }
 
 // networksToDBus converts networks to D-Bus format
@@ -138,12 +342,25 @@ func (s *Service) networksToDBus(networks []state.Network) []NetworkDBus {
 	result := make([]NetworkDBus, len(networks))
 	for i, n := range networks {
 		result[i] = NetworkDBus{
-			SSID:      n.SSID,
-			Security:  n.Security,
-			Signal:    n.Signal,
-			Connected: n.Connected,
-			Frequency: n.Frequency,
+			SSID:          n.SSID,
+			Security:      n.Security,
+			Signal:        n.Signal,
+			Connected:     n.Connected,
+			Frequency:     n.Frequency,
+			SecurityLabel: state.SecurityLabel(n.Security),
 		}
 	}
 	return result
 }
+
+// scanParams builds the ScanParams dict (mode, dwell)
+func scanParams(st state.State) map[string]dbus.Variant {
+	mode := st.ScanMode
+	if mode == "" {
+		mode = iwd.ScanModeActive
+	}
+	return map[string]dbus.Variant{
+		"mode":  dbus.MakeVariant(mode),
+		"dwell": dbus.MakeVariant(st.ScanDwellMs),
+	}
+}
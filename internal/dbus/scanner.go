@@ -0,0 +1,123 @@
+package dbus
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"x-network/internal/state"
+)
+
+const (
+	// backgroundScanInterval is the refresh rate while a network picker is open
+	backgroundScanInterval = 10 * time.Second
+
+	// backgroundScanIdleTimeout disables background scanning if no client renews it
+	backgroundScanIdleTimeout = 3 * time.Minute
+)
+
+// setScanActive starts, renews or stops background scanning
+// Each enable call pushes the idle deadline out; clients re-call to keep it alive
+func (s *Service) setScanActive(enabled bool) {
+	s.scanActiveMu.Lock()
+	defer s.scanActiveMu.Unlock()
+
+	if !enabled {
+		if s.scanActiveStop != nil {
+			log.Printf("Background scanning disabled")
+			close(s.scanActiveStop)
+			s.scanActiveStop = nil
+		}
+		return
+	}
+
+	s.scanActiveDeadline = time.Now().Add(backgroundScanIdleTimeout)
+	if s.scanActiveStop != nil {
+		return // Already running, deadline renewed
+	}
+
+	log.Printf("Background scanning enabled")
+	stopCh := make(chan struct{})
+	s.scanActiveStop = stopCh
+	go s.runBackgroundScan(stopCh)
+}
+
+// runBackgroundScan scans periodically while WiFi is on and not connected
+// Stops itself on a transition to connected and after the idle timeout
+func (s *Service) runBackgroundScan(stopCh chan struct{}) {
+	ticker := time.NewTicker(backgroundScanInterval)
+	defer ticker.Stop()
+
+	prevState := s.stateMgr.Get().ConnectionState
+	s.backgroundScan()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+		}
+
+		s.scanActiveMu.Lock()
+		deadline := s.scanActiveDeadline
+		s.scanActiveMu.Unlock()
+
+		st := s.stateMgr.Get()
+		if reason := backgroundScanStop(prevState, &st, deadline, time.Now()); reason != "" {
+			log.Printf("Background scanning stopped: %s", reason)
+			s.stopBackgroundScan(stopCh)
+			return
+		}
+		prevState = st.ConnectionState
+
+		s.backgroundScan()
+	}
+}
+
+// backgroundScanStop returns why the loop should end ("" = keep going): the
+// idle deadline passed, or the link just connected (off-channel scans would
+// disturb the fresh connection)
+func backgroundScanStop(prev state.ConnectionState, st *state.State, deadline, now time.Time) string {
+	if now.After(deadline) {
+		return fmt.Sprintf("idle for %s", backgroundScanIdleTimeout)
+	}
+	if st.ConnectionState == state.StateConnected && prev != state.StateConnected {
+		return "connected"
+	}
+	return ""
+}
+
+// backgroundScanDue reports whether WiFi is on and idle enough to scan
+func backgroundScanDue(st *state.State) bool {
+	switch st.ConnectionState {
+	case state.StateConnected, state.StateConnecting, state.StateObtaining:
+		return false
+	}
+	return st.WifiEnabled
+}
+
+// backgroundScan runs one scan if WiFi is on and idle, then emits NetworksChanged
+// Always a fresh scan: the tick is as long as Scan's cache window
+func (s *Service) backgroundScan() {
+	st := s.stateMgr.Get()
+	if !backgroundScanDue(&st) {
+		return
+	}
+
+	networks, err := s.iwd.ScanFresh()
+	if err != nil {
+		log.Printf("Background scan failed: %v", err)
+		return
+	}
+	s.EmitSignal("NetworksChanged", s.networksToDBus(networks))
+}
+
+// stopBackgroundScan clears the running loop if it is still the current one
+func (s *Service) stopBackgroundScan(stopCh chan struct{}) {
+	s.scanActiveMu.Lock()
+	defer s.scanActiveMu.Unlock()
+	if s.scanActiveStop == stopCh {
+		close(stopCh)
+		s.scanActiveStop = nil
+	}
+}
@@ -0,0 +1,54 @@
+package dbus
+
+import (
+	"testing"
+	"time"
+
+	"x-network/internal/state"
+)
+
+func TestBackgroundScanStop(t *testing.T) {
+	now := time.Now()
+	later := now.Add(time.Minute)
+	tests := []struct {
+		name     string
+		prev     state.ConnectionState
+		current  state.ConnectionState
+		deadline time.Time
+		stop     bool
+	}{
+		{"idle, renewed", state.StateDisconnected, state.StateDisconnected, later, false},
+		{"idle timeout", state.StateDisconnected, state.StateDisconnected, now.Add(-time.Second), true},
+		{"just connected", state.StateObtaining, state.StateConnected, later, true},
+		{"enabled while connected", state.StateConnected, state.StateConnected, later, false},
+		{"connecting", state.StateDisconnected, state.StateConnecting, later, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := state.State{ConnectionState: tt.current}
+			if got := backgroundScanStop(tt.prev, &st, tt.deadline, now); (got != "") != tt.stop {
+				t.Errorf("backgroundScanStop() = %q, want stop=%v", got, tt.stop)
+			}
+		})
+	}
+}
+
+func TestBackgroundScanDue(t *testing.T) {
+	tests := []struct {
+		name string
+		st   state.State
+		want bool
+	}{
+		{"idle", state.State{WifiEnabled: true, ConnectionState: state.StateDisconnected}, true},
+		{"failed", state.State{WifiEnabled: true, ConnectionState: state.StateFailed}, true},
+		{"wifi off", state.State{ConnectionState: state.StateDisconnected}, false},
+		{"connected", state.State{WifiEnabled: true, ConnectionState: state.StateConnected}, false},
+		{"connecting", state.State{WifiEnabled: true, ConnectionState: state.StateConnecting}, false},
+		{"obtaining", state.State{WifiEnabled: true, ConnectionState: state.StateObtaining}, false},
+	}
+	for _, tt := range tests {
+		if got := backgroundScanDue(&tt.st); got != tt.want {
+			t.Errorf("%s: backgroundScanDue() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -3,14 +3,24 @@ package dbus
 import (
 	"fmt"
 	"log"
+	"sync"
+	"time"
 
+	"x-network/internal/bluez"
+	"x-network/internal/connectivity"
+	"x-network/internal/ipconfig"
 	"x-network/internal/iwd"
+	"x-network/internal/netlink"
+	"x-network/internal/settings"
 	"x-network/internal/state"
 
 	"github.com/godbus/dbus/v5"
 	"github.com/godbus/dbus/v5/introspect"
 )
 
+// ServiceName is the default bus name; NewService can own another one so a
+// second instance (e.g. a per-user test daemon) can run next to the first.
+// Object path and interface stay the same under any name
 const (
 	ServiceName = "org.xshell.Network"
 	ObjectPath  = "/org/xshell/Network"
@@ -22,10 +32,53 @@ type Service struct {
 	conn     *dbus.Conn
 	stateMgr *state.Manager
 	iwd      *iwd.Client
+	bt       *bluez.Client // nil when BlueZ is unavailable
+	ipcfg    *ipconfig.Manager
+	settings *settings.Store // Persisted toggles (nil = not persisted)
+	portal   *connectivity.Portal
+	ipLookup *connectivity.PublicIPResolver // GetPublicIP
+	filter   *netlink.InterfaceFilter       // Links tracked by the daemon (GetInterfaces)
+	polkit   bool                           // Check callers with polkit (system bus only)
+
+	reloadConfig func() (reloaded, restart []string, err error) // nil = ReloadConfig unsupported
+	version      string                                         // Build version (Version property)
+	startedAt    time.Time                                      // Daemon start (DaemonUptimeSeconds)
+
+	connectivityMu   sync.Mutex
+	lastConnectivity string                      // For ConnectionChanged on limited <-> connected
+	lastInternet     bool                        // Last HasInternet signalled
+	lastLinkQuality  string                      // Last LinkQuality signalled
+	lastCaptiveSeq   uint64                      // Last CaptiveCheckSeq signalled
+	lastHotspotSeq   uint64                      // Last HotspotEventSeq signalled
+	lastLinkSeq      uint64                      // Last LinkEventSeq signalled
+	lastRadio        map[string]state.RadioBlock // Last block state signalled per radio type
+	lastPrimary      string                      // Last "type/iface" signalled as primary
+	lastVpn          string                      // Last VPN interface signalled ("" = down)
+	lastAddress      [2]string                   // Last IpAddress and Gateway signalled
+	lastScanning     bool                        // Last WifiScanning signalled
+
+	networkObjs networkObjects // Per-network objects under NetworksPath
+
+	// GetPublicIP result, reused while the connection it was obtained on lasts
+	publicIPMu  sync.Mutex
+	publicIP    connectivity.PublicIP
+	publicIPKey string // publicIPCacheKey of the cached result ("" = none)
+
+	// WifiEnabled before SetAirplaneMode(true), restored when it is turned off
+	airplaneMu    sync.Mutex
+	airplaneSaved bool // wifiBefore holds a value to restore
+	wifiBefore    bool
+
+	// Background scanning (SetScanActive)
+	scanActiveMu       sync.Mutex
+	scanActiveStop     chan struct{} // nil when not running
+	scanActiveDeadline time.Time     // Idle cutoff, renewed by each SetScanActive(true)
 }
 
-// NewService creates and registers the D-Bus service
-func NewService(busType string, stateMgr *state.Manager, iwdClient *iwd.Client) (*Service, error) {
+// NewService creates and registers the D-Bus service under name ("" = ServiceName)
+// portal, publicIP and filter are the instances the daemon's watchers use
+func NewService(busType, name string, stateMgr *state.Manager, iwdClient *iwd.Client, btClient *bluez.Client, ipcfg *ipconfig.Manager, settingsStore *settings.Store,
+	portal *connectivity.Portal, publicIP *connectivity.PublicIPResolver, filter *netlink.InterfaceFilter) (*Service, error) {
 	var conn *dbus.Conn
 	var err error
 
@@ -38,21 +91,33 @@ func NewService(busType string, stateMgr *state.Manager, iwdClient *iwd.Client)
 		return nil, fmt.Errorf("failed to connect to D-Bus: %w", err)
 	}
 
+	if name == "" {
+		name = ServiceName
+	}
 	s := &Service{
 		conn:     conn,
 		stateMgr: stateMgr,
 		iwd:      iwdClient,
+		bt:       btClient,
+		ipcfg:    ipcfg,
+		settings: settingsStore,
+		portal:   portal,
+		ipLookup: publicIP,
+		filter:   filter,
+		polkit:   busType == "system",
 	}
+	// Links enumerated before the service came up aren't transitions
+	s.lastLinkSeq = stateMgr.Get().LinkEventSeq
 
 	// Request service name
-	reply, err := conn.RequestName(ServiceName, dbus.NameFlagDoNotQueue)
+	reply, err := conn.RequestName(name, dbus.NameFlagDoNotQueue)
 	if err != nil {
 		conn.Close()
-		return nil, fmt.Errorf("failed to request name: %w", err)
+		return nil, fmt.Errorf("failed to request name %s: %w", name, err)
 	}
 	if reply != dbus.RequestNameReplyPrimaryOwner {
 		conn.Close()
-		return nil, fmt.Errorf("name already taken")
+		return nil, fmt.Errorf("name %s already taken", name)
 	}
 
 	// Export the service object
@@ -67,11 +132,35 @@ func NewService(busType string, stateMgr *state.Manager, iwdClient *iwd.Client)
 		return nil, fmt.Errorf("failed to export properties: %w", err)
 	}
 
+	// Export the ObjectManager for the per-network objects
+	if err := s.exportObjectManager(); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to export object manager: %w", err)
+	}
+
 	// Export introspection
 	node := &introspect.Node{
 		Name: ObjectPath,
 		Interfaces: []introspect.Interface{
 			introspect.IntrospectData,
+			{
+				Name: ObjectManagerIface,
+				Methods: []introspect.Method{
+					{Name: "GetManagedObjects", Args: []introspect.Arg{
+						{Name: "objects", Type: "a{oa{sa{sv}}}", Direction: "out"},
+					}},
+				},
+				Signals: []introspect.Signal{
+					{Name: "InterfacesAdded", Args: []introspect.Arg{
+						{Name: "object", Type: "o"},
+						{Name: "interfaces", Type: "a{sa{sv}}"},
+					}},
+					{Name: "InterfacesRemoved", Args: []introspect.Arg{
+						{Name: "object", Type: "o"},
+						{Name: "interfaces", Type: "as"},
+					}},
+				},
+			},
 			{
 				Name:       Interface,
 				Methods:    s.methods(),
@@ -88,8 +177,28 @@ func NewService(busType string, stateMgr *state.Manager, iwdClient *iwd.Client)
 	return s, nil
 }
 
+// SetConfigReloader sets what ReloadConfig runs
+func (s *Service) SetConfigReloader(fn func() (reloaded, restart []string, err error)) {
+	s.reloadConfig = fn
+}
+
+// SetBuildInfo sets the Version property and the start time DaemonUptimeSeconds counts from
+func (s *Service) SetBuildInfo(version string, startedAt time.Time) {
+	s.version = version
+	s.startedAt = startedAt
+}
+
+// uptimeSeconds returns how long the daemon has been running
+func (s *Service) uptimeSeconds() uint64 {
+	if s.startedAt.IsZero() {
+		return 0
+	}
+	return uint64(time.Since(s.startedAt).Seconds())
+}
+
 // Close closes the D-Bus connection
 func (s *Service) Close() {
+	s.setScanActive(false)
 	s.conn.Close()
 }
 
@@ -97,6 +206,187 @@ func (s *Service) Close() {
 func (s *Service) onStateChange(st *state.State) {
 	// Emit property changed signals
 	s.emitPropertiesChanged(st)
+	s.syncNetworkObjects(st.Networks)
+	s.emitScanState(st)
+	s.emitConnectivityTransition(st)
+	s.emitInternetStatus(st)
+	s.emitLinkQuality(st)
+	s.emitCaptiveStatus(st)
+	s.emitHotspotState(st)
+	s.emitInterfaceChanged(st)
+	s.emitRadioState(st)
+	s.emitPrimaryConnection(st)
+	s.emitVpnState(st)
+	s.emitAddressChanged(st)
+}
+
+// emitScanState emits ScanStarted/ScanCompleted when WifiScanning flips
+// Every scan path (Scan, CancelScan, the 15s timeout, IWD's own scans) ends by
+// clearing WifiScanning, so this is the only place that signals it
+func (s *Service) emitScanState(st *state.State) {
+	s.connectivityMu.Lock()
+	prev := s.lastScanning
+	s.lastScanning = st.WifiScanning
+	s.connectivityMu.Unlock()
+
+	switch {
+	case st.WifiScanning && !prev:
+		s.EmitSignal("ScanStarted")
+	case !st.WifiScanning && prev:
+		s.EmitSignal("ScanCompleted", uint32(len(st.Networks)))
+	}
+}
+
+// emitAddressChanged emits AddressChanged when IpAddress or Gateway changes
+// A renewed lease with the same values stays quiet
+func (s *Service) emitAddressChanged(st *state.State) {
+	address := [2]string{st.IpAddress, st.Gateway}
+	s.connectivityMu.Lock()
+	prev := s.lastAddress
+	s.lastAddress = address
+	s.connectivityMu.Unlock()
+
+	if prev != address {
+		s.EmitSignal("AddressChanged", st.IpAddress, st.Gateway)
+	}
+}
+
+// emitVpnState emits VpnStateChanged when a tunnel comes up or goes down
+func (s *Service) emitVpnState(st *state.State) {
+	s.connectivityMu.Lock()
+	prev := s.lastVpn
+	s.lastVpn = st.VpnInterface
+	s.connectivityMu.Unlock()
+
+	if prev != st.VpnInterface {
+		iface := st.VpnInterface
+		if !st.VpnActive {
+			iface = prev // Name the tunnel that went down
+		}
+		s.EmitSignal("VpnStateChanged", st.VpnActive, iface)
+	}
+}
+
+// emitPrimaryConnection emits PrimaryConnectionChanged when another link takes the default route
+func (s *Service) emitPrimaryConnection(st *state.State) {
+	key := st.PrimaryType + "/" + st.PrimaryInterface
+
+	s.connectivityMu.Lock()
+	prev := s.lastPrimary
+	s.lastPrimary = key
+	s.connectivityMu.Unlock()
+
+	if prev != "" && prev != key {
+		s.EmitSignal("PrimaryConnectionChanged", st.PrimaryType, st.PrimaryInterface)
+	}
+}
+
+// emitRadioState emits RadioStateChanged when a radio's soft/hard block changes
+func (s *Service) emitRadioState(st *state.State) {
+	radios := map[string]state.RadioBlock{
+		"wlan":      st.WifiBlocked,
+		"bluetooth": st.BluetoothBlocked,
+	}
+
+	s.connectivityMu.Lock()
+	prev := s.lastRadio
+	s.lastRadio = radios
+	s.connectivityMu.Unlock()
+
+	// The first update only records the startup state
+	if prev == nil {
+		return
+	}
+	for _, radio := range []string{"wlan", "bluetooth"} {
+		if b := radios[radio]; b != prev[radio] {
+			s.EmitSignal("RadioStateChanged", radio, b.Soft, b.Hard)
+		}
+	}
+}
+
+// emitHotspotState emits HotspotStateChanged once per hotspot start/stop/failure
+func (s *Service) emitHotspotState(st *state.State) {
+	s.connectivityMu.Lock()
+	prev := s.lastHotspotSeq
+	s.lastHotspotSeq = st.HotspotEventSeq
+	s.connectivityMu.Unlock()
+
+	if st.HotspotEventSeq != prev {
+		s.EmitSignal("HotspotStateChanged", st.HotspotActive, st.HotspotSSID, st.HotspotReason)
+	}
+}
+
+// emitInterfaceChanged emits InterfaceChanged for each link up/down transition
+// the netlink watcher recorded
+func (s *Service) emitInterfaceChanged(st *state.State) {
+	s.connectivityMu.Lock()
+	prev := s.lastLinkSeq
+	s.lastLinkSeq = st.LinkEventSeq
+	s.connectivityMu.Unlock()
+
+	if st.LinkEventSeq != prev {
+		s.EmitSignal("InterfaceChanged", st.LinkEventIface, st.LinkEventUp)
+	}
+}
+
+// emitCaptiveStatus emits CaptivePortalStatus once per completed captive check
+func (s *Service) emitCaptiveStatus(st *state.State) {
+	s.connectivityMu.Lock()
+	prev := s.lastCaptiveSeq
+	s.lastCaptiveSeq = st.CaptiveCheckSeq
+	s.connectivityMu.Unlock()
+
+	if st.CaptiveCheckSeq != prev {
+		s.EmitSignal("CaptivePortalStatus", st.CaptivePortalDetected, st.CaptivePortalURL,
+			st.CaptivePortalEndpoint, st.CaptivePortalStage)
+	}
+}
+
+// emitConnectivityTransition emits ConnectionChanged("limited") when the
+// checker finds only the local network reachable, and "connected" once it recovers
+func (s *Service) emitConnectivityTransition(st *state.State) {
+	s.connectivityMu.Lock()
+	prev := s.lastConnectivity
+	s.lastConnectivity = st.Connectivity
+	s.connectivityMu.Unlock()
+
+	if prev == st.Connectivity {
+		return
+	}
+	if prev != "" {
+		s.EmitSignal("ConnectivityChanged", st.Connectivity)
+	}
+	switch {
+	case st.Connectivity == state.ConnectivityLimited:
+		s.EmitSignal("ConnectionChanged", "limited", st.ActiveSSID, st.SignalStrength, "")
+	case prev == state.ConnectivityLimited && st.Connectivity != state.ConnectivityNone:
+		s.EmitSignal("ConnectionChanged", "connected", st.ActiveSSID, st.SignalStrength, "")
+	}
+}
+
+// emitInternetStatus emits InternetStatusChanged when HasInternet flips
+func (s *Service) emitInternetStatus(st *state.State) {
+	s.connectivityMu.Lock()
+	prev := s.lastInternet
+	s.lastInternet = st.HasInternet
+	s.connectivityMu.Unlock()
+
+	if prev != st.HasInternet {
+		s.EmitSignal("InternetStatusChanged", st.HasInternet)
+	}
+}
+
+// emitLinkQuality emits LinkQualityChanged when LinkQuality moves across a
+// threshold (good <-> degraded) or probing starts or pauses
+func (s *Service) emitLinkQuality(st *state.State) {
+	s.connectivityMu.Lock()
+	prev := s.lastLinkQuality
+	s.lastLinkQuality = st.LinkQuality
+	s.connectivityMu.Unlock()
+
+	if prev != st.LinkQuality {
+		s.EmitSignal("LinkQualityChanged", st.LinkQuality, st.GatewayLatencyMs, st.InternetLatencyMs, st.PacketLossPercent)
+	}
 }
 
 // emitPropertiesChanged emits PropertyChanged for modified properties
@@ -105,16 +395,64 @@ func (s *Service) emitPropertiesChanged(st *state.State) {
 		"WifiEnabled":           dbus.MakeVariant(st.WifiEnabled),
 		"WifiScanning":          dbus.MakeVariant(st.WifiScanning),
 		"ConnectionState":       dbus.MakeVariant(string(st.ConnectionState)),
+		"Connectivity":          dbus.MakeVariant(st.Connectivity),
+		"InterfaceConnectivity": dbus.MakeVariant(nonNilMap(st.InterfaceConnectivity)),
+		"HasInternet":           dbus.MakeVariant(st.HasInternet),
+		"SecurityDowngraded":    dbus.MakeVariant(st.SecurityDowngraded),
+		"AgentRegistered":       dbus.MakeVariant(st.AgentRegistered),
+		"ScanParams":            dbus.MakeVariant(scanParams(*st)),
+		"LastScanTime":          dbus.MakeVariant(unixOrZero(st.LastScanTime)),
 		"ActiveSSID":            dbus.MakeVariant(st.ActiveSSID),
+		"ConnectedSince":        dbus.MakeVariant(unixOrZero(st.ConnectedSince)),
+		"UsbConnectedSince":     dbus.MakeVariant(unixOrZero(st.UsbConnectedSince)),
+		"HotspotSince":          dbus.MakeVariant(unixOrZero(st.HotspotSince)),
+		"DisconnectReason":      dbus.MakeVariant(st.DisconnectReason),
 		"SignalRSSI":            dbus.MakeVariant(st.SignalRSSI),
 		"SignalStrength":        dbus.MakeVariant(st.SignalStrength),
+		"SignalBars":            dbus.MakeVariant(st.SignalBars),
+		"Frequency":             dbus.MakeVariant(st.Frequency),
+		"Band":                  dbus.MakeVariant(state.FrequencyToBand(st.Frequency)),
+		"Channel":               dbus.MakeVariant(st.Channel),
+		"ChannelWidth":          dbus.MakeVariant(st.ChannelWidth),
+		"WifiGeneration":        dbus.MakeVariant(st.WifiGeneration),
 		"IpAddress":             dbus.MakeVariant(st.IpAddress),
+		"IpCidr":                dbus.MakeVariant(state.IPCidr(st.IpAddress, st.IpPrefixLen)),
+		"IpBroadcast":           dbus.MakeVariant(state.IPv4Broadcast(st.IpAddress, st.IpPrefixLen)),
+		"Addresses":             dbus.MakeVariant(addressesToDBus(st.Addresses)),
 		"Gateway":               dbus.MakeVariant(st.Gateway),
+		"GatewayReachable":      dbus.MakeVariant(st.GatewayReachable),
+		"DnsServers":            dbus.MakeVariant(nonNil(st.DnsServers)),
+		"SearchDomains":         dbus.MakeVariant(nonNil(st.SearchDomains)),
 		"TrafficIn":             dbus.MakeVariant(st.TrafficIn),
 		"TrafficOut":            dbus.MakeVariant(st.TrafficOut),
+		"GatewayLatencyMs":      dbus.MakeVariant(st.GatewayLatencyMs),
+		"InternetLatencyMs":     dbus.MakeVariant(st.InternetLatencyMs),
+		"PacketLossPercent":     dbus.MakeVariant(st.PacketLossPercent),
+		"LinkQuality":           dbus.MakeVariant(st.LinkQuality),
 		"AirplaneMode":          dbus.MakeVariant(st.AirplaneMode),
+		"WifiBlocked":           dbus.MakeVariant(st.WifiBlocked.String()),
+		"BluetoothBlocked":      dbus.MakeVariant(st.BluetoothBlocked.String()),
 		"CaptivePortalDetected": dbus.MakeVariant(st.CaptivePortalDetected),
 		"HotspotActive":         dbus.MakeVariant(st.HotspotActive),
+		"HotspotSSID":           dbus.MakeVariant(st.HotspotSSID),
+		"HotspotClients":        dbus.MakeVariant(st.HotspotClients),
+		"HotspotExpiresAt":      dbus.MakeVariant(unixOrZero(st.HotspotExpiresAt)),
+		"HotspotQRString":       dbus.MakeVariant(hotspotQR(st)),
+		"EthernetCablePlugged":  dbus.MakeVariant(st.EthernetCablePlugged),
+		"ConnectionType":        dbus.MakeVariant(st.ConnectionType),
+		"PrimaryInterface":      dbus.MakeVariant(st.PrimaryInterface),
+		"PrimaryPinned":         dbus.MakeVariant(st.PrimaryPinned),
+		"UsbFallbackActive":     dbus.MakeVariant(st.UsbFallbackActive),
+		"UsbIpAddressV6":        dbus.MakeVariant(st.UsbIpAddressV6),
+		"UsbAutoConnect":        dbus.MakeVariant(st.UsbAutoConnect),
+		"VpnActive":             dbus.MakeVariant(st.VpnActive),
+		"VpnInterface":          dbus.MakeVariant(st.VpnInterface),
+		"BtTetheringAvailable":  dbus.MakeVariant(st.BtTetheringAvailable),
+		"BtTetheringConnected":  dbus.MakeVariant(st.BtTetheringConnected),
+		"BtDeviceName":          dbus.MakeVariant(st.BtDeviceName),
+		"BtInterfaceName":       dbus.MakeVariant(st.BtInterfaceName),
+		"SavedNetworks":         dbus.MakeVariant(nonNil(st.SavedNetworks)),
+		"BlockedNetworks":       dbus.MakeVariant(nonNil(st.BlockedNetworks)),
 	}
 
 	err := s.conn.Emit(ObjectPath, "org.freedesktop.DBus.Properties.PropertiesChanged",
@@ -149,6 +487,7 @@ func (s *Service) methods() []introspect.Method {
 			{Name: "success", Type: "b", Direction: "out"},
 		}},
 		{Name: "Disconnect"},
+		{Name: "ClearLastError"},
 		{Name: "Forget", Args: []introspect.Arg{
 			{Name: "ssid", Type: "s", Direction: "in"},
 			{Name: "success", Type: "b", Direction: "out"},
@@ -158,25 +497,169 @@ func (s *Service) methods() []introspect.Method {
 			{Name: "enabled", Type: "b", Direction: "in"},
 			{Name: "success", Type: "b", Direction: "out"},
 		}},
+		{Name: "BlacklistNetwork", Args: []introspect.Arg{
+			{Name: "ssid", Type: "s", Direction: "in"},
+			{Name: "blocked", Type: "b", Direction: "in"},
+			{Name: "success", Type: "b", Direction: "out"},
+		}},
+		{Name: "SetNetworkPriority", Args: []introspect.Arg{
+			{Name: "ssid", Type: "s", Direction: "in"},
+			{Name: "priority", Type: "i", Direction: "in"},
+			{Name: "success", Type: "b", Direction: "out"},
+		}},
 		{Name: "StartHotspot", Args: []introspect.Arg{
 			{Name: "ssid", Type: "s", Direction: "in"},
 			{Name: "password", Type: "s", Direction: "in"},
 			{Name: "success", Type: "b", Direction: "out"},
 		}},
+		{Name: "StartHotspotWithParams", Args: []introspect.Arg{
+			{Name: "params", Type: "a{sv}", Direction: "in"},
+			{Name: "success", Type: "b", Direction: "out"},
+		}},
 		{Name: "StopHotspot"},
 		{Name: "SetAirplaneMode", Args: []introspect.Arg{
 			{Name: "enabled", Type: "b", Direction: "in"},
 			{Name: "success", Type: "b", Direction: "out"},
 		}},
+		{Name: "SetRfkill", Args: []introspect.Arg{
+			{Name: "kind", Type: "s", Direction: "in"},
+			{Name: "blocked", Type: "b", Direction: "in"},
+			{Name: "success", Type: "b", Direction: "out"},
+		}},
+		{Name: "SetRadioBlocked", Args: []introspect.Arg{
+			{Name: "type", Type: "s", Direction: "in"},
+			{Name: "blocked", Type: "b", Direction: "in"},
+			{Name: "success", Type: "b", Direction: "out"},
+		}},
 		{Name: "CheckCaptivePortal", Args: []introspect.Arg{
 			{Name: "detected", Type: "b", Direction: "out"},
 		}},
+		{Name: "CheckCaptivePortalOn", Args: []introspect.Arg{
+			{Name: "interface", Type: "s", Direction: "in"},
+			{Name: "detected", Type: "b", Direction: "out"},
+		}},
 		{Name: "OpenCaptivePortal"},
 		// USB Tethering methods
 		{Name: "RequestUsbNetwork", Args: []introspect.Arg{
 			{Name: "success", Type: "b", Direction: "out"},
 		}},
 		{Name: "ReleaseUsbNetwork"},
+		{Name: "SetUsbAutoConnect", Args: []introspect.Arg{
+			{Name: "enabled", Type: "b", Direction: "in"},
+			{Name: "success", Type: "b", Direction: "out"},
+		}},
+		// Bluetooth tethering methods
+		{Name: "GetBluetoothDevices", Args: []introspect.Arg{
+			{Name: "devices", Type: "a(ssb)", Direction: "out"},
+		}},
+		{Name: "ConnectBluetoothTethering", Args: []introspect.Arg{
+			{Name: "device", Type: "s", Direction: "in"},
+			{Name: "success", Type: "b", Direction: "out"},
+		}},
+		{Name: "DisconnectBluetoothTethering"},
+		{Name: "SetPrimaryConnection", Args: []introspect.Arg{
+			{Name: "interface", Type: "s", Direction: "in"},
+			{Name: "success", Type: "b", Direction: "out"},
+		}},
+		{Name: "SetPortalEndpoints", Args: []introspect.Arg{
+			{Name: "endpoints", Type: "a(sus)", Direction: "in"},
+			{Name: "httpsUrl", Type: "s", Direction: "in"},
+			{Name: "success", Type: "b", Direction: "out"},
+		}},
+		{Name: "GetInterfaceFilter", Args: []introspect.Arg{
+			{Name: "ignore", Type: "as", Direction: "out"},
+			{Name: "include", Type: "as", Direction: "out"},
+		}},
+		{Name: "GetInterfaces", Args: []introspect.Arg{
+			{Name: "interfaces", Type: "aa{sv}", Direction: "out"},
+		}},
+		{Name: "ReloadConfig", Args: []introspect.Arg{
+			{Name: "reloaded", Type: "as", Direction: "out"},
+			{Name: "restartRequired", Type: "as", Direction: "out"},
+		}},
+		{Name: "ApplyPolicy", Args: []introspect.Arg{
+			{Name: "path", Type: "s", Direction: "in"},
+			{Name: "added", Type: "as", Direction: "out"},
+			{Name: "updated", Type: "as", Direction: "out"},
+			{Name: "removed", Type: "as", Direction: "out"},
+		}},
+		{Name: "GetNeighbors", Args: []introspect.Arg{
+			{Name: "neighbors", Type: "a(ssss)", Direction: "out"},
+		}},
+		{Name: "GetPublicIP", Args: []introspect.Arg{
+			{Name: "ipv4", Type: "s", Direction: "out"},
+			{Name: "ipv6", Type: "s", Direction: "out"},
+			{Name: "interface", Type: "s", Direction: "out"},
+			{Name: "ssid", Type: "s", Direction: "out"},
+			{Name: "obtainedAt", Type: "x", Direction: "out"},
+		}},
+		{Name: "GetConnectionHistory", Args: []introspect.Arg{
+			{Name: "limit", Type: "u", Direction: "in"},
+			{Name: "attempts", Type: "a(sxubsy)", Direction: "out"},
+		}},
+		{Name: "GetNetworkStats", Args: []introspect.Arg{
+			{Name: "ssid", Type: "s", Direction: "in"},
+			{Name: "attempts", Type: "u", Direction: "out"},
+			{Name: "successes", Type: "u", Direction: "out"},
+			{Name: "successRate", Type: "d", Direction: "out"},
+			{Name: "avgConnectMs", Type: "u", Direction: "out"},
+			{Name: "lastFailureAt", Type: "x", Direction: "out"},
+			{Name: "lastFailureCode", Type: "s", Direction: "out"},
+		}},
+		{Name: "GetAccessPoints", Args: []introspect.Arg{
+			{Name: "ssid", Type: "s", Direction: "in"},
+			{Name: "accessPoints", Type: "a(sunb)", Direction: "out"},
+		}},
+		{Name: "ScanSSID", Args: []introspect.Arg{
+			{Name: "ssid", Type: "s", Direction: "in"},
+			{Name: "found", Type: "b", Direction: "out"},
+			{Name: "signal", Type: "n", Direction: "out"},
+		}},
+		{Name: "Roam", Args: []introspect.Arg{
+			{Name: "bssid", Type: "s", Direction: "out"},
+		}},
+		{Name: "SetStaticIP", Args: []introspect.Arg{
+			{Name: "iface", Type: "s", Direction: "in"},
+			{Name: "cidr", Type: "s", Direction: "in"},
+			{Name: "gateway", Type: "s", Direction: "in"},
+			{Name: "dns", Type: "as", Direction: "in"},
+			{Name: "success", Type: "b", Direction: "out"},
+		}},
+		{Name: "SetDHCP", Args: []introspect.Arg{
+			{Name: "iface", Type: "s", Direction: "in"},
+			{Name: "success", Type: "b", Direction: "out"},
+		}},
+		{Name: "GetKnownNetworks", Args: []introspect.Arg{
+			{Name: "networks", Type: "aa{sv}", Direction: "out"},
+		}},
+		{Name: "GetDnsLatency", Args: []introspect.Arg{
+			{Name: "latency", Type: "a{si}", Direction: "out"},
+		}},
+		{Name: "GetState", Args: []introspect.Arg{
+			{Name: "state", Type: "s", Direction: "out"},
+		}},
+		{Name: "GetLinkFlapStats", Args: []introspect.Arg{
+			{Name: "flaps", Type: "a{su}", Direction: "out"},
+		}},
+		{Name: "CancelScan", Args: []introspect.Arg{
+			{Name: "cancelled", Type: "b", Direction: "out"},
+		}},
+		{Name: "SetScanActive", Args: []introspect.Arg{
+			{Name: "enabled", Type: "b", Direction: "in"},
+		}},
+		{Name: "SetScanParams", Args: []introspect.Arg{
+			{Name: "params", Type: "a{sv}", Direction: "in"},
+			{Name: "success", Type: "b", Direction: "out"},
+		}},
+		{Name: "SetIPConfig", Args: []introspect.Arg{
+			{Name: "target", Type: "s", Direction: "in"},
+			{Name: "config", Type: "a{sv}", Direction: "in"},
+			{Name: "success", Type: "b", Direction: "out"},
+		}},
+		{Name: "GetIPConfig", Args: []introspect.Arg{
+			{Name: "target", Type: "s", Direction: "in"},
+			{Name: "config", Type: "a{sv}", Direction: "out"},
+		}},
 	}
 }
 
@@ -186,29 +669,82 @@ func (s *Service) properties() []introspect.Property {
 		{Name: "WifiEnabled", Type: "b", Access: "read"},
 		{Name: "WifiScanning", Type: "b", Access: "read"},
 		{Name: "ConnectionState", Type: "s", Access: "read"},
+		{Name: "Connectivity", Type: "s", Access: "read"},
+		{Name: "InterfaceConnectivity", Type: "a{ss}", Access: "read"},
+		{Name: "HasInternet", Type: "b", Access: "read"},
+		{Name: "SecurityDowngraded", Type: "b", Access: "read"},
+		{Name: "AgentRegistered", Type: "b", Access: "read"},
+		{Name: "ScanParams", Type: "a{sv}", Access: "read"},
+		{Name: "LastScanTime", Type: "x", Access: "read"},
 		{Name: "ActiveSSID", Type: "s", Access: "read"},
+		{Name: "ConnectedSince", Type: "x", Access: "read"},
 		{Name: "ActiveSecurity", Type: "s", Access: "read"},
+		{Name: "DisconnectReason", Type: "s", Access: "read"},
 		{Name: "SignalRSSI", Type: "n", Access: "read"},
 		{Name: "SignalStrength", Type: "y", Access: "read"},
+		{Name: "SignalBars", Type: "y", Access: "read"},
 		{Name: "Frequency", Type: "u", Access: "read"},
 		{Name: "IpAddress", Type: "s", Access: "read"},
+		{Name: "IpCidr", Type: "s", Access: "read"},
+		{Name: "IpBroadcast", Type: "s", Access: "read"},
+		{Name: "Addresses", Type: "a(ss)", Access: "read"},
 		{Name: "Gateway", Type: "s", Access: "read"},
+		{Name: "GatewayReachable", Type: "b", Access: "read"},
+		{Name: "DnsServers", Type: "as", Access: "read"},
+		{Name: "SearchDomains", Type: "as", Access: "read"},
 		{Name: "MacAddress", Type: "s", Access: "read"},
 		{Name: "InterfaceName", Type: "s", Access: "read"},
 		{Name: "TrafficIn", Type: "t", Access: "read"},
 		{Name: "TrafficOut", Type: "t", Access: "read"},
-		{Name: "Networks", Type: "a(ssybu)", Access: "read"},
+		{Name: "GatewayLatencyMs", Type: "u", Access: "read"},
+		{Name: "InternetLatencyMs", Type: "u", Access: "read"},
+		{Name: "PacketLossPercent", Type: "y", Access: "read"},
+		{Name: "LinkQuality", Type: "s", Access: "read"},
+		{Name: "Networks", Type: "a(ssybus)", Access: "read"},
 		{Name: "SavedNetworks", Type: "as", Access: "read"},
+		{Name: "BlockedNetworks", Type: "as", Access: "read"},
 		{Name: "AirplaneMode", Type: "b", Access: "read"},
+		{Name: "WifiBlocked", Type: "s", Access: "read"},
+		{Name: "BluetoothBlocked", Type: "s", Access: "read"},
 		{Name: "CaptivePortalDetected", Type: "b", Access: "read"},
 		{Name: "HotspotActive", Type: "b", Access: "read"},
+		{Name: "HotspotSSID", Type: "s", Access: "read"},
+		{Name: "HotspotClients", Type: "u", Access: "read"},
+		{Name: "HotspotExpiresAt", Type: "x", Access: "read"},
+		{Name: "HotspotSince", Type: "x", Access: "read"},
+		{Name: "HotspotQRString", Type: "s", Access: "read"},
 		{Name: "ConnectionType", Type: "s", Access: "read"},
+		{Name: "PrimaryInterface", Type: "s", Access: "read"},
+		{Name: "PrimaryPinned", Type: "s", Access: "read"},
 		{Name: "Band", Type: "s", Access: "read"},
+		{Name: "Channel", Type: "u", Access: "read"},
+		{Name: "ChannelWidth", Type: "u", Access: "read"},
+		{Name: "WifiGeneration", Type: "s", Access: "read"},
+		{Name: "EthernetCablePlugged", Type: "b", Access: "read"},
 		// USB Tethering properties
 		{Name: "UsbInterfaceDetected", Type: "b", Access: "read"},
 		{Name: "UsbTetheringAvailable", Type: "b", Access: "read"},
 		{Name: "UsbTetheringConnected", Type: "b", Access: "read"},
+		{Name: "UsbConnectedSince", Type: "x", Access: "read"},
 		{Name: "UsbInterfaceName", Type: "s", Access: "read"},
+		{Name: "UsbFallbackActive", Type: "b", Access: "read"},
+		{Name: "UsbIpAddressV6", Type: "s", Access: "read"},
+		{Name: "UsbAutoConnect", Type: "b", Access: "read"},
+		{Name: "VpnActive", Type: "b", Access: "read"},
+		{Name: "VpnInterface", Type: "s", Access: "read"},
+		{Name: "BtTetheringAvailable", Type: "b", Access: "read"},
+		{Name: "BtTetheringConnected", Type: "b", Access: "read"},
+		{Name: "BtDeviceName", Type: "s", Access: "read"},
+		{Name: "BtInterfaceName", Type: "s", Access: "read"},
+		// Error reporting
+		{Name: "LastError", Type: "s", Access: "read"},
+		{Name: "LastErrorCode", Type: "s", Access: "read"},
+		// DHCP lease properties
+		{Name: "DhcpServer", Type: "s", Access: "read"},
+		{Name: "DhcpLeaseExpiry", Type: "x", Access: "read"},
+		// Daemon build and uptime
+		{Name: "DaemonUptimeSeconds", Type: "t", Access: "read"},
+		{Name: "Version", Type: "s", Access: "read"},
 	}
 }
 
@@ -216,13 +752,33 @@ func (s *Service) properties() []introspect.Property {
 func (s *Service) signals() []introspect.Signal {
 	return []introspect.Signal{
 		{Name: "WifiStateChanged", Args: []introspect.Arg{{Name: "enabled", Type: "b"}}},
+		{Name: "Shutdown"},
 		{Name: "ScanStarted"},
-		{Name: "ScanCompleted"},
-		{Name: "NetworksChanged", Args: []introspect.Arg{{Name: "networks", Type: "a(ssybu)"}}},
+		{Name: "ScanCompleted", Args: []introspect.Arg{{Name: "count", Type: "u"}}},
+		{Name: "NetworksChanged", Args: []introspect.Arg{{Name: "networks", Type: "a(ssybus)"}}},
+		{Name: "PrimaryConnectionChanged", Args: []introspect.Arg{
+			{Name: "type", Type: "s"},
+			{Name: "interface", Type: "s"},
+		}},
+		{Name: "VpnStateChanged", Args: []introspect.Arg{
+			{Name: "active", Type: "b"},
+			{Name: "interface", Type: "s"},
+		}},
+		{Name: "RadioStateChanged", Args: []introspect.Arg{
+			{Name: "type", Type: "s"},
+			{Name: "soft", Type: "b"},
+			{Name: "hard", Type: "b"},
+		}},
+		{Name: "HotspotStateChanged", Args: []introspect.Arg{
+			{Name: "active", Type: "b"},
+			{Name: "ssid", Type: "s"},
+			{Name: "reason", Type: "s"},
+		}},
 		{Name: "ConnectionChanged", Args: []introspect.Arg{
 			{Name: "state", Type: "s"},
 			{Name: "ssid", Type: "s"},
 			{Name: "signal", Type: "y"},
+			{Name: "code", Type: "s"},
 		}},
 		{Name: "TrafficUpdated", Args: []introspect.Arg{
 			{Name: "inBytes", Type: "t"},
@@ -239,10 +795,25 @@ func (s *Service) signals() []introspect.Signal {
 		{Name: "CaptivePortalStatus", Args: []introspect.Arg{
 			{Name: "detected", Type: "b"},
 			{Name: "url", Type: "s"},
+			{Name: "endpoint", Type: "s"},
+			{Name: "stage", Type: "s"},
+		}},
+		{Name: "ConnectivityChanged", Args: []introspect.Arg{
+			{Name: "connectivity", Type: "s"},
+		}},
+		{Name: "InternetStatusChanged", Args: []introspect.Arg{
+			{Name: "hasInternet", Type: "b"},
+		}},
+		{Name: "LinkQualityChanged", Args: []introspect.Arg{
+			{Name: "quality", Type: "s"},
+			{Name: "gatewayLatencyMs", Type: "u"},
+			{Name: "internetLatencyMs", Type: "u"},
+			{Name: "packetLossPercent", Type: "y"},
 		}},
 		{Name: "Error", Args: []introspect.Arg{
 			{Name: "operation", Type: "s"},
 			{Name: "message", Type: "s"},
+			{Name: "code", Type: "s"},
 		}},
 	}
 }
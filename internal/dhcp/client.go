@@ -0,0 +1,273 @@
+package dhcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	clientPort = 68
+	serverPort = 67
+
+	// Default lease timings when the server omits them
+	defaultLeaseTime = 1 * time.Hour
+)
+
+// ErrPermission is returned when the native client can't open its socket
+// (no CAP_NET_RAW / CAP_NET_BIND_SERVICE) - callers fall back to an external client
+var ErrPermission = errors.New("dhcp: no permission for DHCP socket")
+
+// ErrNak is returned when the server rejects our request
+var ErrNak = errors.New("dhcp: server sent NAK")
+
+// paramRequestList asks the server for the options we care about
+var paramRequestList = []byte{
+	optSubnetMask, optRouter, optDNS, optDomainName, optBroadcast,
+	optLeaseTime, optRenewalTime, optRebindTime, optDomainSearch,
+}
+
+// Lease holds an acquired DHCPv4 lease
+type Lease struct {
+	Address   net.IP
+	PrefixLen uint8
+	Router    net.IP
+	Server    net.IP
+	DNS       []net.IP
+	Domain    string
+	Duration  time.Duration
+	T1        time.Duration // Renewal time
+	T2        time.Duration // Rebinding time
+	Obtained  time.Time
+}
+
+// Expiry returns when the lease runs out
+func (l *Lease) Expiry() time.Time {
+	return l.Obtained.Add(l.Duration)
+}
+
+// Client is a minimal DHCPv4 client bound to one interface
+// Implements DISCOVER/OFFER/REQUEST/ACK, renew, rebind and release
+type Client struct {
+	iface *net.Interface
+}
+
+// NewClient creates a DHCP client for the named interface
+func NewClient(ifaceName string) (*Client, error) {
+	iface, err := net.InterfaceByName(ifaceName)
+	if err != nil {
+		return nil, err
+	}
+	if len(iface.HardwareAddr) != 6 {
+		return nil, fmt.Errorf("dhcp: %s has no ethernet hardware address", ifaceName)
+	}
+	return &Client{iface: iface}, nil
+}
+
+// listen opens a UDP socket on port 68 bound to the interface
+// Broadcast is enabled so DISCOVER can go out before we have an address
+func (c *Client) listen() (net.PacketConn, error) {
+	lc := net.ListenConfig{
+		Control: func(network, address string, raw syscall.RawConn) error {
+			var sockErr error
+			err := raw.Control(func(fd uintptr) {
+				if sockErr = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_REUSEADDR, 1); sockErr != nil {
+					return
+				}
+				if sockErr = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_BROADCAST, 1); sockErr != nil {
+					return
+				}
+				sockErr = syscall.BindToDevice(int(fd), c.iface.Name)
+			})
+			if err != nil {
+				return err
+			}
+			return sockErr
+		},
+	}
+
+	pc, err := lc.ListenPacket(context.Background(), "udp4", fmt.Sprintf("0.0.0.0:%d", clientPort))
+	if err != nil {
+		if errors.Is(err, os.ErrPermission) {
+			return nil, fmt.Errorf("%w: %v", ErrPermission, err)
+		}
+		return nil, err
+	}
+	return pc, nil
+}
+
+// Acquire runs the full DISCOVER/OFFER/REQUEST/ACK exchange
+func (c *Client) Acquire(ctx context.Context) (*Lease, error) {
+	pc, err := c.listen()
+	if err != nil {
+		return nil, err
+	}
+	defer pc.Close()
+
+	xid := rand.Uint32()
+
+	discover := newPacket(MsgDiscover, xid, c.iface.HardwareAddr)
+	discover.Flags = flagBroadcast // No address yet - ask for broadcast replies
+	discover.Options[optParamRequest] = paramRequestList
+
+	offer, err := c.exchange(ctx, pc, discover, net.IPv4bcast, MsgOffer)
+	if err != nil {
+		return nil, fmt.Errorf("no offer: %w", err)
+	}
+
+	request := newPacket(MsgRequest, xid, c.iface.HardwareAddr)
+	request.Flags = flagBroadcast
+	request.Options[optParamRequest] = paramRequestList
+	request.Options[optRequestedIP] = offer.YIAddr.To4()
+	if sid := offer.optIP(optServerID); sid != nil {
+		request.Options[optServerID] = sid.To4()
+	}
+
+	ack, err := c.exchange(ctx, pc, request, net.IPv4bcast, MsgAck)
+	if err != nil {
+		return nil, fmt.Errorf("no ack: %w", err)
+	}
+
+	return leaseFromAck(ack), nil
+}
+
+// Renew extends the lease by unicasting a REQUEST to the server (RENEWING, T1)
+func (c *Client) Renew(ctx context.Context, lease *Lease) (*Lease, error) {
+	return c.extend(ctx, lease, lease.Server)
+}
+
+// Rebind extends the lease by broadcasting a REQUEST to any server (REBINDING, T2)
+func (c *Client) Rebind(ctx context.Context, lease *Lease) (*Lease, error) {
+	return c.extend(ctx, lease, net.IPv4bcast)
+}
+
+// extend sends a REQUEST for the current address to dst
+func (c *Client) extend(ctx context.Context, lease *Lease, dst net.IP) (*Lease, error) {
+	pc, err := c.listen()
+	if err != nil {
+		return nil, err
+	}
+	defer pc.Close()
+
+	request := newPacket(MsgRequest, rand.Uint32(), c.iface.HardwareAddr)
+	request.CIAddr = lease.Address
+	request.Options[optParamRequest] = paramRequestList
+
+	ack, err := c.exchange(ctx, pc, request, dst, MsgAck)
+	if err != nil {
+		return nil, err
+	}
+	return leaseFromAck(ack), nil
+}
+
+// Release tells the server we're giving the address back (no reply expected)
+func (c *Client) Release(lease *Lease) error {
+	pc, err := c.listen()
+	if err != nil {
+		return err
+	}
+	defer pc.Close()
+
+	release := newPacket(MsgRelease, rand.Uint32(), c.iface.HardwareAddr)
+	release.CIAddr = lease.Address
+	release.Options[optServerID] = lease.Server.To4()
+
+	_, err = pc.WriteTo(release.Marshal(), &net.UDPAddr{IP: lease.Server, Port: serverPort})
+	return err
+}
+
+// exchange sends pkt and waits for a reply of the wanted type
+// Retransmits with exponential backoff (2s, 4s, 8s...) until ctx is done
+func (c *Client) exchange(ctx context.Context, pc net.PacketConn, pkt *Packet, dst net.IP, want uint8) (*Packet, error) {
+	wire := pkt.Marshal()
+	addr := &net.UDPAddr{IP: dst, Port: serverPort}
+	buf := make([]byte, 1500)
+	backoff := 2 * time.Second
+
+	for {
+		if _, err := pc.WriteTo(wire, addr); err != nil {
+			return nil, err
+		}
+
+		deadline := time.Now().Add(backoff)
+		if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+			deadline = d
+		}
+		pc.SetReadDeadline(deadline)
+
+		for {
+			n, _, err := pc.ReadFrom(buf)
+			if err != nil {
+				var ne net.Error
+				if errors.As(err, &ne) && ne.Timeout() {
+					break // Retransmit
+				}
+				return nil, err
+			}
+
+			reply, err := Unmarshal(buf[:n])
+			if err != nil || reply.Op != bootReply || reply.XID != pkt.XID {
+				continue
+			}
+			switch reply.MsgType() {
+			case want:
+				return reply, nil
+			case MsgNak:
+				return nil, ErrNak
+			}
+		}
+
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if backoff < 16*time.Second {
+			backoff *= 2
+		}
+	}
+}
+
+// leaseFromAck builds a Lease from an ACK, filling RFC 2131 default timers
+func leaseFromAck(ack *Packet) *Lease {
+	lease := &Lease{
+		Address:   ack.YIAddr,
+		PrefixLen: 24,
+		Router:    nil,
+		Server:    ack.optIP(optServerID),
+		DNS:       ack.optIPs(optDNS),
+		Duration:  ack.optDuration(optLeaseTime),
+		T1:        ack.optDuration(optRenewalTime),
+		T2:        ack.optDuration(optRebindTime),
+		Obtained:  time.Now(),
+	}
+
+	if mask := ack.Options[optSubnetMask]; len(mask) == 4 {
+		ones, _ := net.IPMask(mask).Size()
+		lease.PrefixLen = uint8(ones)
+	}
+	if routers := ack.optIPs(optRouter); len(routers) > 0 {
+		lease.Router = routers[0]
+	}
+	if v := ack.Options[optDomainName]; len(v) > 0 {
+		lease.Domain = strings.TrimRight(string(v), "\x00")
+	}
+	if lease.Server == nil {
+		lease.Server = ack.SIAddr
+	}
+
+	if lease.Duration == 0 {
+		lease.Duration = defaultLeaseTime
+	}
+	if lease.T1 == 0 {
+		lease.T1 = lease.Duration / 2
+	}
+	if lease.T2 == 0 {
+		lease.T2 = lease.Duration * 7 / 8
+	}
+	return lease
+}
@@ -0,0 +1,93 @@
+package dhcp
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os/exec"
+
+	"x-network/internal/netlink"
+)
+
+// ErrNoExternalClient is returned when the native client can't run and no
+// DHCP client binary is installed
+var ErrNoExternalClient = errors.New("dhcp: no external DHCP client found")
+
+// External is a DHCP client binary the manager hands an interface to when
+// the native client lacks permissions
+type External interface {
+	Name() string
+	// Start blocks until the interface has a lease (or the client gives up)
+	Start(iface string) error
+	// Release drops the lease and stops the client on iface
+	Release(iface string) error
+}
+
+// externalClients lists the supported binaries in detection order
+var externalClients = []External{dhcpcdClient{}, dhclientClient{}}
+
+// DetectExternal returns the first supported DHCP client found in PATH (nil if none)
+func DetectExternal() External {
+	for _, c := range externalClients {
+		if _, err := exec.LookPath(c.Name()); err == nil {
+			return c
+		}
+	}
+	return nil
+}
+
+// ExternalByName returns the client named name; "auto" (or "") detects it
+func ExternalByName(name string) (External, error) {
+	if name == "" || name == "auto" {
+		return DetectExternal(), nil
+	}
+	for _, c := range externalClients {
+		if c.Name() == name {
+			return c, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown DHCP client %q (want auto, dhcpcd or dhclient)", name)
+}
+
+// dhcpcdClient drives dhcpcd
+// USB tethering runs dual-stack: some phones only hand out IPv6
+type dhcpcdClient struct{}
+
+func (dhcpcdClient) Name() string { return "dhcpcd" }
+
+func (dhcpcdClient) Start(iface string) error {
+	log.Printf("Running dhcpcd on %s", iface)
+	if netlink.InterfaceType(iface) == "usb" {
+		return exec.Command("sudo", "dhcpcd", "-q", iface).Run()
+	}
+	return exec.Command("sudo", "dhcpcd", "-4", "-q", iface).Run()
+}
+
+func (dhcpcdClient) Release(iface string) error {
+	return exec.Command("sudo", "-n", "dhcpcd", "-k", iface).Run()
+}
+
+// dhclientClient drives ISC dhclient
+// -1 makes it exit instead of retrying forever when no server answers;
+// the DHCPv6 client for USB tethering runs in the background (-nw)
+type dhclientClient struct{}
+
+func (dhclientClient) Name() string { return "dhclient" }
+
+func (dhclientClient) Start(iface string) error {
+	log.Printf("Running dhclient on %s", iface)
+	if netlink.InterfaceType(iface) == "usb" {
+		if err := exec.Command("sudo", "dhclient", "-6", "-nw", iface).Run(); err != nil {
+			log.Printf("dhclient -6 on %s failed: %v", iface, err)
+		}
+	}
+	return exec.Command("sudo", "dhclient", "-4", "-1", iface).Run()
+}
+
+func (dhclientClient) Release(iface string) error {
+	err := exec.Command("sudo", "-n", "dhclient", "-4", "-r", iface).Run()
+	if netlink.InterfaceType(iface) == "usb" {
+		exec.Command("sudo", "-n", "dhclient", "-6", "-r", iface).Run() // No v6 lease is fine
+	}
+	return err
+}
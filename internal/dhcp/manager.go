@@ -0,0 +1,412 @@
+package dhcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"x-network/internal/netlink"
+	"x-network/internal/state"
+
+	"github.com/jsimonetti/rtnetlink"
+)
+
+const (
+	acquireTimeout = 30 * time.Second
+	renewTimeout   = 10 * time.Second
+	minRetryWait   = 10 * time.Second
+	maxRetryWait   = 60 * time.Second
+
+	// defaultRouteMetric keeps DHCP-installed routes below typical WiFi metrics
+	defaultRouteMetric = 100
+)
+
+// ErrReleased is returned by Start when Release ran before the lease was installed
+var ErrReleased = errors.New("dhcp: released while acquiring")
+
+// Test seams for the native client
+var (
+	newClient = NewClient
+	acquire   = (*Client).Acquire
+)
+
+// Manager owns DHCP sessions per interface
+// Uses the native client and falls back to an external client (dhcpcd,
+// dhclient) when socket permissions are missing
+type Manager struct {
+	stateMgr *state.Manager
+	external External // nil when no client binary is installed
+	mu       sync.Mutex
+	sessions map[string]*session
+}
+
+// session is one running lease on an interface
+type session struct {
+	client   *Client
+	lease    *Lease
+	fallback bool // The external client is managing this interface
+	stopCh   chan struct{}
+}
+
+// NewManager creates a DHCP manager with an auto-detected external client
+func NewManager(stateMgr *state.Manager) *Manager {
+	return &Manager{
+		stateMgr: stateMgr,
+		external: DetectExternal(),
+		sessions: make(map[string]*session),
+	}
+}
+
+// SetExternal overrides the external client used as fallback (nil disables it)
+func (m *Manager) SetExternal(c External) {
+	m.mu.Lock()
+	m.external = c
+	m.mu.Unlock()
+}
+
+// Start acquires a lease on iface and keeps it renewed
+// Blocks until the lease is installed (or the fallback client returns)
+func (m *Manager) Start(iface string) error {
+	m.mu.Lock()
+	if _, ok := m.sessions[iface]; ok {
+		m.mu.Unlock()
+		return nil // Already running
+	}
+	sess := &session{stopCh: make(chan struct{})}
+	m.sessions[iface] = sess
+	m.mu.Unlock()
+
+	err := m.startNative(iface, sess)
+	if errors.Is(err, ErrPermission) || errors.Is(err, netlink.ErrNoCapability) {
+		if ext := m.externalClient(); ext != nil {
+			log.Printf("Native DHCP unavailable on %s (%v), falling back to %s", iface, err, ext.Name())
+			m.mu.Lock()
+			sess.fallback = true
+			m.mu.Unlock()
+			err = ext.Start(iface)
+		} else {
+			err = fmt.Errorf("%w: %v", ErrNoExternalClient, err)
+		}
+	}
+
+	if err != nil {
+		m.mu.Lock()
+		if m.sessions[iface] == sess {
+			delete(m.sessions, iface) // Not a newer session started after a Release
+		}
+		m.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// startNative runs the native client and installs the lease
+func (m *Manager) startNative(iface string, sess *session) error {
+	client, err := newClient(iface)
+	if err != nil {
+		return err
+	}
+	sess.client = client
+
+	log.Printf("DHCP: acquiring lease on %s", iface)
+	ctx, cancel := context.WithTimeout(context.Background(), acquireTimeout)
+	lease, err := acquire(client, ctx)
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	// Release (or Close) may have run while we waited for the server
+	if !m.current(iface, sess) {
+		log.Printf("DHCP: %s released while acquiring, not applying %s", iface, lease.Address)
+		return ErrReleased
+	}
+	if err := m.apply(iface, lease); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	current := m.sessions[iface] == sess
+	if current {
+		sess.lease = lease
+	}
+	m.mu.Unlock()
+	if !current {
+		// Released during apply: Release saw no lease, so take the address off here
+		m.remove(iface, lease)
+		return ErrReleased
+	}
+
+	go m.maintain(iface, sess)
+	return nil
+}
+
+// Release gives up the lease on iface (DHCPRELEASE, or the external client's
+// release in fallback mode)
+func (m *Manager) Release(iface string) error {
+	m.mu.Lock()
+	sess, ok := m.sessions[iface]
+	delete(m.sessions, iface)
+	var fallback bool
+	var lease *Lease
+	if ok {
+		fallback, lease = sess.fallback, sess.lease
+	}
+	m.mu.Unlock()
+
+	if !ok {
+		// Not ours - the external client may still hold it from a previous run
+		return m.releaseExternal(iface)
+	}
+
+	close(sess.stopCh)
+
+	if fallback {
+		return m.releaseExternal(iface)
+	}
+
+	if lease == nil {
+		return nil
+	}
+
+	var err error
+	if lease.Server != nil {
+		log.Printf("DHCP: releasing %s on %s", lease.Address, iface)
+		err = sess.client.Release(lease)
+	} else {
+		// No server identifier to address a DHCPRELEASE to; the lease just lapses
+		log.Printf("DHCP: dropping %s on %s without release (no server identifier)", lease.Address, iface)
+	}
+	m.remove(iface, lease)
+	return err
+}
+
+// current reports whether sess is still the running session on iface
+func (m *Manager) current(iface string, sess *session) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.sessions[iface] == sess
+}
+
+// Lease returns the current native lease for iface (nil if none or fallback)
+func (m *Manager) Lease(iface string) *Lease {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if sess, ok := m.sessions[iface]; ok {
+		return sess.lease
+	}
+	return nil
+}
+
+// Close stops all renew loops without releasing leases
+func (m *Manager) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for iface, sess := range m.sessions {
+		close(sess.stopCh)
+		delete(m.sessions, iface)
+	}
+}
+
+// maintain renews at T1, rebinds at T2 and re-acquires after expiry
+func (m *Manager) maintain(iface string, sess *session) {
+	for {
+		m.mu.Lock()
+		lease := sess.lease
+		m.mu.Unlock()
+
+		renewAt := lease.Obtained.Add(lease.T1)
+		rebindAt := lease.Obtained.Add(lease.T2)
+		expiry := lease.Expiry()
+
+		if !sleepUntil(renewAt, sess.stopCh) {
+			return
+		}
+
+		// RENEWING: unicast to our server until T2
+		newLease := m.retryUntil(rebindAt, sess, func(ctx context.Context) (*Lease, error) {
+			return sess.client.Renew(ctx, lease)
+		})
+
+		// REBINDING: broadcast to any server until expiry
+		if newLease == nil {
+			newLease = m.retryUntil(expiry, sess, func(ctx context.Context) (*Lease, error) {
+				return sess.client.Rebind(ctx, lease)
+			})
+		}
+
+		select {
+		case <-sess.stopCh:
+			return
+		default:
+		}
+
+		if newLease == nil {
+			// Lease expired - drop the address and start over
+			log.Printf("DHCP: lease on %s expired", iface)
+			m.remove(iface, lease)
+			newLease = m.reacquire(iface, sess)
+			if newLease == nil {
+				return
+			}
+		}
+
+		if err := m.apply(iface, newLease); err != nil {
+			log.Printf("DHCP: failed to apply renewed lease on %s: %v", iface, err)
+		}
+		m.mu.Lock()
+		sess.lease = newLease
+		m.mu.Unlock()
+	}
+}
+
+// retryUntil retries fn until it succeeds or deadline passes
+// Waits half the remaining time between attempts (RFC 2131 4.4.5), bounded
+func (m *Manager) retryUntil(deadline time.Time, sess *session, fn func(context.Context) (*Lease, error)) *Lease {
+	for time.Now().Before(deadline) {
+		ctx, cancel := context.WithTimeout(context.Background(), renewTimeout)
+		lease, err := fn(ctx)
+		cancel()
+		if err == nil {
+			return lease
+		}
+		if errors.Is(err, ErrNak) {
+			return nil
+		}
+
+		wait := time.Until(deadline) / 2
+		if wait < minRetryWait {
+			wait = minRetryWait
+		}
+		if wait > maxRetryWait {
+			wait = maxRetryWait
+		}
+		if !sleepUntil(time.Now().Add(wait), sess.stopCh) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// reacquire runs DISCOVER again with backoff until it succeeds or we're stopped
+func (m *Manager) reacquire(iface string, sess *session) *Lease {
+	wait := minRetryWait
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), acquireTimeout)
+		lease, err := sess.client.Acquire(ctx)
+		cancel()
+		if err == nil {
+			return lease
+		}
+		log.Printf("DHCP: re-acquire on %s failed: %v", iface, err)
+
+		if !sleepUntil(time.Now().Add(wait), sess.stopCh) {
+			return nil
+		}
+		if wait < maxRetryWait {
+			wait *= 2
+		}
+	}
+}
+
+// apply installs the lease address and default route and publishes it to state
+func (m *Manager) apply(iface string, lease *Lease) error {
+	conn, err := rtnetlink.Dial(nil)
+	if err != nil {
+		return fmt.Errorf("failed to dial rtnetlink: %w", err)
+	}
+	defer conn.Close()
+
+	link, err := net.InterfaceByName(iface)
+	if err != nil {
+		return err
+	}
+	index := uint32(link.Index)
+
+	if err := netlink.AddAddress(conn, index, lease.Address, lease.PrefixLen); err != nil {
+		if errors.Is(err, os.ErrPermission) {
+			return fmt.Errorf("%w: %v", netlink.ErrNoCapability, err)
+		}
+		return err
+	}
+	if lease.Router != nil {
+		if err := netlink.ReplaceDefaultRoute(conn, index, lease.Router, defaultRouteMetric); err != nil {
+			log.Printf("DHCP: failed to install default route via %s: %v", lease.Router, err)
+		}
+	}
+
+	log.Printf("DHCP: %s/%d on %s (server %s, expires %s)",
+		lease.Address, lease.PrefixLen, iface, lease.Server, lease.Expiry().Format(time.RFC3339))
+
+	dns := make([]string, len(lease.DNS))
+	for i, ip := range lease.DNS {
+		dns[i] = ip.String()
+	}
+
+	server := ""
+	if lease.Server != nil {
+		server = lease.Server.String()
+	}
+	m.stateMgr.Update(func(st *state.State) {
+		st.DhcpInterface = iface
+		st.DhcpServer = server
+		st.DhcpLeaseExpiry = lease.Expiry()
+		st.DhcpDNS = dns
+	})
+	return nil
+}
+
+// remove deletes the lease address/route and clears lease state
+func (m *Manager) remove(iface string, lease *Lease) {
+	if conn, err := rtnetlink.Dial(nil); err == nil {
+		if link, err := net.InterfaceByName(iface); err == nil {
+			index := uint32(link.Index)
+			if lease.Router != nil {
+				netlink.DelDefaultRoute(conn, index, lease.Router, defaultRouteMetric)
+			}
+			netlink.DelAddress(conn, index, lease.Address, lease.PrefixLen)
+		}
+		conn.Close()
+	}
+
+	m.stateMgr.Update(func(st *state.State) {
+		if st.DhcpInterface == iface {
+			st.DhcpInterface = ""
+			st.DhcpServer = ""
+			st.DhcpLeaseExpiry = time.Time{}
+			st.DhcpDNS = nil
+		}
+	})
+}
+
+// externalClient returns the configured fallback client
+func (m *Manager) externalClient() External {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.external
+}
+
+// releaseExternal has the fallback client drop its lease on iface
+func (m *Manager) releaseExternal(iface string) error {
+	ext := m.externalClient()
+	if ext == nil {
+		return nil
+	}
+	return ext.Release(iface)
+}
+
+// sleepUntil waits until t; returns false if stopCh closed first
+func sleepUntil(t time.Time, stopCh chan struct{}) bool {
+	timer := time.NewTimer(time.Until(t))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-stopCh:
+		return false
+	}
+}
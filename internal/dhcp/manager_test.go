@@ -0,0 +1,61 @@
+package dhcp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"x-network/internal/state"
+)
+
+func TestReleaseWithoutServerID(t *testing.T) {
+	m := &Manager{stateMgr: state.NewManager(), sessions: make(map[string]*session)}
+	// No client: a DHCPRELEASE attempt would dereference it
+	m.sessions["xn-test0"] = &session{
+		lease:  &Lease{Address: net.IPv4(10, 0, 0, 2), PrefixLen: 24},
+		stopCh: make(chan struct{}),
+	}
+	if err := m.Release("xn-test0"); err != nil {
+		t.Fatalf("Release() = %v", err)
+	}
+	if m.Lease("xn-test0") != nil {
+		t.Fatal("session still holds a lease after Release")
+	}
+}
+
+func TestReleaseDuringAcquire(t *testing.T) {
+	entered := make(chan struct{})
+	unblock := make(chan struct{})
+	defer func(nc func(string) (*Client, error), acq func(*Client, context.Context) (*Lease, error)) {
+		newClient, acquire = nc, acq
+	}(newClient, acquire)
+	newClient = func(string) (*Client, error) { return &Client{}, nil }
+	acquire = func(*Client, context.Context) (*Lease, error) {
+		close(entered)
+		<-unblock
+		return &Lease{Address: net.IPv4(10, 0, 0, 2), PrefixLen: 24, Obtained: time.Now(), T1: time.Hour, T2: time.Hour}, nil
+	}
+
+	stateMgr := state.NewManager()
+	m := &Manager{stateMgr: stateMgr, sessions: make(map[string]*session)}
+	done := make(chan error, 1)
+	go func() { done <- m.Start("xn-test0") }()
+
+	<-entered
+	if err := m.Release("xn-test0"); err != nil {
+		t.Fatalf("Release() = %v", err)
+	}
+	close(unblock)
+
+	if err := <-done; !errors.Is(err, ErrReleased) {
+		t.Fatalf("Start() = %v, want ErrReleased", err)
+	}
+	if m.Lease("xn-test0") != nil {
+		t.Fatal("released session picked up the late lease")
+	}
+	if st := stateMgr.Get(); st.DhcpInterface != "" {
+		t.Fatalf("late lease published for %s", st.DhcpInterface)
+	}
+}
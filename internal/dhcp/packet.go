@@ -0,0 +1,211 @@
+package dhcp
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"time"
+)
+
+// DHCP message types (RFC 2132 option 53)
+const (
+	MsgDiscover = 1
+	MsgOffer    = 2
+	MsgRequest  = 3
+	MsgDecline  = 4
+	MsgAck      = 5
+	MsgNak      = 6
+	MsgRelease  = 7
+	MsgInform   = 8
+)
+
+// DHCP option codes used by the client
+const (
+	optSubnetMask   = 1
+	optRouter       = 3
+	optDNS          = 6
+	optHostname     = 12
+	optDomainName   = 15
+	optBroadcast    = 28
+	optRequestedIP  = 50
+	optLeaseTime    = 51
+	optMsgType      = 53
+	optServerID     = 54
+	optParamRequest = 55
+	optRenewalTime  = 58
+	optRebindTime   = 59
+	optClientID     = 61
+	optDomainSearch = 119
+	optEnd          = 255
+	optPad          = 0
+)
+
+const (
+	bootRequest = 1
+	bootReply   = 2
+
+	headerLen     = 236
+	flagBroadcast = 0x8000
+)
+
+var magicCookie = []byte{99, 130, 83, 99}
+
+var errShortPacket = errors.New("dhcp: packet too short")
+
+// Packet is a decoded BOOTP/DHCP message
+type Packet struct {
+	Op      uint8
+	XID     uint32
+	Flags   uint16
+	CIAddr  net.IP
+	YIAddr  net.IP
+	SIAddr  net.IP
+	GIAddr  net.IP
+	CHAddr  net.HardwareAddr
+	Options map[uint8][]byte
+}
+
+// newPacket creates a client request with common fields filled in
+func newPacket(msgType uint8, xid uint32, mac net.HardwareAddr) *Packet {
+	return &Packet{
+		Op:     bootRequest,
+		XID:    xid,
+		CIAddr: net.IPv4zero,
+		YIAddr: net.IPv4zero,
+		SIAddr: net.IPv4zero,
+		GIAddr: net.IPv4zero,
+		CHAddr: mac,
+		Options: map[uint8][]byte{
+			optMsgType:  {msgType},
+			optClientID: append([]byte{1}, mac...),
+		},
+	}
+}
+
+// MsgType returns the DHCP message type option (0 if missing)
+func (p *Packet) MsgType() uint8 {
+	if v := p.Options[optMsgType]; len(v) == 1 {
+		return v[0]
+	}
+	return 0
+}
+
+// Marshal encodes the packet to wire format
+func (p *Packet) Marshal() []byte {
+	b := make([]byte, headerLen, 300)
+	b[0] = p.Op
+	b[1] = 1 // htype: ethernet
+	b[2] = 6 // hlen
+	binary.BigEndian.PutUint32(b[4:8], p.XID)
+	binary.BigEndian.PutUint16(b[10:12], p.Flags)
+	copy(b[12:16], p.CIAddr.To4())
+	copy(b[16:20], p.YIAddr.To4())
+	copy(b[20:24], p.SIAddr.To4())
+	copy(b[24:28], p.GIAddr.To4())
+	copy(b[28:44], p.CHAddr)
+
+	b = append(b, magicCookie...)
+	// Message type must come first for some picky servers
+	if v, ok := p.Options[optMsgType]; ok {
+		b = append(b, optMsgType, byte(len(v)))
+		b = append(b, v...)
+	}
+	for code, v := range p.Options {
+		if code == optMsgType {
+			continue
+		}
+		b = append(b, code, byte(len(v)))
+		b = append(b, v...)
+	}
+	b = append(b, optEnd)
+
+	// Pad to the BOOTP minimum of 300 bytes
+	for len(b) < 300 {
+		b = append(b, optPad)
+	}
+	return b
+}
+
+// Unmarshal decodes a wire-format packet
+func Unmarshal(b []byte) (*Packet, error) {
+	if len(b) < headerLen+len(magicCookie) {
+		return nil, errShortPacket
+	}
+
+	hlen := int(b[2])
+	if hlen > 16 {
+		hlen = 16
+	}
+
+	p := &Packet{
+		Op:      b[0],
+		XID:     binary.BigEndian.Uint32(b[4:8]),
+		Flags:   binary.BigEndian.Uint16(b[10:12]),
+		CIAddr:  net.IP(append([]byte(nil), b[12:16]...)),
+		YIAddr:  net.IP(append([]byte(nil), b[16:20]...)),
+		SIAddr:  net.IP(append([]byte(nil), b[20:24]...)),
+		GIAddr:  net.IP(append([]byte(nil), b[24:28]...)),
+		CHAddr:  net.HardwareAddr(append([]byte(nil), b[28:28+hlen]...)),
+		Options: make(map[uint8][]byte),
+	}
+
+	opts := b[headerLen:]
+	if string(opts[:4]) != string(magicCookie) {
+		return nil, errors.New("dhcp: bad magic cookie")
+	}
+	opts = opts[4:]
+
+	for len(opts) > 0 {
+		code := opts[0]
+		if code == optEnd {
+			break
+		}
+		if code == optPad {
+			opts = opts[1:]
+			continue
+		}
+		if len(opts) < 2 || len(opts) < 2+int(opts[1]) {
+			return nil, errShortPacket
+		}
+		n := int(opts[1])
+		// Concatenate repeated options (RFC 3396)
+		p.Options[code] = append(p.Options[code], opts[2:2+n]...)
+		opts = opts[2+n:]
+	}
+
+	return p, nil
+}
+
+// optIP returns a single IPv4 option value
+func (p *Packet) optIP(code uint8) net.IP {
+	if v := p.Options[code]; len(v) >= 4 {
+		return net.IP(append([]byte(nil), v[:4]...))
+	}
+	return nil
+}
+
+// optIPs returns a list of IPv4 addresses from an option
+func (p *Packet) optIPs(code uint8) []net.IP {
+	v := p.Options[code]
+	var ips []net.IP
+	for len(v) >= 4 {
+		ips = append(ips, net.IP(append([]byte(nil), v[:4]...)))
+		v = v[4:]
+	}
+	return ips
+}
+
+// optDuration returns a seconds option as a duration
+func (p *Packet) optDuration(code uint8) time.Duration {
+	if v := p.Options[code]; len(v) == 4 {
+		return time.Duration(binary.BigEndian.Uint32(v)) * time.Second
+	}
+	return 0
+}
+
+// uint32Opt encodes a uint32 option value
+func uint32Opt(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, v)
+	return b
+}
@@ -0,0 +1,293 @@
+package dhcp
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// serverLeaseTime is the lease handed to hotspot clients
+const serverLeaseTime = 12 * time.Hour
+
+// maxPoolScan bounds the free-address search on large subnets
+const maxPoolScan = 1 << 16
+
+// serverLease is one address handed out by Server
+type serverLease struct {
+	ip     net.IP
+	expiry time.Time
+}
+
+// Server is a minimal DHCPv4 server for the hotspot subnet
+// Answers DISCOVER/REQUEST/RELEASE/DECLINE; leases live in memory only
+type Server struct {
+	iface    *net.Interface
+	serverIP net.IP
+	subnet   *net.IPNet
+	dns      []net.IP
+
+	mu     sync.Mutex
+	leases map[string]serverLease // By client MAC
+	pc     net.PacketConn
+}
+
+// NewServer creates a server on ifaceName handing out addresses from subnet
+// serverIP is our own address on the subnet and the clients' router
+func NewServer(ifaceName string, serverIP net.IP, subnet *net.IPNet, dns []net.IP) (*Server, error) {
+	iface, err := net.InterfaceByName(ifaceName)
+	if err != nil {
+		return nil, err
+	}
+	if serverIP.To4() == nil || !subnet.Contains(serverIP) {
+		return nil, fmt.Errorf("dhcp: server address %s not in %s", serverIP, subnet)
+	}
+	return &Server{
+		iface:    iface,
+		serverIP: serverIP.To4(),
+		subnet:   subnet,
+		dns:      dns,
+		leases:   make(map[string]serverLease),
+	}, nil
+}
+
+// Start opens port 67 on the interface and serves until Close
+func (s *Server) Start() error {
+	lc := net.ListenConfig{
+		Control: func(network, address string, raw syscall.RawConn) error {
+			var sockErr error
+			err := raw.Control(func(fd uintptr) {
+				if sockErr = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_REUSEADDR, 1); sockErr != nil {
+					return
+				}
+				if sockErr = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_BROADCAST, 1); sockErr != nil {
+					return
+				}
+				sockErr = syscall.BindToDevice(int(fd), s.iface.Name)
+			})
+			if err != nil {
+				return err
+			}
+			return sockErr
+		},
+	}
+
+	pc, err := lc.ListenPacket(context.Background(), "udp4", fmt.Sprintf("0.0.0.0:%d", serverPort))
+	if err != nil {
+		if errors.Is(err, os.ErrPermission) {
+			return fmt.Errorf("%w: %v", ErrPermission, err)
+		}
+		return err
+	}
+
+	s.mu.Lock()
+	s.pc = pc
+	s.mu.Unlock()
+
+	log.Printf("DHCP server: serving %s on %s", s.subnet, s.iface.Name)
+	go s.serve(pc)
+	return nil
+}
+
+// Close stops serving
+func (s *Server) Close() {
+	s.mu.Lock()
+	pc := s.pc
+	s.pc = nil
+	s.mu.Unlock()
+	if pc != nil {
+		pc.Close()
+	}
+}
+
+// serve answers requests until the socket is closed
+func (s *Server) serve(pc net.PacketConn) {
+	buf := make([]byte, 1500)
+	for {
+		n, _, err := pc.ReadFrom(buf)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Printf("DHCP server: read failed: %v", err)
+			}
+			return
+		}
+		req, err := Unmarshal(buf[:n])
+		if err != nil || req.Op != bootRequest || len(req.CHAddr) < 6 {
+			continue
+		}
+		if reply := s.handle(req); reply != nil {
+			s.send(pc, req, reply)
+		}
+	}
+}
+
+// handle builds the reply for one client message (nil = stay silent)
+func (s *Server) handle(req *Packet) *Packet {
+	mac := req.CHAddr[:6].String()
+
+	switch req.MsgType() {
+	case MsgDiscover:
+		// Offer only - the lease is committed by REQUEST, so clients cycling
+		// MAC addresses can't drain the pool with DISCOVERs
+		ip := s.allocate(mac, req.optIP(optRequestedIP), false)
+		if ip == nil {
+			log.Printf("DHCP server: pool %s exhausted", s.subnet)
+			return nil
+		}
+		return s.reply(req, MsgOffer, ip)
+
+	case MsgRequest:
+		if id := req.optIP(optServerID); id != nil && !id.Equal(s.serverIP) {
+			s.release(mac) // Client picked another server
+			return nil
+		}
+		want := req.optIP(optRequestedIP)
+		if want == nil {
+			want = req.CIAddr // Renewing
+		}
+		ip := s.allocate(mac, want, true)
+		if ip == nil || !ip.Equal(want) {
+			return s.reply(req, MsgNak, nil)
+		}
+		log.Printf("DHCP server: %s -> %s", mac, ip)
+		return s.reply(req, MsgAck, ip)
+
+	case MsgRelease, MsgDecline:
+		s.release(mac)
+	}
+	return nil
+}
+
+// reply creates a server message for req
+func (s *Server) reply(req *Packet, msgType uint8, ip net.IP) *Packet {
+	p := &Packet{
+		Op:     bootReply,
+		XID:    req.XID,
+		Flags:  req.Flags,
+		CIAddr: net.IPv4zero,
+		YIAddr: net.IPv4zero,
+		SIAddr: s.serverIP,
+		GIAddr: req.GIAddr,
+		CHAddr: req.CHAddr,
+		Options: map[uint8][]byte{
+			optMsgType:  {msgType},
+			optServerID: s.serverIP,
+		},
+	}
+	if msgType == MsgNak {
+		return p
+	}
+
+	p.YIAddr = ip
+	p.Options[optLeaseTime] = uint32Opt(uint32(serverLeaseTime / time.Second))
+	p.Options[optRenewalTime] = uint32Opt(uint32(serverLeaseTime / 2 / time.Second))
+	p.Options[optRebindTime] = uint32Opt(uint32(serverLeaseTime * 7 / 8 / time.Second))
+	p.Options[optSubnetMask] = []byte(net.IP(s.subnet.Mask).To4())
+	p.Options[optRouter] = s.serverIP
+	if b := broadcastOf(s.subnet); b != nil {
+		p.Options[optBroadcast] = b
+	}
+	var dns []byte
+	for _, d := range s.dns {
+		if d4 := d.To4(); d4 != nil {
+			dns = append(dns, d4...)
+		}
+	}
+	if len(dns) > 0 {
+		p.Options[optDNS] = dns
+	}
+	return p
+}
+
+// send delivers a reply: unicast to a client that already has an address,
+// otherwise broadcast on the interface
+func (s *Server) send(pc net.PacketConn, req, reply *Packet) {
+	dst := net.IPv4bcast
+	if !req.CIAddr.IsUnspecified() && reply.MsgType() != MsgNak {
+		dst = req.CIAddr
+	}
+	if _, err := pc.WriteTo(reply.Marshal(), &net.UDPAddr{IP: dst, Port: clientPort}); err != nil {
+		log.Printf("DHCP server: send to %s failed: %v", req.CHAddr, err)
+	}
+}
+
+// allocate returns mac's address: its current lease, the requested address if
+// free, or the first free address in the pool. commit records the lease
+func (s *Server) allocate(mac string, requested net.IP, commit bool) net.IP {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	inUse := make(map[string]bool, len(s.leases))
+	for m, l := range s.leases {
+		if m != mac && now.Before(l.expiry) {
+			inUse[l.ip.String()] = true
+		}
+	}
+	usable := func(ip net.IP) bool {
+		return ip != nil && s.inPool(ip) && !inUse[ip.String()]
+	}
+
+	var ip net.IP
+	switch {
+	case usable(requested):
+		ip = requested.To4()
+	case usable(s.leases[mac].ip):
+		ip = s.leases[mac].ip
+	default:
+		base := binary.BigEndian.Uint32(s.subnet.IP.To4())
+		for i := uint32(1); i < maxPoolScan; i++ {
+			candidate := make(net.IP, 4)
+			binary.BigEndian.PutUint32(candidate, base+i)
+			if !s.subnet.Contains(candidate) {
+				break
+			}
+			if usable(candidate) {
+				ip = candidate
+				break
+			}
+		}
+	}
+	if ip != nil && commit {
+		s.leases[mac] = serverLease{ip: ip, expiry: now.Add(serverLeaseTime)}
+	}
+	return ip
+}
+
+// inPool reports whether ip can be handed out (not network, broadcast or ours)
+func (s *Server) inPool(ip net.IP) bool {
+	ip = ip.To4()
+	if ip == nil || !s.subnet.Contains(ip) || ip.Equal(s.serverIP) {
+		return false
+	}
+	if ip.Equal(s.subnet.IP.To4()) || ip.Equal(broadcastOf(s.subnet)) {
+		return false
+	}
+	return true
+}
+
+// release frees mac's lease
+func (s *Server) release(mac string) {
+	s.mu.Lock()
+	delete(s.leases, mac)
+	s.mu.Unlock()
+}
+
+// broadcastOf returns the IPv4 broadcast address of subnet
+func broadcastOf(subnet *net.IPNet) net.IP {
+	ip := subnet.IP.To4()
+	if ip == nil || len(subnet.Mask) != 4 {
+		return nil
+	}
+	b := make(net.IP, 4)
+	for i := range b {
+		b[i] = ip[i] | ^subnet.Mask[i]
+	}
+	return b
+}
@@ -0,0 +1,50 @@
+package dhcp
+
+import (
+	"net"
+	"testing"
+)
+
+func testServer(t *testing.T, cidr string) *Server {
+	t.Helper()
+	ip, subnet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Server{serverIP: ip.To4(), subnet: subnet, leases: make(map[string]serverLease)}
+}
+
+func testMAC(i byte) net.HardwareAddr {
+	return net.HardwareAddr{0x02, 0, 0, 0, 0, i}
+}
+
+func TestServerDiscoverDoesNotReserve(t *testing.T) {
+	// /30: one address for clients next to ours
+	s := testServer(t, "10.42.0.1/30")
+
+	for i := byte(1); i <= 5; i++ {
+		reply := s.handle(newPacket(MsgDiscover, uint32(i), testMAC(i)))
+		if reply == nil || reply.MsgType() != MsgOffer {
+			t.Fatalf("DISCOVER %d: no offer", i)
+		}
+	}
+	if len(s.leases) != 0 {
+		t.Fatalf("DISCOVER committed %d leases", len(s.leases))
+	}
+}
+
+func TestServerRequestCommits(t *testing.T) {
+	s := testServer(t, "10.42.0.1/30")
+
+	offer := s.handle(newPacket(MsgDiscover, 1, testMAC(1)))
+	req := newPacket(MsgRequest, 1, testMAC(1))
+	req.Options[optRequestedIP] = offer.YIAddr.To4()
+	if ack := s.handle(req); ack == nil || ack.MsgType() != MsgAck {
+		t.Fatalf("REQUEST for the offered address not acknowledged")
+	}
+
+	// The only client address is taken now
+	if reply := s.handle(newPacket(MsgDiscover, 2, testMAC(2))); reply != nil {
+		t.Fatalf("second client got an offer from a full pool: %v", reply.YIAddr)
+	}
+}
@@ -0,0 +1,81 @@
+package dns
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// LatencyProbeHost is resolved to time each server
+	LatencyProbeHost = "www.google.com"
+
+	// LatencyTimeout bounds each server's lookup
+	LatencyTimeout = 2 * time.Second
+
+	// defaultPort is used for servers given without one
+	defaultPort = "53"
+)
+
+// DialFunc opens the connection a lookup is sent over; same signature as
+// net.Dialer.DialContext
+type DialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// MeasureLatency resolves host against each server in parallel and returns
+// the round trip in milliseconds; failed or timed-out servers map to -1
+// Servers are addresses with an optional port (53 when omitted); dial opens
+// the connections (nil = net.Dialer)
+func MeasureLatency(servers []string, host string, timeout time.Duration, dial DialFunc) map[string]int32 {
+	if dial == nil {
+		var d net.Dialer
+		dial = d.DialContext
+	}
+	results := make(map[string]int32, len(servers))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, server := range servers {
+		wg.Add(1)
+		go func(server string) {
+			defer wg.Done()
+			ms := int32(-1)
+			if d, err := lookupVia(dial, server, host, timeout); err == nil {
+				ms = int32(d.Milliseconds())
+			}
+			mu.Lock()
+			results[server] = ms
+			mu.Unlock()
+		}(server)
+	}
+	wg.Wait()
+	return results
+}
+
+// lookupVia times one uncached A/AAAA lookup sent straight to server
+func lookupVia(dial DialFunc, server, host string, timeout time.Duration) (time.Duration, error) {
+	addr := serverAddr(server)
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return dial(ctx, network, addr)
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	start := time.Now()
+	_, err := resolver.LookupIPAddr(ctx, host)
+	return time.Since(start), err
+}
+
+// serverAddr returns server as host:port, adding the default DNS port
+// unless it already has one ("192.0.2.1:5353", "[2001:db8::1]:5353")
+func serverAddr(server string) string {
+	if _, _, err := net.SplitHostPort(server); err == nil {
+		return server
+	}
+	return net.JoinHostPort(strings.Trim(server, "[]"), defaultPort)
+}
@@ -0,0 +1,104 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// serveDNS answers every A query on a local UDP socket with 192.0.2.1
+// (AAAA gets an empty answer); returns the socket address
+func serveDNS(t *testing.T) string {
+	t.Helper()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pc.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var req dnsmessage.Message
+			if err := req.Unpack(buf[:n]); err != nil || len(req.Questions) != 1 {
+				continue
+			}
+			q := req.Questions[0]
+			resp := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: req.ID, Response: true, RecursionDesired: req.RecursionDesired, RecursionAvailable: true},
+				Questions: req.Questions,
+			}
+			if q.Type == dnsmessage.TypeA {
+				resp.Answers = []dnsmessage.Resource{{
+					Header: dnsmessage.ResourceHeader{Name: q.Name, Type: q.Type, Class: q.Class, TTL: 60},
+					Body:   &dnsmessage.AResource{A: [4]byte{192, 0, 2, 1}},
+				}}
+			}
+			out, err := resp.Pack()
+			if err != nil {
+				continue
+			}
+			pc.WriteTo(out, addr)
+		}
+	}()
+	return pc.LocalAddr().String()
+}
+
+func TestMeasureLatency(t *testing.T) {
+	server := serveDNS(t)
+	_, port, _ := net.SplitHostPort(server)
+
+	// Failing server: its dial is refused without touching the network
+	refused := "192.0.2.53"
+	dial := func(ctx context.Context, network, address string) (net.Conn, error) {
+		if address == net.JoinHostPort(refused, defaultPort) {
+			return nil, errors.New("refused")
+		}
+		var d net.Dialer
+		return d.DialContext(ctx, network, address)
+	}
+
+	got := MeasureLatency([]string{server, refused}, "probe.example", time.Second, dial)
+	if ms, ok := got[server]; !ok || ms < 0 {
+		t.Errorf("latency of %s = %d, %v; want a measurement", server, ms, ok)
+	}
+	if ms := got[refused]; ms != -1 {
+		t.Errorf("latency of %s = %d, want -1", refused, ms)
+	}
+
+	// The dial function can redirect a server to another address
+	redirect := func(ctx context.Context, network, address string) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, network, net.JoinHostPort("127.0.0.1", port))
+	}
+	if ms := MeasureLatency([]string{"198.51.100.1"}, "probe.example", time.Second, redirect)["198.51.100.1"]; ms < 0 {
+		t.Errorf("redirected lookup failed (%d)", ms)
+	}
+}
+
+func TestServerAddr(t *testing.T) {
+	tests := []struct {
+		server string
+		want   string
+	}{
+		{"192.0.2.1", "192.0.2.1:53"},
+		{"192.0.2.1:5353", "192.0.2.1:5353"},
+		{"2001:db8::1", "[2001:db8::1]:53"},
+		{"[2001:db8::1]", "[2001:db8::1]:53"},
+		{"[2001:db8::1]:5353", "[2001:db8::1]:5353"},
+		{"fe80::1%wlan0", "[fe80::1%wlan0]:53"},
+	}
+	for _, tt := range tests {
+		if got := serverAddr(tt.server); got != tt.want {
+			t.Errorf("serverAddr(%q) = %q, want %q", tt.server, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package dns
+
+import (
+	"bufio"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"x-network/internal/state"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	resolvConf = "/etc/resolv.conf"
+
+	resolvedService = "org.freedesktop.resolve1"
+	resolvedPath    = "/org/freedesktop/resolve1"
+	resolvedManager = "org.freedesktop.resolve1.Manager"
+
+	// stubResolver is systemd-resolved's local listener
+	stubResolver = "127.0.0.53"
+)
+
+// Watcher tracks the system resolver configuration
+// Sources, in order: systemd-resolved (when resolv.conf points at its stub),
+// /etc/resolv.conf, then DNS servers from the native DHCP lease
+type Watcher struct {
+	stateMgr *state.Manager
+	conn     *dbus.Conn // System bus for systemd-resolved (nil if unavailable)
+	inotify  *os.File   // Non-blocking inotify fd wrapped for the runtime poller
+	stopCh   chan struct{}
+}
+
+// NewWatcher creates a resolver watcher
+func NewWatcher(stateMgr *state.Manager) (*Watcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Watcher{
+		stateMgr: stateMgr,
+		inotify:  os.NewFile(uintptr(fd), "inotify"),
+		stopCh:   make(chan struct{}),
+	}
+
+	// Watch the directories, not the file - resolv.conf is usually replaced
+	// via rename or is a symlink into /run
+	for _, dir := range w.watchDirs() {
+		mask := uint32(syscall.IN_CLOSE_WRITE | syscall.IN_MOVED_TO | syscall.IN_CREATE | syscall.IN_DELETE)
+		if _, err := syscall.InotifyAddWatch(fd, dir, mask); err != nil {
+			log.Printf("DNS: cannot watch %s: %v", dir, err)
+		}
+	}
+
+	if conn, err := dbus.SystemBus(); err == nil {
+		w.conn = conn
+	}
+
+	return w, nil
+}
+
+// Close stops the watcher
+func (w *Watcher) Close() {
+	close(w.stopCh)
+	w.inotify.Close() // Unblocks Run's pending read
+}
+
+// Run refreshes once, then on every resolver change
+func (w *Watcher) Run() {
+	w.Refresh()
+
+	// systemd-resolved pushes DNS changes as PropertiesChanged
+	if w.conn != nil {
+		rule := "type='signal',sender='" + resolvedService + "',interface='org.freedesktop.DBus.Properties',member='PropertiesChanged',path='" + resolvedPath + "'"
+		if err := w.conn.BusObject().Call("org.freedesktop.DBus.AddMatch", 0, rule).Err; err == nil {
+			ch := make(chan *dbus.Signal, 10)
+			w.conn.Signal(ch)
+			go func() {
+				for sig := range ch {
+					if sig.Path == resolvedPath && sig.Name == "org.freedesktop.DBus.Properties.PropertiesChanged" {
+						w.Refresh()
+					}
+				}
+			}()
+		}
+	}
+
+	buf := make([]byte, 4096)
+	for {
+		n, err := w.inotify.Read(buf)
+		select {
+		case <-w.stopCh:
+			return
+		default:
+		}
+		if err != nil {
+			log.Printf("DNS: inotify read error: %v", err)
+			return
+		}
+		if touchesResolvConf(buf[:n]) {
+			w.Refresh()
+		}
+	}
+}
+
+// Refresh re-reads the resolver configuration and publishes it to state
+func (w *Watcher) Refresh() {
+	servers, domains := readResolvConf(resolvConf)
+
+	// Stub resolver hides the real upstreams - ask resolved directly
+	if len(servers) == 1 && servers[0] == stubResolver && w.conn != nil {
+		if rs, rd, ok := w.queryResolved(); ok {
+			servers, domains = rs, rd
+		}
+	}
+
+	st := w.stateMgr.Get()
+	if len(servers) == 0 {
+		servers = st.DhcpDNS
+	}
+
+	if reflect.DeepEqual(servers, st.DnsServers) && reflect.DeepEqual(domains, st.SearchDomains) {
+		return
+	}
+
+	log.Printf("DNS: servers=%v search=%v", servers, domains)
+	w.stateMgr.Update(func(st *state.State) {
+		st.DnsServers = servers
+		st.SearchDomains = domains
+	})
+}
+
+// watchDirs returns /etc plus the directory resolv.conf links into
+func (w *Watcher) watchDirs() []string {
+	dirs := []string{filepath.Dir(resolvConf)}
+	if target, err := filepath.EvalSymlinks(resolvConf); err == nil {
+		if dir := filepath.Dir(target); dir != dirs[0] {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// queryResolved reads global + per-link DNS and Domains from systemd-resolved
+func (w *Watcher) queryResolved() (servers, domains []string, ok bool) {
+	obj := w.conn.Object(resolvedService, resolvedPath)
+
+	var dnsEntries []struct {
+		Ifindex int32
+		Family  int32
+		Address []byte
+	}
+	v, err := obj.GetProperty(resolvedManager + ".DNS")
+	if err != nil {
+		return nil, nil, false
+	}
+	if err := dbus.Store([]interface{}{v.Value()}, &dnsEntries); err != nil {
+		return nil, nil, false
+	}
+	for _, e := range dnsEntries {
+		if ip := net.IP(e.Address); len(ip) == net.IPv4len || len(ip) == net.IPv6len {
+			servers = appendUnique(servers, ip.String())
+		}
+	}
+
+	var domainEntries []struct {
+		Ifindex   int32
+		Domain    string
+		RouteOnly bool
+	}
+	if v, err := obj.GetProperty(resolvedManager + ".Domains"); err == nil {
+		if err := dbus.Store([]interface{}{v.Value()}, &domainEntries); err == nil {
+			for _, d := range domainEntries {
+				if !d.RouteOnly && d.Domain != "" {
+					domains = appendUnique(domains, d.Domain)
+				}
+			}
+		}
+	}
+
+	return servers, domains, true
+}
+
+// readResolvConf parses nameserver and search/domain lines
+func readResolvConf(path string) (servers, domains []string) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") || strings.HasPrefix(fields[0], ";") {
+			continue
+		}
+		switch fields[0] {
+		case "nameserver":
+			servers = appendUnique(servers, fields[1])
+		case "search", "domain":
+			// Last search/domain line wins (resolv.conf(5))
+			domains = append([]string(nil), fields[1:]...)
+		}
+	}
+	return servers, domains
+}
+
+// touchesResolvConf reports whether an inotify batch mentions resolv.conf
+func touchesResolvConf(buf []byte) bool {
+	for len(buf) >= syscall.SizeofInotifyEvent {
+		ev := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[0]))
+		nameLen := int(ev.Len)
+		end := syscall.SizeofInotifyEvent + nameLen
+		if end > len(buf) {
+			break
+		}
+		name := strings.TrimRight(string(buf[syscall.SizeofInotifyEvent:end]), "\x00")
+		if strings.Contains(name, "resolv.conf") {
+			return true
+		}
+		buf = buf[end:]
+	}
+	return false
+}
+
+// appendUnique appends s unless already present
+func appendUnique(list []string, s string) []string {
+	for _, v := range list {
+		if v == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
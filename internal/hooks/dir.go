@@ -0,0 +1,116 @@
+package hooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Events passed to hooks.d executables as their argument and XNET_EVENT
+const (
+	EventConnected       = "connected"
+	EventDisconnected    = "disconnected"
+	EventResumed         = "resumed"
+	EventAddressAcquired = "address-acquired" // First IPv4 after startup or resume
+	EventCaptivePortal   = "captive-portal-detected"
+	EventHotspotStarted  = "hotspot-started"
+)
+
+// DefaultHookTimeout bounds a single hook run
+const DefaultHookTimeout = 30 * time.Second
+
+// Info describes the event to a hook
+type Info struct {
+	Reason string // startup/resume for address-acquired, "" otherwise
+	SSID   string
+	Iface  string
+	IP     string
+	Type   string // state.ConnectionType ("" when not known for the event)
+}
+
+// DefaultDir returns ~/.config/x-network/hooks.d
+func DefaultDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "x-network", "hooks.d")
+}
+
+// hookFiles lists the executables in dir in name order
+// A missing directory just means no hooks
+func hookFiles(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Printf("Hooks: cannot read %s: %v", dir, err)
+		}
+		return nil
+	}
+
+	var files []string
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil || !info.Mode().IsRegular() || info.Mode().Perm()&0111 == 0 {
+			continue
+		}
+		files = append(files, filepath.Join(dir, e.Name()))
+	}
+	sort.Strings(files)
+	return files
+}
+
+// runDir runs every hook in dir for event, one after another, each bounded by
+// timeout. Failures are logged; the first one is returned
+func runDir(dir string, timeout time.Duration, event string, info Info) error {
+	env := environment(event, info)
+
+	var firstErr error
+	for _, path := range hookFiles(dir) {
+		out, err := runHook(path, env, timeout, event)
+		if err != nil {
+			log.Printf("Hooks: %s %s failed: %v (%s)", filepath.Base(path), event, err, out)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("%s: %w", filepath.Base(path), err)
+			}
+			continue
+		}
+		log.Printf("Hooks: ran %s %s", filepath.Base(path), event)
+	}
+	return firstErr
+}
+
+// environment describes the event to a script or hook; up/down scripts and
+// hooks.d executables get the same variables
+func environment(event string, info Info) []string {
+	return append(os.Environ(),
+		"XNET_EVENT="+event,
+		"XNET_SSID="+info.SSID,
+		"XNET_IFACE="+info.Iface,
+		"XNET_IP="+info.IP,
+		"XNET_TYPE="+info.Type,
+		"XNET_REASON="+info.Reason,
+	)
+}
+
+// runHook runs one executable with env, killing it after timeout
+func runHook(path string, env []string, timeout time.Duration, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Env = env
+	cmd.WaitDelay = time.Second // Don't wait on children still holding the output pipe
+	out, err := cmd.CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		log.Printf("Hooks: killed %s after %s", path, timeout)
+		err = fmt.Errorf("timed out after %s", timeout)
+	}
+	return out, err
+}
@@ -0,0 +1,197 @@
+package hooks
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"x-network/internal/state"
+)
+
+// Event names passed to up/down scripts as XNET_EVENT
+const (
+	EventUp   = "up"
+	EventDown = "down"
+)
+
+// Runner runs user scripts when the connection comes up or goes down, and the
+// hooks.d executables on every event
+// Transitions are detected in one place (a state listener) so IWD, netlink
+// and USB handlers don't each need to know about scripts
+type Runner struct {
+	upScript   string
+	downScript string
+	dir        string        // hooks.d directory ("" = none)
+	timeout    time.Duration // Per up/down script and hooks.d executable
+
+	mu         sync.Mutex
+	seq        uint64 // Seq of the last state observed
+	up         bool
+	last       state.State // Snapshot from the last "up", used for the down environment
+	resumed    bool        // Last WasResumed seen
+	captiveSeq uint64      // Last CaptiveCheckSeq seen
+	hotspot    bool        // Last HotspotActive seen
+	queue      chan job
+	stopCh     chan struct{}
+}
+
+// job is one queued script invocation, or one hooks.d event (dir set)
+type job struct {
+	script string
+	event  string
+	dir    bool
+	info   Info
+}
+
+// NewRunner creates a runner; empty paths disable the respective hook
+func NewRunner(upScript, downScript string) *Runner {
+	return &Runner{
+		upScript:   upScript,
+		downScript: downScript,
+		timeout:    DefaultHookTimeout,
+		queue:      make(chan job, 16),
+		stopCh:     make(chan struct{}),
+	}
+}
+
+// SetDir sets the hooks.d directory and the timeout for every script and
+// hook (0 keeps the default). Call before Attach
+func (r *Runner) SetDir(dir string, timeout time.Duration) {
+	r.dir = dir
+	if timeout > 0 {
+		r.timeout = timeout
+	}
+}
+
+// Run runs the hooks.d executables for event and waits for them
+// Used for events with their own dedup (address-acquired); the error is the
+// first failing hook
+func (r *Runner) Run(event string, info Info) error {
+	if r.dir == "" {
+		return nil
+	}
+	return runDir(r.dir, r.timeout, event, info)
+}
+
+// Attach subscribes to state changes and starts the worker
+func (r *Runner) Attach(stateMgr *state.Manager) {
+	go r.run()
+	stateMgr.AddListener(r.observe)
+}
+
+// Close stops the worker; queued scripts are dropped
+func (r *Runner) Close() {
+	close(r.stopCh)
+}
+
+// isUp reports whether any uplink is usable
+func isUp(st *state.State) bool {
+	return st.ConnectionState == state.StateConnected || st.UsbTetheringConnected || st.BtTetheringConnected
+}
+
+// observe queues scripts and hooks on transitions (never blocks state updates)
+// Each event fires once per rising edge, so repeated updates can't stack runs
+func (r *Runner) observe(st *state.State) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if st.Seq <= r.seq {
+		return // Older than a state already seen
+	}
+	r.seq = st.Seq
+
+	if st.WasResumed && !r.resumed {
+		r.enqueueDir(EventResumed, Info{Reason: "resume"})
+	}
+	r.resumed = st.WasResumed
+
+	if st.CaptiveCheckSeq != r.captiveSeq && st.CaptivePortalDetected {
+		r.enqueueDir(EventCaptivePortal, info(st))
+	}
+	r.captiveSeq = st.CaptiveCheckSeq
+
+	if st.HotspotActive && !r.hotspot {
+		r.enqueueDir(EventHotspotStarted, Info{SSID: st.HotspotSSID, Iface: st.InterfaceName})
+	}
+	r.hotspot = st.HotspotActive
+
+	up := isUp(st)
+	if up == r.up {
+		if up {
+			r.last = *st // Keep the latest details (IP may arrive after "connected")
+		}
+		return
+	}
+	r.up = up
+
+	if up {
+		r.last = *st
+		r.enqueue(r.upScript, EventUp, st)
+		r.enqueueDir(EventConnected, info(st))
+	} else {
+		r.enqueue(r.downScript, EventDown, &r.last)
+		r.enqueueDir(EventDisconnected, info(&r.last))
+	}
+}
+
+// enqueueDir hands a hooks.d event to the worker (caller holds mu)
+func (r *Runner) enqueueDir(event string, info Info) {
+	if r.dir == "" {
+		return
+	}
+	select {
+	case r.queue <- job{event: event, dir: true, info: info}:
+	default:
+		log.Printf("Hooks: queue full, dropping %s hooks", event)
+	}
+}
+
+// enqueue hands a script to the worker (caller holds mu)
+func (r *Runner) enqueue(script, event string, st *state.State) {
+	if script == "" {
+		return
+	}
+	j := job{script: script, event: event, info: info(st)}
+	select {
+	case r.queue <- j:
+	default:
+		log.Printf("Hooks: queue full, dropping %s script", event)
+	}
+}
+
+// activeInterface returns the interface carrying the connection
+func activeInterface(st *state.State) string {
+	if st.ConnectionType == "usb" && st.UsbInterfaceName != "" {
+		return st.UsbInterfaceName
+	}
+	if st.ConnectionType == "bluetooth" && st.BtInterfaceName != "" {
+		return st.BtInterfaceName
+	}
+	return st.InterfaceName
+}
+
+// info describes the connection to a script or hooks.d executable
+func info(st *state.State) Info {
+	return Info{SSID: st.ActiveSSID, Iface: activeInterface(st), IP: st.IpAddress, Type: st.ConnectionType}
+}
+
+// run executes queued scripts one at a time so up/down stay ordered
+func (r *Runner) run() {
+	for {
+		select {
+		case <-r.stopCh:
+			return
+		case j := <-r.queue:
+			if j.dir {
+				runDir(r.dir, r.timeout, j.event, j.info) // Failures are logged
+				continue
+			}
+			// Bounded like hooks.d: a hung script would stall every later event
+			if out, err := runHook(j.script, environment(j.event, j.info), r.timeout); err != nil {
+				log.Printf("Hooks: %s script %s failed: %v (%s)", j.event, j.script, err, out)
+			} else {
+				log.Printf("Hooks: ran %s script %s", j.event, j.script)
+			}
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package hooks
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"x-network/internal/state"
+)
+
+// script writes an executable shell script to dir
+func script(t *testing.T, dir, name, body string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestHungScriptIsKilled(t *testing.T) {
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "down-ran")
+	up := script(t, dir, "up", "exec sleep 30\n")
+	down := script(t, dir, "down", "touch "+marker+"\n")
+
+	r := NewRunner(up, down)
+	r.SetDir("", 200*time.Millisecond)
+	mgr := state.NewManager()
+	r.Attach(mgr)
+	defer r.Close()
+
+	mgr.Update(func(st *state.State) { st.ConnectionState = state.StateConnected })
+	mgr.Update(func(st *state.State) { st.ConnectionState = state.StateDisconnected })
+
+	// The down script only runs once the hung up script was killed
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(marker); err == nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("down script never ran behind a hung up script")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// waitFile waits for path to appear and returns its contents
+func waitFile(t *testing.T, path string) string {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if data, err := os.ReadFile(path); err == nil && len(data) > 0 {
+			return string(data)
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s was never written", path)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestScriptsAndHooksShareEnvironment(t *testing.T) {
+	dir := t.TempDir()
+	hooksDir := filepath.Join(dir, "hooks.d")
+	if err := os.Mkdir(hooksDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	dump := `env | grep '^XNET_' | grep -v '^XNET_EVENT=' | sort > "$0.env.tmp" && mv "$0.env.tmp" "$0.env"` + "\n"
+	up := script(t, dir, "up", dump)
+	hook := script(t, hooksDir, "10-dump", dump)
+
+	r := NewRunner(up, "")
+	r.SetDir(hooksDir, 0)
+	mgr := state.NewManager()
+	r.Attach(mgr)
+	defer r.Close()
+
+	mgr.Update(func(st *state.State) {
+		st.ConnectionState = state.StateConnected
+		st.ActiveSSID = "Cafe"
+		st.InterfaceName = "wlan0"
+		st.IpAddress = "192.168.1.20"
+		st.ConnectionType = "wifi"
+	})
+
+	scriptEnv := waitFile(t, up+".env")
+	hookEnv := waitFile(t, hook+".env")
+	if scriptEnv != hookEnv {
+		t.Errorf("up script env:\n%s\nhook env:\n%s", scriptEnv, hookEnv)
+	}
+	want := "XNET_IFACE=wlan0\nXNET_IP=192.168.1.20\nXNET_REASON=\nXNET_SSID=Cafe\nXNET_TYPE=wifi\n"
+	if scriptEnv != want {
+		t.Errorf("up script env = %q, want %q", scriptEnv, want)
+	}
+}
@@ -0,0 +1,15 @@
+package hotspot
+
+import "strings"
+
+// qrEscaper escapes the characters that are special in a WIFI: payload
+var qrEscaper = strings.NewReplacer(`\`, `\\`, `;`, `\;`, `,`, `\,`, `:`, `\:`, `"`, `\"`)
+
+// QRString renders the WIFI: payload phones scan to join a network
+// security is "open" or "wpa2"
+func QRString(ssid, password, security string) string {
+	if security == "open" {
+		return "WIFI:T:nopass;S:" + qrEscaper.Replace(ssid) + ";;"
+	}
+	return "WIFI:T:WPA;S:" + qrEscaper.Replace(ssid) + ";P:" + qrEscaper.Replace(password) + ";;"
+}
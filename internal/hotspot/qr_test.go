@@ -0,0 +1,29 @@
+package hotspot
+
+import "testing"
+
+func TestQRString(t *testing.T) {
+	tests := []struct {
+		name     string
+		ssid     string
+		password string
+		security string
+		want     string
+	}{
+		{"plain", "Cafe", "secret123", "wpa2", `WIFI:T:WPA;S:Cafe;P:secret123;;`},
+		{"backslash", `Back\slash`, `pa\ss`, "wpa2", `WIFI:T:WPA;S:Back\\slash;P:pa\\ss;;`},
+		{"semicolon", "Semi;colon", "pa;ss", "wpa2", `WIFI:T:WPA;S:Semi\;colon;P:pa\;ss;;`},
+		{"comma", "Com,ma", "pa,ss", "wpa2", `WIFI:T:WPA;S:Com\,ma;P:pa\,ss;;`},
+		{"colon", "Co:lon", "pa:ss", "wpa2", `WIFI:T:WPA;S:Co\:lon;P:pa\:ss;;`},
+		{"open", "Free;WiFi", "", "open", `WIFI:T:nopass;S:Free\;WiFi;;`},
+		{"open ignores password", "Free", "unused", "open", `WIFI:T:nopass;S:Free;;`},
+		{"empty password", "Cafe", "", "wpa2", `WIFI:T:WPA;S:Cafe;P:;;`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := QRString(tt.ssid, tt.password, tt.security); got != tt.want {
+				t.Errorf("QRString(%q, %q, %q) = %q, want %q", tt.ssid, tt.password, tt.security, got, tt.want)
+			}
+		})
+	}
+}
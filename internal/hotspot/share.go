@@ -0,0 +1,336 @@
+// Package hotspot gives the access point interface an address, hands out
+// leases to clients and NATs their traffic to the uplink
+package hotspot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"x-network/internal/dhcp"
+	"x-network/internal/netlink"
+
+	"github.com/jsimonetti/rtnetlink"
+)
+
+// DefaultSubnet is the AP address and client subnet when none is configured
+const DefaultSubnet = "10.42.0.1/24"
+
+// nftTable holds our masquerade rule; deleting it removes all NAT we added
+const nftTable = "x_network_hotspot"
+
+const ipForwardPath = "/proc/sys/net/ipv4/ip_forward"
+
+// fallbackDNS is handed to clients when the host only has a local stub resolver
+var fallbackDNS = []net.IP{net.IPv4(9, 9, 9, 9), net.IPv4(1, 1, 1, 1)}
+
+// marker records what Start changed so a restarted daemon can undo it
+type marker struct {
+	Iface       string `json:"iface"`
+	Address     string `json:"address"`
+	PrefixLen   uint8  `json:"prefix"`
+	ForwardPrev string `json:"forward_prev"` // ip_forward before we set it
+}
+
+// Share is a running hotspot network
+type Share struct {
+	marker
+	server *dhcp.Server
+}
+
+// markerDir holds the marker; tmpfs, so it is gone after reboot like the rules.
+// The marker drives root-level teardown, so it is only trusted in a root-owned
+// directory nobody else can write - never in a shared location like /tmp
+const markerDir = "/run/x-network"
+
+// maxMarkerSize bounds what CleanupStale reads
+const maxMarkerSize = 4096
+
+// MarkerPath returns where the running share is recorded
+func MarkerPath() string {
+	return filepath.Join(markerDir, "hotspot.json")
+}
+
+// checkOwner rejects anything not owned by root or writable by group/others
+func checkOwner(fi os.FileInfo, mode os.FileMode) error {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok || st.Uid != 0 {
+		return fmt.Errorf("%s is not owned by root", fi.Name())
+	}
+	if fi.Mode().Perm()&^mode != 0 {
+		return fmt.Errorf("%s has mode %04o, want %04o", fi.Name(), fi.Mode().Perm(), mode)
+	}
+	return nil
+}
+
+// ensureMarkerDir creates markerDir (root, 0700) or verifies an existing one
+func ensureMarkerDir() error {
+	if err := os.Mkdir(markerDir, 0700); err != nil && !errors.Is(err, os.ErrExist) {
+		return err
+	}
+	return checkMarkerDir()
+}
+
+// checkMarkerDir verifies markerDir is a real root-owned 0700 directory
+func checkMarkerDir() error {
+	fi, err := os.Lstat(markerDir)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		return fmt.Errorf("%s is not a directory", markerDir)
+	}
+	return checkOwner(fi, 0700)
+}
+
+// readMarker opens the marker without following links and only trusts a
+// regular root-owned 0600 file in a verified markerDir
+func readMarker() (marker, error) {
+	var m marker
+	if err := checkMarkerDir(); err != nil {
+		return m, err
+	}
+	f, err := os.OpenFile(MarkerPath(), os.O_RDONLY|syscall.O_NOFOLLOW, 0)
+	if err != nil {
+		return m, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return m, err
+	}
+	if !fi.Mode().IsRegular() {
+		return m, fmt.Errorf("%s is not a regular file", MarkerPath())
+	}
+	if err := checkOwner(fi, 0600); err != nil {
+		return m, err
+	}
+	data, err := io.ReadAll(io.LimitReader(f, maxMarkerSize))
+	if err != nil {
+		return m, err
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return m, fmt.Errorf("failed to parse %s: %w", MarkerPath(), err)
+	}
+	return m, nil
+}
+
+// Start configures iface for AP clients: address from cidr, DHCP server,
+// IPv4 forwarding and masquerading out of uplink ("" = anything but iface)
+// dns lists the host's resolvers; loopback stubs are replaced by public ones
+func Start(iface, cidr, uplink string, dns []string) (*Share, error) {
+	ip, subnet, err := net.ParseCIDR(cidr)
+	if err != nil || ip.To4() == nil {
+		return nil, fmt.Errorf("invalid hotspot subnet %q", cidr)
+	}
+	prefix, _ := subnet.Mask.Size()
+
+	s := &Share{marker: marker{Iface: iface, Address: ip.String(), PrefixLen: uint8(prefix)}}
+	ok := false
+	defer func() {
+		if !ok {
+			s.Stop()
+		}
+	}()
+
+	conn, err := rtnetlink.Dial(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial rtnetlink: %w", err)
+	}
+	defer conn.Close()
+	link, err := net.InterfaceByName(iface)
+	if err != nil {
+		return nil, err
+	}
+	if err := netlink.AddAddress(conn, uint32(link.Index), ip, uint8(prefix)); err != nil {
+		return nil, fmt.Errorf("failed to add %s to %s: %w", cidr, iface, err)
+	}
+
+	prev, err := setForwarding("1")
+	if err != nil {
+		return nil, fmt.Errorf("failed to enable IPv4 forwarding: %w", err)
+	}
+	s.ForwardPrev = prev
+	s.save()
+
+	if err := runNft(masqueradeRules(subnet, iface, uplink)); err != nil {
+		return nil, fmt.Errorf("failed to install NAT: %w", err)
+	}
+
+	s.server, err = dhcp.NewServer(iface, ip, subnet, clientDNS(dns))
+	if err == nil {
+		err = s.server.Start()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to start DHCP server: %w", err)
+	}
+
+	ok = true
+	log.Printf("Hotspot sharing on %s: %s, NAT via %s", iface, cidr, uplinkName(uplink))
+	return s, nil
+}
+
+// Stop undoes everything Start did; safe to call on a partial setup
+func (s *Share) Stop() error {
+	if s.server != nil {
+		s.server.Close()
+		s.server = nil
+	}
+	err := teardown(s.marker)
+	os.Remove(MarkerPath())
+	return err
+}
+
+// CleanupStale removes a share left behind by a daemon that exited mid-hotspot
+func CleanupStale() {
+	m, err := readMarker()
+	if errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	if err != nil {
+		// Not ours to act on - and not ours to delete either
+		log.Printf("Warning: ignoring hotspot marker: %v", err)
+		return
+	}
+	log.Printf("Cleaning up hotspot sharing left on %s", m.Iface)
+	if err := teardown(m); err != nil {
+		log.Printf("Hotspot cleanup: %v", err)
+	}
+	os.Remove(MarkerPath())
+}
+
+// teardown removes the NAT table, address and forwarding recorded in m
+func teardown(m marker) error {
+	var errs []error
+	if err := runNft("delete table ip " + nftTable + "\n"); err != nil && !strings.Contains(err.Error(), "No such file") {
+		errs = append(errs, fmt.Errorf("remove NAT: %w", err))
+	}
+	if m.ForwardPrev != "" && m.ForwardPrev != "1" {
+		if _, err := setForwarding(m.ForwardPrev); err != nil {
+			errs = append(errs, fmt.Errorf("restore forwarding: %w", err))
+		}
+	}
+	if ip := net.ParseIP(m.Address); ip != nil {
+		if link, err := net.InterfaceByName(m.Iface); err == nil {
+			if conn, err := rtnetlink.Dial(nil); err == nil {
+				netlink.DelAddress(conn, uint32(link.Index), ip, m.PrefixLen)
+				conn.Close()
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// save writes the marker (best effort - only needed for crash cleanup)
+func (s *Share) save() {
+	data, err := json.Marshal(s.marker)
+	if err != nil {
+		return
+	}
+	if err := writeMarker(data); err != nil {
+		log.Printf("Warning: cannot record hotspot state: %v", err)
+	}
+}
+
+// writeMarker replaces the marker in markerDir, never through a link
+func writeMarker(data []byte) error {
+	if err := ensureMarkerDir(); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(MarkerPath(), os.O_WRONLY|os.O_CREATE|os.O_TRUNC|syscall.O_NOFOLLOW, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// masqueradeRules renders the nft script for our NAT table
+func masqueradeRules(subnet *net.IPNet, iface, uplink string) string {
+	out := fmt.Sprintf("oifname != %q", iface)
+	if uplink != "" {
+		out = fmt.Sprintf("oifname %q", uplink)
+	}
+	// Re-create from scratch so a stale table never leaves a second rule
+	return fmt.Sprintf(`table ip %[1]s {}
+delete table ip %[1]s
+table ip %[1]s {
+	chain postrouting {
+		type nat hook postrouting priority srcnat; policy accept;
+		ip saddr %[2]s %[3]s masquerade
+	}
+	chain forward {
+		type filter hook forward priority filter; policy accept;
+		iifname %[4]q accept
+		oifname %[4]q ct state established,related accept
+	}
+}
+`, nftTable, subnet, out, iface)
+}
+
+// runNft feeds a script to nft, via sudo when the daemon lacks CAP_NET_ADMIN
+func runNft(script string) error {
+	cmd := exec.Command("nft", "-f", "-")
+	cmd.Stdin = strings.NewReader(script)
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		return nil
+	}
+	if !strings.Contains(string(out), "Operation not permitted") && !errors.Is(err, exec.ErrNotFound) {
+		return fmt.Errorf("%v (%s)", err, strings.TrimSpace(string(out)))
+	}
+
+	cmd = exec.Command("sudo", "-n", "nft", "-f", "-")
+	cmd.Stdin = strings.NewReader(script)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%v (%s)", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// setForwarding writes ip_forward and returns the previous value
+func setForwarding(value string) (string, error) {
+	prev, err := os.ReadFile(ipForwardPath)
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(ipForwardPath, []byte(value), 0644); err != nil {
+		if out, sErr := exec.Command("sudo", "-n", "sysctl", "-w", "net.ipv4.ip_forward="+value).CombinedOutput(); sErr != nil {
+			return "", fmt.Errorf("%v (%s)", sErr, strings.TrimSpace(string(out)))
+		}
+	}
+	return strings.TrimSpace(string(prev)), nil
+}
+
+// clientDNS picks resolvers reachable from the AP subnet
+func clientDNS(servers []string) []net.IP {
+	var ips []net.IP
+	for _, s := range servers {
+		if ip := net.ParseIP(s); ip != nil && ip.To4() != nil && !ip.IsLoopback() {
+			ips = append(ips, ip)
+		}
+	}
+	if len(ips) == 0 {
+		return fallbackDNS
+	}
+	return ips
+}
+
+// uplinkName describes the NAT egress for logs
+func uplinkName(uplink string) string {
+	if uplink == "" {
+		return "any uplink"
+	}
+	return uplink
+}
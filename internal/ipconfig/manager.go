@@ -0,0 +1,185 @@
+package ipconfig
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"sync"
+
+	"x-network/internal/dhcp"
+	"x-network/internal/netlink"
+
+	"github.com/jsimonetti/rtnetlink"
+)
+
+// staticRouteMetric matches the DHCP manager's default route metric
+const staticRouteMetric = 100
+
+// Manager decides between static addressing and DHCP per SSID/interface
+// Implements netlink.DHCPRunner so carrier events go through it
+type Manager struct {
+	store *Store
+	dhcp  *dhcp.Manager
+
+	mu      sync.Mutex
+	applied map[string]Profile // Static profiles currently installed, by interface
+}
+
+// NewManager creates an IP configuration manager
+func NewManager(store *Store, dhcpMgr *dhcp.Manager) *Manager {
+	return &Manager{
+		store:   store,
+		dhcp:    dhcpMgr,
+		applied: make(map[string]Profile),
+	}
+}
+
+// Profile returns the stored profile for target (DHCP if none)
+func (m *Manager) Profile(target string) Profile {
+	if p, ok := m.store.Get(target); ok {
+		return p
+	}
+	return Profile{Method: MethodDHCP}
+}
+
+// SetProfile validates and persists a profile for target
+func (m *Manager) SetProfile(target string, p Profile) error {
+	if target == "" {
+		return errors.New("target required")
+	}
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	return m.store.Set(target, p)
+}
+
+// Start configures iface from its interface profile, or runs DHCP
+func (m *Manager) Start(iface string) error {
+	if p, ok := m.store.Get(iface); ok && p.Method == MethodStatic {
+		return m.applyStatic(iface, p)
+	}
+	return m.dhcp.Start(iface)
+}
+
+// Release removes a static configuration or releases the DHCP lease
+func (m *Manager) Release(iface string) error {
+	if m.ClearStatic(iface) {
+		return nil
+	}
+	return m.dhcp.Release(iface)
+}
+
+// SetStatic persists a static profile for iface and applies it now if the link
+// is running, replacing any DHCP lease or previous static address
+func (m *Manager) SetStatic(iface string, p Profile) error {
+	if err := m.SetProfile(iface, p); err != nil {
+		return err
+	}
+	if !linkRunning(iface) {
+		return nil // Applied on the next carrier up
+	}
+	if !m.ClearStatic(iface) {
+		m.dhcp.Release(iface) // Ignore error - there may be no lease
+	}
+	return m.applyStatic(iface, p)
+}
+
+// SetDHCP clears the static profile for iface and, if one was installed,
+// removes it and starts DHCP
+func (m *Manager) SetDHCP(iface string) error {
+	if err := m.SetProfile(iface, Profile{Method: MethodDHCP}); err != nil {
+		return err
+	}
+	if m.ClearStatic(iface) && linkRunning(iface) {
+		return m.dhcp.Start(iface)
+	}
+	return nil
+}
+
+// linkRunning reports whether iface exists and has carrier
+func linkRunning(iface string) bool {
+	link, err := net.InterfaceByName(iface)
+	return err == nil && link.Flags&net.FlagRunning != 0
+}
+
+// ApplySSID installs the static profile for ssid on iface
+// Returns false when the SSID has no static profile (leave addressing to DHCP)
+func (m *Manager) ApplySSID(ssid, iface string) (bool, error) {
+	p, ok := m.store.Get(ssid)
+	if !ok || p.Method != MethodStatic {
+		return false, nil
+	}
+	return true, m.applyStatic(iface, p)
+}
+
+// ClearStatic removes a static configuration we installed on iface
+// Returns false if nothing was installed
+func (m *Manager) ClearStatic(iface string) bool {
+	m.mu.Lock()
+	p, ok := m.applied[iface]
+	delete(m.applied, iface)
+	m.mu.Unlock()
+	if !ok {
+		return false
+	}
+
+	log.Printf("IP config: removing static %s/%d from %s", p.Address, p.PrefixLen, iface)
+	conn, err := rtnetlink.Dial(nil)
+	if err != nil {
+		return true
+	}
+	defer conn.Close()
+
+	if link, err := net.InterfaceByName(iface); err == nil {
+		index := uint32(link.Index)
+		if gw := net.ParseIP(p.Gateway); gw != nil {
+			netlink.DelDefaultRoute(conn, index, gw, staticRouteMetric)
+		}
+		netlink.DelAddress(conn, index, net.ParseIP(p.Address), p.PrefixLen)
+	}
+	return true
+}
+
+// applyStatic installs address, default route and DNS for a static profile
+func (m *Manager) applyStatic(iface string, p Profile) error {
+	conn, err := rtnetlink.Dial(nil)
+	if err != nil {
+		return fmt.Errorf("failed to dial rtnetlink: %w", err)
+	}
+	defer conn.Close()
+
+	link, err := net.InterfaceByName(iface)
+	if err != nil {
+		return err
+	}
+	index := uint32(link.Index)
+
+	log.Printf("IP config: applying static %s/%d on %s", p.Address, p.PrefixLen, iface)
+	if err := netlink.AddAddress(conn, index, net.ParseIP(p.Address), p.PrefixLen); err != nil {
+		if errors.Is(err, os.ErrPermission) {
+			return fmt.Errorf("%w: %v", netlink.ErrNoCapability, err)
+		}
+		return err
+	}
+	if gw := net.ParseIP(p.Gateway); gw != nil {
+		if err := netlink.ReplaceDefaultRoute(conn, index, gw, staticRouteMetric); err != nil {
+			log.Printf("IP config: failed to install default route via %s: %v", gw, err)
+		}
+	}
+
+	m.mu.Lock()
+	m.applied[iface] = p
+	m.mu.Unlock()
+
+	if len(p.DNS) > 0 {
+		// systemd-resolved owns per-link DNS; the resolver watcher picks up the change
+		args := append([]string{"dns", iface}, p.DNS...)
+		if err := exec.Command("resolvectl", args...).Run(); err != nil {
+			log.Printf("IP config: failed to set DNS on %s: %v", iface, err)
+		}
+	}
+	return nil
+}
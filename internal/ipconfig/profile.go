@@ -0,0 +1,163 @@
+package ipconfig
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Addressing methods
+const (
+	MethodDHCP   = "dhcp"
+	MethodStatic = "static"
+)
+
+// Profile is the IP configuration for one SSID or interface
+type Profile struct {
+	Method    string   `json:"method"`
+	Address   string   `json:"address,omitempty"`
+	PrefixLen uint8    `json:"prefix,omitempty"`
+	Gateway   string   `json:"gateway,omitempty"`
+	DNS       []string `json:"dns,omitempty"`
+}
+
+// Validate checks a static profile is usable and fills defaults
+func (p *Profile) Validate() error {
+	switch p.Method {
+	case MethodDHCP:
+		return nil
+	case MethodStatic:
+	default:
+		return fmt.Errorf("unknown method: %q", p.Method)
+	}
+
+	if ip := net.ParseIP(p.Address); ip == nil || ip.To4() == nil {
+		return fmt.Errorf("invalid IPv4 address: %q", p.Address)
+	}
+	if p.PrefixLen == 0 {
+		p.PrefixLen = 24
+	}
+	if p.PrefixLen > 32 {
+		return fmt.Errorf("invalid prefix length: %d", p.PrefixLen)
+	}
+	if p.Gateway != "" && net.ParseIP(p.Gateway).To4() == nil {
+		return fmt.Errorf("invalid gateway: %q", p.Gateway)
+	}
+	for _, d := range p.DNS {
+		if net.ParseIP(d) == nil {
+			return fmt.Errorf("invalid DNS server: %q", d)
+		}
+	}
+	return nil
+}
+
+// StaticProfile builds a static profile from CIDR notation ("192.168.1.10/24")
+func StaticProfile(cidr, gateway string, dns []string) (Profile, error) {
+	ip, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil || ip.To4() == nil {
+		return Profile{}, fmt.Errorf("invalid IPv4 CIDR: %q", cidr)
+	}
+	prefix, _ := ipNet.Mask.Size()
+	if prefix == 0 {
+		return Profile{}, fmt.Errorf("invalid prefix length in %q", cidr)
+	}
+
+	p := Profile{
+		Method:    MethodStatic,
+		Address:   ip.String(),
+		PrefixLen: uint8(prefix),
+		Gateway:   gateway,
+		DNS:       dns,
+	}
+	if err := p.Validate(); err != nil {
+		return Profile{}, err
+	}
+	if gw := net.ParseIP(gateway); gw != nil {
+		if !ipNet.Contains(gw) {
+			return Profile{}, fmt.Errorf("gateway %s is outside %s", gateway, ipNet)
+		}
+		if gw.Equal(ip) {
+			return Profile{}, fmt.Errorf("gateway %s is the interface address", gateway)
+		}
+	}
+	return p, nil
+}
+
+// Store persists profiles keyed by SSID or interface name
+type Store struct {
+	path     string
+	mu       sync.Mutex
+	profiles map[string]Profile
+}
+
+// DefaultPath returns ~/.config/x-network/ipconfig.json
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = "/etc"
+	}
+	return filepath.Join(dir, "x-network", "ipconfig.json")
+}
+
+// NewStore loads profiles from path (a missing file is an empty store)
+// On a parse error the returned store is still usable, just empty
+func NewStore(path string) (*Store, error) {
+	s := &Store{
+		path:     path,
+		profiles: make(map[string]Profile),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(data, &s.profiles); err != nil {
+		s.profiles = make(map[string]Profile)
+		return s, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Get returns the profile for target
+func (s *Store) Get(target string) (Profile, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.profiles[target]
+	return p, ok
+}
+
+// Set stores a profile for target; a DHCP profile clears the entry
+func (s *Store) Set(target string, p Profile) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p.Method == MethodDHCP {
+		delete(s.profiles, target)
+	} else {
+		s.profiles[target] = p
+	}
+	return s.save()
+}
+
+// save writes profiles atomically (caller holds mu)
+func (s *Store) save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s.profiles, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
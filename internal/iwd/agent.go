@@ -1,6 +1,7 @@
 package iwd
 
 import (
+	"errors"
 	"log"
 	"sync"
 	"time"
@@ -16,11 +17,17 @@ const (
 )
 
 // PendingCredential holds credentials waiting for IWD callback
+// Password is a private copy that is zeroed once used or dropped
 type PendingCredential struct {
-	Password string
+	Password []byte
 	Created  time.Time
 }
 
+// wipe overwrites the password buffer
+func (p PendingCredential) wipe() {
+	zero(p.Password)
+}
+
 // Agent implements net.connman.iwd.Agent D-Bus interface
 // IWD calls RequestPassphrase when it needs a password for PSK/SAE networks
 type Agent struct {
@@ -28,6 +35,15 @@ type Agent struct {
 	client  *Client
 	mu      sync.RWMutex
 	pending map[dbus.ObjectPath]PendingCredential
+	creds   CredentialProvider // Keyring fallback when nothing is pending (nil = none)
+
+	// Hidden networks have no object path until IWD creates it during
+	// ConnectHiddenNetwork, so their credential is keyed by SSID instead
+	pendingSSID map[string]PendingCredential
+
+	// Per-attempt diagnostics used to explain a failed connection
+	served       bool   // A passphrase was handed to IWD during this attempt
+	cancelReason string // Reason from the last Cancel call
 }
 
 // NewAgent creates a new IWD Agent
@@ -36,27 +52,123 @@ func NewAgent(conn *dbus.Conn, client *Client) *Agent {
 		conn:    conn,
 		client:  client,
 		pending: make(map[dbus.ObjectPath]PendingCredential),
+
+		pendingSSID: make(map[string]PendingCredential),
 	}
 }
 
-// SetPending stores a password for the given network path
+// SetPending stores a copy of password for the given network path
 // Called by Connect() before triggering Network.Connect
-func (a *Agent) SetPending(network dbus.ObjectPath, password string) {
+func (a *Agent) SetPending(network dbus.ObjectPath, password []byte) {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
 	log.Printf("Agent: Setting pending credential for %s (%d chars)", network, len(password))
+	if old, ok := a.pending[network]; ok {
+		old.wipe()
+	}
 	a.pending[network] = PendingCredential{
-		Password: password,
+		Password: append([]byte(nil), password...),
 		Created:  time.Now(),
 	}
 }
 
-// ClearPending removes a pending credential (on failure or timeout)
+// ClearPending removes and zeroes a pending credential (on failure or timeout)
 func (a *Agent) ClearPending(network dbus.ObjectPath) {
 	a.mu.Lock()
 	defer a.mu.Unlock()
-	delete(a.pending, network)
+	if cred, ok := a.pending[network]; ok {
+		cred.wipe()
+		delete(a.pending, network)
+	}
+}
+
+// SetPendingSSID stores a copy of password for a hidden network by SSID
+// Used when the network object doesn't exist yet; path entries take precedence
+func (a *Agent) SetPendingSSID(ssid string, password []byte) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	log.Printf("Agent: Setting pending credential for hidden %s (%d chars)", ssid, len(password))
+	if old, ok := a.pendingSSID[ssid]; ok {
+		old.wipe()
+	}
+	a.pendingSSID[ssid] = PendingCredential{
+		Password: append([]byte(nil), password...),
+		Created:  time.Now(),
+	}
+}
+
+// ClearPendingSSID removes and zeroes a hidden network's pending credential
+func (a *Agent) ClearPendingSSID(ssid string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if cred, ok := a.pendingSSID[ssid]; ok {
+		cred.wipe()
+		delete(a.pendingSSID, ssid)
+	}
+}
+
+// clearAllPending zeroes and drops every pending credential (caller holds mu)
+func (a *Agent) clearAllPending() {
+	for _, cred := range a.pending {
+		cred.wipe()
+	}
+	for _, cred := range a.pendingSSID {
+		cred.wipe()
+	}
+	a.pending = make(map[dbus.ObjectPath]PendingCredential)
+	a.pendingSSID = make(map[string]PendingCredential)
+}
+
+// takePendingSSID moves a hidden network's credential (matched by the network's
+// Name) out of the SSID map (caller holds mu)
+// If the Name can't be read, a single pending hidden credential is used: only
+// one connect runs at a time, so it belongs to the network IWD just created
+func (a *Agent) takePendingSSID(network dbus.ObjectPath) (PendingCredential, bool) {
+	if len(a.pendingSSID) == 0 {
+		return PendingCredential{}, false
+	}
+	ssid, err := a.networkName(network)
+	if err != nil {
+		if len(a.pendingSSID) != 1 {
+			return PendingCredential{}, false
+		}
+		log.Printf("Agent: Cannot read name of %s (%v), using the pending hidden credential", network, err)
+		for name := range a.pendingSSID {
+			ssid = name
+		}
+	}
+	cred, ok := a.pendingSSID[ssid]
+	if ok {
+		delete(a.pendingSSID, ssid)
+	}
+	return cred, ok
+}
+
+// networkName reads the SSID of an IWD network object
+func (a *Agent) networkName(network dbus.ObjectPath) (string, error) {
+	v, err := a.conn.Object(IWDService, network).GetProperty(NetworkIface + ".Name")
+	if err != nil {
+		return "", err
+	}
+	ssid, _ := v.Value().(string)
+	return ssid, nil
+}
+
+// ResetAttempt clears per-attempt diagnostics (called when a new connect starts)
+func (a *Agent) ResetAttempt() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.served = false
+	a.cancelReason = ""
+}
+
+// AttemptInfo reports whether a passphrase was served and the last cancel reason
+func (a *Agent) AttemptInfo() (served bool, cancelReason string) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.served, a.cancelReason
 }
 
 // RequestPassphrase is called by IWD when it needs a password
@@ -68,7 +180,17 @@ func (a *Agent) RequestPassphrase(network dbus.ObjectPath) (string, *dbus.Error)
 	log.Printf("Agent: RequestPassphrase called for %s", network)
 
 	cred, ok := a.pending[network]
+	if ok {
+		delete(a.pending, network)
+	} else {
+		cred, ok = a.takePendingSSID(network)
+	}
 	if !ok {
+		if passphrase, err := a.lookupStored(network); err == nil {
+			a.served = true
+			log.Printf("Agent: Returning keyring password for %s", network)
+			return passphrase, nil
+		}
 		log.Printf("Agent: No pending credential for %s", network)
 		return "", dbus.NewError(AgentIface+".Error.Canceled",
 			[]interface{}{"No credential available"})
@@ -77,15 +199,29 @@ func (a *Agent) RequestPassphrase(network dbus.ObjectPath) (string, *dbus.Error)
 	// Check TTL - expire stale credentials
 	if time.Since(cred.Created) > CredentialTTL {
 		log.Printf("Agent: Credential for %s expired (age: %v)", network, time.Since(cred.Created))
-		delete(a.pending, network)
+		cred.wipe()
 		return "", dbus.NewError(AgentIface+".Error.Canceled",
 			[]interface{}{"Credential expired"})
 	}
 
-	// Clean up after use
-	delete(a.pending, network)
-	log.Printf("Agent: Returning password for %s (%d chars)", network, len(cred.Password))
-	return cred.Password, nil
+	// Clean up after use - the D-Bus reply needs a string, the buffer is wiped
+	passphrase := string(cred.Password)
+	cred.wipe()
+	a.served = true
+	log.Printf("Agent: Returning password for %s (%d chars)", network, len(passphrase))
+	return passphrase, nil
+}
+
+// lookupStored fetches the network's passphrase from the credential provider
+func (a *Agent) lookupStored(network dbus.ObjectPath) (string, error) {
+	if a.creds == nil {
+		return "", ErrNoCredential
+	}
+	ssid, err := a.networkName(network)
+	if err != nil {
+		return "", err
+	}
+	return a.creds.Lookup(ssid)
 }
 
 // RequestPrivateKeyPassphrase is called for 802.1x networks
@@ -119,7 +255,8 @@ func (a *Agent) Cancel(reason string) *dbus.Error {
 
 	// Clear all pending to prevent stale state
 	a.mu.Lock()
-	a.pending = make(map[dbus.ObjectPath]PendingCredential)
+	a.clearAllPending()
+	a.cancelReason = reason
 	a.mu.Unlock()
 
 	return nil
@@ -131,9 +268,12 @@ func (a *Agent) Release() *dbus.Error {
 
 	// Clear all pending
 	a.mu.Lock()
-	a.pending = make(map[dbus.ObjectPath]PendingCredential)
+	a.clearAllPending()
 	a.mu.Unlock()
 
+	// IWD is going away; the next init registers again
+	go a.client.handleAgentReleased()
+
 	return nil
 }
 
@@ -150,15 +290,25 @@ func (a *Agent) RegisterWithIWD() error {
 
 	// Register with IWD AgentManager
 	obj := a.conn.Object(IWDService, "/net/connman/iwd")
-	call := obj.Call(AgentMgrIface+".RegisterAgent", 0, dbus.ObjectPath(AgentPath))
-	if call.Err != nil {
-		return call.Err
+	if err := obj.Call(AgentMgrIface+".RegisterAgent", 0, dbus.ObjectPath(AgentPath)).Err; err != nil {
+		return err
 	}
 
 	log.Printf("Agent: Registered with IWD AgentManager")
 	return nil
 }
 
+// agentRegistered reports whether RegisterAgent left our agent registered
+// IWD answers AlreadyExists only for a path this connection registered before
+// (other apps' agents sit next to ours), so that counts as registered
+func agentRegistered(err error) bool {
+	if err == nil {
+		return true
+	}
+	var dbusErr dbus.Error
+	return errors.As(err, &dbusErr) && dbusErr.Name == IWDService+".AlreadyExists"
+}
+
 // UnregisterFromIWD unregisters the agent from IWD
 func (a *Agent) UnregisterFromIWD() error {
 	obj := a.conn.Object(IWDService, "/net/connman/iwd")
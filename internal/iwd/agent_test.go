@@ -0,0 +1,60 @@
+package iwd
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"x-network/internal/state"
+
+	"github.com/godbus/dbus/v5"
+)
+
+func TestAgentRegistered(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"registered", nil, true},
+		{"already ours", dbus.Error{Name: IWDService + ".AlreadyExists"}, true},
+		{"iwd failure", dbus.Error{Name: IWDService + ".Failed"}, false},
+		{"bus error", errors.New("connection closed"), false},
+	}
+	for _, tt := range tests {
+		if got := agentRegistered(tt.err); got != tt.want {
+			t.Errorf("%s: agentRegistered(%v) = %v, want %v", tt.name, tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestCurrentAgentAcrossReinit(t *testing.T) {
+	c := &Client{stateMgr: state.NewManager()}
+	if c.currentAgent() != nil {
+		t.Fatal("agent before init")
+	}
+
+	// Connect paths read the agent while an IWD restart replaces it (-race)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			c.agentMu.Lock()
+			c.agent = NewAgent(nil, c)
+			c.agentMu.Unlock()
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			if agent := c.currentAgent(); agent != nil {
+				agent.ResetAttempt()
+			}
+		}
+	}()
+	wg.Wait()
+	if c.currentAgent() == nil {
+		t.Fatal("agent lost after re-init")
+	}
+}
@@ -0,0 +1,155 @@
+package iwd
+
+import (
+	"bufio"
+	"log"
+	"os/exec"
+	"strings"
+	"time"
+
+	"x-network/internal/state"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// AccessPointDiagnosticIface lists the stations associated to our AP
+const AccessPointDiagnosticIface = "net.connman.iwd.AccessPointDiagnostic"
+
+// hotspotClientPoll is how often the client count is refreshed while the AP runs
+// (IWD signals nothing when a station joins or leaves)
+const hotspotClientPoll = 5 * time.Second
+
+// handleAccessPointChange drives hotspot state from AccessPoint.Started/Name
+func (c *Client) handleAccessPointChange(props map[string]dbus.Variant) {
+	name, _ := props["Name"].Value().(string)
+	v, ok := props["Started"]
+	if !ok {
+		if name != "" {
+			c.stateMgr.Update(func(st *state.State) {
+				if st.HotspotActive {
+					st.HotspotSSID = name
+				}
+			})
+		}
+		return
+	}
+
+	started, _ := v.Value().(bool)
+	if started {
+		if name == "" {
+			if nv, err := c.conn.Object(IWDService, c.device()).GetProperty(AccessPointIface + ".Name"); err == nil {
+				name, _ = nv.Value().(string)
+			}
+		}
+		log.Printf("Hotspot %s started", name)
+		c.stateMgr.Update(func(st *state.State) {
+			st.SetHotspotState(true, name, state.HotspotReasonStarted)
+		})
+		c.startHotspotMonitor()
+		return
+	}
+	c.hotspotStopped()
+}
+
+// hotspotStopped records the AP going down, by request or not
+func (c *Client) hotspotStopped() {
+	c.stopHotspotMonitor()
+	c.stopSharing()
+
+	c.hotspotMu.Lock()
+	reason := c.hotspotStopReason
+	if reason == "" {
+		reason = state.HotspotReasonAPStopped
+	}
+	c.hotspotStopReason = ""
+	c.hotspotMu.Unlock()
+
+	c.stateMgr.Update(func(st *state.State) {
+		if st.HotspotActive {
+			log.Printf("Hotspot %s stopped (%s)", st.HotspotSSID, reason)
+			st.SetHotspotState(false, st.HotspotSSID, reason)
+		}
+	})
+}
+
+// startHotspotMonitor polls the associated station count until the AP stops,
+// and stops the AP once it has been without clients for hotspotTimeout
+func (c *Client) startHotspotMonitor() {
+	c.hotspotMu.Lock()
+	defer c.hotspotMu.Unlock()
+	if c.hotspotMonitorStop != nil {
+		return
+	}
+	stopCh := make(chan struct{})
+	c.hotspotMonitorStop = stopCh
+	timeout := c.hotspotTimeout
+
+	go func() {
+		ticker := time.NewTicker(hotspotClientPoll)
+		defer ticker.Stop()
+
+		var expires time.Time
+		if timeout > 0 {
+			expires = time.Now().Add(timeout)
+		}
+		for {
+			n := c.hotspotClientCount()
+			if timeout > 0 && n > 0 {
+				expires = time.Now().Add(timeout) // Countdown restarts when the last client leaves
+			}
+			c.stateMgr.Update(func(st *state.State) {
+				if st.HotspotActive && (st.HotspotClients != n || !st.HotspotExpiresAt.Equal(expires)) {
+					st.HotspotClients = n
+					st.HotspotExpiresAt = expires
+				}
+			})
+			if !expires.IsZero() && time.Now().After(expires) {
+				log.Printf("Hotspot idle for %s, stopping", timeout)
+				go c.stopHotspot(state.HotspotReasonTimeout)
+				return
+			}
+			select {
+			case <-stopCh:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// stopHotspotMonitor stops the client count poller, if running
+func (c *Client) stopHotspotMonitor() {
+	c.hotspotMu.Lock()
+	defer c.hotspotMu.Unlock()
+	if c.hotspotMonitorStop != nil {
+		close(c.hotspotMonitorStop)
+		c.hotspotMonitorStop = nil
+	}
+}
+
+// hotspotClientCount returns the number of stations associated to the AP
+// Uses AccessPointDiagnostic, falling back to the nl80211 station dump
+func (c *Client) hotspotClientCount() uint32 {
+	var stations []map[string]dbus.Variant
+	err := c.conn.Object(IWDService, c.device()).Call(AccessPointDiagnosticIface+".GetDiagnostics", 0).Store(&stations)
+	if err == nil {
+		return uint32(len(stations))
+	}
+
+	iface := c.deviceName()
+	if iface == "" {
+		return 0
+	}
+	out, err := exec.Command("iw", "dev", iface, "station", "dump").Output()
+	if err != nil {
+		return 0
+	}
+	var n uint32
+	scanner := bufio.NewScanner(strings.NewReader(string(out)))
+	for scanner.Scan() {
+		if strings.HasPrefix(scanner.Text(), "Station ") {
+			n++
+		}
+	}
+	return n
+}
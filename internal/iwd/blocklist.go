@@ -0,0 +1,105 @@
+package iwd
+
+import (
+	"errors"
+	"log"
+	"sort"
+
+	"x-network/internal/state"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// ErrNetworkBlocked is returned when connecting to a blacklisted network without force
+var ErrNetworkBlocked = errors.New("network is blacklisted")
+
+// SetBlockedNetworks loads the persisted blacklist
+func (c *Client) SetBlockedNetworks(ssids []string) {
+	c.knownMu.Lock()
+	c.blocked = make(map[string]bool, len(ssids))
+	for _, ssid := range ssids {
+		c.blocked[ssid] = true
+	}
+	c.knownMu.Unlock()
+	c.publishBlocked()
+}
+
+// IsBlocked reports whether ssid is blacklisted
+func (c *Client) IsBlocked(ssid string) bool {
+	c.knownMu.Lock()
+	defer c.knownMu.Unlock()
+	return c.blocked[ssid]
+}
+
+// BlockNetwork adds or removes ssid from the blacklist
+// Blocking also turns IWD's AutoConnect off for a saved network; unblocking
+// turns it back on. The caller persists the list
+func (c *Client) BlockNetwork(ssid string, blocked bool) error {
+	if ssid == "" {
+		return errors.New("SSID required")
+	}
+
+	c.knownMu.Lock()
+	if c.blocked == nil {
+		c.blocked = make(map[string]bool)
+	}
+	if blocked {
+		c.blocked[ssid] = true
+	} else {
+		delete(c.blocked, ssid)
+	}
+	saved := false
+	for _, k := range c.known {
+		if k.Name == ssid {
+			saved = true
+			break
+		}
+	}
+	c.knownMu.Unlock()
+	c.publishBlocked()
+
+	log.Printf("Network %s blacklisted=%v", ssid, blocked)
+	if !saved {
+		return nil // Applied if it gets saved later (enforceBlocked)
+	}
+	if err := c.SetAutoConnect(ssid, !blocked); err != nil {
+		return err
+	}
+	c.RefreshKnownNetworks()
+	return nil
+}
+
+// BlockedNetworks returns the blacklist, sorted
+func (c *Client) BlockedNetworks() []string {
+	c.knownMu.Lock()
+	defer c.knownMu.Unlock()
+	list := make([]string, 0, len(c.blocked))
+	for ssid := range c.blocked {
+		list = append(list, ssid)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// publishBlocked copies the blacklist into state
+func (c *Client) publishBlocked() {
+	list := c.BlockedNetworks()
+	c.stateMgr.Update(func(st *state.State) {
+		st.BlockedNetworks = list
+	})
+}
+
+// enforceBlocked turns AutoConnect off for a newly saved blacklisted network
+func (c *Client) enforceBlocked(k knownNetwork) {
+	if !k.AutoConnect || !c.IsBlocked(k.Name) {
+		return
+	}
+	log.Printf("Disabling autoconnect for blacklisted network %s", k.Name)
+	go func() {
+		err := c.conn.Object(IWDService, k.Path).Call("org.freedesktop.DBus.Properties.Set", 0,
+			KnownNetworkIface, "AutoConnect", dbus.MakeVariant(false)).Err
+		if err != nil {
+			log.Printf("Failed to disable autoconnect for %s: %v", k.Name, err)
+		}
+	}()
+}
@@ -0,0 +1,221 @@
+package iwd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// StationDebugIface is only present when iwd runs in developer mode (-E)
+const StationDebugIface = "net.connman.iwd.StationDebug"
+
+var (
+	// ErrBSSIDMismatch is returned when a BSSID-pinned connect associated elsewhere
+	ErrBSSIDMismatch = errors.New("associated to a different access point than requested")
+
+	// ErrRoamUnsupported is returned when IWD can't switch BSS without dropping the link
+	ErrRoamUnsupported = errors.New("roaming needs IWD developer mode (iwd -E)")
+)
+
+// AccessPoint is one BSS of a network as seen in the last scan
+type AccessPoint struct {
+	BSSID     string
+	SSID      string
+	Frequency uint32 // MHz
+	SignalDBm int16
+	Connected bool
+}
+
+// GetAccessPoints lists the BSSs advertising ssid, strongest first
+// IWD doesn't publish per-BSS signal on D-Bus, so this reads the kernel scan
+// cache (nl80211 via `iw ... scan dump`)
+func (c *Client) GetAccessPoints(ssid string) ([]AccessPoint, error) {
+	iface := c.deviceName()
+	if iface == "" {
+		return nil, errors.New("no WiFi device")
+	}
+
+	aps, err := scanDump(iface)
+	if err != nil {
+		return nil, err
+	}
+
+	result := aps[:0]
+	for _, ap := range aps {
+		if ap.SSID == ssid {
+			result = append(result, ap)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].SignalDBm > result[j].SignalDBm })
+	return result, nil
+}
+
+// scanDump reads the kernel scan cache with `iw dev <iface> scan dump`
+func scanDump(iface string) ([]AccessPoint, error) {
+	out, err := exec.Command(iwCommand, "dev", iface, "scan", "dump").Output()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read scan cache: %w", err)
+	}
+	return parseScanDump(string(out)), nil
+}
+
+// parseScanDump parses `iw ... scan dump` output
+func parseScanDump(out string) []AccessPoint {
+	var aps []AccessPoint
+	var cur *AccessPoint
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "BSS ") {
+			// "BSS aa:bb:cc:dd:ee:ff(on wlan0) -- associated"
+			fields := strings.FieldsFunc(line[4:], func(r rune) bool { return r == '(' || r == ' ' })
+			if len(fields) == 0 {
+				cur = nil
+				continue
+			}
+			aps = append(aps, AccessPoint{
+				BSSID:     strings.ToLower(fields[0]),
+				Connected: strings.HasSuffix(line, "-- associated"),
+			})
+			cur = &aps[len(aps)-1]
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch key {
+		case "SSID":
+			cur.SSID = unescapeSSID(value)
+		case "freq":
+			if f, err := strconv.ParseFloat(value, 64); err == nil {
+				cur.Frequency = uint32(f)
+			}
+		case "signal":
+			// "-52.00 dBm"
+			if f, err := strconv.ParseFloat(strings.TrimSuffix(value, " dBm"), 64); err == nil {
+				cur.SignalDBm = int16(f)
+			}
+		}
+	}
+	return aps
+}
+
+// unescapeSSID undoes iw's SSID escaping: bytes that aren't printable, a
+// backslash and leading/trailing spaces are printed as \xHH
+func unescapeSSID(s string) string {
+	if !strings.Contains(s, `\x`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) && s[i+1] == 'x' {
+			if v, err := strconv.ParseUint(s[i+2:i+4], 16, 8); err == nil {
+				b.WriteByte(byte(v))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// hasStationDebug reports whether IWD exposes StationDebug (developer mode)
+func (c *Client) hasStationDebug() bool {
+	var xml string
+	err := c.conn.Object(IWDService, c.station()).Call("org.freedesktop.DBus.Introspectable.Introspect", 0).Store(&xml)
+	return err == nil && strings.Contains(xml, `"`+StationDebugIface+`"`)
+}
+
+// connectToBSS asks IWD to join a specific BSS
+// Needs StationDebug (iwd -E); returns ok=false when that interface is missing
+func (c *Client) connectToBSS(bssid string) (ok bool, err error) {
+	mac, err := net.ParseMAC(bssid)
+	if err != nil {
+		return true, fmt.Errorf("invalid bssid %q", bssid)
+	}
+
+	err = c.conn.Object(IWDService, c.station()).Call(StationDebugIface+".ConnectBssid", 0, []byte(mac)).Err
+	var dbusErr dbus.Error
+	if errors.As(err, &dbusErr) &&
+		(dbusErr.Name == "org.freedesktop.DBus.Error.UnknownMethod" ||
+			dbusErr.Name == "org.freedesktop.DBus.Error.UnknownInterface" ||
+			dbusErr.Name == "org.freedesktop.DBus.Error.UnknownObject") {
+		return false, nil
+	}
+	return true, err
+}
+
+// connectedBSS returns the BSSID the station is associated with
+func (c *Client) connectedBSS() (string, error) {
+	var diag map[string]dbus.Variant
+	err := c.conn.Object(IWDService, c.station()).Call(StationDiagnosticIface+".GetDiagnostics", 0).Store(&diag)
+	if err != nil {
+		return "", err
+	}
+	bssid, _ := diag["ConnectedBss"].Value().(string)
+	return strings.ToLower(bssid), nil
+}
+
+// verifyBSS checks a pinned connect landed on the requested BSS
+func (c *Client) verifyBSS(bssid string) error {
+	got, err := c.connectedBSS()
+	if err != nil {
+		log.Printf("Cannot verify BSSID after connect: %v", err)
+		return nil // Can't tell - don't fail a working connection
+	}
+	if got != strings.ToLower(bssid) {
+		log.Printf("Requested BSS %s but associated to %s", bssid, got)
+		return fmt.Errorf("%w: wanted %s, got %s", ErrBSSIDMismatch, bssid, got)
+	}
+	return nil
+}
+
+// ScanSSID sends directed probe requests for ssid and reports whether an
+// access point answered, with the strongest signal in dBm
+// Hidden networks only reply to probes naming them, so this is how a
+// ConnectHiddenNetwork target can be checked before asking for a password
+func (c *Client) ScanSSID(ssid string) (found bool, signalDBm int16, err error) {
+	if ssid == "" {
+		return false, 0, errors.New("SSID required")
+	}
+	iface := c.deviceName()
+	if iface == "" {
+		return false, 0, errors.New("no WiFi device")
+	}
+
+	// nl80211 directed scan - IWD only probes for hidden networks it already knows
+	// Waits for IWD's own scan first so the two don't collide
+	ctx := context.Background()
+	c.waitScanIdle(ctx)
+	if err := runIW(ctx, "dev", iface, "scan", "ssid", ssid); err != nil {
+		return false, 0, fmt.Errorf("directed scan failed: %w", err)
+	}
+
+	aps, err := scanDump(iface)
+	if err != nil {
+		return false, 0, err
+	}
+	for _, ap := range aps {
+		if ap.SSID == ssid && (!found || ap.SignalDBm > signalDBm) {
+			found, signalDBm = true, ap.SignalDBm
+		}
+	}
+	log.Printf("Directed scan for %q: found=%v signal=%d dBm", ssid, found, signalDBm)
+	return found, signalDBm, nil
+}
@@ -0,0 +1,47 @@
+package iwd
+
+import "testing"
+
+func TestUnescapeSSID(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Cafe", "Cafe"},
+		{`\x20Cafe\x20`, " Cafe "},
+		{`Caf\xc3\xa9`, "Café"},
+		{`back\x5cslash`, `back\slash`},
+		{`tab\x09`, "tab\t"},
+		{`short\x4`, `short\x4`},
+		{`not\xzzhex`, `not\xzzhex`},
+	}
+	for _, tt := range tests {
+		if got := unescapeSSID(tt.in); got != tt.want {
+			t.Errorf("unescapeSSID(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseScanDump(t *testing.T) {
+	out := `BSS aa:bb:cc:dd:ee:01(on wlan0) -- associated
+	freq: 2437
+	signal: -52.00 dBm
+	SSID: Caf\xc3\xa9\x20
+BSS AA:BB:CC:DD:EE:02(on wlan0)
+	freq: 5180.0
+	signal: -67.00 dBm
+	SSID: Office: 5G
+`
+	got := parseScanDump(out)
+	want := []AccessPoint{
+		{BSSID: "aa:bb:cc:dd:ee:01", SSID: "Café ", Frequency: 2437, SignalDBm: -52, Connected: true},
+		{BSSID: "aa:bb:cc:dd:ee:02", SSID: "Office: 5G", Frequency: 5180, SignalDBm: -67},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("parseScanDump() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("entry %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
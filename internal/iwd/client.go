@@ -1,15 +1,20 @@
 package iwd
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
-	"os/exec"
+	"net"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"x-network/internal/connectivity"
+	"x-network/internal/hotspot"
+	"x-network/internal/ipconfig"
 	"x-network/internal/state"
 
 	"github.com/godbus/dbus/v5"
@@ -24,32 +29,110 @@ const (
 	AccessPointIface  = "net.connman.iwd.AccessPoint"
 )
 
+// Connect retry defaults
+const (
+	DefaultConnectAttempts = 3
+	connectRetryBase       = 1 * time.Second
+)
+
+// DefaultScanTimeout is how long a scan may run before results are read anyway
+const DefaultScanTimeout = 15 * time.Second
+
+// captiveAddrWaitSteps bounds the wait (seconds) for an IPv4 address before the portal check
+const captiveAddrWaitSteps = 15
+
+// scanCacheWindow is how long a completed scan's results are served without rescanning
+// Background scanning (SetScanActive) uses ScanFresh, so its 10s period doesn't hit the cache
+const scanCacheWindow = 10 * time.Second
+
 // Client is the IWD D-Bus client
 type Client struct {
-	conn        *dbus.Conn
-	stateMgr    *state.Manager
-	devicePath  dbus.ObjectPath
-	stationPath dbus.ObjectPath
-	initialized bool   // Idempotency flag for maybeInitIWD
-	agent       *Agent // IWD D-Bus Agent for credential handling
+	conn     *dbus.Conn
+	stateMgr *state.Manager
+	ipcfg    *ipconfig.Manager    // Static IP profiles, DHCP otherwise
+	portal   *connectivity.Portal // Captive portal check after connecting
+	creds    CredentialProvider   // Optional keyring for passphrases (nil = IWD stores them)
+	agent    *Agent               // IWD D-Bus Agent for credential handling
+
+	// Device/station paths and readiness, replaced on IWD restarts (see ready.go)
+	initMu  sync.Mutex // Serializes maybeInitIWD and handleIWDDisappear
+	readyMu sync.Mutex
+	rd      readiness
+	onBus   func() bool // Overrides the NameHasOwner check (tests)
 
 	// Connection state management
-	connectMu sync.Mutex // Prevents concurrent connection attempts
-	connectID uint64     // Increments on each new connection attempt
+	connectMu       sync.Mutex // Prevents concurrent connection attempts
+	connectID       uint64     // Increments on each new connection attempt
+	connectAttempts int        // Max Network.Connect attempts for transient failures (1 = no retry)
+
+	mergeBSS    atomic.Bool  // Collapse same SSID+security scan entries (see merge.go)
+	scanTimeout atomic.Int64 // time.Duration, see SetScanTimeout
+
+	signal  signalFilter   // Active connection RSSI smoothing (see signal.go)
+	history connectHistory // Connection attempts (see history.go)
+
+	// Scan coalescing: concurrent callers wait on the in-flight scan
+	scanMu       sync.Mutex
+	scanInFlight *scanCall
+
+	// The agent is replaced on every IWD (re)init; read it with currentAgent
+	agentMu sync.Mutex
+
+	// Reconnect after an IWD restart (see restore.go)
+	restoreMu        sync.Mutex
+	restoreSSID      string    // Saved network to rejoin after re-init ("" = none)
+	userDisconnectAt time.Time // Last user-requested Disconnect
+
+	// USB tethering used while WiFi was down (see usbfallback.go)
+	usbFallbackMu    sync.Mutex
+	usbFallbackIface string        // Link tryUsbFallback brought up ("" = none)
+	usbSticky        bool          // Keep USB primary after WiFi returns
+	usbReleaseAfter  time.Duration // Release the USB lease this long after WiFi returns (0 = keep it)
+	usbReturnPending bool          // A switch back to WiFi is in progress
+	btFallback       func() error  // Connects Bluetooth tethering when USB isn't available (nil = off)
+
+	// Closed by Close so in-flight Connect retries stop
+	closing   chan struct{}
+	closeOnce sync.Once
+
+	// KnownNetwork objects by path, kept in sync with InterfacesAdded/Removed
+	knownMu    sync.Mutex
+	known      map[dbus.ObjectPath]knownNetwork
+	priorities map[string]int32 // User priority by SSID (see SetNetworkPriority)
+	blocked    map[string]bool  // Blacklisted SSIDs (see blocklist.go)
+
+	// Hotspot tracking (see apstate.go)
+	hotspotMu          sync.Mutex
+	hotspotStopReason  string         // Set by stopHotspot ("" = the next stop is unexpected)
+	hotspotTimeout     time.Duration  // Auto-off after this long without clients (0 = never)
+	hotspotMonitorStop chan struct{}  // Client count poller (nil = not running)
+	hotspotSubnet      string         // AP address/prefix handed to clients ("" = hotspot.DefaultSubnet)
+	hotspotShare       *hotspot.Share // Address, DHCP server and NAT while the AP runs
 }
 
 // NewClient creates a new IWD client with event-driven service detection
-func NewClient(stateMgr *state.Manager) (*Client, error) {
+// portal runs the captive portal check after each new connection
+func NewClient(stateMgr *state.Manager, ipcfg *ipconfig.Manager, portal *connectivity.Portal) (*Client, error) {
 	conn, err := dbus.SystemBus()
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to system bus: %w", err)
 	}
 
 	c := &Client{
-		conn:        conn,
-		stateMgr:    stateMgr,
-		initialized: false,
+		conn:     conn,
+		stateMgr: stateMgr,
+		ipcfg:    ipcfg,
+		portal:   portal,
+		rd:       newReadiness(),
+		closing:  make(chan struct{}),
+
+		connectAttempts: DefaultConnectAttempts,
 	}
+	c.mergeBSS.Store(true)
+	c.scanTimeout.Store(int64(DefaultScanTimeout))
+	c.signal.alpha = DefaultSignalAlpha
+	c.signal.deltaPct = DefaultSignalDeltaPercent
+	c.signal.deltaDBm = DefaultSignalDeltaDBm
 
 	// Subscribe to NameOwnerChanged for IWD service lifecycle
 	if err := c.subscribeToIWDLifecycle(); err != nil {
@@ -74,10 +157,13 @@ func (c *Client) subscribeToIWDLifecycle() error {
 		return err
 	}
 
-	// Match InterfacesAdded from IWD ObjectManager (for Station appearing at boot)
-	ifaceRule := "type='signal',sender='net.connman.iwd',interface='org.freedesktop.DBus.ObjectManager',member='InterfacesAdded'"
-	if err := c.conn.BusObject().Call("org.freedesktop.DBus.AddMatch", 0, ifaceRule).Err; err != nil {
-		log.Printf("Warning: Failed to subscribe to InterfacesAdded: %v", err)
+	// Match InterfacesAdded/Removed from IWD ObjectManager (Station appearing at
+	// boot, KnownNetworks added or forgotten by other clients)
+	for _, member := range []string{"InterfacesAdded", "InterfacesRemoved"} {
+		ifaceRule := "type='signal',sender='net.connman.iwd',interface='org.freedesktop.DBus.ObjectManager',member='" + member + "'"
+		if err := c.conn.BusObject().Call("org.freedesktop.DBus.AddMatch", 0, ifaceRule).Err; err != nil {
+			log.Printf("Warning: Failed to subscribe to %s: %v", member, err)
+		}
 	}
 
 	// Handle signals in goroutine
@@ -109,16 +195,20 @@ func (c *Client) subscribeToIWDLifecycle() error {
 				}
 
 			case "org.freedesktop.DBus.ObjectManager.InterfacesAdded":
-				// Station interface appeared - this handles boot race condition
 				if len(signal.Body) >= 2 {
+					path, _ := signal.Body[0].(dbus.ObjectPath)
 					ifaces, ok := signal.Body[1].(map[string]map[string]dbus.Variant)
 					if ok {
-						if _, hasStation := ifaces[StationIface]; hasStation {
-							log.Printf("Station interface appeared, initializing...")
-							if err := c.maybeInitIWD(); err != nil {
-								log.Printf("Failed to initialize IWD after Station appeared: %v", err)
-							}
-						}
+						c.handleInterfacesAdded(path, ifaces)
+					}
+				}
+
+			case "org.freedesktop.DBus.ObjectManager.InterfacesRemoved":
+				if len(signal.Body) >= 2 {
+					path, _ := signal.Body[0].(dbus.ObjectPath)
+					ifaces, ok := signal.Body[1].([]string)
+					if ok {
+						c.handleInterfacesRemoved(path, ifaces)
 					}
 				}
 			}
@@ -128,9 +218,44 @@ func (c *Client) subscribeToIWDLifecycle() error {
 	return nil
 }
 
+// handleInterfacesAdded dispatches new IWD objects by interface type
+func (c *Client) handleInterfacesAdded(path dbus.ObjectPath, ifaces map[string]map[string]dbus.Variant) {
+	for iface, props := range ifaces {
+		switch iface {
+		case StationIface:
+			// Station interface appeared - this handles boot race condition
+			log.Printf("Station interface appeared, initializing...")
+			if err := c.maybeInitIWD(); err != nil {
+				log.Printf("Failed to initialize IWD after Station appeared: %v", err)
+			}
+		case KnownNetworkIface:
+			k := parseKnownNetwork(path, props)
+			c.addKnownNetwork(k)
+			c.enforceBlocked(k)
+		case AccessPointIface:
+			c.handleAccessPointChange(props)
+		}
+	}
+}
+
+// handleInterfacesRemoved dispatches removed IWD objects by interface type
+func (c *Client) handleInterfacesRemoved(path dbus.ObjectPath, ifaces []string) {
+	for _, iface := range ifaces {
+		switch iface {
+		case KnownNetworkIface:
+			c.removeKnownNetwork(path)
+		case AccessPointIface:
+			// Device left AP mode
+			c.hotspotStopped()
+		}
+	}
+}
+
 // maybeInitIWD initializes IWD connection with idempotency
 func (c *Client) maybeInitIWD() error {
-	if c.initialized {
+	c.initMu.Lock()
+	defer c.initMu.Unlock()
+	if c.isInitialized() {
 		return nil // Already initialized
 	}
 
@@ -145,13 +270,14 @@ func (c *Client) maybeInitIWD() error {
 	}
 
 	// Create and register Agent with IWD
-	c.agent = NewAgent(c.conn, c)
-	if err := c.agent.RegisterWithIWD(); err != nil {
-		log.Printf("Warning: Failed to register Agent with IWD: %v", err)
-		// Non-fatal - saved networks can still connect without agent
-	}
-
-	c.initialized = true
+	agent := NewAgent(c.conn, c)
+	agent.creds = c.creds
+	c.agentMu.Lock()
+	c.agent = agent
+	c.agentMu.Unlock()
+	c.registerAgent(agent)
+
+	c.markReady()
 	log.Printf("IWD client connected")
 
 	// Fetch initial Networks list (important when daemon starts with active connection)
@@ -166,27 +292,95 @@ func (c *Client) maybeInitIWD() error {
 		}
 	}()
 
+	// Rejoin the network we had before an IWD restart
+	go c.restoreConnection()
+
 	return nil
 }
 
+// registerAgent registers our agent and publishes AgentRegistered
+// IWD keeps every registered agent and asks the most recent one, so another
+// app's agent never blocks ours; a failure here means IWD itself refused
+func (c *Client) registerAgent(agent *Agent) {
+	err := agent.RegisterWithIWD()
+	registered := agentRegistered(err)
+	c.stateMgr.Update(func(st *state.State) {
+		st.AgentRegistered = registered
+	})
+	if !registered {
+		// Non-fatal - saved networks can still connect without agent
+		log.Printf("Warning: Failed to register Agent with IWD: %v", err)
+	}
+}
+
+// currentAgent returns the agent of the current IWD session (nil before init)
+func (c *Client) currentAgent() *Agent {
+	c.agentMu.Lock()
+	defer c.agentMu.Unlock()
+	return c.agent
+}
+
+// handleAgentReleased handles IWD releasing our agent (IWD shutting down);
+// the next init registers a fresh one
+func (c *Client) handleAgentReleased() {
+	c.stateMgr.Update(func(st *state.State) {
+		st.AgentRegistered = false
+	})
+}
+
 // handleIWDDisappear handles IWD service disappearing
 func (c *Client) handleIWDDisappear() {
-	c.initialized = false
-	c.devicePath = ""
-	c.stationPath = ""
+	c.initMu.Lock()
+	defer c.initMu.Unlock()
+	c.rememberForRestore(c.stateMgr.Get())
+	c.markGone()
 
 	c.stateMgr.Update(func(st *state.State) {
+		if st.ConnectionState == state.StateConnected || st.ConnectionState == state.StateObtaining {
+			st.DisconnectReason = state.DisconnectReasonIWDStopped
+		}
+		st.AgentRegistered = false
 		st.WifiEnabled = false
 		st.WifiScanning = false
 		st.ConnectionState = state.StateDisconnected
 		st.ActiveSSID = ""
 		st.SignalStrength = 0
+		st.SignalBars = 0
+		st.Frequency, st.Channel, st.ChannelWidth = 0, 0, 0
+		st.WifiGeneration = ""
 	})
 }
 
 // Close closes the D-Bus connection
+// Unregisters the agent first so IWD doesn't keep a dead reference, and
+// cancels in-flight Connect attempts
 func (c *Client) Close() {
-	c.conn.Close()
+	c.closeOnce.Do(func() {
+		close(c.closing)
+
+		// Make any running attempt stale so it won't retry or touch state
+		c.connectMu.Lock()
+		c.connectID++
+		c.connectMu.Unlock()
+
+		if agent := c.currentAgent(); agent != nil && c.isInitialized() {
+			if err := agent.UnregisterFromIWD(); err != nil {
+				log.Printf("Agent: unregister on shutdown failed: %v", err)
+			} else {
+				log.Printf("Agent: unregistered from IWD")
+			}
+			agent.mu.Lock()
+			agent.clearAllPending()
+			agent.mu.Unlock()
+		}
+
+		// Don't leave NAT and forwarding behind
+		c.stopHotspotMonitor()
+		c.stopSharing()
+
+		// Pending D-Bus calls (e.g. Network.Connect) return with an error
+		c.conn.Close()
+	})
 }
 
 // findDevice finds the WiFi device object path (single attempt, no polling)
@@ -201,16 +395,17 @@ func (c *Client) findDevice() error {
 	}
 
 	// Find device and station paths, and known networks
-	savedNetworks := []string{}
+	var known []knownNetwork
+	var devicePath, stationPath dbus.ObjectPath
 	for path, ifaces := range result {
 		// Look for Station interface (not just Device)
 		if stationProps, ok := ifaces[StationIface]; ok {
-			c.stationPath = path
+			stationPath = path
 			log.Printf("Found Station at: %s", path)
 
 			// Also set device path (parent or same)
 			if devProps, ok := ifaces[DeviceIface]; ok {
-				c.devicePath = path
+				devicePath = path
 				// IMPORTANT: Read device props (including Powered) from the same path!
 				c.updateDeviceProps(devProps)
 			}
@@ -220,33 +415,30 @@ func (c *Client) findDevice() error {
 		}
 
 		// Find device if we haven't yet (fallback for separate device path)
-		if c.devicePath == "" {
+		if devicePath == "" {
 			if devProps, ok := ifaces[DeviceIface]; ok {
-				c.devicePath = path
+				devicePath = path
 				c.updateDeviceProps(devProps)
 			}
 		}
 
 		// Collect known networks (saved)
 		if knProps, ok := ifaces[KnownNetworkIface]; ok {
-			if nameV, ok := knProps["Name"]; ok {
-				ssid := nameV.Value().(string)
-				savedNetworks = append(savedNetworks, ssid)
-				log.Printf("Found known network: %s", ssid)
-			}
+			k := parseKnownNetwork(path, knProps)
+			known = append(known, k)
+			log.Printf("Found known network: %s", k.Name)
 		}
 	}
 
-	if c.stationPath == "" {
+	if stationPath == "" {
 		return fmt.Errorf("no WiFi station found")
 	}
+	c.setPaths(devicePath, stationPath)
 
 	// Update saved networks in state AFTER successful Station check
 	// This prevents partial updates when findDevice fails at boot
-	if len(savedNetworks) > 0 {
-		c.stateMgr.Update(func(st *state.State) {
-			st.SavedNetworks = savedNetworks
-		})
+	if len(known) > 0 {
+		c.publishKnownNetworks(known)
 	}
 
 	return nil
@@ -267,6 +459,28 @@ func (c *Client) updateDeviceProps(props map[string]dbus.Variant) {
 	})
 }
 
+// associatedState is the ConnectionState once IWD reports connected: obtaining
+// until iface has a routable IPv4 address, which a static setup already has
+func (c *Client) associatedState(iface string) state.ConnectionState {
+	if iface == "" {
+		iface = c.deviceName() // Startup: device properties not read yet
+	}
+	link, err := net.InterfaceByName(iface)
+	if err != nil {
+		return state.StateObtaining
+	}
+	addrs, err := link.Addrs()
+	if err != nil {
+		return state.StateObtaining
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.To4() != nil && !ipNet.IP.IsLinkLocalUnicast() {
+			return state.StateConnected
+		}
+	}
+	return state.StateObtaining
+}
+
 // updateStationState updates state from station properties
 func (c *Client) updateStationState(props map[string]dbus.Variant) {
 	c.stateMgr.Update(func(st *state.State) {
@@ -278,10 +492,8 @@ func (c *Client) updateStationState(props map[string]dbus.Variant) {
 				st.ConnectionState = state.StateDisconnected
 			case "connecting":
 				st.ConnectionState = state.StateConnecting
-			case "connected":
-				st.ConnectionState = state.StateConnected
-			case "roaming":
-				st.ConnectionState = state.StateConnected
+			case "connected", "roaming":
+				st.ConnectionState = c.associatedState(st.InterfaceName)
 			}
 		}
 		if v, ok := props["Scanning"]; ok {
@@ -354,6 +566,8 @@ func (c *Client) handlePropertyChange(sig *dbus.Signal) {
 		c.handleStationChange(props)
 	case DeviceIface:
 		c.handleDeviceChange(props)
+	case AccessPointIface:
+		c.handleAccessPointChange(props)
 	}
 }
 
@@ -373,53 +587,92 @@ func (c *Client) handleStationChange(props map[string]dbus.Variant) {
 		if v, ok := props["State"]; ok {
 			stateStr := v.Value().(string)
 			prevState := st.ConnectionState
+			if stateStr == "connected" || stateStr == "disconnected" || stateStr == "roaming" {
+				c.signal.reset()
+			}
 			switch stateStr {
 			case "disconnected":
 				st.ConnectionState = state.StateDisconnected
 				st.ActiveSSID = ""
 				st.ConnectingSSID = "" // Always clear on disconnected
+				st.SecurityDowngraded = false
+				st.Frequency, st.Channel, st.ChannelWidth = 0, 0, 0
+				st.WifiGeneration = ""
 				// Reset captive portal guard to allow re-check on reconnect
 				st.LastCaptiveCheckSSID = ""
-				st.CaptivePortalDetected = false
-				st.CaptivePortalURL = ""
-				// Detect authentication failure: connecting -> disconnected
+				if st.CaptivePortalDetected {
+					st.SetCaptiveResult(false, "", "", "") // Lets the UI dismiss its sign-in prompt
+				}
+				// Connection failure: connecting -> disconnected
+				// Auth vs out-of-range vs timeout is decided from what the agent saw
 				if prevState == state.StateConnecting {
-					st.LastError = "Authentication failed"
+					code, msg := c.disconnectReason()
+					st.LastError = msg
+					st.LastErrorCode = code
 					st.ConnectionState = state.StateFailed
-					log.Printf("Authentication failure detected (connecting -> disconnected)")
+					log.Printf("Connection failure detected (connecting -> disconnected): %s", code)
+				}
+				// Drop any static address we installed for the old network
+				if prevState == state.StateConnected || prevState == state.StateObtaining {
+					st.DisconnectReason = c.linkLossReason(st)
+					go c.clearStaticIP()
 				}
-				// Trigger USB fallback if available
-				if prevState == state.StateConnected && st.UsbTetheringAvailable && st.UsbInterfaceName != "" {
+				// Trigger USB fallback if available (and allowed to touch the link),
+				// else Bluetooth tethering from a paired phone
+				if prevState == state.StateConnected && st.UsbAutoConnect && st.UsbTetheringAvailable && st.UsbInterfaceName != "" {
 					log.Printf("WiFi disconnected, attempting USB tethering fallback on %s", st.UsbInterfaceName)
 					go c.tryUsbFallback(st.UsbInterfaceName)
+				} else if prevState == state.StateConnected && st.BtTetheringAvailable && !st.BtTetheringConnected {
+					go c.tryBluetoothFallback()
 				}
 			case "connecting":
 				st.ConnectionState = state.StateConnecting
 				st.LastError = "" // Clear any previous error on new attempt
+				st.LastErrorCode = ""
 			case "connected":
-				st.ConnectionState = state.StateConnected
+				// WiFi is back after a USB fallback - hand the default route back
+				if st.UsbTetheringConnected {
+					go c.returnFromUsbFallback()
+				}
+				// Obtaining until DHCP brings an address (netlink promotes to connected)
+				st.ConnectionState = c.associatedState(st.InterfaceName)
 				st.ConnectingSSID = "" // Clear on connected - connection complete
 				st.LastError = ""      // Clear any error on successful connection
+				st.LastErrorCode = ""
+				st.DisconnectReason = ""
 			case "roaming":
-				st.ConnectionState = state.StateConnected
+				if prevState != state.StateObtaining {
+					st.ConnectionState = state.StateConnected
+				}
 			}
 		}
-		if v, ok := props["Scanning"]; ok {
-			st.WifiScanning = v.Value().(bool)
+		// Scanning=false is published together with the results below, so
+		// ScanCompleted carries the fresh network count
+		if v, ok := props["Scanning"]; ok && v.Value().(bool) {
+			st.WifiScanning = true
 		}
 		if v, ok := props["ConnectedNetwork"]; ok {
 			networkPath := v.Value().(dbus.ObjectPath)
 			c.fetchNetworkDetails(networkPath, st)
 		}
+		// After ConnectedNetwork so autoconnect attempts know their SSID
+		if v, ok := props["State"]; ok {
+			c.recordStationState(v.Value().(string), st)
+		}
 	})
 
 	// Fetch networks AFTER state update (outside the Update lock)
 	if scanCompleted {
 		networks := c.fetchNetworksFromIWD()
-		if networks != nil {
-			c.stateMgr.Update(func(st *state.State) {
+		c.stateMgr.Update(func(st *state.State) {
+			st.WifiScanning = false
+			if networks != nil {
 				st.Networks = networks
-			})
+			}
+		})
+		// Scans refresh RSSI - feed the active signal filter
+		if c.stateMgr.Get().ConnectionState == state.StateConnected {
+			c.refreshActiveSignal()
 		}
 	}
 
@@ -431,6 +684,8 @@ func (c *Client) handleStationChange(props map[string]dbus.Variant) {
 			connectedSSID := c.stateMgr.Get().ActiveSSID
 
 			go func() {
+				c.applyStaticIP(connectedSSID)
+				c.readStationDiagnostics()
 				c.refreshKnownNetworks()
 				// Also refresh Networks array so active flag is updated
 				networks := c.fetchNetworksFromIWD()
@@ -441,14 +696,22 @@ func (c *Client) handleStationChange(props map[string]dbus.Variant) {
 				}
 
 				// === Captive Portal Auto-Detection ===
-				// Wait for DHCP/routing to settle before checking
+				// Wait for DHCP/routing to settle: an IPv4 address must be present
+				st := c.stateMgr.Get()
+				for i := 0; i < captiveAddrWaitSteps && st.IpAddress == ""; i++ {
+					time.Sleep(time.Second)
+					st = c.stateMgr.Get()
+				}
 				time.Sleep(2 * time.Second)
+				st = c.stateMgr.Get()
 
-				// Get current state for verification
-				st := c.stateMgr.Get()
+				if st.IpAddress == "" {
+					log.Printf("Captive check skipped: no IPv4 address")
+					return
+				}
 
 				// Guards: verify still connected, same SSID, not already checked
-				if st.ConnectionState != state.StateConnected {
+				if st.ConnectionState != state.StateConnected && st.ConnectionState != state.StateObtaining {
 					log.Printf("Captive check skipped: no longer connected")
 					return
 				}
@@ -463,17 +726,17 @@ func (c *Client) handleStationChange(props map[string]dbus.Variant) {
 
 				// Perform captive portal check
 				log.Printf("Checking captive portal for SSID: %s", connectedSSID)
-				detected, url := checkCaptivePortal()
+				result := c.portal.Detect("")
+				detected := result.Detected()
 
 				// Update state with results
 				c.stateMgr.Update(func(st *state.State) {
-					st.CaptivePortalDetected = detected
-					st.CaptivePortalURL = url
+					st.SetCaptiveResult(detected, result.URL, result.Endpoint, result.Stage)
 					st.LastCaptiveCheckSSID = connectedSSID
 				})
 
 				if detected {
-					log.Printf("Captive portal detected! URL: %s", url)
+					log.Printf("Captive portal detected! URL: %s (%s stage)", result.URL, result.Stage)
 				} else {
 					log.Printf("No captive portal detected")
 				}
@@ -482,6 +745,33 @@ func (c *Client) handleStationChange(props map[string]dbus.Variant) {
 	}
 }
 
+// applyStaticIP installs the SSID's static profile on the WiFi interface
+// Networks without a profile keep using DHCP
+func (c *Client) applyStaticIP(ssid string) {
+	iface := c.deviceName()
+	if iface == "" || ssid == "" {
+		return
+	}
+	applied, err := c.ipcfg.ApplySSID(ssid, iface)
+	if err != nil {
+		log.Printf("Failed to apply static IP for %s on %s: %v", ssid, iface, err)
+		c.stateMgr.Update(func(st *state.State) {
+			st.LastError = fmt.Sprintf("Static IP configuration failed: %v", err)
+		})
+		return
+	}
+	if applied {
+		log.Printf("Applied static IP profile for %s on %s", ssid, iface)
+	}
+}
+
+// clearStaticIP removes a static address left on the WiFi interface
+func (c *Client) clearStaticIP() {
+	if iface := c.deviceName(); iface != "" {
+		c.ipcfg.ClearStatic(iface)
+	}
+}
+
 // handleDeviceChange handles Device property changes
 func (c *Client) handleDeviceChange(props map[string]dbus.Variant) {
 	c.stateMgr.Update(func(st *state.State) {
@@ -518,7 +808,7 @@ func (c *Client) fetchNetworkDetails(path dbus.ObjectPath, st *state.State) {
 
 // fetchActiveSignal gets signal strength for the active network from GetOrderedNetworks
 func (c *Client) fetchActiveSignal(st *state.State, activePath dbus.ObjectPath) {
-	stationObj := c.conn.Object(IWDService, c.stationPath)
+	stationObj := c.conn.Object(IWDService, c.station())
 
 	type orderedNetwork struct {
 		Path dbus.ObjectPath
@@ -536,10 +826,7 @@ func (c *Client) fetchActiveSignal(st *state.State, activePath dbus.ObjectPath)
 	for _, net := range result {
 		if net.Path == activePath {
 			// RSSI is in 1/100 dBm units, convert to dBm
-			rssiDBm := int16(net.RSSI / 100)
-			st.SignalRSSI = rssiDBm
-			st.SignalStrength = state.DBmToPercent(rssiDBm)
-			log.Printf("Active network signal: %d dBm = %d%%", rssiDBm, st.SignalStrength)
+			c.applySignal(st, int16(net.RSSI/100))
 			return
 		}
 	}
@@ -554,70 +841,137 @@ func (c *Client) refreshState() {
 // refreshKnownNetworks fetches known networks from IWD and updates SavedNetworks
 // Called when connection state changes to "connected" to sync after forget+reconnect
 func (c *Client) refreshKnownNetworks() {
-	obj := c.conn.Object(IWDService, "/")
-
-	var result map[dbus.ObjectPath]map[string]map[string]dbus.Variant
-	err := obj.Call("org.freedesktop.DBus.ObjectManager.GetManagedObjects", 0).Store(&result)
+	known, err := c.knownNetworks()
 	if err != nil {
 		log.Printf("refreshKnownNetworks: failed to get managed objects: %v", err)
 		return
 	}
-
-	savedNetworks := []string{}
-	for _, ifaces := range result {
-		if knProps, ok := ifaces[KnownNetworkIface]; ok {
-			if nameV, ok := knProps["Name"]; ok {
-				ssid := nameV.Value().(string)
-				savedNetworks = append(savedNetworks, ssid)
-			}
-		}
-	}
-
-	if len(savedNetworks) > 0 {
-		c.stateMgr.Update(func(st *state.State) {
-			st.SavedNetworks = savedNetworks
-		})
-		log.Printf("Refreshed SavedNetworks: %v", savedNetworks)
+	if len(known) > 0 {
+		c.publishKnownNetworks(known)
 	}
 }
 
 // RefreshKnownNetworks refreshes the saved networks list from IWD
 func (c *Client) RefreshKnownNetworks() {
-	obj := c.conn.Object(IWDService, "/")
-	var result map[dbus.ObjectPath]map[string]map[string]dbus.Variant
-	err := obj.Call("org.freedesktop.DBus.ObjectManager.GetManagedObjects", 0).Store(&result)
+	known, err := c.knownNetworks()
 	if err != nil {
 		log.Printf("Failed to refresh known networks: %v", err)
 		return
 	}
-
-	var savedNetworks []string
-	for _, ifaces := range result {
-		if knProps, ok := ifaces[KnownNetworkIface]; ok {
-			if nameV, ok := knProps["Name"]; ok {
-				ssid := nameV.Value().(string)
-				savedNetworks = append(savedNetworks, ssid)
-			}
-		}
-	}
-
-	c.stateMgr.Update(func(st *state.State) {
-		st.SavedNetworks = savedNetworks
-	})
-	log.Printf("Refreshed known networks: %v", savedNetworks)
+	c.publishKnownNetworks(known)
 }
 
 // SetWifiEnabled enables/disables WiFi
 func (c *Client) SetWifiEnabled(enabled bool) error {
-	obj := c.conn.Object(IWDService, c.devicePath)
+	obj := c.conn.Object(IWDService, c.device())
 	return obj.Call("org.freedesktop.DBus.Properties.Set", 0, DeviceIface, "Powered", dbus.MakeVariant(enabled)).Err
 }
 
+// ErrScanCancelled is returned (with partial results) when CancelScan aborts the wait
+var ErrScanCancelled = errors.New("scan cancelled")
+
+// scanCall is a scan shared by every caller that arrives while it runs
+type scanCall struct {
+	done      chan struct{}
+	cancel    chan struct{} // Closed by CancelScan
+	cancelled bool
+	networks  []state.Network
+	err       error
+}
+
 // Scan scans for WiFi networks
-// Scan triggers a WiFi network scan (ASYNC)
-// Uses IWD PropertiesChanged signal to detect scan completion (no polling)
+// Returns cached results if a scan completed within scanCacheWindow, and joins
+// an in-flight scan instead of starting another (avoids IWD "Busy")
 func (c *Client) Scan() ([]state.Network, error) {
-	obj := c.conn.Object(IWDService, c.stationPath)
+	st := c.stateMgr.Get()
+	if !st.LastScanTime.IsZero() && time.Since(st.LastScanTime) < scanCacheWindow {
+		log.Printf("Scan: returning cached results (%s old)", time.Since(st.LastScanTime).Round(time.Second))
+		return st.Networks, nil
+	}
+	return c.ScanFresh()
+}
+
+// ScanFresh scans without serving cached results; it still joins an in-flight scan
+func (c *Client) ScanFresh() ([]state.Network, error) {
+	c.scanMu.Lock()
+	if call := c.scanInFlight; call != nil {
+		c.scanMu.Unlock()
+		<-call.done
+		return call.networks, call.err
+	}
+	call := &scanCall{done: make(chan struct{}), cancel: make(chan struct{})}
+	c.scanInFlight = call
+	c.scanMu.Unlock()
+
+	call.networks, call.err = c.scan(call.cancel)
+	if call.err == nil {
+		c.stateMgr.Update(func(st *state.State) {
+			st.LastScanTime = time.Now()
+		})
+	}
+
+	c.scanMu.Lock()
+	c.scanInFlight = nil
+	c.scanMu.Unlock()
+	close(call.done)
+
+	return call.networks, call.err
+}
+
+// CancelScan stops waiting for the in-flight scan; its callers get the networks
+// found so far and ErrScanCancelled. Returns false if no scan was running
+// IWD has no scan abort, so its scan finishes in the background and
+// WifiScanning stays set until it does; a tuned scan is aborted outright
+func (c *Client) CancelScan() bool {
+	c.scanMu.Lock()
+	defer c.scanMu.Unlock()
+
+	call := c.scanInFlight
+	if call == nil || call.cancelled {
+		return false
+	}
+	call.cancelled = true
+	close(call.cancel)
+	log.Printf("Scan cancelled")
+	return true
+}
+
+// scan triggers a WiFi network scan and waits for it (or for cancel)
+// Uses IWD PropertiesChanged signal to detect scan completion (no polling)
+func (c *Client) scan(cancel <-chan struct{}) ([]state.Network, error) {
+	// Tuned scans go through nl80211 directly (IWD has no passive/dwell knobs)
+	if st := c.stateMgr.Get(); hasCustomScanParams(st) {
+		ctx, stop := context.WithCancel(context.Background())
+		defer stop()
+		go func() {
+			select {
+			case <-cancel:
+				stop()
+			case <-ctx.Done():
+			}
+		}()
+
+		err := tunedScan(ctx, c.deviceName(), st, c.waitScanIdle)
+		if errors.Is(err, ErrScanCancelled) {
+			// iw was stopped and the kernel scan aborted: the scan is over
+			networks := c.fetchNetworksFromIWD()
+			c.publishCancelledScan(networks, false)
+			return networks, err
+		}
+		if err != nil {
+			log.Printf("Tuned scan failed: %v", err)
+			return nil, err
+		}
+		networks := c.fetchNetworksFromIWD()
+		if networks != nil {
+			c.stateMgr.Update(func(st *state.State) {
+				st.Networks = networks
+			})
+		}
+		return networks, nil
+	}
+
+	obj := c.conn.Object(IWDService, c.station())
 
 	// Trigger scan - this returns immediately
 	err := obj.Call(StationIface+".Scan", 0).Err
@@ -630,7 +984,7 @@ func (c *Client) Scan() ([]state.Network, error) {
 	scanDone := make(chan bool, 1)
 
 	// Subscribe to PropertiesChanged signal on Station (with arg0 filter for Station interface)
-	matchRule := fmt.Sprintf("type='signal',interface='org.freedesktop.DBus.Properties',member='PropertiesChanged',path='%s',arg0='%s'", c.stationPath, StationIface)
+	matchRule := fmt.Sprintf("type='signal',interface='org.freedesktop.DBus.Properties',member='PropertiesChanged',path='%s',arg0='%s'", c.station(), StationIface)
 	c.conn.BusObject().Call("org.freedesktop.DBus.AddMatch", 0, matchRule)
 
 	// Channel for receiving signals
@@ -648,7 +1002,7 @@ func (c *Client) Scan() ([]state.Network, error) {
 			if sig.Name != "org.freedesktop.DBus.Properties.PropertiesChanged" {
 				continue
 			}
-			if sig.Path != c.stationPath {
+			if sig.Path != c.station() {
 				continue
 			}
 			if len(sig.Body) < 2 {
@@ -676,12 +1030,18 @@ func (c *Client) Scan() ([]state.Network, error) {
 		}
 	}()
 
-	// Wait for scan completion with 15s timeout fallback
+	// Wait for scan completion with timeout fallback
+	timeout := time.Duration(c.scanTimeout.Load())
 	select {
 	case <-scanDone:
 		// Signal received - scan completed
-	case <-time.After(15 * time.Second):
-		log.Printf("Scan timeout after 15s, proceeding anyway")
+	case <-time.After(timeout):
+		log.Printf("Scan timeout after %s, proceeding anyway", timeout)
+	case <-cancel:
+		// Return what IWD has seen so far, without the empty-list retry
+		networks := c.fetchNetworksFromIWD()
+		c.publishCancelledScan(networks, c.stationScanning())
+		return networks, ErrScanCancelled
 	}
 
 	// Fetch fresh network list
@@ -705,10 +1065,51 @@ func (c *Client) Scan() ([]state.Network, error) {
 	return networks, nil
 }
 
+// publishCancelledScan stores the partial results of a cancelled scan
+// WifiScanning is only cleared when the radio is done; otherwise the Station's
+// Scanning=false signal clears it (never set here, so that signal can't be undone)
+func (c *Client) publishCancelledScan(networks []state.Network, stillScanning bool) {
+	c.stateMgr.Update(func(st *state.State) {
+		if !stillScanning {
+			st.WifiScanning = false
+		}
+		if networks != nil {
+			st.Networks = networks
+		}
+	})
+}
+
+// waitScanIdle blocks while IWD runs its own scan so a tuned scan doesn't
+// fight it for the radio; gives up after the scan timeout or when ctx ends
+func (c *Client) waitScanIdle(ctx context.Context) {
+	deadline := time.Now().Add(time.Duration(c.scanTimeout.Load()))
+	for c.stationScanning() {
+		if time.Now().After(deadline) {
+			log.Printf("IWD still scanning, starting the tuned scan anyway")
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(ownerPollInterval):
+		}
+	}
+}
+
+// stationScanning reads IWD's Station.Scanning (false if it can't be read)
+func (c *Client) stationScanning() bool {
+	v, err := c.conn.Object(IWDService, c.station()).GetProperty(StationIface + ".Scanning")
+	if err != nil {
+		return false
+	}
+	scanning, _ := v.Value().(bool)
+	return scanning
+}
+
 // fetchNetworksFromIWD fetches the current network list from IWD
 // Called from signal handler when scan completes
 func (c *Client) fetchNetworksFromIWD() []state.Network {
-	obj := c.conn.Object(IWDService, c.stationPath)
+	obj := c.conn.Object(IWDService, c.station())
 
 	var result []struct {
 		Path dbus.ObjectPath
@@ -744,9 +1145,28 @@ func (c *Client) fetchNetworksFromIWD() []state.Network {
 		}
 	}
 
+	if c.mergeBSS.Load() {
+		networks = mergeNetworks(networks)
+	}
+	sortNetworks(networks)
 	return networks
 }
 
+// sortNetworks orders the connected network first, then by descending signal,
+// then by SSID so equal-signal entries keep their place between scans
+func sortNetworks(networks []state.Network) {
+	sort.SliceStable(networks, func(i, j int) bool {
+		a, b := networks[i], networks[j]
+		if a.Connected != b.Connected {
+			return a.Connected
+		}
+		if a.SignalDBm != b.SignalDBm {
+			return a.SignalDBm > b.SignalDBm
+		}
+		return a.SSID < b.SSID
+	})
+}
+
 // getNetworkInfo gets info for a network
 func (c *Client) getNetworkInfo(path dbus.ObjectPath, rssi int16) *state.Network {
 	obj := c.conn.Object(IWDService, path)
@@ -761,6 +1181,8 @@ func (c *Client) getNetworkInfo(path dbus.ObjectPath, rssi int16) *state.Network
 		ObjectPath: string(path),
 		SignalDBm:  rssi / 100, // IWD returns 1/100 dBm units, convert to dBm
 		Signal:     state.DBmToPercent(rssi / 100),
+		SignalBars: state.DBmToBars(rssi / 100),
+		BSSCount:   bssCount(props),
 	}
 
 	if v, ok := props["Name"]; ok {
@@ -780,20 +1202,28 @@ func (c *Client) getNetworkInfo(path dbus.ObjectPath, rssi int16) *state.Network
 }
 
 // Connect connects to a network
-func (c *Client) Connect(ssid, password, security string, hidden bool) error {
+// password is borrowed: the caller zeroes it after Connect returns
+// bssid ("" = any) pins the access point; without IWD developer mode it is only
+// verified after connecting (ErrBSSIDMismatch)
+func (c *Client) Connect(ssid string, password []byte, security string, hidden bool, bssid string) error {
 	// Lock to prevent concurrent connection attempts
 	c.connectMu.Lock()
 
 	// Increment connection ID for this attempt
 	c.connectID++
 	myConnectID := c.connectID
-	log.Printf("IWD Connect called: ssid=%s, password=%d chars, security=%s, hidden=%v (connectID=%d)",
-		ssid, len(password), security, hidden, myConnectID)
+	log.Printf("IWD Connect called: ssid=%s, password=%d chars, security=%s, hidden=%v, bssid=%q (connectID=%d)",
+		ssid, len(password), security, hidden, bssid, myConnectID)
 
 	// Unlock after setting up state - actual IWD call will be made without lock
 	// but we hold lock during state setup to ensure atomicity
 	c.connectMu.Unlock()
 
+	// Fresh attempt - forget what the agent saw last time
+	if agent := c.currentAgent(); agent != nil {
+		agent.ResetAttempt()
+	}
+
 	// Find network by SSID
 	log.Printf("Starting scan for network %s", ssid)
 	networks, err := c.Scan()
@@ -814,17 +1244,44 @@ func (c *Client) Connect(ssid, password, security string, hidden bool) error {
 		}
 	}
 
+	// A "hidden" SSID that is broadcast would fail ConnectHiddenNetwork with
+	// AlreadyExists - join it like any visible network instead
+	if hidden && networkPath != "" {
+		log.Printf("Network %s is not hidden, connecting normally", ssid)
+		hidden = false
+	}
+
 	if networkPath == "" && !hidden {
 		log.Printf("Network not found: %s", ssid)
 		return fmt.Errorf("network not found: %s", ssid)
 	}
 
+	// Probe for the hidden SSID first so a typo fails as "not found" instead
+	// of a password prompt that can never succeed
+	if hidden && networkPath == "" {
+		found, _, err := c.ScanSSID(ssid)
+		if err != nil {
+			log.Printf("Directed scan unavailable, connecting blindly: %v", err)
+		} else if !found {
+			log.Printf("Hidden network not found: %s", ssid)
+			return fmt.Errorf("network not found: %s", ssid)
+		}
+	}
+
 	// For PSK/SAE networks with password, set pending credential for agent
 	// IWD will call Agent.RequestPassphrase to get the password
 	netPath := dbus.ObjectPath(networkPath)
-	if password != "" && (networkSecurity == "psk" || security == "psk" || networkSecurity == "wpa2" || networkSecurity == "wpa3") {
-		if c.agent != nil {
-			c.agent.SetPending(netPath, password)
+	credentialSet := false
+	if hidden {
+		// No network object yet - the agent matches the credential by SSID
+		if agent := c.currentAgent(); len(password) > 0 && agent != nil {
+			agent.SetPendingSSID(ssid, password)
+			credentialSet = true
+		}
+	} else if len(password) > 0 && (networkSecurity == "psk" || security == "psk" || networkSecurity == "wpa2" || networkSecurity == "wpa3") {
+		if agent := c.currentAgent(); agent != nil {
+			agent.SetPending(netPath, password)
+			credentialSet = true
 		} else {
 			log.Printf("Warning: Agent not available, connection may require saved credentials")
 		}
@@ -838,7 +1295,7 @@ func (c *Client) Connect(ssid, password, security string, hidden bool) error {
 	if hidden {
 		// Connect to hidden network
 		log.Printf("Connecting to hidden network %s", ssid)
-		obj := c.conn.Object(IWDService, c.stationPath)
+		obj := c.conn.Object(IWDService, c.station())
 		err := obj.Call(StationIface+".ConnectHiddenNetwork", 0, ssid).Err
 
 		// Clear ConnectingSSID only if this is still the current connection attempt
@@ -852,16 +1309,30 @@ func (c *Client) Connect(ssid, password, security string, hidden bool) error {
 		}
 		c.connectMu.Unlock()
 
-		if err != nil && c.agent != nil {
-			c.agent.ClearPending(netPath)
+		// Served or not, the credential must not outlive the attempt
+		if agent := c.currentAgent(); agent != nil {
+			agent.ClearPendingSSID(ssid)
+		}
+		if err == nil && credentialSet {
+			go c.storeCredential(ssid, append([]byte(nil), password...))
 		}
 		return err
 	}
 
-	// Connect to visible network
-	log.Printf("Calling IWD Network.Connect on %s", networkPath)
-	obj := c.conn.Object(IWDService, netPath)
-	err = obj.Call(NetworkIface+".Connect", 0).Err
+	// Connect to visible network (transient failures are retried with backoff)
+	pinned := false
+	if bssid != "" {
+		pinned, err = c.connectToBSS(bssid)
+		if !pinned {
+			log.Printf("StationDebug unavailable, connecting normally and verifying BSS %s", bssid)
+		}
+	}
+	if !pinned {
+		err = c.connectWithRetry(netPath, password, credentialSet, myConnectID)
+	}
+	if err == nil && bssid != "" {
+		err = c.verifyBSS(bssid)
+	}
 
 	// Clear ConnectingSSID only if this is still the current connection attempt
 	c.connectMu.Lock()
@@ -874,55 +1345,126 @@ func (c *Client) Connect(ssid, password, security string, hidden bool) error {
 	}
 	c.connectMu.Unlock()
 
-	if err != nil {
+	if err != nil && !errors.Is(err, ErrBSSIDMismatch) {
 		log.Printf("IWD Network.Connect failed: %v", err)
 		// Clear pending credential on failure
-		if c.agent != nil {
-			c.agent.ClearPending(netPath)
+		if agent := c.currentAgent(); agent != nil {
+			agent.ClearPending(netPath)
 		}
 	} else {
 		log.Printf("IWD Network.Connect succeeded")
+		if credentialSet {
+			go c.storeCredential(ssid, append([]byte(nil), password...))
+		}
 	}
 	return err
 }
 
-// writeIWDConfig writes the password to IWD config file using sudo
-func (c *Client) writeIWDConfig(ssid, password, security string) error {
-	// IWD stores configs in /var/lib/iwd/SSID.psk (or .open, .8021x)
-	configPath := fmt.Sprintf("/var/lib/iwd/%s.%s", ssid, security)
+// SetScanTimeout sets how long Scan waits for IWD before reading results anyway
+func (c *Client) SetScanTimeout(d time.Duration) {
+	if d <= 0 {
+		d = DefaultScanTimeout
+	}
+	c.scanTimeout.Store(int64(d))
+}
 
-	// Use printf for proper newline handling, pipe to tee for sudo write
-	// Format: [Security]\nPassphrase=xxx\n
-	cmd := exec.Command("sudo", "tee", configPath)
-	cmd.Stdin = strings.NewReader(fmt.Sprintf("[Security]\nPassphrase=%s\n", password))
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to write IWD config: %w", err)
+// SetConnectAttempts sets how many times Network.Connect is tried on transient failures
+func (c *Client) SetConnectAttempts(n int) {
+	if n < 1 {
+		n = 1
 	}
+	c.connectMu.Lock()
+	c.connectAttempts = n
+	c.connectMu.Unlock()
+}
 
-	// Set permissions (IWD requires 600)
-	chmodCmd := exec.Command("sudo", "chmod", "600", configPath)
-	if err := chmodCmd.Run(); err != nil {
-		log.Printf("Warning: failed to chmod IWD config: %v", err)
+// connectWithRetry calls Network.Connect, retrying transient failures with
+// exponential backoff. Authentication failures are never retried, and a newer
+// connect attempt (connectID changed) cancels any pending retries.
+func (c *Client) connectWithRetry(netPath dbus.ObjectPath, password []byte, credentialSet bool, myConnectID uint64) error {
+	c.connectMu.Lock()
+	maxAttempts := c.connectAttempts
+	c.connectMu.Unlock()
+
+	backoff := connectRetryBase
+	obj := c.conn.Object(IWDService, netPath)
+
+	for attempt := 1; ; attempt++ {
+		log.Printf("Calling IWD Network.Connect on %s (attempt %d/%d)", netPath, attempt, maxAttempts)
+		err := obj.Call(NetworkIface+".Connect", 0).Err
+		if err == nil || attempt >= maxAttempts || !isTransientConnectError(err) {
+			return err
+		}
+
+		// connecting -> disconnected means bad credentials - retrying would hammer the AP
+		if c.stateMgr.Get().LastErrorCode == state.ErrCodeAuthFailed {
+			log.Printf("Not retrying connect: authentication failure")
+			return err
+		}
+
+		log.Printf("Transient connect failure (%v), retrying in %v", err, backoff)
+		select {
+		case <-c.closing:
+			log.Printf("Connect retry cancelled - shutting down")
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+
+		if c.isStaleConnect(myConnectID) {
+			log.Printf("Connect retry cancelled - superseded by newer attempt (myID=%d)", myConnectID)
+			return err
+		}
+
+		// Agent consumes the credential on RequestPassphrase - re-arm it
+		if agent := c.currentAgent(); credentialSet && agent != nil {
+			agent.SetPending(netPath, password)
+		}
 	}
+}
 
-	log.Printf("Wrote IWD config for %s", ssid)
-	return nil
+// isStaleConnect reports whether a newer connect attempt has started
+func (c *Client) isStaleConnect(myConnectID uint64) bool {
+	c.connectMu.Lock()
+	defer c.connectMu.Unlock()
+	return c.connectID != myConnectID
+}
+
+// isTransientConnectError reports whether a Network.Connect error is worth retrying
+func isTransientConnectError(err error) bool {
+	var dbusErr dbus.Error
+	if errors.As(err, &dbusErr) {
+		switch dbusErr.Name {
+		case IWDService + ".Busy", IWDService + ".InProgress", IWDService + ".Timeout",
+			IWDService + ".NotAvailable", "org.freedesktop.DBus.Error.NoReply":
+			return true
+		}
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "carrier") || strings.Contains(msg, "timed out")
 }
 
 // ConnectSaved connects to a saved network
 func (c *Client) ConnectSaved(ssid string) error {
 	// For saved networks, we need to find the KnownNetwork and trigger connect
-	return c.Connect(ssid, "", "", false)
+	return c.Connect(ssid, nil, "", false, "")
 }
 
 // Disconnect disconnects from current network
 func (c *Client) Disconnect() error {
-	obj := c.conn.Object(IWDService, c.stationPath)
+	c.noteUserDisconnect()
+	obj := c.conn.Object(IWDService, c.station())
 	return obj.Call(StationIface+".Disconnect", 0).Err
 }
 
 // Forget forgets a saved network
 func (c *Client) Forget(ssid string) error {
+	if c.creds != nil {
+		if err := c.creds.Delete(ssid); err != nil {
+			log.Printf("Failed to remove keyring credential for %s: %v", ssid, err)
+		}
+	}
+
 	// Find known network by SSID
 	obj := c.conn.Object(IWDService, "/")
 
@@ -967,108 +1509,3 @@ func (c *Client) SetAutoConnect(ssid string, enabled bool) error {
 
 	return fmt.Errorf("known network not found: %s", ssid)
 }
-
-// StartHotspot starts WiFi hotspot
-func (c *Client) StartHotspot(ssid, password string) error {
-	// Switch to AP mode
-	obj := c.conn.Object(IWDService, c.devicePath)
-	err := obj.Call("org.freedesktop.DBus.Properties.Set", 0, DeviceIface, "Mode", dbus.MakeVariant("ap")).Err
-	if err != nil {
-		return err
-	}
-
-	// Start AP with profile
-	apObj := c.conn.Object(IWDService, c.devicePath)
-	return apObj.Call(AccessPointIface+".Start", 0, ssid, password).Err
-}
-
-// StopHotspot stops WiFi hotspot
-func (c *Client) StopHotspot() error {
-	apObj := c.conn.Object(IWDService, c.devicePath)
-	err := apObj.Call(AccessPointIface+".Stop", 0).Err
-	if err != nil {
-		return err
-	}
-
-	// Switch back to station mode
-	obj := c.conn.Object(IWDService, c.devicePath)
-	return obj.Call("org.freedesktop.DBus.Properties.Set", 0, DeviceIface, "Mode", dbus.MakeVariant("station")).Err
-}
-
-// tryUsbFallback attempts to establish USB tethering connection as fallback
-func (c *Client) tryUsbFallback(ifaceName string) {
-	log.Printf("Attempting USB tethering fallback on %s", ifaceName)
-
-	// Bring up the interface (requires sudo)
-	if err := exec.Command("sudo", "ip", "link", "set", ifaceName, "up").Run(); err != nil {
-		log.Printf("Failed to bring up USB interface %s: %v", ifaceName, err)
-		return
-	}
-
-	// Run dhcpcd to get IP address (requires sudo)
-	log.Printf("Running DHCP on USB interface %s", ifaceName)
-	cmd := exec.Command("sudo", "dhcpcd", "-4", "-w", ifaceName)
-	if err := cmd.Run(); err != nil {
-		log.Printf("DHCP failed on USB interface %s: %v", ifaceName, err)
-		return
-	}
-
-	log.Printf("USB tethering fallback established on %s", ifaceName)
-
-	// Update state
-	c.stateMgr.Update(func(st *state.State) {
-		st.UsbTetheringConnected = true
-		st.ConnectionType = "usb"
-	})
-}
-
-// checkCaptivePortal checks for captive portal by HTTP probe
-// Returns detected=true if captive portal is present, with redirect URL if available
-func checkCaptivePortal() (detected bool, url string) {
-	// Use common captive portal detection endpoints
-	endpoints := []string{
-		"http://detectportal.firefox.com/success.txt",
-		"http://www.gstatic.com/generate_204",
-		"http://captive.apple.com/hotspot-detect.html",
-	}
-
-	client := &http.Client{
-		Timeout: 5 * time.Second,
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			// Capture redirect URL
-			url = req.URL.String()
-			return http.ErrUseLastResponse
-		},
-	}
-
-	for _, endpoint := range endpoints {
-		resp, err := client.Get(endpoint)
-		if err != nil {
-			continue
-		}
-		defer resp.Body.Close()
-
-		// Check for redirect (captive portal)
-		if resp.StatusCode == 302 || resp.StatusCode == 301 {
-			return true, url
-		}
-
-		// Check content for Firefox endpoint
-		if strings.Contains(endpoint, "firefox") {
-			body, _ := io.ReadAll(resp.Body)
-			if !strings.Contains(string(body), "success") {
-				return true, endpoint
-			}
-		}
-
-		// Check for 204 (Google endpoint)
-		if strings.Contains(endpoint, "generate_204") && resp.StatusCode != 204 {
-			return true, endpoint
-		}
-
-		// Got expected response - no captive portal
-		return false, ""
-	}
-
-	return false, ""
-}
@@ -0,0 +1,190 @@
+package iwd
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// CredentialProvider keeps WiFi passphrases in a keyring so the agent can
+// answer IWD without asking the UI again. It doesn't replace IWD's storage:
+// IWD still saves a passphrase it was handed in its own profile
+type CredentialProvider interface {
+	Lookup(ssid string) (string, error)
+	Store(ssid string, passphrase []byte) error
+	Delete(ssid string) error
+}
+
+// ErrNoCredential is returned by Lookup when nothing is stored for the SSID
+var ErrNoCredential = errors.New("no stored credential")
+
+// Secret Service (org.freedesktop.secrets) names
+const (
+	secretsService    = "org.freedesktop.secrets"
+	secretsPath       = "/org/freedesktop/secrets"
+	secretsDefault    = "/org/freedesktop/secrets/aliases/default"
+	secretServiceIfc  = "org.freedesktop.Secret.Service"
+	secretCollectIfc  = "org.freedesktop.Secret.Collection"
+	secretItemIfc     = "org.freedesktop.Secret.Item"
+	secretApplication = "x-network"
+)
+
+// secret mirrors the Secret Service (oayays) struct
+type secret struct {
+	Session     dbus.ObjectPath
+	Parameters  []byte
+	Value       []byte
+	ContentType string
+}
+
+// SecretServiceProvider keeps passphrases in the user's keyring
+// Uses a "plain" session - the session bus is local to the user
+type SecretServiceProvider struct {
+	conn    *dbus.Conn
+	session dbus.ObjectPath
+}
+
+// NewSecretServiceProvider connects to the keyring on a user's session bus:
+// address ("unix:path=/run/user/1000/bus"), or the daemon's own session bus
+// when empty. The bus only accepts its owner, so a daemon running as root on
+// the system bus can't reach a user's keyring - run it as that user instead
+// Fails if no Secret Service implementation is running
+func NewSecretServiceProvider(address string) (*SecretServiceProvider, error) {
+	var conn *dbus.Conn
+	var err error
+	if address == "" {
+		conn, err = dbus.SessionBus()
+	} else {
+		conn, err = dbus.Connect(address)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("no session bus: %w", err)
+	}
+
+	var output dbus.Variant
+	var session dbus.ObjectPath
+	err = conn.Object(secretsService, secretsPath).
+		Call(secretServiceIfc+".OpenSession", 0, "plain", dbus.MakeVariant("")).
+		Store(&output, &session)
+	if err != nil {
+		return nil, fmt.Errorf("secret service unavailable: %w", err)
+	}
+
+	return &SecretServiceProvider{conn: conn, session: session}, nil
+}
+
+// Lookup returns the stored passphrase for ssid
+func (p *SecretServiceProvider) Lookup(ssid string) (string, error) {
+	item, err := p.findItem(ssid)
+	if err != nil {
+		return "", err
+	}
+
+	var s secret
+	err = p.conn.Object(secretsService, item).Call(secretItemIfc+".GetSecret", 0, p.session).Store(&s)
+	if err != nil {
+		return "", err
+	}
+	passphrase := string(s.Value)
+	zero(s.Value)
+	return passphrase, nil
+}
+
+// Store saves (or replaces) the passphrase for ssid in the default collection
+func (p *SecretServiceProvider) Store(ssid string, passphrase []byte) error {
+	props := map[string]dbus.Variant{
+		secretItemIfc + ".Label":      dbus.MakeVariant("Wi-Fi password for " + ssid),
+		secretItemIfc + ".Attributes": dbus.MakeVariant(attributes(ssid)),
+	}
+	s := secret{
+		Session:     p.session,
+		Value:       passphrase,
+		ContentType: "text/plain",
+	}
+
+	var item, prompt dbus.ObjectPath
+	err := p.conn.Object(secretsService, secretsDefault).
+		Call(secretCollectIfc+".CreateItem", 0, props, s, true).
+		Store(&item, &prompt)
+	if err != nil {
+		return err
+	}
+	if prompt != "/" {
+		return errors.New("keyring is locked")
+	}
+	return nil
+}
+
+// Delete removes the stored passphrase for ssid
+func (p *SecretServiceProvider) Delete(ssid string) error {
+	item, err := p.findItem(ssid)
+	if errors.Is(err, ErrNoCredential) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var prompt dbus.ObjectPath
+	return p.conn.Object(secretsService, item).Call(secretItemIfc+".Delete", 0).Store(&prompt)
+}
+
+// findItem returns the unlocked keyring item for ssid
+func (p *SecretServiceProvider) findItem(ssid string) (dbus.ObjectPath, error) {
+	var unlocked, locked []dbus.ObjectPath
+	err := p.conn.Object(secretsService, secretsPath).
+		Call(secretServiceIfc+".SearchItems", 0, attributes(ssid)).
+		Store(&unlocked, &locked)
+	if err != nil {
+		return "", err
+	}
+	if len(unlocked) > 0 {
+		return unlocked[0], nil
+	}
+	if len(locked) > 0 {
+		// Unlocking needs a user prompt - not something a daemon can drive
+		return "", errors.New("keyring is locked")
+	}
+	return "", ErrNoCredential
+}
+
+// attributes identifies our items in the keyring
+func attributes(ssid string) map[string]string {
+	return map[string]string{
+		"application": secretApplication,
+		"ssid":        ssid,
+	}
+}
+
+// zero overwrites a secret buffer
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+// SetCredentialProvider makes the agent fall back to the provider when no
+// pending credential exists, and stores passphrases there after connecting
+func (c *Client) SetCredentialProvider(p CredentialProvider) {
+	c.creds = p
+	if agent := c.currentAgent(); agent != nil {
+		agent.mu.Lock()
+		agent.creds = p
+		agent.mu.Unlock()
+	}
+}
+
+// storeCredential saves a passphrase that just connected successfully
+// Takes ownership of passphrase and zeroes it when done
+func (c *Client) storeCredential(ssid string, passphrase []byte) {
+	defer zero(passphrase)
+	if c.creds == nil || len(passphrase) == 0 {
+		return
+	}
+	if err := c.creds.Store(ssid, passphrase); err != nil {
+		log.Printf("Failed to store credential for %s in keyring: %v", ssid, err)
+		return
+	}
+	log.Printf("Stored credential for %s in keyring", ssid)
+}
@@ -0,0 +1,83 @@
+package iwd
+
+import (
+	"log"
+	"strconv"
+	"strings"
+
+	"x-network/internal/state"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// StationDiagnosticIface reports negotiated link parameters for the connected BSS
+const StationDiagnosticIface = "net.connman.iwd.StationDiagnostic"
+
+// readStationDiagnostics publishes the connected BSS's frequency, channel,
+// channel width and WiFi generation, and checks for a security downgrade
+// Called on every (re)connect, so roams pick up the new BSS
+func (c *Client) readStationDiagnostics() {
+	var diag map[string]dbus.Variant
+	err := c.conn.Object(IWDService, c.station()).Call(StationDiagnosticIface+".GetDiagnostics", 0).Store(&diag)
+	if err != nil {
+		log.Printf("Cannot read station diagnostics: %v", err)
+		return
+	}
+
+	freq, _ := diag["Frequency"].Value().(uint32)
+	width := channelWidth(diag["ChannelWidth"])
+	rxMode, _ := diag["RxMode"].Value().(string)
+	txMode, _ := diag["TxMode"].Value().(string)
+	generation := wifiGeneration(rxMode, freq)
+	if generation == "" {
+		generation = wifiGeneration(txMode, freq)
+	}
+	downgraded := c.securityDowngraded(diag)
+
+	c.stateMgr.Update(func(st *state.State) {
+		st.Frequency = freq
+		st.Channel = state.FrequencyToChannel(freq)
+		st.ChannelWidth = width
+		st.WifiGeneration = generation
+		st.SecurityDowngraded = downgraded
+	})
+}
+
+// wifiGeneration maps IWD's PHY mode ("802.11ax", or HT/VHT/HE) to its
+// marketing name; HE on 6 GHz is Wi-Fi 6E. "" when the mode is unknown
+func wifiGeneration(mode string, freq uint32) string {
+	switch strings.ToUpper(strings.TrimSpace(mode)) {
+	case "802.11N", "HT":
+		return "Wi-Fi 4"
+	case "802.11AC", "VHT":
+		return "Wi-Fi 5"
+	case "802.11AX", "HE":
+		if freq >= 5925 {
+			return "Wi-Fi 6E"
+		}
+		return "Wi-Fi 6"
+	}
+	return ""
+}
+
+// channelWidth parses the width (MHz) from a diagnostics value, which IWD
+// only reports with some drivers and versions; 0 when missing or unexpected
+func channelWidth(v dbus.Variant) uint32 {
+	var mhz uint64
+	switch w := v.Value().(type) {
+	case byte:
+		mhz = uint64(w)
+	case uint16:
+		mhz = uint64(w)
+	case uint32:
+		mhz = uint64(w)
+	case string:
+		// "80", "80MHz", "80 MHz"
+		mhz, _ = strconv.ParseUint(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(w), "MHz")), 10, 32)
+	}
+	switch mhz {
+	case 20, 40, 80, 160, 320:
+		return uint32(mhz)
+	}
+	return 0
+}
@@ -0,0 +1,83 @@
+package iwd
+
+import (
+	"errors"
+	"strings"
+
+	"x-network/internal/state"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// connectErrors translates IWD error names returned by Network.Connect and
+// Station.ConnectHiddenNetwork into a stable code and a message for the UI
+var connectErrors = map[string]struct{ code, message string }{
+	// .Failed covers any failed attempt (association, DHCP-less timeouts,
+	// driver errors) - only the key errors mean the password was wrong
+	IWDService + ".Failed":               {state.ErrCodeConnectFailed, "Connection failed"},
+	IWDService + ".InvalidKey":           {state.ErrCodeAuthFailed, "Authentication failed"},
+	IWDService + ".AuthenticationFailed": {state.ErrCodeAuthFailed, "Authentication failed"},
+
+	IWDService + ".InvalidFormat": {state.ErrCodeInvalidFormat, "Password has an invalid format"},
+	IWDService + ".NotFound":      {state.ErrCodeOutOfRange, "Network out of range"},
+	IWDService + ".Timeout":       {state.ErrCodeTimeout, "Connection timed out"},
+	IWDService + ".Busy":          {state.ErrCodeBusy, "WiFi device is busy"},
+	IWDService + ".InProgress":    {state.ErrCodeInProgress, "Already connecting"},
+	IWDService + ".Aborted":       {state.ErrCodeAborted, "Connection attempt was aborted"},
+	IWDService + ".NoAgent":       {state.ErrCodeNoAgent, "No agent available to provide the password"},
+	IWDService + ".NotSupported":  {state.ErrCodeNotSupported, "Network security not supported"},
+	IWDService + ".NotConnected":  {state.ErrCodeNotConnected, "Not connected"},
+
+	// ConnectHiddenNetwork: too many hidden BSSs to tell which one is meant
+	IWDService + ".ServiceSetOverflow": {state.ErrCodeFailed, "Too many hidden networks nearby"},
+	// ConnectHiddenNetwork: the SSID is broadcast, so it isn't hidden
+	IWDService + ".AlreadyExists": {state.ErrCodeFailed, "Network is not hidden"},
+}
+
+// ClassifyConnectError maps a Connect failure to a LastErrorCode and message
+// Unknown D-Bus errors keep IWD's description rather than the raw error name
+func ClassifyConnectError(err error) (code, message string) {
+	var dbusErr dbus.Error
+	if errors.As(err, &dbusErr) {
+		if e, ok := connectErrors[dbusErr.Name]; ok {
+			return e.code, e.message
+		}
+		if len(dbusErr.Body) > 0 {
+			if msg, ok := dbusErr.Body[0].(string); ok && msg != "" {
+				return state.ErrCodeUnknown, msg
+			}
+		}
+	}
+	if errors.Is(err, ErrBSSIDMismatch) {
+		return state.ErrCodeBSSIDMismatch, "Connected, but to a different access point than requested"
+	}
+	if errors.Is(err, ErrNotReady) {
+		return state.ErrCodeInitializing, err.Error()
+	}
+	if strings.HasPrefix(err.Error(), "network not found") {
+		return state.ErrCodeOutOfRange, "Network out of range"
+	}
+	return state.ErrCodeUnknown, err.Error()
+}
+
+// disconnectReason explains a connecting -> disconnected transition
+// Uses the agent's view of the attempt: a Cancel("out-of-range") or a timeout
+// beats the auth heuristic, and auth is only blamed if IWD asked for a passphrase
+func (c *Client) disconnectReason() (code, message string) {
+	agent := c.currentAgent()
+	if agent == nil {
+		return state.ErrCodeConnectFailed, "Connection failed"
+	}
+
+	served, cancelReason := agent.AttemptInfo()
+	switch cancelReason {
+	case "out-of-range":
+		return state.ErrCodeOutOfRange, "Network out of range"
+	case "timed-out":
+		return state.ErrCodeTimeout, "Connection timed out"
+	}
+	if served {
+		return state.ErrCodeAuthFailed, "Authentication failed"
+	}
+	return state.ErrCodeConnectFailed, "Connection failed"
+}
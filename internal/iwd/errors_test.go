@@ -0,0 +1,49 @@
+package iwd
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"x-network/internal/state"
+
+	"github.com/godbus/dbus/v5"
+)
+
+func TestClassifyConnectError(t *testing.T) {
+	iwdErr := func(name string) error {
+		return dbus.Error{Name: IWDService + "." + name, Body: []interface{}{"iwd says " + name}}
+	}
+	tests := []struct {
+		name string
+		err  error
+		code string
+	}{
+		{"generic failure is not auth", iwdErr("Failed"), state.ErrCodeConnectFailed},
+		{"invalid key", iwdErr("InvalidKey"), state.ErrCodeAuthFailed},
+		{"authentication failed", iwdErr("AuthenticationFailed"), state.ErrCodeAuthFailed},
+		{"invalid format", iwdErr("InvalidFormat"), state.ErrCodeInvalidFormat},
+		{"not found", iwdErr("NotFound"), state.ErrCodeOutOfRange},
+		{"no agent", iwdErr("NoAgent"), state.ErrCodeNoAgent},
+		{"hidden overflow", iwdErr("ServiceSetOverflow"), state.ErrCodeFailed},
+		{"unknown iwd error", iwdErr("SomethingNew"), state.ErrCodeUnknown},
+		{"wrapped", fmt.Errorf("connect: %w", iwdErr("Timeout")), state.ErrCodeTimeout},
+		{"bssid mismatch", ErrBSSIDMismatch, state.ErrCodeBSSIDMismatch},
+		{"not ready", ErrNotReady, state.ErrCodeInitializing},
+		{"scan miss", errors.New("network not found: Cafe"), state.ErrCodeOutOfRange},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code, _ := ClassifyConnectError(tt.err); code != tt.code {
+				t.Errorf("ClassifyConnectError() code = %q, want %q", code, tt.code)
+			}
+		})
+	}
+}
+
+func TestClassifyConnectErrorKeepsIWDMessage(t *testing.T) {
+	err := dbus.Error{Name: IWDService + ".SomethingNew", Body: []interface{}{"Operation failed"}}
+	if _, msg := ClassifyConnectError(err); msg != "Operation failed" {
+		t.Errorf("message = %q, want IWD's description", msg)
+	}
+}
@@ -0,0 +1,172 @@
+package iwd
+
+import (
+	"sync"
+	"time"
+
+	"x-network/internal/state"
+)
+
+// HistorySize bounds the in-memory connection history
+const HistorySize = 200
+
+// Attempt is one connection attempt, from IWD's connecting state to
+// connected or back to disconnected
+type Attempt struct {
+	SSID        string        `json:"ssid"`
+	Started     time.Time     `json:"started"`
+	Duration    time.Duration `json:"duration"` // Until connected or failed
+	Success     bool          `json:"success"`
+	FailureCode string        `json:"failure_code,omitempty"` // state.ErrCode* ("" on success)
+	Signal      uint8         `json:"signal"`                 // Scan signal of the network when the attempt started (0-100)
+}
+
+// NetworkStats aggregates the recorded attempts of one SSID
+type NetworkStats struct {
+	Attempts        uint32
+	Successes       uint32
+	AvgConnectTime  time.Duration // Mean Duration of the successful attempts
+	LastFailure     time.Time     // Zero when no attempt failed
+	LastFailureCode string
+}
+
+// SuccessRate returns the share of successful attempts (0 without attempts)
+func (n NetworkStats) SuccessRate() float64 {
+	if n.Attempts == 0 {
+		return 0
+	}
+	return float64(n.Successes) / float64(n.Attempts)
+}
+
+// connectHistory records attempts in a ring of HistorySize entries
+type connectHistory struct {
+	mu       sync.Mutex
+	attempts []Attempt // Oldest first
+	pending  *Attempt  // Attempt in progress (nil = none)
+}
+
+// recordStationState feeds a Station.State change into the history
+// Roaming is part of the current connection, so it neither starts nor ends
+// an attempt; a connected state without a pending attempt is a roam finishing
+func (c *Client) recordStationState(stationState string, st *state.State) {
+	h := &c.history
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := time.Now()
+	switch stationState {
+	case "connecting":
+		ssid := st.ConnectingSSID
+		if ssid == "" {
+			ssid = st.ActiveSSID
+		}
+		if h.pending != nil && h.pending.SSID == ssid {
+			return // IWD retrying the same network within one attempt
+		}
+		h.pending = &Attempt{SSID: ssid, Started: now, Signal: scanSignal(st.Networks, ssid)}
+	case "connected":
+		if h.pending == nil {
+			return
+		}
+		if h.pending.SSID == "" {
+			h.pending.SSID = st.ActiveSSID
+		}
+		h.pending.Success = true
+		h.finish(now)
+	case "disconnected":
+		if h.pending == nil {
+			return
+		}
+		h.pending.FailureCode = st.LastErrorCode
+		if h.pending.FailureCode == "" {
+			h.pending.FailureCode = state.ErrCodeUnknown
+		}
+		h.finish(now)
+	}
+}
+
+// finish closes the pending attempt and appends it, dropping the oldest
+// entry once the ring is full. Caller holds h.mu
+func (h *connectHistory) finish(now time.Time) {
+	a := *h.pending
+	h.pending = nil
+	a.Duration = now.Sub(a.Started)
+	if len(h.attempts) >= HistorySize {
+		h.attempts = append(h.attempts[:0], h.attempts[len(h.attempts)-HistorySize+1:]...)
+	}
+	h.attempts = append(h.attempts, a)
+}
+
+// scanSignal returns the strongest scan signal of ssid (0 if not in the scan)
+func scanSignal(networks []state.Network, ssid string) uint8 {
+	var best uint8
+	for _, n := range networks {
+		if n.SSID == ssid && n.Signal > best {
+			best = n.Signal
+		}
+	}
+	return best
+}
+
+// ConnectionHistory returns up to limit recorded attempts, newest first
+// (limit 0 = all)
+func (c *Client) ConnectionHistory(limit int) []Attempt {
+	h := &c.history
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	n := len(h.attempts)
+	if limit > 0 && limit < n {
+		n = limit
+	}
+	result := make([]Attempt, n)
+	for i := range result {
+		result[i] = h.attempts[len(h.attempts)-1-i]
+	}
+	return result
+}
+
+// RestoreHistory puts attempts saved by a previous run (newest first, as
+// returned by ConnectionHistory) in front of the ones recorded since start
+func (c *Client) RestoreHistory(attempts []Attempt) {
+	h := &c.history
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	restored := make([]Attempt, 0, len(attempts)+len(h.attempts))
+	for i := len(attempts) - 1; i >= 0; i-- {
+		restored = append(restored, attempts[i])
+	}
+	restored = append(restored, h.attempts...)
+	if len(restored) > HistorySize {
+		restored = restored[len(restored)-HistorySize:]
+	}
+	h.attempts = restored
+}
+
+// NetworkStats aggregates the recorded attempts of ssid
+func (c *Client) NetworkStats(ssid string) NetworkStats {
+	h := &c.history
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var stats NetworkStats
+	var connectTime time.Duration
+	for _, a := range h.attempts {
+		if a.SSID != ssid {
+			continue
+		}
+		stats.Attempts++
+		if a.Success {
+			stats.Successes++
+			connectTime += a.Duration
+			continue
+		}
+		stats.LastFailure = a.Started
+		stats.LastFailureCode = a.FailureCode
+	}
+	if stats.Successes > 0 {
+		stats.AvgConnectTime = connectTime / time.Duration(stats.Successes)
+	}
+	return stats
+}
@@ -0,0 +1,344 @@
+package iwd
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"x-network/internal/connectivity"
+	"x-network/internal/hotspot"
+	"x-network/internal/state"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// Hotspot security modes accepted by StartHotspot
+// IWD access points only implement WPA2-PSK, so there is no WPA3/SAE mode
+const (
+	HotspotSecurityOpen = "open"
+	HotspotSecurityWPA2 = "wpa2"
+)
+
+// Hotspot bands accepted by StartHotspot
+const (
+	HotspotBand24 = "2.4"
+	HotspotBand5  = "5"
+)
+
+// apProfileDir is where IWD looks up AccessPoint.StartProfile profiles
+const apProfileDir = "/var/lib/iwd/ap"
+
+var (
+	// ErrInvalidHotspot is returned for hotspot settings that can never work
+	ErrInvalidHotspot = errors.New("invalid hotspot settings")
+
+	// ErrHotspotUnsupported is returned for settings IWD or the adapter can't provide
+	ErrHotspotUnsupported = errors.New("hotspot setting not supported")
+)
+
+// HotspotOptions are the optional StartHotspot settings
+type HotspotOptions struct {
+	Band    string        // HotspotBand24 or HotspotBand5 ("" = IWD's choice)
+	Channel uint16        // 0 = IWD's choice (or the band's default)
+	Hidden  bool          // Not broadcasting the SSID - IWD APs can't do this
+	Timeout time.Duration // Stop after this long without clients (0 = never)
+}
+
+// validateHotspot checks StartHotspot input and fills defaults
+func validateHotspot(ssid, password string, security *string, opts *HotspotOptions) error {
+	if ssid == "" || len(ssid) > 32 {
+		return fmt.Errorf("%w: ssid must be 1-32 bytes", ErrInvalidHotspot)
+	}
+	// The SSID is the AP profile's file name (see apProfilePath)
+	if strings.Contains(ssid, "/") || strings.HasPrefix(ssid, ".") {
+		return fmt.Errorf("%w: ssid must not contain '/' or start with '.'", ErrInvalidHotspot)
+	}
+
+	if *security == "" {
+		*security = HotspotSecurityWPA2
+	}
+	switch *security {
+	case HotspotSecurityOpen:
+		// Open AP - password is ignored
+	case HotspotSecurityWPA2:
+		if len(password) < 8 || len(password) > 63 {
+			return fmt.Errorf("%w: %s passphrase must be 8-63 characters", ErrInvalidHotspot, *security)
+		}
+		if hasControlChars(password) {
+			return fmt.Errorf("%w: passphrase contains control characters", ErrInvalidHotspot)
+		}
+	default:
+		return fmt.Errorf("%w: unsupported security %q (open, wpa2)", ErrInvalidHotspot, *security)
+	}
+
+	switch opts.Band {
+	case "", HotspotBand24, HotspotBand5:
+	default:
+		return fmt.Errorf("%w: band must be %q or %q", ErrInvalidHotspot, HotspotBand24, HotspotBand5)
+	}
+	if opts.Channel != 0 {
+		band := channelBand(opts.Channel)
+		if band == "" {
+			return fmt.Errorf("%w: invalid channel %d", ErrInvalidHotspot, opts.Channel)
+		}
+		if opts.Band != "" && opts.Band != band {
+			return fmt.Errorf("%w: channel %d is not in the %s GHz band", ErrInvalidHotspot, opts.Channel, opts.Band)
+		}
+	} else if opts.Band == HotspotBand5 {
+		opts.Channel = 36 // IWD picks 2.4 GHz unless told otherwise
+	}
+
+	if opts.Hidden {
+		return fmt.Errorf("%w: IWD access points always broadcast their SSID", ErrHotspotUnsupported)
+	}
+	if opts.Timeout < 0 {
+		return fmt.Errorf("%w: negative timeout", ErrInvalidHotspot)
+	}
+	return nil
+}
+
+// hasControlChars reports whether s contains a byte that would break a line
+// based profile file (newline, NUL, other C0 controls or DEL)
+func hasControlChars(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 0x20 || s[i] == 0x7f {
+			return true
+		}
+	}
+	return false
+}
+
+// channelBand returns the band of a WiFi channel number ("" = not a channel)
+func channelBand(ch uint16) string {
+	switch {
+	case ch >= 1 && ch <= 14:
+		return HotspotBand24
+	case ch >= 32 && ch <= 177:
+		return HotspotBand5
+	}
+	return ""
+}
+
+// StartHotspot starts WiFi hotspot with the requested security and options
+// Plain wpa2 uses AccessPoint.Start directly; open and a fixed channel need a
+// generated profile. Any failure puts the device back in station mode
+func (c *Client) StartHotspot(ssid, password, security string, opts HotspotOptions) error {
+	if err := validateHotspot(ssid, password, &security, &opts); err != nil {
+		return err
+	}
+
+	// Switch to AP mode
+	obj := c.conn.Object(IWDService, c.device())
+	err := obj.Call("org.freedesktop.DBus.Properties.Set", 0, DeviceIface, "Mode", dbus.MakeVariant("ap")).Err
+	if err != nil {
+		return err
+	}
+
+	c.hotspotMu.Lock()
+	c.hotspotTimeout = opts.Timeout
+	c.hotspotMu.Unlock()
+
+	apObj := c.conn.Object(IWDService, c.device())
+	if security == HotspotSecurityWPA2 && opts.Channel == 0 {
+		// Start AP with passphrase (IWD default is WPA2-PSK)
+		err = apObj.Call(AccessPointIface+".Start", 0, ssid, password).Err
+	} else {
+		if err = writeAPProfile(ssid, password, security, opts.Channel); err == nil {
+			err = apObj.Call(AccessPointIface+".StartProfile", 0, ssid).Err
+		}
+	}
+	if err != nil {
+		c.restoreStationMode()
+		return err
+	}
+
+	// Kept for HotspotQRString until the AP stops
+	c.stateMgr.Update(func(st *state.State) {
+		st.HotspotPassword = password
+		st.HotspotSecurity = security
+	})
+
+	// Clients only get online with an address, DHCP and NAT on our side
+	if err := c.startSharing(); err != nil {
+		log.Printf("Hotspot sharing failed, stopping AP: %v", err)
+		c.StopHotspot()
+		return err
+	}
+	return nil
+}
+
+// SetHotspotSubnet sets the AP address and client subnet ("10.42.0.1/24")
+func (c *Client) SetHotspotSubnet(cidr string) error {
+	if ip, _, err := net.ParseCIDR(cidr); err != nil || ip.To4() == nil {
+		return fmt.Errorf("invalid hotspot subnet %q", cidr)
+	}
+	c.hotspotMu.Lock()
+	c.hotspotSubnet = cidr
+	c.hotspotMu.Unlock()
+	return nil
+}
+
+// startSharing configures the AP interface and NAT toward the current uplink
+func (c *Client) startSharing() error {
+	iface := c.deviceName()
+	if iface == "" {
+		return fmt.Errorf("no WiFi device")
+	}
+	uplink, _ := connectivity.DefaultRouteInterface()
+	if uplink == iface {
+		uplink = ""
+	}
+
+	c.hotspotMu.Lock()
+	defer c.hotspotMu.Unlock()
+	if c.hotspotShare != nil {
+		return nil
+	}
+	cidr := c.hotspotSubnet
+	if cidr == "" {
+		cidr = hotspot.DefaultSubnet
+	}
+	share, err := hotspot.Start(iface, cidr, uplink, c.stateMgr.Get().DnsServers)
+	if err != nil {
+		return err
+	}
+	c.hotspotShare = share
+	return nil
+}
+
+// stopSharing tears down the AP network, if set up
+func (c *Client) stopSharing() {
+	c.hotspotMu.Lock()
+	share := c.hotspotShare
+	c.hotspotShare = nil
+	c.hotspotMu.Unlock()
+
+	if share != nil {
+		if err := share.Stop(); err != nil {
+			log.Printf("Hotspot sharing teardown: %v", err)
+		}
+	}
+}
+
+// StopHotspot stops WiFi hotspot
+func (c *Client) StopHotspot() error {
+	return c.stopHotspot(state.HotspotReasonStopped)
+}
+
+// stopHotspot stops the AP, reporting reason in HotspotStateChanged
+func (c *Client) stopHotspot(reason string) error {
+	c.hotspotMu.Lock()
+	c.hotspotStopReason = reason
+	c.hotspotMu.Unlock()
+
+	c.stopSharing()
+
+	apObj := c.conn.Object(IWDService, c.device())
+	err := apObj.Call(AccessPointIface+".Stop", 0).Err
+	if err != nil {
+		c.hotspotMu.Lock()
+		c.hotspotStopReason = ""
+		c.hotspotMu.Unlock()
+		return err
+	}
+
+	// Switch back to station mode
+	return c.setStationMode()
+}
+
+// setStationMode switches the device back from AP to station mode
+func (c *Client) setStationMode() error {
+	obj := c.conn.Object(IWDService, c.device())
+	return obj.Call("org.freedesktop.DBus.Properties.Set", 0, DeviceIface, "Mode", dbus.MakeVariant("station")).Err
+}
+
+// restoreStationMode undoes the AP mode switch of a failed StartHotspot
+func (c *Client) restoreStationMode() {
+	if err := c.setStationMode(); err != nil {
+		log.Printf("Failed to restore station mode: %v", err)
+	}
+}
+
+// apProfile renders the IWD AP profile for the given security and channel
+// The passphrase was checked for control characters by validateHotspot
+func apProfile(password, security string, channel uint16) string {
+	var b strings.Builder
+	b.WriteString("[General]\n")
+	if channel != 0 {
+		fmt.Fprintf(&b, "Channel=%d\n", channel)
+	}
+	// No [Security] section = open network
+	if security != HotspotSecurityOpen {
+		fmt.Fprintf(&b, "\n[Security]\nPassphrase=%s\n", password)
+	}
+	return b.String()
+}
+
+// apProfilePath returns the profile AccessPoint.StartProfile(ssid) loads
+// IWD looks AP profiles up by the verbatim SSID (no hex encoding as for
+// network profiles); validateHotspot keeps ssid inside the directory
+func apProfilePath(ssid string) string {
+	return filepath.Join(apProfileDir, ssid+".ap")
+}
+
+// writeAPProfile writes an AP profile to /var/lib/iwd/ap using sudo
+func writeAPProfile(ssid, password, security string, channel uint16) error {
+	configPath := apProfilePath(ssid)
+
+	if err := exec.Command("sudo", "mkdir", "-p", apProfileDir).Run(); err != nil {
+		return fmt.Errorf("failed to create AP profile dir: %w", err)
+	}
+
+	cmd := exec.Command("sudo", "tee", configPath)
+	cmd.Stdin = strings.NewReader(apProfile(password, security, channel))
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to write AP profile: %w", err)
+	}
+
+	// Set permissions (IWD requires 600)
+	if err := exec.Command("sudo", "chmod", "600", configPath).Run(); err != nil {
+		log.Printf("Warning: failed to chmod AP profile: %v", err)
+	}
+
+	log.Printf("Wrote AP profile for %s (%s)", ssid, security)
+	return nil
+}
+
+// phyInfo returns `iw phy <phy> info` output for the IWD device's phy
+// The phy name comes from sysfs
+func (c *Client) phyInfo() (string, error) {
+	iface := c.deviceName()
+	if iface == "" {
+		return "", fmt.Errorf("no WiFi device")
+	}
+
+	phy, err := os.ReadFile("/sys/class/net/" + iface + "/phy80211/name")
+	if err != nil {
+		return "", fmt.Errorf("cannot read phy for %s: %w", iface, err)
+	}
+
+	out, err := exec.Command("iw", "phy", strings.TrimSpace(string(phy)), "info").Output()
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// deviceName returns the kernel interface name of the IWD device
+func (c *Client) deviceName() string {
+	if c.device() == "" {
+		return ""
+	}
+	v, err := c.conn.Object(IWDService, c.device()).GetProperty(DeviceIface + ".Name")
+	if err != nil {
+		return ""
+	}
+	name, _ := v.Value().(string)
+	return name
+}
@@ -0,0 +1,62 @@
+package iwd
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateHotspot(t *testing.T) {
+	tests := []struct {
+		name     string
+		ssid     string
+		password string
+		security string
+		want     error
+	}{
+		{"wpa2", "Cafe", "password1", HotspotSecurityWPA2, nil},
+		{"default security", "Cafe", "password1", "", nil},
+		{"open ignores password", "Cafe", "", HotspotSecurityOpen, nil},
+		{"short passphrase", "Cafe", "short", HotspotSecurityWPA2, ErrInvalidHotspot},
+		{"newline in passphrase", "Cafe", "password1\nAutoConnect=true", HotspotSecurityWPA2, ErrInvalidHotspot},
+		{"nul in passphrase", "Cafe", "pass\x00word1", HotspotSecurityWPA2, ErrInvalidHotspot},
+		{"wpa3", "Cafe", "password1", "wpa3", ErrInvalidHotspot},
+		{"empty ssid", "", "password1", HotspotSecurityWPA2, ErrInvalidHotspot},
+		{"ssid with spaces", "My Phone", "password1", HotspotSecurityWPA2, nil},
+		{"ssid with slash", "a/b", "password1", HotspotSecurityWPA2, ErrInvalidHotspot},
+		{"dot ssid", "..", "password1", HotspotSecurityWPA2, ErrInvalidHotspot},
+		{"hidden-file ssid", ".cafe", "password1", HotspotSecurityWPA2, ErrInvalidHotspot},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			security := tt.security
+			err := validateHotspot(tt.ssid, tt.password, &security, &HotspotOptions{})
+			if !errors.Is(err, tt.want) || (tt.want == nil && err != nil) {
+				t.Fatalf("validateHotspot() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPProfile(t *testing.T) {
+	got := apProfile("password1", HotspotSecurityWPA2, 6)
+	want := "[General]\nChannel=6\n\n[Security]\nPassphrase=password1\n"
+	if got != want {
+		t.Errorf("apProfile() = %q, want %q", got, want)
+	}
+	if got := apProfile("", HotspotSecurityOpen, 0); strings.Contains(got, "[Security]") {
+		t.Errorf("open profile has a [Security] section: %q", got)
+	}
+}
+
+func TestAPProfilePath(t *testing.T) {
+	// StartProfile("My Phone") loads ap/My Phone.ap: the name must be verbatim
+	for ssid, want := range map[string]string{
+		"My Phone":    "/var/lib/iwd/ap/My Phone.ap",
+		"cafe-guest!": "/var/lib/iwd/ap/cafe-guest!.ap",
+	} {
+		if got := apProfilePath(ssid); got != want {
+			t.Errorf("apProfilePath(%q) = %q, want %q", ssid, got, want)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package iwd
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"x-network/internal/state"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// knownNetwork is a KnownNetwork object as read from GetManagedObjects
+type knownNetwork struct {
+	Path          dbus.ObjectPath
+	Name          string
+	Type          string
+	Hidden        bool
+	AutoConnect   bool
+	LastConnected time.Time // Zero if never connected
+}
+
+// parseKnownNetwork reads the KnownNetwork interface properties
+func parseKnownNetwork(path dbus.ObjectPath, props map[string]dbus.Variant) knownNetwork {
+	k := knownNetwork{Path: path}
+	k.Name, _ = props["Name"].Value().(string)
+	k.Type, _ = props["Type"].Value().(string)
+	k.Hidden, _ = props["Hidden"].Value().(bool)
+	k.AutoConnect, _ = props["AutoConnect"].Value().(bool)
+	if v, ok := props["LastConnectedTime"].Value().(string); ok {
+		// ISO 8601, absent for networks provisioned but never joined
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			k.LastConnected = t
+		}
+	}
+	return k
+}
+
+// knownNetworks lists IWD's KnownNetwork objects
+func (c *Client) knownNetworks() ([]knownNetwork, error) {
+	var result map[dbus.ObjectPath]map[string]map[string]dbus.Variant
+	err := c.conn.Object(IWDService, "/").Call("org.freedesktop.DBus.ObjectManager.GetManagedObjects", 0).Store(&result)
+	if err != nil {
+		return nil, err
+	}
+
+	var known []knownNetwork
+	for path, ifaces := range result {
+		if props, ok := ifaces[KnownNetworkIface]; ok {
+			known = append(known, parseKnownNetwork(path, props))
+		}
+	}
+	return known, nil
+}
+
+// publishKnownNetworks replaces the known-network set and publishes it
+func (c *Client) publishKnownNetworks(known []knownNetwork) {
+	c.knownMu.Lock()
+	c.known = make(map[dbus.ObjectPath]knownNetwork, len(known))
+	for _, k := range known {
+		c.known[k.Path] = k
+	}
+	c.publishKnownLocked()
+	c.knownMu.Unlock()
+	log.Printf("Refreshed known networks: %d", len(known))
+}
+
+// addKnownNetwork records a KnownNetwork created by IWD (by us or another client)
+func (c *Client) addKnownNetwork(k knownNetwork) {
+	c.knownMu.Lock()
+	defer c.knownMu.Unlock()
+	if c.known == nil {
+		c.known = make(map[dbus.ObjectPath]knownNetwork)
+	}
+	c.known[k.Path] = k
+	log.Printf("Known network added: %s", k.Name)
+	c.publishKnownLocked()
+}
+
+// removeKnownNetwork drops a KnownNetwork IWD removed (e.g. forgotten via iwctl)
+func (c *Client) removeKnownNetwork(path dbus.ObjectPath) {
+	c.knownMu.Lock()
+	defer c.knownMu.Unlock()
+	k, ok := c.known[path]
+	if !ok {
+		return
+	}
+	delete(c.known, path)
+	log.Printf("Known network removed: %s", k.Name)
+	c.publishKnownLocked()
+}
+
+// publishKnownLocked stores the detailed list and the SavedNetworks names
+// derived from it, highest priority first then by name (caller holds knownMu)
+func (c *Client) publishKnownLocked() {
+	names := make([]string, 0, len(c.known))
+	details := make([]state.KnownNetwork, 0, len(c.known))
+	for _, k := range c.known {
+		details = append(details, state.KnownNetwork{
+			SSID:          k.Name,
+			Security:      k.Type,
+			Hidden:        k.Hidden,
+			AutoConnect:   k.AutoConnect,
+			LastConnected: k.LastConnected,
+			Priority:      c.priorities[k.Name],
+		})
+	}
+	sort.Slice(details, func(i, j int) bool {
+		if details[i].Priority != details[j].Priority {
+			return details[i].Priority > details[j].Priority
+		}
+		return details[i].SSID < details[j].SSID
+	})
+	for _, k := range details {
+		names = append(names, k.SSID)
+	}
+
+	c.stateMgr.Update(func(st *state.State) {
+		st.SavedNetworks = names
+		st.KnownNetworks = details
+	})
+}
+
+// SetNetworkPriorities loads saved priorities (by SSID) without validation,
+// so entries for networks IWD doesn't know yet are kept
+func (c *Client) SetNetworkPriorities(priorities map[string]int32) {
+	c.knownMu.Lock()
+	defer c.knownMu.Unlock()
+	c.priorities = make(map[string]int32, len(priorities))
+	for ssid, p := range priorities {
+		c.priorities[ssid] = p
+	}
+	c.publishKnownLocked()
+}
+
+// SetNetworkPriority sets a saved network's priority (0 clears it)
+// IWD has no priority setting, so the caller persists it; unknown SSIDs fail
+func (c *Client) SetNetworkPriority(ssid string, priority int32) error {
+	c.knownMu.Lock()
+	defer c.knownMu.Unlock()
+
+	found := false
+	for _, k := range c.known {
+		if k.Name == ssid {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("known network not found: %s", ssid)
+	}
+
+	c.setPriorityLocked(ssid, priority)
+	return nil
+}
+
+// setPriorityLocked records and publishes a priority (caller holds knownMu)
+func (c *Client) setPriorityLocked(ssid string, priority int32) {
+	if c.priorities == nil {
+		c.priorities = make(map[string]int32)
+	}
+	if priority == 0 {
+		delete(c.priorities, ssid)
+	} else {
+		c.priorities[ssid] = priority
+	}
+	log.Printf("Priority of %s set to %d", ssid, priority)
+	c.publishKnownLocked()
+}
+
+// NetworkPriorities returns a copy of the priorities, for persisting
+func (c *Client) NetworkPriorities() map[string]int32 {
+	c.knownMu.Lock()
+	defer c.knownMu.Unlock()
+	priorities := make(map[string]int32, len(c.priorities))
+	for ssid, p := range c.priorities {
+		priorities[ssid] = p
+	}
+	return priorities
+}
@@ -0,0 +1,51 @@
+package iwd
+
+import (
+	"x-network/internal/state"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// SetMergeBSS controls whether scan results with the same SSID and security
+// collapse into one entry (on by default); per-BSS data stays available
+// through GetAccessPoints
+func (c *Client) SetMergeBSS(enabled bool) {
+	c.mergeBSS.Store(enabled)
+}
+
+// mergeNetworks collapses entries sharing SSID+security into the strongest
+// one, keeping its position and summing BSSCount
+func mergeNetworks(networks []state.Network) []state.Network {
+	type key struct{ ssid, security string }
+	index := make(map[key]int, len(networks))
+
+	merged := make([]state.Network, 0, len(networks))
+	for _, n := range networks {
+		k := key{n.SSID, n.Security}
+		i, seen := index[k]
+		if !seen {
+			index[k] = len(merged)
+			merged = append(merged, n)
+			continue
+		}
+
+		m := &merged[i]
+		count, connected, saved := m.BSSCount+n.BSSCount, m.Connected || n.Connected, m.Saved || n.Saved
+		if n.SignalDBm > m.SignalDBm {
+			*m = n
+		}
+		m.BSSCount, m.Connected, m.Saved = count, connected, saved
+	}
+	return merged
+}
+
+// bssCount returns how many BSSs back a Network object (at least 1)
+// ExtendedServiceSet is only published by newer IWD versions
+func bssCount(props map[string]dbus.Variant) int {
+	if v, ok := props["ExtendedServiceSet"]; ok {
+		if paths, ok := v.Value().([]dbus.ObjectPath); ok && len(paths) > 0 {
+			return len(paths)
+		}
+	}
+	return 1
+}
@@ -0,0 +1,439 @@
+package iwd
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// Policy declares the saved networks the daemon should converge to
+// Keys that aren't supported are rejected rather than silently ignored. Band
+// preferences are out of scope: IWD has no per-network band setting and the
+// daemon doesn't steer bands, so a "band" key fails like any unknown key
+type Policy struct {
+	Networks       []PolicyNetwork `json:"networks"`
+	RemoveUnlisted bool            `json:"remove_unlisted"` // Forget known networks not in the policy
+}
+
+// PolicyNetwork is one declared network
+type PolicyNetwork struct {
+	SSID        string `json:"ssid"`
+	Security    string `json:"security,omitempty"`    // "open" or "psk" ("" = psk with passphrase, else open)
+	Passphrase  string `json:"passphrase,omitempty"`  // Required for psk
+	AutoConnect *bool  `json:"autoconnect,omitempty"` // nil = leave as is (new networks default to true)
+	Priority    *int32 `json:"priority,omitempty"`    // nil = leave as is, 0 clears (see SetNetworkPriority)
+	Blocked     *bool  `json:"blocked,omitempty"`     // nil = leave as is (see BlockNetwork)
+}
+
+// PolicyResult lists what ApplyPolicy changed
+type PolicyResult struct {
+	Added   []string
+	Updated []string
+	Removed []string
+}
+
+// policyAction is one reconcile step
+type policyAction struct {
+	kind    string // "add", "passphrase", "autoconnect", "forget", then "priority", "block"
+	network PolicyNetwork
+	known   knownNetwork
+}
+
+// policyCurrent is what planPolicy compares the policy with
+type policyCurrent struct {
+	known             []knownNetwork
+	priorities        map[string]int32
+	blocked           map[string]bool
+	passphraseChanged func(PolicyNetwork) bool // Whether a known network's stored passphrase differs
+}
+
+// knownNetworkSettleTime is how long IWD gets to pick up written config files
+const knownNetworkSettleTime = 2 * time.Second
+
+// LoadPolicy reads and validates a JSON policy file
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var p Policy
+	if err := dec.Decode(&p); err != nil {
+		return nil, fmt.Errorf("invalid policy %s: %w", path, err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Validate normalizes security types and checks each network is usable
+func (p *Policy) Validate() error {
+	seen := make(map[string]bool)
+	for i := range p.Networks {
+		n := &p.Networks[i]
+		if n.SSID == "" || len(n.SSID) > 32 {
+			return fmt.Errorf("network %d: ssid must be 1-32 bytes", i)
+		}
+		if seen[n.SSID] {
+			return fmt.Errorf("network %q listed twice", n.SSID)
+		}
+		seen[n.SSID] = true
+
+		if n.Security == "" {
+			n.Security = "open"
+			if n.Passphrase != "" {
+				n.Security = "psk"
+			}
+		}
+		switch n.Security {
+		case "open":
+			if n.Passphrase != "" {
+				return fmt.Errorf("network %q: open networks take no passphrase", n.SSID)
+			}
+		case "psk":
+			if len(n.Passphrase) < 8 || len(n.Passphrase) > 63 {
+				return fmt.Errorf("network %q: passphrase must be 8-63 characters", n.SSID)
+			}
+			if hasControlChars(n.Passphrase) {
+				return fmt.Errorf("network %q: passphrase contains control characters", n.SSID)
+			}
+		default:
+			return fmt.Errorf("network %q: unsupported security %q (open, psk)", n.SSID, n.Security)
+		}
+		if n.Blocked != nil && *n.Blocked && n.AutoConnect != nil && *n.AutoConnect {
+			return fmt.Errorf("network %q: blocked networks can't autoconnect", n.SSID)
+		}
+	}
+	return nil
+}
+
+// planPolicy compares the policy with the current networks and returns the
+// steps to converge; applying the plan twice yields an empty second plan
+// IWD profile steps come first, priority and blacklist steps after them
+func planPolicy(p *Policy, cur policyCurrent) []policyAction {
+	bySSID := make(map[string]knownNetwork, len(cur.known))
+	for _, k := range cur.known {
+		bySSID[k.Name] = k
+	}
+
+	var actions, settings []policyAction
+	listed := make(map[string]bool, len(p.Networks))
+	for _, n := range p.Networks {
+		listed[n.SSID] = true
+		k, ok := bySSID[n.SSID]
+		switch {
+		case !ok || k.Type != n.Security:
+			// New, or security changed - IWD keys profiles by SSID+type
+			actions = append(actions, policyAction{kind: "add", network: n, known: k})
+		case n.Security == "psk" && cur.passphraseChanged(n):
+			actions = append(actions, policyAction{kind: "passphrase", network: n, known: k})
+		case n.AutoConnect != nil && *n.AutoConnect != k.AutoConnect:
+			actions = append(actions, policyAction{kind: "autoconnect", network: n, known: k})
+		}
+
+		if n.Priority != nil && *n.Priority != cur.priorities[n.SSID] {
+			settings = append(settings, policyAction{kind: "priority", network: n})
+		}
+		if n.Blocked != nil && *n.Blocked != cur.blocked[n.SSID] {
+			settings = append(settings, policyAction{kind: "block", network: n})
+		}
+	}
+
+	if p.RemoveUnlisted {
+		for _, k := range cur.known {
+			if !listed[k.Name] {
+				actions = append(actions, policyAction{kind: "forget", known: k})
+			}
+		}
+	}
+	return append(actions, settings...)
+}
+
+// ApplyPolicy reconciles IWD's known networks, priorities and blacklist with p
+// Passphrases are provisioned as IWD config files (and the keyring, if set);
+// the caller persists priorities and blacklist like their D-Bus setters do
+func (c *Client) ApplyPolicy(p *Policy) (PolicyResult, error) {
+	var res PolicyResult
+
+	known, err := c.knownNetworks()
+	if err != nil {
+		return res, err
+	}
+
+	c.knownMu.Lock()
+	cur := policyCurrent{
+		known:             known,
+		priorities:        make(map[string]int32, len(c.priorities)),
+		blocked:           make(map[string]bool, len(c.blocked)),
+		passphraseChanged: c.passphraseChanged,
+	}
+	for ssid, prio := range c.priorities {
+		cur.priorities[ssid] = prio
+	}
+	for ssid := range c.blocked {
+		cur.blocked[ssid] = true
+	}
+	c.knownMu.Unlock()
+
+	actions := planPolicy(p, cur)
+	if len(actions) == 0 {
+		log.Printf("Policy: %d networks already in sync", len(p.Networks))
+		return res, nil
+	}
+
+	var errs []error
+	written, settled := false, false
+	for _, a := range actions {
+		ssid := a.network.SSID
+		if (a.kind == "priority" || a.kind == "block") && !settled {
+			// Let IWD list the new profiles so blocking can turn their AutoConnect off
+			if written {
+				time.Sleep(knownNetworkSettleTime)
+			}
+			c.RefreshKnownNetworks()
+			settled = true
+		}
+		switch a.kind {
+		case "add", "passphrase":
+			if a.kind == "add" && a.known.Path != "" {
+				// Security type changed - drop the old profile first
+				if err := c.forgetKnown(a.known); err != nil {
+					errs = append(errs, err)
+					continue
+				}
+			}
+			n := a.network
+			if a.kind == "passphrase" && n.AutoConnect == nil {
+				autoConnect := a.known.AutoConnect // Rewriting the profile must not reset it
+				n.AutoConnect = &autoConnect
+			}
+			if err := c.provisionNetwork(n); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			written = true
+			if a.kind == "add" {
+				res.Added = append(res.Added, ssid)
+			} else {
+				res.Updated = appendOnce(res.Updated, ssid)
+			}
+		case "autoconnect":
+			err := c.conn.Object(IWDService, a.known.Path).Call("org.freedesktop.DBus.Properties.Set", 0,
+				KnownNetworkIface, "AutoConnect", dbus.MakeVariant(*a.network.AutoConnect)).Err
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", ssid, err))
+				continue
+			}
+			res.Updated = appendOnce(res.Updated, ssid)
+		case "forget":
+			if err := c.forgetKnown(a.known); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			res.Removed = append(res.Removed, a.known.Name)
+		case "priority":
+			c.knownMu.Lock()
+			c.setPriorityLocked(ssid, *a.network.Priority)
+			c.knownMu.Unlock()
+			res.Updated = appendOnce(res.Updated, ssid)
+		case "block":
+			if err := c.BlockNetwork(ssid, *a.network.Blocked); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", ssid, err))
+				continue
+			}
+			res.Updated = appendOnce(res.Updated, ssid)
+		}
+	}
+
+	if !settled {
+		if written {
+			time.Sleep(knownNetworkSettleTime)
+		}
+		c.RefreshKnownNetworks()
+	}
+
+	log.Printf("Policy applied: added %v, updated %v, removed %v", res.Added, res.Updated, res.Removed)
+	return res, errors.Join(errs...)
+}
+
+// appendOnce appends ssid unless it is already the last entry
+func appendOnce(list []string, ssid string) []string {
+	if len(list) > 0 && list[len(list)-1] == ssid {
+		return list
+	}
+	return append(list, ssid)
+}
+
+// provisionNetwork writes the IWD profile and mirrors the passphrase to the keyring
+func (c *Client) provisionNetwork(n PolicyNetwork) error {
+	passphrase := []byte(n.Passphrase)
+	defer zero(passphrase)
+
+	autoConnect := (n.AutoConnect == nil || *n.AutoConnect) && (n.Blocked == nil || !*n.Blocked)
+	if err := c.writeIWDConfig(n.SSID, n.Security, passphrase, autoConnect); err != nil {
+		return fmt.Errorf("%s: %w", n.SSID, err)
+	}
+	if len(passphrase) > 0 {
+		c.storeCredential(n.SSID, append([]byte(nil), passphrase...))
+	}
+	return nil
+}
+
+// passphraseChanged compares against the keyring copy, or else against the
+// key in IWD's profile; a profile that can't be read is rewritten
+func (c *Client) passphraseChanged(n PolicyNetwork) bool {
+	if c.creds != nil {
+		if stored, err := c.creds.Lookup(n.SSID); err == nil {
+			return stored != n.Passphrase
+		}
+	}
+	profile, err := readIWDConfig(n.SSID, n.Security)
+	if err != nil {
+		log.Printf("Policy: can't read the profile of %s, rewriting it: %v", n.SSID, err)
+		return true
+	}
+	defer zero(profile)
+	return !profileHasPassphrase(profile, n.SSID, n.Passphrase)
+}
+
+// profileHasPassphrase reports whether an IWD profile's [Security] section
+// holds passphrase, as the derived PreSharedKey or in clear
+func profileHasPassphrase(profile []byte, ssid, passphrase string) bool {
+	section := ""
+	for _, line := range bytes.Split(profile, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) > 1 && line[0] == '[' && line[len(line)-1] == ']' {
+			section = string(line[1 : len(line)-1])
+			continue
+		}
+		key, value, ok := bytes.Cut(line, []byte("="))
+		if !ok || section != "Security" {
+			continue
+		}
+		switch string(bytes.TrimSpace(key)) {
+		case "PreSharedKey":
+			stored, err := hex.DecodeString(string(bytes.TrimSpace(value)))
+			if err != nil {
+				return false
+			}
+			psk := preSharedKey([]byte(passphrase), ssid)
+			defer zero(psk)
+			return hmac.Equal(stored, psk)
+		case "Passphrase":
+			return string(value) == passphrase
+		}
+	}
+	return false
+}
+
+// forgetKnown removes a known network profile (and its keyring entry)
+func (c *Client) forgetKnown(k knownNetwork) error {
+	if c.creds != nil {
+		if err := c.creds.Delete(k.Name); err != nil {
+			log.Printf("Failed to remove keyring credential for %s: %v", k.Name, err)
+		}
+	}
+	if err := c.conn.Object(IWDService, k.Path).Call(KnownNetworkIface+".Forget", 0).Err; err != nil {
+		return fmt.Errorf("%s: %w", k.Name, err)
+	}
+	return nil
+}
+
+// iwdConfigName returns IWD's file name stem for ssid: the SSID itself when it
+// is plain alphanumeric/-/_, otherwise "=" followed by its hex encoding
+func iwdConfigName(ssid string) string {
+	for _, r := range ssid {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return "=" + hex.EncodeToString([]byte(ssid))
+		}
+	}
+	return ssid
+}
+
+// preSharedKey derives the WPA2 PSK from a passphrase (IEEE 802.11i:
+// PBKDF2-HMAC-SHA1, SSID as salt, 4096 rounds, 256 bits)
+func preSharedKey(passphrase []byte, ssid string) []byte {
+	const rounds, blocks = 4096, 2 // 2 SHA-1 blocks cover 32 bytes
+	prf := hmac.New(sha1.New, passphrase)
+	key := make([]byte, 0, blocks*sha1.Size)
+	for block := 1; block <= blocks; block++ {
+		prf.Reset()
+		prf.Write([]byte(ssid))
+		prf.Write([]byte{0, 0, 0, byte(block)})
+		u := prf.Sum(nil)
+		t := append([]byte(nil), u...)
+		for i := 1; i < rounds; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+		zero(t)
+		zero(u)
+	}
+	return key[:32]
+}
+
+// iwdConfigPath returns the profile path: IWD stores configs in
+// /var/lib/iwd/SSID.psk (or .open, .8021x)
+func iwdConfigPath(ssid, security string) string {
+	return fmt.Sprintf("/var/lib/iwd/%s.%s", iwdConfigName(ssid), security)
+}
+
+// readIWDConfig reads a network profile using sudo (the directory is root-only)
+func readIWDConfig(ssid, security string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("sudo", "-n", "cat", "--", iwdConfigPath(ssid, security))
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read IWD config: %v (%s)", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return out, nil
+}
+
+// writeIWDConfig writes a network profile to /var/lib/iwd using sudo
+// Only the derived PreSharedKey goes into the file, never the passphrase
+func (c *Client) writeIWDConfig(ssid, security string, passphrase []byte, autoConnect bool) error {
+	configPath := iwdConfigPath(ssid, security)
+
+	// Sized up front so the key is never copied into a discarded buffer
+	content := make([]byte, 0, 128)
+	defer func() { zero(content) }()
+	if !autoConnect {
+		content = append(content, "[Settings]\nAutoConnect=false\n\n"...)
+	}
+	if len(passphrase) > 0 {
+		psk := preSharedKey(passphrase, ssid)
+		content = append(content, "[Security]\nPreSharedKey="...)
+		content = hex.AppendEncode(content, psk)
+		content = append(content, '\n')
+		zero(psk)
+	}
+
+	// Pipe to tee for the privileged write; the file must be created 0600
+	cmd := exec.Command("sudo", "-n", "sh", "-c", `umask 077 && tee "$1" >/dev/null`, "sh", configPath)
+	cmd.Stdin = bytes.NewReader(content)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to write IWD config: %v (%s)", err, bytes.TrimSpace(out))
+	}
+
+	log.Printf("Wrote IWD config for %s", ssid)
+	return nil
+}
@@ -0,0 +1,177 @@
+package iwd
+
+import (
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPreSharedKey(t *testing.T) {
+	// IEEE 802.11i-2004, annex H.4 test vectors
+	tests := []struct {
+		passphrase, ssid, want string
+	}{
+		{"password", "IEEE", "f42c6fc52df0ebef9ebb4b90b38a5f902e83fe1b135a70e23aed762e9710a12e"},
+		{"ThisIsAPassword", "ThisIsASSID", "0dc0d6eb90555ed6419756b9a15ec3e3209b63df707dd508d14581f8982721af"},
+	}
+	for _, tt := range tests {
+		if got := hex.EncodeToString(preSharedKey([]byte(tt.passphrase), tt.ssid)); got != tt.want {
+			t.Errorf("preSharedKey(%q, %q) = %s, want %s", tt.passphrase, tt.ssid, got, tt.want)
+		}
+	}
+}
+
+func boolPtr(b bool) *bool         { return &b }
+func int32Ptr(i int32) *int32      { return &i }
+func unchanged(PolicyNetwork) bool { return false }
+
+// actionKinds renders a plan as "kind:ssid" entries
+func actionKinds(actions []policyAction) []string {
+	var kinds []string
+	for _, a := range actions {
+		ssid := a.network.SSID
+		if a.kind == "forget" {
+			ssid = a.known.Name
+		}
+		kinds = append(kinds, a.kind+":"+ssid)
+	}
+	return kinds
+}
+
+func TestPlanPolicy(t *testing.T) {
+	known := []knownNetwork{
+		{Path: "/k/home", Name: "home", Type: "psk", AutoConnect: true},
+		{Path: "/k/cafe", Name: "cafe", Type: "open", AutoConnect: true},
+		{Path: "/k/old", Name: "old", Type: "psk", AutoConnect: true},
+	}
+	tests := []struct {
+		name    string
+		policy  Policy
+		current policyCurrent
+		want    []string
+	}{
+		{
+			name: "in sync",
+			policy: Policy{Networks: []PolicyNetwork{
+				{SSID: "home", Security: "psk", Passphrase: "password1", AutoConnect: boolPtr(true), Priority: int32Ptr(5)},
+				{SSID: "cafe", Security: "open", Blocked: boolPtr(false)},
+			}},
+			current: policyCurrent{priorities: map[string]int32{"home": 5}},
+		},
+		{
+			name:   "add missing",
+			policy: Policy{Networks: []PolicyNetwork{{SSID: "new", Security: "psk", Passphrase: "password1"}}},
+			want:   []string{"add:new"},
+		},
+		{
+			name:   "security changed",
+			policy: Policy{Networks: []PolicyNetwork{{SSID: "cafe", Security: "psk", Passphrase: "password1"}}},
+			want:   []string{"add:cafe"},
+		},
+		{
+			name:    "passphrase changed",
+			policy:  Policy{Networks: []PolicyNetwork{{SSID: "home", Security: "psk", Passphrase: "password2"}}},
+			current: policyCurrent{passphraseChanged: func(PolicyNetwork) bool { return true }},
+			want:    []string{"passphrase:home"},
+		},
+		{
+			name:   "autoconnect changed",
+			policy: Policy{Networks: []PolicyNetwork{{SSID: "cafe", Security: "open", AutoConnect: boolPtr(false)}}},
+			want:   []string{"autoconnect:cafe"},
+		},
+		{
+			name: "priority and blacklist after profiles",
+			policy: Policy{Networks: []PolicyNetwork{
+				{SSID: "cafe", Security: "open", Priority: int32Ptr(3), Blocked: boolPtr(true)},
+				{SSID: "new", Security: "open"},
+			}},
+			want: []string{"add:new", "priority:cafe", "block:cafe"},
+		},
+		{
+			name:    "priority cleared and unblocked",
+			policy:  Policy{Networks: []PolicyNetwork{{SSID: "home", Security: "psk", Passphrase: "password1", Priority: int32Ptr(0), Blocked: boolPtr(false)}}},
+			current: policyCurrent{priorities: map[string]int32{"home": 5}, blocked: map[string]bool{"home": true}},
+			want:    []string{"priority:home", "block:home"},
+		},
+		{
+			name:   "remove unlisted",
+			policy: Policy{Networks: []PolicyNetwork{{SSID: "home", Security: "psk", Passphrase: "password1"}}, RemoveUnlisted: true},
+			want:   []string{"forget:cafe", "forget:old"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cur := tt.current
+			cur.known = known
+			if cur.passphraseChanged == nil {
+				cur.passphraseChanged = unchanged
+			}
+			if err := tt.policy.Validate(); err != nil {
+				t.Fatalf("Validate() = %v", err)
+			}
+			got := actionKinds(planPolicy(&tt.policy, cur))
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Fatalf("planPolicy() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		network PolicyNetwork
+		ok      bool
+	}{
+		{"psk", PolicyNetwork{SSID: "home", Passphrase: "password1"}, true},
+		{"open", PolicyNetwork{SSID: "cafe"}, true},
+		{"short passphrase", PolicyNetwork{SSID: "home", Passphrase: "short"}, false},
+		{"newline in passphrase", PolicyNetwork{SSID: "home", Passphrase: "password1\nAutoConnect=true"}, false},
+		{"control character in passphrase", PolicyNetwork{SSID: "home", Passphrase: "pass\x7fword1"}, false},
+		{"open with passphrase", PolicyNetwork{SSID: "cafe", Security: "open", Passphrase: "password1"}, false},
+		{"blocked autoconnect", PolicyNetwork{SSID: "cafe", Blocked: boolPtr(true), AutoConnect: boolPtr(true)}, false},
+		{"unsupported security", PolicyNetwork{SSID: "corp", Security: "8021x"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := Policy{Networks: []PolicyNetwork{tt.network}}
+			if err := p.Validate(); (err == nil) != tt.ok {
+				t.Fatalf("Validate() = %v, want ok=%v", err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestProfileHasPassphrase(t *testing.T) {
+	psk := hex.EncodeToString(preSharedKey([]byte("password1"), "home"))
+	tests := []struct {
+		name    string
+		profile string
+		want    bool
+	}{
+		{"same key", "[Security]\nPreSharedKey=" + psk + "\n", true},
+		{"other key", "[Security]\nPreSharedKey=" + strings.Repeat("00", 32) + "\n", false},
+		{"clear passphrase", "[Settings]\nAutoConnect=false\n\n[Security]\nPassphrase=password1\n", true},
+		{"other passphrase", "[Security]\nPassphrase=password2\n", false},
+		{"key outside security", "[Settings]\nPreSharedKey=" + psk + "\n", false},
+		{"no key", "[Settings]\nAutoConnect=false\n", false},
+	}
+	for _, tt := range tests {
+		if got := profileHasPassphrase([]byte(tt.profile), "home", "password1"); got != tt.want {
+			t.Errorf("%s: profileHasPassphrase() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLoadPolicyRejectsUnsupportedKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	data := `{"networks": [{"ssid": "home", "passphrase": "password1", "band": "5"}]}`
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadPolicy(path); err == nil {
+		t.Fatal("LoadPolicy() accepted a band preference")
+	}
+}
@@ -0,0 +1,138 @@
+package iwd
+
+import (
+	"errors"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// iwdRestartGrace is how long after IWD left the bus WaitReady still expects
+// it back (systemd restart, package upgrade) instead of failing at once
+const iwdRestartGrace = 10 * time.Second
+
+// ownerPollInterval is how often WaitReady re-checks that IWD is on the bus
+const ownerPollInterval = 250 * time.Millisecond
+
+// Readiness errors for callers racing an IWD (re)start
+var (
+	ErrIWDUnavailable = errors.New("IWD not available")
+	ErrNotReady       = errors.New("WiFi is initializing, retry shortly")
+)
+
+// readiness is the station the client is bound to, replaced as IWD comes and goes
+type readiness struct {
+	initialized bool
+	devicePath  dbus.ObjectPath
+	stationPath dbus.ObjectPath
+	ready       chan struct{} // Closed once initialized with a station
+	goneAt      time.Time     // When IWD last left the bus
+}
+
+// newReadiness returns the not-yet-initialized state
+func newReadiness() readiness {
+	return readiness{ready: make(chan struct{})}
+}
+
+// station returns the Station object path ("" while not initialized)
+func (c *Client) station() dbus.ObjectPath {
+	c.readyMu.Lock()
+	defer c.readyMu.Unlock()
+	return c.rd.stationPath
+}
+
+// device returns the Device object path ("" while not initialized)
+func (c *Client) device() dbus.ObjectPath {
+	c.readyMu.Lock()
+	defer c.readyMu.Unlock()
+	return c.rd.devicePath
+}
+
+// isInitialized reports whether maybeInitIWD completed since IWD last appeared
+func (c *Client) isInitialized() bool {
+	c.readyMu.Lock()
+	defer c.readyMu.Unlock()
+	return c.rd.initialized
+}
+
+// setPaths records the objects found by findDevice
+func (c *Client) setPaths(device, station dbus.ObjectPath) {
+	c.readyMu.Lock()
+	defer c.readyMu.Unlock()
+	c.rd.devicePath = device
+	c.rd.stationPath = station
+}
+
+// markReady flags the client initialized and wakes WaitReady callers
+func (c *Client) markReady() {
+	c.readyMu.Lock()
+	defer c.readyMu.Unlock()
+	if c.rd.initialized {
+		return
+	}
+	c.rd.initialized = true
+	close(c.rd.ready)
+}
+
+// markGone drops the station after IWD left the bus; WaitReady callers
+// block on a fresh channel until the next init
+func (c *Client) markGone() {
+	c.readyMu.Lock()
+	defer c.readyMu.Unlock()
+	if c.rd.initialized {
+		c.rd.ready = make(chan struct{})
+	}
+	c.rd.initialized = false
+	c.rd.devicePath = ""
+	c.rd.stationPath = ""
+	c.rd.goneAt = time.Now()
+}
+
+// readyState returns the channel closed on readiness and whether IWD left
+// the bus recently enough to still be expected back
+func (c *Client) readyState() (<-chan struct{}, bool) {
+	c.readyMu.Lock()
+	defer c.readyMu.Unlock()
+	restarting := !c.rd.goneAt.IsZero() && time.Since(c.rd.goneAt) < iwdRestartGrace
+	return c.rd.ready, restarting
+}
+
+// iwdOnBus reports whether IWD currently owns its bus name
+func (c *Client) iwdOnBus() bool {
+	if c.onBus != nil {
+		return c.onBus()
+	}
+	var hasOwner bool
+	err := c.conn.BusObject().Call("org.freedesktop.DBus.NameHasOwner", 0, IWDService).Store(&hasOwner)
+	return err == nil && hasOwner
+}
+
+// WaitReady waits up to timeout for the client to finish (re)initializing
+// Returns ErrIWDUnavailable when IWD isn't on the bus and didn't just restart,
+// ErrNotReady when the timeout passes before the station is set up
+func (c *Client) WaitReady(timeout time.Duration) error {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	poll := time.NewTicker(ownerPollInterval)
+	defer poll.Stop()
+
+	for {
+		ready, restarting := c.readyState()
+		select {
+		case <-ready:
+			return nil
+		default:
+		}
+		if !restarting && !c.iwdOnBus() {
+			return ErrIWDUnavailable
+		}
+
+		select {
+		case <-ready:
+			return nil
+		case <-deadline.C:
+			return ErrNotReady
+		case <-poll.C:
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package iwd
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestClient(onBus bool) *Client {
+	c := &Client{rd: newReadiness()}
+	c.onBus = func() bool { return onBus }
+	return c
+}
+
+func TestWaitReadyAlreadyReady(t *testing.T) {
+	c := newTestClient(true)
+	c.setPaths("/net/connman/iwd/0/3", "/net/connman/iwd/0/3")
+	c.markReady()
+	if err := c.WaitReady(time.Second); err != nil {
+		t.Fatalf("WaitReady() = %v, want nil", err)
+	}
+}
+
+func TestWaitReadyWakesOnInit(t *testing.T) {
+	c := newTestClient(true)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		c.markReady()
+	}()
+
+	start := time.Now()
+	if err := c.WaitReady(5 * time.Second); err != nil {
+		t.Fatalf("WaitReady() = %v, want nil", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("WaitReady() took %s after init", elapsed)
+	}
+}
+
+func TestWaitReadyUnavailable(t *testing.T) {
+	c := newTestClient(false)
+	if err := c.WaitReady(time.Second); !errors.Is(err, ErrIWDUnavailable) {
+		t.Fatalf("WaitReady() = %v, want ErrIWDUnavailable", err)
+	}
+}
+
+func TestWaitReadyTimeout(t *testing.T) {
+	c := newTestClient(true)
+	if err := c.WaitReady(50 * time.Millisecond); !errors.Is(err, ErrNotReady) {
+		t.Fatalf("WaitReady() = %v, want ErrNotReady", err)
+	}
+}
+
+func TestWaitReadyThroughRestart(t *testing.T) {
+	// IWD left the bus and isn't back yet: callers wait instead of failing
+	c := newTestClient(false)
+	c.markReady()
+	c.markGone()
+	if c.station() != "" || c.isInitialized() {
+		t.Fatal("markGone kept the old station")
+	}
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		c.setPaths("/net/connman/iwd/0/4", "/net/connman/iwd/0/4")
+		c.markReady()
+	}()
+	if err := c.WaitReady(5 * time.Second); err != nil {
+		t.Fatalf("WaitReady() = %v, want nil after re-init", err)
+	}
+	if c.station() != "/net/connman/iwd/0/4" {
+		t.Errorf("station() = %q after re-init", c.station())
+	}
+}
+
+func TestReadinessConcurrent(t *testing.T) {
+	c := newTestClient(true)
+	var stop atomic.Bool
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for !stop.Load() {
+			c.setPaths("/a", "/a")
+			c.markReady()
+			c.markGone()
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for !stop.Load() {
+			_ = c.station()
+			_ = c.WaitReady(time.Millisecond)
+		}
+	}()
+	time.Sleep(50 * time.Millisecond)
+	stop.Store(true)
+	wg.Wait()
+}
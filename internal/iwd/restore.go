@@ -0,0 +1,98 @@
+package iwd
+
+import (
+	"log"
+	"time"
+
+	"x-network/internal/state"
+)
+
+const (
+	// restoreDelay gives IWD's own autoconnect a chance before we step in
+	restoreDelay = 5 * time.Second
+
+	// userDisconnectGuard: a Disconnect this recent means the user wanted off
+	userDisconnectGuard = 30 * time.Second
+)
+
+// rememberForRestore records the active saved network when IWD goes away
+func (c *Client) rememberForRestore(st state.State) {
+	c.restoreMu.Lock()
+	defer c.restoreMu.Unlock()
+
+	c.restoreSSID = ""
+	if st.ActiveSSID == "" || st.ConnectionState != state.StateConnected {
+		return
+	}
+	if time.Since(c.userDisconnectAt) < userDisconnectGuard {
+		log.Printf("Not restoring %s after IWD restart: user disconnected", st.ActiveSSID)
+		return
+	}
+	for _, saved := range st.SavedNetworks {
+		if saved == st.ActiveSSID {
+			c.restoreSSID = st.ActiveSSID
+			log.Printf("Will reconnect to %s once IWD is back", st.ActiveSSID)
+			return
+		}
+	}
+}
+
+// noteUserDisconnect cancels any pending restore
+func (c *Client) noteUserDisconnect() {
+	c.restoreMu.Lock()
+	c.userDisconnectAt = time.Now()
+	c.restoreSSID = ""
+	c.restoreMu.Unlock()
+}
+
+// localDisconnectWindow: a link loss this soon after our own Disconnect is ours
+const localDisconnectWindow = 5 * time.Second
+
+// linkLossReason explains an established connection going down, from what the
+// daemon knows for certain (our Disconnect, radio off); "" when IWD gave no cause
+func (c *Client) linkLossReason(st *state.State) string {
+	c.restoreMu.Lock()
+	local := time.Since(c.userDisconnectAt) < localDisconnectWindow
+	c.restoreMu.Unlock()
+
+	switch {
+	case local:
+		return state.DisconnectReasonLocal
+	case !st.WifiEnabled || st.WifiBlocked.Soft || st.WifiBlocked.Hard:
+		return state.DisconnectReasonRadioOff
+	}
+	return ""
+}
+
+// restoreConnection reconnects to the remembered network after re-init,
+// unless IWD autoconnected or the user acted in the meantime
+func (c *Client) restoreConnection() {
+	c.restoreMu.Lock()
+	ssid := c.restoreSSID
+	c.restoreMu.Unlock()
+	if ssid == "" {
+		return
+	}
+
+	time.Sleep(restoreDelay)
+
+	c.restoreMu.Lock()
+	if c.restoreSSID != ssid {
+		c.restoreMu.Unlock()
+		return // Cancelled by a user Disconnect
+	}
+	c.restoreSSID = ""
+	c.restoreMu.Unlock()
+
+	st := c.stateMgr.Get()
+	if st.ConnectionState == state.StateConnected || st.ConnectionState == state.StateConnecting ||
+		st.ConnectionState == state.StateObtaining {
+		log.Printf("IWD restart: already %s, not restoring %s", st.ConnectionState, ssid)
+		return
+	}
+
+	log.Printf("IWD restart: reconnecting to %s", ssid)
+	if err := c.ConnectSaved(ssid); err != nil {
+		log.Printf("IWD restart: reconnect to %s failed: %v", ssid, err)
+	}
+}
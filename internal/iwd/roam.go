@@ -0,0 +1,89 @@
+package iwd
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"x-network/internal/state"
+)
+
+// RoamThreshold is the minimum signal gain (dB) that justifies a reassociation
+const RoamThreshold int16 = 8
+
+var (
+	// ErrNoAlternativeAP is returned by FindRoamTarget when the network has no other BSS in range
+	ErrNoAlternativeAP = errors.New("no other access point for this network")
+
+	// ErrRoamNotWorthwhile is returned when the best alternative isn't RoamThreshold stronger
+	ErrRoamNotWorthwhile = errors.New("no access point is significantly stronger")
+)
+
+// FindRoamTarget scans and returns the current BSS and the strongest other BSS
+// of the connected network, failing if the gain is below RoamThreshold
+// Fails with ErrRoamUnsupported up front when IWD can't switch BSS in place
+func (c *Client) FindRoamTarget() (current, target AccessPoint, err error) {
+	st := c.stateMgr.Get()
+	if st.ConnectionState != state.StateConnected || st.ActiveSSID == "" {
+		return current, target, errors.New("not connected")
+	}
+	if !c.hasStationDebug() {
+		return current, target, ErrRoamUnsupported
+	}
+
+	bssid, err := c.connectedBSS()
+	if err != nil {
+		return current, target, fmt.Errorf("cannot read connected BSS: %w", err)
+	}
+	if _, err := c.Scan(); err != nil {
+		return current, target, err
+	}
+	aps, err := c.GetAccessPoints(st.ActiveSSID)
+	if err != nil {
+		return current, target, err
+	}
+
+	found := false
+	for _, ap := range aps {
+		if ap.BSSID == bssid {
+			current, found = ap, true
+		} else if target.BSSID == "" {
+			target = ap // Strongest first
+		}
+	}
+	if !found {
+		// Not in the scan cache - fall back to the station's own reading
+		current = AccessPoint{BSSID: bssid, SSID: st.ActiveSSID, SignalDBm: st.SignalRSSI, Connected: true}
+	}
+	if target.BSSID == "" {
+		return current, target, ErrNoAlternativeAP
+	}
+	if gain := target.SignalDBm - current.SignalDBm; gain < RoamThreshold {
+		return current, target, fmt.Errorf("%w: best is %s at %d dBm vs %d dBm (+%d dB, need +%d)",
+			ErrRoamNotWorthwhile, target.BSSID, target.SignalDBm, current.SignalDBm, gain, RoamThreshold)
+	}
+	return current, target, nil
+}
+
+// RoamTo reassociates with target through IWD developer mode (StationDebug),
+// verified afterwards. Without it ErrRoamUnsupported is returned and the
+// current link is left alone: a plain reconnect can't be pinned to a BSS
+// A no-op when IWD already moved to target since FindRoamTarget, so a late
+// call never bounces a connection that is already where we want it
+func (c *Client) RoamTo(target AccessPoint) error {
+	if bssid, err := c.connectedBSS(); err == nil && bssid == strings.ToLower(target.BSSID) {
+		log.Printf("Already on %s, not roaming", target.BSSID)
+		return nil
+	}
+	log.Printf("Roaming %s to %s (%d dBm)", target.SSID, target.BSSID, target.SignalDBm)
+
+	ok, err := c.connectToBSS(target.BSSID)
+	if !ok {
+		return ErrRoamUnsupported
+	}
+	if err != nil {
+		return err
+	}
+	return c.verifyBSS(target.BSSID)
+}
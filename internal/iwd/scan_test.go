@@ -0,0 +1,124 @@
+package iwd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"x-network/internal/state"
+)
+
+func TestScanServesRecentResults(t *testing.T) {
+	// No D-Bus connection: a real scan would panic
+	c := &Client{stateMgr: state.NewManager()}
+	cached := []state.Network{{SSID: "cafe"}}
+	c.stateMgr.Update(func(st *state.State) {
+		st.Networks = cached
+		st.LastScanTime = time.Now().Add(-scanCacheWindow + time.Second)
+	})
+
+	networks, err := c.Scan()
+	if err != nil || len(networks) != 1 || networks[0].SSID != "cafe" {
+		t.Fatalf("Scan() = %v, %v; want the cached results", networks, err)
+	}
+}
+
+func TestPublishCancelledScan(t *testing.T) {
+	partial := []state.Network{{SSID: "cafe"}}
+	for _, stillScanning := range []bool{true, false} {
+		c := &Client{stateMgr: state.NewManager()}
+		c.stateMgr.Update(func(st *state.State) { st.WifiScanning = true })
+
+		c.publishCancelledScan(partial, stillScanning)
+		st := c.stateMgr.Get()
+		if st.WifiScanning != stillScanning {
+			t.Errorf("radio scanning=%v: WifiScanning = %v", stillScanning, st.WifiScanning)
+		}
+		if len(st.Networks) != 1 {
+			t.Errorf("radio scanning=%v: partial results not published", stillScanning)
+		}
+	}
+}
+
+// fakeIW installs a script as iw that logs its arguments to calls in dir and
+// then runs body; returns the log path
+func fakeIW(t *testing.T, dir, body string) string {
+	t.Helper()
+	logPath := filepath.Join(dir, "calls")
+	script := "#!/bin/sh\necho \"$*\" >> " + logPath + "\n" + body
+	path := filepath.Join(dir, "iw")
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	old := iwCommand
+	iwCommand = path
+	t.Cleanup(func() { iwCommand = old })
+	return logPath
+}
+
+func TestTunedScanCancel(t *testing.T) {
+	// Sleeps through scans until interrupted
+	logPath := fakeIW(t, t.TempDir(), "case \"$*\" in *abort*) exit 0 ;; esac\nexec sleep 10\n")
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	err := tunedScan(ctx, "wlan0", state.State{ScanMode: ScanModePassive}, nil)
+	if !errors.Is(err, ErrScanCancelled) {
+		t.Fatalf("tunedScan() = %v, want ErrScanCancelled", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("tunedScan() returned %s after the cancel", elapsed)
+	}
+
+	calls, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "dev wlan0 scan passive\ndev wlan0 scan abort\n"
+	if string(calls) != want {
+		t.Errorf("iw calls = %q, want %q", calls, want)
+	}
+}
+
+func TestTunedScanWaitsOutBusyRadio(t *testing.T) {
+	dir := t.TempDir()
+	// Fails with EBUSY while the busy marker exists, consuming one per call
+	logPath := fakeIW(t, dir, "for f in "+dir+"/busy*; do\n"+
+		"  [ -e \"$f\" ] && rm \"$f\" && echo 'command failed: Device or resource busy (-16)' >&2 && exit 240\n"+
+		"done\nexit 0\n")
+	busy := func(n int) {
+		for i := 0; i < n; i++ {
+			if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("busy%d", i)), nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	idleWaits := 0
+	waitIdle := func(context.Context) { idleWaits++ }
+	st := state.State{ScanMode: ScanModePassive}
+
+	busy(1)
+	if err := tunedScan(context.Background(), "wlan0", st, waitIdle); err != nil {
+		t.Fatalf("tunedScan() = %v, want the retry to succeed", err)
+	}
+	if idleWaits != 2 {
+		t.Errorf("waited for IWD %d times, want before each of the 2 attempts", idleWaits)
+	}
+	calls, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "dev wlan0 scan passive\ndev wlan0 scan passive\n"; string(calls) != want {
+		t.Errorf("iw calls = %q, want %q", calls, want)
+	}
+
+	busy(scanBusyRetries + 1)
+	if err := tunedScan(context.Background(), "wlan0", st, waitIdle); !errors.Is(err, errScanBusy) {
+		t.Errorf("tunedScan() on a radio that stays busy = %v, want errScanBusy", err)
+	}
+}
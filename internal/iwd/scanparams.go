@@ -0,0 +1,183 @@
+package iwd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"x-network/internal/state"
+)
+
+// Scan modes accepted by SetScanParams
+const (
+	ScanModeActive  = "active"  // Probe requests on every channel (fast, more power)
+	ScanModePassive = "passive" // Listen for beacons only (slow, less power)
+)
+
+// Dwell bounds in milliseconds; 0 means driver default
+const (
+	minScanDwellMs = 10
+	maxScanDwellMs = 1000
+)
+
+// maxScanSSIDsRe matches the active-scan capability line of `iw phy info`
+var maxScanSSIDsRe = regexp.MustCompile(`max # scan SSIDs:\s*(\d+)`)
+
+// SetScanParams validates scan tuning against the adapter and stores it
+// Defaults (active, dwell 0) keep IWD's own scanning
+func (c *Client) SetScanParams(mode string, dwellMs uint32) error {
+	if mode == "" {
+		mode = ScanModeActive
+	}
+	if mode != ScanModeActive && mode != ScanModePassive {
+		return fmt.Errorf("unsupported scan mode: %s", mode)
+	}
+	if dwellMs != 0 && (dwellMs < minScanDwellMs || dwellMs > maxScanDwellMs) {
+		return fmt.Errorf("dwell time must be %d-%d ms (or 0 for driver default)", minScanDwellMs, maxScanDwellMs)
+	}
+
+	// Custom params need the adapter to support them
+	if mode != ScanModeActive || dwellMs != 0 {
+		info, err := c.phyInfo()
+		if err != nil {
+			return fmt.Errorf("cannot query adapter capabilities: %w", err)
+		}
+		if err := validateScanCapabilities(info, mode, dwellMs); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("Scan params: mode=%s dwell=%dms", mode, dwellMs)
+	c.stateMgr.Update(func(st *state.State) {
+		st.ScanMode = mode
+		st.ScanDwellMs = dwellMs
+	})
+	return nil
+}
+
+// validateScanCapabilities checks `iw phy info` output for the requested features
+func validateScanCapabilities(info, mode string, dwellMs uint32) error {
+	if mode == ScanModeActive {
+		m := maxScanSSIDsRe.FindStringSubmatch(info)
+		if m == nil {
+			return fmt.Errorf("adapter does not report active scan support")
+		}
+		if n, _ := strconv.Atoi(m[1]); n == 0 {
+			return fmt.Errorf("adapter does not support active scanning")
+		}
+	}
+	if dwellMs != 0 && !strings.Contains(info, "SET_SCAN_DWELL") {
+		return fmt.Errorf("adapter does not support setting scan dwell time")
+	}
+	return nil
+}
+
+// hasCustomScanParams reports whether scans must bypass IWD's default scan
+func hasCustomScanParams(st state.State) bool {
+	return st.ScanMode == ScanModePassive || st.ScanDwellMs != 0
+}
+
+// iwCommand is the nl80211 tool tuned scans run
+var iwCommand = "iw"
+
+// scanBusyRetries is how often a tuned scan waits out a scan it collided with
+const scanBusyRetries = 2
+
+// errScanBusy is nl80211's EBUSY: another scan, usually IWD's own, holds the radio
+var errScanBusy = errors.New("another scan is running")
+
+// tunedScan runs a blocking nl80211 scan with the configured mode/dwell on iface
+// IWD picks up externally triggered scan results, so the network list refreshes as usual
+// waitIdle (optional) blocks while IWD scans so the two never run at once;
+// a collision anyway (EBUSY) is waited out and retried
+// Cancelling ctx stops iw and aborts the kernel scan; it returns ErrScanCancelled
+func tunedScan(ctx context.Context, iface string, st state.State, waitIdle func(context.Context)) error {
+	if iface == "" {
+		return fmt.Errorf("no WiFi device")
+	}
+
+	args := []string{"dev", iface, "scan"}
+	if st.ScanDwellMs != 0 {
+		// iw takes the duration in TUs (1 TU = 1.024 ms)
+		tu := uint32(float64(st.ScanDwellMs) / 1.024)
+		args = append(args, "duration", strconv.FormatUint(uint64(tu), 10))
+	}
+	if st.ScanMode == ScanModePassive {
+		args = append(args, "passive")
+	}
+
+	var err error
+	for attempt := 0; ; attempt++ {
+		if waitIdle != nil {
+			waitIdle(ctx)
+		}
+		err = runIW(ctx, args...)
+		if !errors.Is(err, errScanBusy) || attempt == scanBusyRetries || ctx.Err() != nil {
+			break
+		}
+		log.Printf("Tuned scan on %s collided with another scan, retrying", iface)
+	}
+	if ctx.Err() != nil {
+		// The kernel scan outlives iw; abort it so the radio is free again
+		if err := runIW(context.Background(), "dev", iface, "scan", "abort"); err != nil {
+			log.Printf("Failed to abort tuned scan on %s: %v", iface, err)
+		}
+		return ErrScanCancelled
+	}
+	if err != nil {
+		return fmt.Errorf("tuned scan failed: %w", err)
+	}
+	return nil
+}
+
+// runIW runs iw, retrying through sudo since triggering scans needs CAP_NET_ADMIN
+// Cancelling ctx interrupts it (sudo passes SIGINT on to iw)
+func runIW(ctx context.Context, args ...string) error {
+	out, err := iwCmd(ctx, iwCommand, args...).CombinedOutput()
+	if err == nil || ctx.Err() != nil || isScanBusy(out) {
+		return iwError(err, out)
+	}
+	if sudoOut, sudoErr := iwCmd(ctx, "sudo", append([]string{"-n", iwCommand}, args...)...).CombinedOutput(); sudoErr != nil {
+		if isScanBusy(sudoOut) {
+			return errScanBusy
+		}
+		return iwError(err, out)
+	}
+	return nil
+}
+
+// isScanBusy reports whether iw failed with EBUSY
+// ("command failed: Device or resource busy (-16)")
+func isScanBusy(out []byte) bool {
+	return bytes.Contains(out, []byte("(-16)"))
+}
+
+// iwError turns a failed iw run into an error carrying its message
+func iwError(err error, out []byte) error {
+	switch {
+	case err == nil:
+		return nil
+	case isScanBusy(out):
+		return errScanBusy
+	}
+	if msg := strings.TrimSpace(string(out)); msg != "" {
+		return fmt.Errorf("%v: %s", err, msg)
+	}
+	return err
+}
+
+// iwCmd builds a command that gets SIGINT, not SIGKILL, when ctx is cancelled
+func iwCmd(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Cancel = func() error { return cmd.Process.Signal(os.Interrupt) }
+	cmd.WaitDelay = time.Second
+	return cmd
+}
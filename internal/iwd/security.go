@@ -0,0 +1,73 @@
+package iwd
+
+import (
+	"bufio"
+	"log"
+	"os/exec"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// securityDowngraded compares the connected BSS's advertised AKMs with the
+// negotiated security in diag and flags WPA3-capable networks joined over WPA2
+func (c *Client) securityDowngraded(diag map[string]dbus.Variant) bool {
+	negotiated, _ := diag["Security"].Value().(string)
+	bssid, _ := diag["ConnectedBss"].Value().(string)
+	if negotiated == "" || bssid == "" {
+		return false
+	}
+
+	akms := c.advertisedAKMs(bssid)
+	downgraded := isSecurityDowngraded(akms, negotiated)
+	if downgraded {
+		log.Printf("Security downgrade: %s offers %v but negotiated %s", bssid, akms, negotiated)
+	}
+	return downgraded
+}
+
+// isSecurityDowngraded reports whether a BSS offering SAE (WPA3 transition mode)
+// was joined with a WPA2 key management
+func isSecurityDowngraded(advertised []string, negotiated string) bool {
+	offersSAE := false
+	for _, akm := range advertised {
+		if akm == "SAE" || akm == "FT/SAE" {
+			offersSAE = true
+			break
+		}
+	}
+	return offersSAE && !strings.Contains(negotiated, "WPA3")
+}
+
+// advertisedAKMs returns the RSN authentication suites of bssid from the
+// kernel's scan cache (`iw dev <iface> scan dump`)
+func (c *Client) advertisedAKMs(bssid string) []string {
+	iface := c.deviceName()
+	if iface == "" {
+		return nil
+	}
+
+	out, err := exec.Command("iw", "dev", iface, "scan", "dump").Output()
+	if err != nil {
+		log.Printf("Cannot read scan cache: %v", err)
+		return nil
+	}
+
+	inBSS := false
+	scanner := bufio.NewScanner(strings.NewReader(string(out)))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "BSS ") {
+			// "BSS aa:bb:cc:dd:ee:ff(on wlan0) -- associated"
+			inBSS = strings.HasPrefix(strings.ToLower(line[4:]), strings.ToLower(bssid))
+			continue
+		}
+		if !inBSS {
+			continue
+		}
+		if i := strings.Index(line, "Authentication suites:"); i >= 0 {
+			return strings.Fields(line[i+len("Authentication suites:"):])
+		}
+	}
+	return nil
+}
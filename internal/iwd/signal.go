@@ -0,0 +1,122 @@
+package iwd
+
+import (
+	"log"
+	"sync"
+
+	"x-network/internal/state"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	// DefaultSignalAlpha is the EWMA weight of each new RSSI sample
+	DefaultSignalAlpha = 0.3
+
+	// DefaultSignalDeltaPercent / DefaultSignalDeltaDBm are how far the
+	// smoothed signal must move before SignalStrength/SignalRSSI are republished
+	DefaultSignalDeltaPercent = 5
+	DefaultSignalDeltaDBm     = 3
+)
+
+// signalFilter smooths the active connection's RSSI with an exponential
+// moving average; reset on connect, disconnect and roam so a new AP doesn't
+// inherit the old one's history
+type signalFilter struct {
+	mu     sync.Mutex
+	alpha  float64
+	value  float64
+	primed bool
+
+	// Hysteresis: publish when the percentage moves by deltaPct or the dBm by more than deltaDBm
+	deltaPct int
+	deltaDBm int
+}
+
+// add feeds one sample and returns the smoothed dBm and whether it is the
+// first sample since reset
+func (f *signalFilter) add(dBm int16) (int16, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	first := !f.primed
+	if first {
+		f.value = float64(dBm)
+		f.primed = true
+	} else {
+		f.value += f.alpha * (float64(dBm) - f.value)
+	}
+	return int16(f.value - 0.5), first // Round half away from zero (dBm is negative)
+}
+
+func (f *signalFilter) reset() {
+	f.mu.Lock()
+	f.primed = false
+	f.mu.Unlock()
+}
+
+// SetSignalSmoothing sets the EWMA weight of new RSSI samples (0 < alpha <= 1)
+// 1 disables smoothing; out-of-range values fall back to DefaultSignalAlpha
+func (c *Client) SetSignalSmoothing(alpha float64) {
+	if alpha <= 0 || alpha > 1 {
+		alpha = DefaultSignalAlpha
+	}
+	c.signal.mu.Lock()
+	c.signal.alpha = alpha
+	c.signal.mu.Unlock()
+}
+
+// SetSignalHysteresis sets how far the smoothed signal must move before it is
+// republished: pct percentage points, or more than dBm; 0 publishes every change
+func (c *Client) SetSignalHysteresis(pct, dBm int) {
+	c.signal.mu.Lock()
+	c.signal.deltaPct = max(pct, 0)
+	c.signal.deltaDBm = max(dBm, 0)
+	c.signal.mu.Unlock()
+}
+
+// significant reports whether a move from the published signal is worth an update
+func (f *signalFilter) significant(pctDelta, dBmDelta int) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return abs(pctDelta) >= f.deltaPct || abs(dBmDelta) > f.deltaDBm
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// applySignal publishes a raw RSSI sample for the active network through the filter
+func (c *Client) applySignal(st *state.State, rssiDBm int16) {
+	smoothed, first := c.signal.add(rssiDBm)
+	pct := state.DBmToPercent(smoothed)
+
+	if !first && !c.signal.significant(int(pct)-int(st.SignalStrength), int(smoothed)-int(st.SignalRSSI)) {
+		return
+	}
+	st.SignalRSSI = smoothed
+	st.SignalStrength = pct
+	st.SignalBars = state.DBmToBars(smoothed)
+	log.Printf("Active network signal: %d dBm (raw %d) = %d%%", smoothed, rssiDBm, pct)
+}
+
+// refreshActiveSignal samples the connected network's RSSI again (after scans)
+func (c *Client) refreshActiveSignal() {
+	if c.station() == "" {
+		return
+	}
+	v, err := c.conn.Object(IWDService, c.station()).GetProperty(StationIface + ".ConnectedNetwork")
+	if err != nil {
+		return
+	}
+	path, _ := v.Value().(dbus.ObjectPath)
+	if path == "" {
+		return
+	}
+	c.stateMgr.Update(func(st *state.State) {
+		c.fetchActiveSignal(st, path)
+	})
+}
@@ -0,0 +1,154 @@
+package iwd
+
+import (
+	"log"
+	"time"
+
+	"x-network/internal/netlink"
+	"x-network/internal/state"
+)
+
+// usbReturnWaitSteps bounds the wait (seconds) for WiFi to get an address
+// before the switch back from USB is given up
+const usbReturnWaitSteps = 30
+
+// SetUsbFallbackPolicy sets what happens when WiFi recovers after tryUsbFallback
+// sticky keeps USB as the primary link (the pre-priority behavior); otherwise
+// WiFi takes the default route back and, with releaseAfter > 0, the USB lease
+// is released once WiFi has stayed up that long
+func (c *Client) SetUsbFallbackPolicy(sticky bool, releaseAfter time.Duration) {
+	c.usbFallbackMu.Lock()
+	c.usbSticky = sticky
+	c.usbReleaseAfter = releaseAfter
+	c.usbFallbackMu.Unlock()
+}
+
+// SetBluetoothFallback sets how Bluetooth tethering is connected when WiFi
+// drops and no USB tethering is available; nil disables the fallback
+func (c *Client) SetBluetoothFallback(connect func() error) {
+	c.usbFallbackMu.Lock()
+	c.btFallback = connect
+	c.usbFallbackMu.Unlock()
+}
+
+// tryBluetoothFallback connects Bluetooth tethering as fallback
+// The netlink watcher marks it connected once the lease lands, and the priority
+// engine hands the default route back when WiFi returns
+func (c *Client) tryBluetoothFallback() {
+	c.usbFallbackMu.Lock()
+	connect := c.btFallback
+	c.usbFallbackMu.Unlock()
+	if connect == nil {
+		return
+	}
+
+	log.Printf("WiFi disconnected, attempting Bluetooth tethering fallback")
+	if err := connect(); err != nil {
+		log.Printf("Bluetooth tethering fallback failed: %v", err)
+	}
+}
+
+// tryUsbFallback attempts to establish USB tethering connection as fallback
+func (c *Client) tryUsbFallback(ifaceName string) {
+	log.Printf("Attempting USB tethering fallback on %s", ifaceName)
+
+	// Bring up the interface via rtnetlink (needs CAP_NET_ADMIN)
+	if err := netlink.BringUpInterface(c.stateMgr, ifaceName); err != nil {
+		log.Printf("Failed to bring up USB interface %s: %v", ifaceName, err)
+		return
+	}
+
+	// Acquire an address (static profile, else native DHCP with dhcpcd/dhclient fallback)
+	log.Printf("Configuring IP on USB interface %s", ifaceName)
+	if err := c.ipcfg.Start(ifaceName); err != nil {
+		log.Printf("DHCP failed on USB interface %s: %v", ifaceName, err)
+		return
+	}
+
+	log.Printf("USB tethering fallback established on %s", ifaceName)
+
+	c.usbFallbackMu.Lock()
+	c.usbFallbackIface = ifaceName
+	sticky := c.usbSticky
+	c.usbFallbackMu.Unlock()
+
+	// Update state
+	c.stateMgr.Update(func(st *state.State) {
+		st.UsbTetheringConnected = true
+		st.ConnectionType = "usb"
+		if sticky && st.PrimaryPinned == "" {
+			st.PrimaryPinned = ifaceName // Outranks WiFi when it comes back
+		}
+	})
+}
+
+// returnFromUsbFallback runs when WiFi reconnects while USB tethering is up
+// The priority engine moves the default route (and ConnectionType) back to
+// WiFi once it has an address; a lease we took for the fallback is released
+// after the grace period if WiFi is still connected
+func (c *Client) returnFromUsbFallback() {
+	c.usbFallbackMu.Lock()
+	iface := c.usbFallbackIface
+	sticky := c.usbSticky
+	releaseAfter := c.usbReleaseAfter
+	if iface == "" || sticky || c.usbReturnPending {
+		c.usbFallbackMu.Unlock()
+		return
+	}
+	c.usbReturnPending = true
+	c.usbFallbackMu.Unlock()
+
+	defer func() {
+		c.usbFallbackMu.Lock()
+		c.usbReturnPending = false
+		c.usbFallbackMu.Unlock()
+	}()
+
+	// Wait for DHCP on WiFi - until then USB is the only working route
+	st := c.stateMgr.Get()
+	for i := 0; i < usbReturnWaitSteps && st.ConnectionState != state.StateConnected; i++ {
+		time.Sleep(time.Second)
+		st = c.stateMgr.Get()
+	}
+	if st.ConnectionState != state.StateConnected {
+		log.Printf("WiFi did not get an address, staying on USB %s", iface)
+		return
+	}
+	log.Printf("WiFi recovered, preferring it over USB fallback %s", iface)
+
+	if releaseAfter <= 0 {
+		c.clearUsbFallback(iface)
+		return
+	}
+
+	select {
+	case <-c.closing:
+		return
+	case <-time.After(releaseAfter):
+	}
+
+	st = c.stateMgr.Get()
+	if st.ConnectionState != state.StateConnected || st.PrimaryInterface == iface {
+		log.Printf("Keeping USB lease on %s: WiFi not primary after %s", iface, releaseAfter)
+		return
+	}
+	log.Printf("Releasing USB fallback lease on %s", iface)
+	if err := c.ipcfg.Release(iface); err != nil {
+		log.Printf("Failed to release USB lease on %s: %v", iface, err)
+	}
+	c.clearUsbFallback(iface)
+	c.stateMgr.Update(func(st *state.State) {
+		if st.UsbInterfaceName == iface {
+			st.UsbTetheringConnected = false
+		}
+	})
+}
+
+// clearUsbFallback forgets the fallback link if it is still iface
+func (c *Client) clearUsbFallback(iface string) {
+	c.usbFallbackMu.Lock()
+	if c.usbFallbackIface == iface {
+		c.usbFallbackIface = ""
+	}
+	c.usbFallbackMu.Unlock()
+}
@@ -0,0 +1,104 @@
+package netlink
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"x-network/internal/state"
+)
+
+// trackAddress adds or removes an address in the per-interface list and
+// returns a fresh copy of that list (IPv4 first, then in arrival order)
+func (w *Watcher) trackAddress(index uint32, ip net.IP, prefixLen uint8, removed bool) []state.Address {
+	if ip == nil {
+		return append([]state.Address(nil), w.addrs[index]...)
+	}
+	family := "inet"
+	if ip.To4() == nil {
+		family = "inet6"
+	}
+	entry := state.Address{CIDR: fmt.Sprintf("%s/%d", ip, prefixLen), Family: family}
+
+	var list []state.Address
+	for _, a := range w.addrs[index] {
+		if addressIP(a) != ip.String() {
+			list = append(list, a)
+		}
+	}
+	if !removed {
+		list = append(list, entry)
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Family == "inet" && list[j].Family != "inet" })
+
+	if len(list) == 0 {
+		delete(w.addrs, index)
+		return nil
+	}
+	w.addrs[index] = list
+	return append([]state.Address(nil), list...)
+}
+
+// addressIP returns the address part of an Address
+func addressIP(a state.Address) string {
+	ip, _, _ := strings.Cut(a.CIDR, "/")
+	return ip
+}
+
+// primaryIPv4 returns the first IPv4 address of a list and its prefix
+// length ("", 0 if none)
+func primaryIPv4(addrs []state.Address) (string, uint8) {
+	for _, a := range addrs {
+		if a.Family != "inet" {
+			continue
+		}
+		ip, prefix, _ := strings.Cut(a.CIDR, "/")
+		prefixLen, _ := strconv.Atoi(prefix)
+		return ip, uint8(prefixLen)
+	}
+	return "", 0
+}
+
+// ownsAddresses reports whether ifaceName is the interface behind IpAddress
+// and Addresses: the tethering link for USB/Bluetooth, else the WiFi/Ethernet one
+func ownsAddresses(st *state.State, ifaceName string, isUsb, isBt bool) bool {
+	switch {
+	case isUsb:
+		return st.UsbInterfaceName == ifaceName
+	case isBt:
+		return st.BtInterfaceName == ifaceName
+	}
+	return st.InterfaceName == ifaceName
+}
+
+// removeAddress applies an RTM_DELADDR on the interface behind IpAddress
+// IpAddress falls back to the next IPv4 left, or clears. A wired link left
+// without IPv4 drops its gateway and connectivity; WiFi's ConnectionState
+// belongs to IWD and tethering links to their DHCP/link handlers, so both
+// only lose the address
+func removeAddress(st *state.State, ifaceName string, ip net.IP, addrs []state.Address, tethering bool) {
+	st.Addresses = addrs
+	if ip == nil || ip.String() != st.IpAddress {
+		return
+	}
+	st.IpAddress, st.IpPrefixLen = primaryIPv4(addrs)
+	if st.IpAddress != "" || tethering || isWifiInterface(ifaceName) {
+		return
+	}
+
+	// Connectivity follows from the checker's next probe
+	log.Printf("No IPv4 left on %s, clearing gateway", ifaceName)
+	st.Gateway = ""
+	st.GatewayReachable = false
+	if st.ConnectionType == "ethernet" && st.ConnectionState == state.StateConnected {
+		// Cable still in: waiting for a new lease
+		if st.EthernetCablePlugged {
+			st.ConnectionState = state.StateObtaining
+		} else {
+			st.ConnectionState = state.StateDisconnected
+		}
+	}
+}
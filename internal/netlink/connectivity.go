@@ -0,0 +1,88 @@
+package netlink
+
+import (
+	"log"
+	"net"
+	"syscall"
+
+	"x-network/internal/state"
+
+	"github.com/jsimonetti/rtnetlink"
+)
+
+// RTM_NEWROUTE lets us promote a pending connection once a default route shows up
+const RTM_NEWROUTE = syscall.RTM_NEWROUTE // 24
+
+// linkLocalNet is the IPv4 autoconfiguration range (RFC 3927)
+var linkLocalNet = net.IPNet{IP: net.IPv4(169, 254, 0, 0), Mask: net.CIDRMask(16, 32)}
+
+// isLinkLocal reports whether ip is an IPv4 link-local (169.254/16) address
+func isLinkLocal(ip net.IP) bool {
+	ip4 := ip.To4()
+	return ip4 != nil && linkLocalNet.Contains(ip4)
+}
+
+// handleRouteMessage refreshes the gateway and promotes a pending connection
+// when a default route is added
+func (w *Watcher) handleRouteMessage(data []byte) {
+	var msg rtnetlink.RouteMessage
+	if err := msg.UnmarshalBinary(data); err != nil {
+		return
+	}
+	if msg.DstLength != 0 || msg.Attributes.Gateway == nil {
+		return // Not a default route
+	}
+	if msg.Family == syscall.AF_INET6 {
+		w.handleRouteV6(&msg)
+		return
+	}
+
+	w.fetchGateway()
+	w.triggerGatewayProbe()
+
+	st := w.stateMgr.Get()
+	if st.ConnectionState != state.StateConnecting && st.ConnectionState != state.StateObtaining {
+		return
+	}
+	link, err := net.InterfaceByName(st.InterfaceName)
+	if err != nil || uint32(link.Index) != msg.Attributes.OutIface {
+		return
+	}
+
+	if ip := w.routableAddress(msg.Attributes.OutIface); ip != nil {
+		w.stateMgr.Update(func(st *state.State) {
+			w.promoteConnected(st, ip, msg.Attributes.OutIface)
+		})
+	}
+}
+
+// promoteConnected marks the WiFi/Ethernet link connected once ip is routable
+// and ifaceIndex has a default route. Link-local or route-less addresses never
+// promote. Connectivity itself is only written by the connectivity checker,
+// which probes on the resulting state change
+func (w *Watcher) promoteConnected(st *state.State, ip net.IP, ifaceIndex uint32) {
+	if isLinkLocal(ip) || !w.hasDefaultRoute(ifaceIndex, syscall.AF_INET) {
+		return
+	}
+	if st.ConnectionState == state.StateConnecting || st.ConnectionState == state.StateObtaining {
+		log.Printf("Connected: %s with default route", ip)
+		st.ConnectionState = state.StateConnected
+	}
+}
+
+// routableAddress returns a non-link-local IPv4 address on ifaceIndex, if any
+func (w *Watcher) routableAddress(ifaceIndex uint32) net.IP {
+	addrs, err := w.rt().Address.List()
+	if err != nil {
+		return nil
+	}
+	for _, addr := range addrs {
+		if addr.Index != ifaceIndex || addr.Family != syscall.AF_INET || addr.Attributes == nil {
+			continue
+		}
+		if ip := addr.Attributes.Address; ip != nil && !isLinkLocal(ip) {
+			return ip
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package netlink
+
+import (
+	"log"
+
+	"x-network/internal/state"
+)
+
+// isWiredInterface reports whether name is a physical Ethernet port
+// (has a device, is neither WiFi nor USB tethering)
+func isWiredInterface(name string) bool {
+	return isPhysicalInterface(name) && !isWifiInterface(name) && !isUsbInterface(name)
+}
+
+// updateCablePlugged tracks carrier on wired interfaces and publishes
+// EthernetCablePlugged, independent of whether an address was obtained
+func (w *Watcher) updateCablePlugged(iface string, index uint32, carrier, removed bool) {
+	if removed {
+		if _, ok := w.wiredCarrier[index]; !ok {
+			return
+		}
+		delete(w.wiredCarrier, index)
+	} else {
+		if !isWiredInterface(iface) {
+			return
+		}
+		w.wiredCarrier[index] = carrier
+	}
+
+	plugged := false
+	for _, c := range w.wiredCarrier {
+		plugged = plugged || c
+	}
+
+	if plugged != w.stateMgr.Get().EthernetCablePlugged {
+		log.Printf("Ethernet cable plugged: %v (%s)", plugged, iface)
+		w.stateMgr.Update(func(st *state.State) {
+			st.EthernetCablePlugged = plugged
+		})
+	}
+}
+
+// startWired configures a wired port whose carrier just came up: its static
+// profile if one is stored, DHCP otherwise (see ipconfig.Manager.Start)
+func (w *Watcher) startWired(iface string, carrier, prevCarrier bool) {
+	if !carrier || prevCarrier || !isWiredInterface(iface) {
+		return
+	}
+	log.Printf("Ethernet carrier up on %s, configuring addresses", iface)
+	w.runDHCPOnInterface(iface)
+}
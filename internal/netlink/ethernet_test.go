@@ -0,0 +1,59 @@
+package netlink
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"x-network/internal/state"
+)
+
+// fakeSysfs points sysClassNet at a temporary tree with a wired port (eth0,
+// eth1), a WiFi card (wlan0), a USB tethering link (usb0) and a virtual link
+func fakeSysfs(t *testing.T) {
+	t.Helper()
+	root := t.TempDir()
+	for _, dir := range []string{"eth0/device", "eth1/device", "wlan0/device", "wlan0/wireless", "usb0/device", "veth0"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("../../../bus/usb", filepath.Join(root, "usb0/device/subsystem")); err != nil {
+		t.Fatal(err)
+	}
+	prev := sysClassNet
+	sysClassNet = root
+	t.Cleanup(func() { sysClassNet = prev })
+}
+
+func TestUpdateCablePlugged(t *testing.T) {
+	fakeSysfs(t)
+	w := &Watcher{stateMgr: state.NewManager(), wiredCarrier: make(map[uint32]bool)}
+
+	steps := []struct {
+		name    string
+		iface   string
+		index   uint32
+		carrier bool
+		removed bool
+		want    bool
+	}{
+		{"wifi carrier ignored", "wlan0", 3, true, false, false},
+		{"usb carrier ignored", "usb0", 4, true, false, false},
+		{"virtual carrier ignored", "veth0", 5, true, false, false},
+		{"eth0 down", "eth0", 1, false, false, false},
+		{"eth0 plugged", "eth0", 1, true, false, true},
+		{"eth1 plugged", "eth1", 2, true, false, true},
+		{"eth0 unplugged, eth1 still up", "eth0", 1, false, false, true},
+		{"unknown link removed", "veth0", 5, false, true, true},
+		{"eth1 removed", "eth1", 2, false, true, false},
+		{"eth0 plugged again", "eth0", 1, true, false, true},
+		{"eth0 removed", "eth0", 1, false, true, false},
+	}
+	for _, s := range steps {
+		w.updateCablePlugged(s.iface, s.index, s.carrier, s.removed)
+		if got := w.stateMgr.Get().EthernetCablePlugged; got != s.want {
+			t.Fatalf("%s: EthernetCablePlugged = %v, want %v", s.name, got, s.want)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package netlink
+
+import (
+	"time"
+
+	"x-network/internal/state"
+)
+
+// LinkFlapWindow is the rolling window for carrier transition counts
+const LinkFlapWindow = 10 * time.Minute
+
+// recordCarrier notes a carrier transition and publishes the per-interface history
+// The first event for an interface only seeds the baseline
+func (w *Watcher) recordCarrier(iface string, index uint32, carrier bool) {
+	prev, seen := w.lastCarrier[index]
+	w.lastCarrier[index] = carrier
+	if !seen || prev == carrier {
+		return
+	}
+
+	now := time.Now()
+	events := pruneFlaps(append(w.flaps[iface], now), now)
+	w.flaps[iface] = events
+
+	// Publish a fresh copy - state snapshots must not share backing arrays
+	snapshot := make(map[string][]time.Time, len(w.flaps))
+	for name, ev := range w.flaps {
+		snapshot[name] = append([]time.Time(nil), ev...)
+	}
+	w.stateMgr.Update(func(st *state.State) {
+		st.LinkFlaps = snapshot
+	})
+}
+
+// pruneFlaps drops events older than LinkFlapWindow
+func pruneFlaps(events []time.Time, now time.Time) []time.Time {
+	cutoff := now.Add(-LinkFlapWindow)
+	i := 0
+	for i < len(events) && events[i].Before(cutoff) {
+		i++
+	}
+	return events[i:]
+}
+
+// FlapCounts returns carrier transitions per interface within LinkFlapWindow
+func FlapCounts(flaps map[string][]time.Time, now time.Time) map[string]uint32 {
+	counts := make(map[string]uint32, len(flaps))
+	for iface, events := range flaps {
+		counts[iface] = uint32(len(pruneFlaps(events, now)))
+	}
+	return counts
+}
@@ -0,0 +1,161 @@
+package netlink
+
+import (
+	"errors"
+	"log"
+	"net"
+	"time"
+
+	"x-network/internal/probe"
+	"x-network/internal/state"
+)
+
+const (
+	gatewayProbeInterval = 30 * time.Second
+	gatewayProbeTimeout  = 2 * time.Second
+
+	// gatewayProbeSettle lets the default route land after a new address
+	gatewayProbeSettle = time.Second
+
+	// neighborProbeTimeout covers re-verifying a STALE entry: the kernel's
+	// DELAY (5s) and unicast probes (3 x 1s) with their default timers
+	neighborProbeTimeout = 9 * time.Second
+	neighborPollInterval = 200 * time.Millisecond
+)
+
+// Neighbor states from rtnetlink(7)
+const (
+	nudIncomplete = 0x01
+	nudReachable  = 0x02
+	nudStale      = 0x04
+	nudDelay      = 0x08
+	nudProbe      = 0x10
+	nudFailed     = 0x20
+	nudNoARP      = 0x40
+	nudPermanent  = 0x80
+)
+
+// runGatewayProbe checks gateway reachability while connected: periodically
+// and shortly after an address or default route appears
+func (w *Watcher) runGatewayProbe() {
+	ticker := time.NewTicker(gatewayProbeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stopCh:
+			return
+		case <-ticker.C:
+			w.probeGateway()
+		case <-w.gatewayProbe:
+			select {
+			case <-w.stopCh:
+				return
+			case <-time.After(gatewayProbeSettle):
+			}
+			w.probeGateway()
+		}
+	}
+}
+
+// triggerGatewayProbe requests a probe from the probe goroutine (non-blocking),
+// keeping ping/ARP waits off the netlink event path
+func (w *Watcher) triggerGatewayProbe() {
+	select {
+	case w.gatewayProbe <- struct{}{}:
+	default:
+	}
+}
+
+// probeGateway checks the current gateway and updates GatewayReachable
+func (w *Watcher) probeGateway() {
+	st := w.stateMgr.Get()
+
+	connected := st.ConnectionState == state.StateConnected || st.UsbTetheringConnected || st.BtTetheringConnected
+	gw := net.ParseIP(st.Gateway)
+	if !connected || gw == nil {
+		if st.GatewayReachable {
+			w.stateMgr.Update(func(st *state.State) {
+				st.GatewayReachable = false
+			})
+		}
+		return
+	}
+
+	reachable := w.isGatewayReachable(gw)
+	if reachable != st.GatewayReachable {
+		log.Printf("Gateway %s reachable: %v", gw, reachable)
+		w.stateMgr.Update(func(st *state.State) {
+			st.GatewayReachable = reachable
+		})
+	}
+}
+
+// isGatewayReachable pings the gateway, falling back to the kernel neighbor
+// table (ARP) when ICMP sockets aren't permitted
+func (w *Watcher) isGatewayReachable(gw net.IP) bool {
+	_, err := probe.Ping(gw, gatewayProbeTimeout)
+	if err == nil {
+		return true
+	}
+	if !errors.Is(err, probe.ErrNoPermission) {
+		// Some routers drop ICMP - let ARP have the final say
+		log.Printf("Gateway ping failed: %v", err)
+	}
+	return w.neighborReachable(gw)
+}
+
+// neighborReachable pokes the gateway with a UDP datagram so the kernel resolves
+// (or re-verifies) it, then waits for the neighbor entry to settle
+func (w *Watcher) neighborReachable(gw net.IP) bool {
+	if conn, err := net.DialTimeout("udp", net.JoinHostPort(gw.String(), "9"), time.Second); err == nil {
+		conn.Write([]byte{0})
+		conn.Close()
+	}
+	return waitNeighbor(func() (uint16, bool) { return w.neighborState(gw) }, neighborProbeTimeout, neighborPollInterval)
+}
+
+// waitNeighbor polls lookup until the entry is confirmed or failed
+// STALE, DELAY and PROBE only say the address was resolved at some point:
+// the datagram above moves a STALE entry through DELAY and PROBE, so keep
+// waiting for the kernel's verdict rather than trusting the old entry
+func waitNeighbor(lookup func() (uint16, bool), timeout, poll time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		if neighState, found := lookup(); found {
+			if reachable, final := neighborVerdict(neighState); final {
+				return reachable
+			}
+		}
+		if !time.Now().Add(poll).Before(deadline) {
+			return false
+		}
+		time.Sleep(poll)
+	}
+}
+
+// neighborVerdict classifies a neighbor state: final is false while the
+// kernel hasn't confirmed or given up on the entry
+func neighborVerdict(neighState uint16) (reachable, final bool) {
+	switch {
+	case neighState&(nudReachable|nudPermanent|nudNoARP) != 0:
+		return true, true
+	case neighState&nudFailed != 0:
+		return false, true
+	}
+	return false, false // INCOMPLETE, STALE, DELAY, PROBE
+}
+
+// neighborState looks up the neighbor table entry for ip
+func (w *Watcher) neighborState(ip net.IP) (uint16, bool) {
+	neighs, err := w.rt().Neigh.List()
+	if err != nil {
+		return 0, false
+	}
+	for _, n := range neighs {
+		if n.Attributes != nil && n.Attributes.Address.Equal(ip) {
+			return n.State, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,63 @@
+package netlink
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNeighborVerdict(t *testing.T) {
+	tests := []struct {
+		name      string
+		state     uint16
+		reachable bool
+		final     bool
+	}{
+		{"reachable", nudReachable, true, true},
+		{"permanent", nudPermanent, true, true},
+		{"noarp", nudNoARP, true, true},
+		{"failed", nudFailed, false, true},
+		{"stale", nudStale, false, false},
+		{"delay", nudDelay, false, false},
+		{"probe", nudProbe, false, false},
+		{"incomplete", nudIncomplete, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reachable, final := neighborVerdict(tt.state)
+			if reachable != tt.reachable || final != tt.final {
+				t.Errorf("neighborVerdict(%#x) = %v, %v; want %v, %v", tt.state, reachable, final, tt.reachable, tt.final)
+			}
+		})
+	}
+}
+
+// sequence replays neighbor states, repeating the last one
+func sequence(states ...uint16) func() (uint16, bool) {
+	i := 0
+	return func() (uint16, bool) {
+		s := states[min(i, len(states)-1)]
+		i++
+		return s, true
+	}
+}
+
+func TestWaitNeighbor(t *testing.T) {
+	const poll = time.Millisecond
+	tests := []struct {
+		name   string
+		lookup func() (uint16, bool)
+		want   bool
+	}{
+		{"stale entry re-verified", sequence(nudStale, nudDelay, nudProbe, nudReachable), true},
+		{"stale entry that fails", sequence(nudStale, nudDelay, nudProbe, nudFailed), false},
+		{"stays stale", sequence(nudStale), false},
+		{"no entry", func() (uint16, bool) { return 0, false }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := waitNeighbor(tt.lookup, 50*time.Millisecond, poll); got != tt.want {
+				t.Errorf("waitNeighbor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
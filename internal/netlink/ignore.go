@@ -0,0 +1,61 @@
+package netlink
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// DefaultIgnorePatterns keeps container, VM and overlay links out of the
+// interface tracking; they would otherwise race WiFi for InterfaceName
+var DefaultIgnorePatterns = []string{"veth*", "docker*", "virbr*", "br-*", "tailscale*"}
+
+// InterfaceFilter decides which links are tracked
+// Both lists are shell globs (filepath.Match); an include match wins over an
+// ignore match, so a single bridge can be managed while br-* stays ignored
+type InterfaceFilter struct {
+	ignore  []string
+	include []string
+}
+
+// NewInterfaceFilter validates the patterns and returns the filter
+func NewInterfaceFilter(ignore, include []string) (*InterfaceFilter, error) {
+	for _, p := range append(append([]string(nil), ignore...), include...) {
+		if _, err := filepath.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("invalid interface pattern %q: %w", p, err)
+		}
+	}
+	return &InterfaceFilter{
+		ignore:  append([]string(nil), ignore...),
+		include: append([]string(nil), include...),
+	}, nil
+}
+
+// Patterns returns the ignore patterns and include list
+func (f *InterfaceFilter) Patterns() (ignore, include []string) {
+	if f == nil {
+		return nil, nil
+	}
+	return append([]string(nil), f.ignore...), append([]string(nil), f.include...)
+}
+
+// Ignored reports whether name is loopback or matches an ignore pattern
+// without matching the include list (a nil filter only ignores loopback)
+func (f *InterfaceFilter) Ignored(name string) bool {
+	if name == "lo" {
+		return true
+	}
+	if f == nil {
+		return false
+	}
+	return matchAny(f.ignore, name) && !matchAny(f.include, name)
+}
+
+// matchAny reports whether name matches one of the globs
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := filepath.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,29 @@
+package netlink
+
+import "testing"
+
+func TestInterfaceFilter(t *testing.T) {
+	f, err := NewInterfaceFilter(DefaultIgnorePatterns, []string{"br-lan"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]bool{
+		"lo":      true,
+		"veth12":  true,
+		"br-1234": true,
+		"br-lan":  false, // Included despite br-*
+		"wlan0":   false,
+	} {
+		if got := f.Ignored(name); got != want {
+			t.Errorf("Ignored(%q) = %v, want %v", name, got, want)
+		}
+	}
+
+	var none *InterfaceFilter
+	if none.Ignored("veth12") || !none.Ignored("lo") {
+		t.Error("a nil filter must only ignore loopback")
+	}
+	if _, err := NewInterfaceFilter([]string{"[bad"}, nil); err == nil {
+		t.Error("invalid pattern accepted")
+	}
+}
@@ -0,0 +1,76 @@
+package netlink
+
+import (
+	"fmt"
+	"net"
+	"sort"
+
+	"github.com/jsimonetti/rtnetlink"
+)
+
+// Interface is one network link as seen at call time
+type Interface struct {
+	Name      string
+	Type      string // "wifi", "ethernet", "usb", "bluetooth" or "virtual"
+	OperState string // RFC 2863 operstate as in /sys/class/net/<iface>/operstate
+	Carrier   bool
+	Index     uint32
+	MAC       string // Empty for links without a hardware address
+}
+
+// operStates names rtnetlink operational states like sysfs does
+var operStates = map[rtnetlink.OperationalState]string{
+	rtnetlink.OperStateUnknown:        "unknown",
+	rtnetlink.OperStateNotPresent:     "notpresent",
+	rtnetlink.OperStateDown:           "down",
+	rtnetlink.OperStateLowerLayerDown: "lowerlayerdown",
+	rtnetlink.OperStateTesting:        "testing",
+	rtnetlink.OperStateDormant:        "dormant",
+	rtnetlink.OperStateUp:             "up",
+}
+
+// ListInterfaces returns every link not excluded by filter, ordered by ifindex
+func ListInterfaces(filter *InterfaceFilter) ([]Interface, error) {
+	conn, err := rtnetlink.Dial(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial rtnetlink: %w", err)
+	}
+	defer conn.Close()
+
+	links, err := conn.Link.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list links: %w", err)
+	}
+
+	result := make([]Interface, 0, len(links))
+	for _, link := range links {
+		if link.Attributes == nil || filter.Ignored(link.Attributes.Name) {
+			continue
+		}
+		name := link.Attributes.Name
+
+		kind := getConnectionType(name)
+		if kind == "unknown" || isTunnelLink(&link) {
+			kind = "virtual" // No backing device in sysfs (bridge, veth, tun, lo)
+		}
+		operState, ok := operStates[link.Attributes.OperationalState]
+		if !ok {
+			operState = "unknown"
+		}
+		mac := ""
+		if len(link.Attributes.Address) > 0 {
+			mac = net.HardwareAddr(link.Attributes.Address).String()
+		}
+
+		result = append(result, Interface{
+			Name:      name,
+			Type:      kind,
+			OperState: operState,
+			Carrier:   link.Attributes.Carrier != nil && *link.Attributes.Carrier == 1,
+			Index:     link.Index,
+			MAC:       mac,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Index < result[j].Index })
+	return result, nil
+}
@@ -0,0 +1,56 @@
+package netlink
+
+import (
+	"log"
+	"net"
+	"syscall"
+
+	"x-network/internal/state"
+
+	"github.com/jsimonetti/rtnetlink"
+)
+
+// IPv6 multicast groups, joined so tethered phones that only hand out IPv6
+// (SLAAC or DHCPv6) still count as connected
+const (
+	rtmgrpIPv6IfAddr = 0x100 // RTMGRP_IPV6_IFADDR
+	rtmgrpIPv6Route  = 0x400 // RTMGRP_IPV6_ROUTE
+)
+
+// handleAddressV6 records a global IPv6 address on the USB tethering link
+// Other links only report IPv4 (IpAddress), so their IPv6 addresses are ignored
+func (w *Watcher) handleAddressV6(ifaceName string, index uint32, ip net.IP) {
+	if ip == nil || !ip.IsGlobalUnicast() || !isUsbInterface(ifaceName) {
+		return // Link-local fe80::/10 is present on every link
+	}
+
+	w.stateMgr.Update(func(st *state.State) {
+		if st.UsbInterfaceName != ifaceName {
+			return
+		}
+		st.UsbIpAddressV6 = ip.String()
+		w.promoteUsbV6(st, index)
+	})
+}
+
+// handleRouteV6 promotes USB tethering once an IPv6 default route shows up
+// after the address (router advertisements often arrive second)
+func (w *Watcher) handleRouteV6(msg *rtnetlink.RouteMessage) {
+	st := w.stateMgr.Get()
+	if st.UsbIpAddressV6 == "" || st.UsbTetheringConnected || msg.Attributes.OutIface != st.UsbInterfaceIndex {
+		return
+	}
+	w.stateMgr.Update(func(st *state.State) {
+		w.promoteUsbV6(st, msg.Attributes.OutIface)
+	})
+}
+
+// promoteUsbV6 marks USB tethering connected over IPv6 (address + default route)
+func (w *Watcher) promoteUsbV6(st *state.State, index uint32) {
+	if st.UsbTetheringConnected || !w.hasDefaultRoute(index, syscall.AF_INET6) {
+		return
+	}
+	st.UsbTetheringConnected = true
+	st.ConnectionType = "usb"
+	log.Printf("USB tethering connected on %s over IPv6: %s", st.UsbInterfaceName, st.UsbIpAddressV6)
+}
@@ -0,0 +1,72 @@
+package netlink
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"syscall"
+
+	"x-network/internal/state"
+
+	"github.com/jsimonetti/rtnetlink"
+)
+
+// ErrNoCapability is returned when the daemon lacks CAP_NET_ADMIN for link changes
+var ErrNoCapability = errors.New("missing CAP_NET_ADMIN")
+
+// SetLinkUp sets IFF_UP on an interface via rtnetlink (requires CAP_NET_ADMIN)
+func SetLinkUp(conn *rtnetlink.Conn, name string) error {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return err
+	}
+	index := uint32(iface.Index)
+
+	msg, err := conn.Link.Get(index)
+	if err != nil {
+		return fmt.Errorf("failed to get link %s: %w", name, err)
+	}
+
+	err = conn.Link.Set(&rtnetlink.LinkMessage{
+		Family: syscall.AF_UNSPEC,
+		Type:   msg.Type,
+		Index:  index,
+		Flags:  syscall.IFF_UP,
+		Change: syscall.IFF_UP,
+	})
+	if err != nil {
+		if errors.Is(err, os.ErrPermission) {
+			return fmt.Errorf("%w: %v", ErrNoCapability, err)
+		}
+		return fmt.Errorf("failed to set %s up: %w", name, err)
+	}
+	return nil
+}
+
+// BringUpInterface brings up an interface via rtnetlink
+// Used by callers that don't hold a long-lived rtnetlink connection
+func BringUpInterface(stateMgr *state.Manager, name string) error {
+	conn, err := rtnetlink.Dial(nil)
+	if err != nil {
+		return fmt.Errorf("failed to dial rtnetlink: %w", err)
+	}
+	defer conn.Close()
+
+	return bringUp(conn, stateMgr, name)
+}
+
+// bringUp sets the link up and surfaces a missing CAP_NET_ADMIN in LastError,
+// since that needs fixing in the service unit rather than retrying
+func bringUp(conn *rtnetlink.Conn, stateMgr *state.Manager, name string) error {
+	err := SetLinkUp(conn, name)
+	if errors.Is(err, ErrNoCapability) {
+		log.Printf("Cannot bring up %s: %v", name, err)
+		stateMgr.Update(func(st *state.State) {
+			st.LastError = fmt.Sprintf("Cannot bring up %s: daemon lacks CAP_NET_ADMIN", name)
+			st.LastErrorCode = state.ErrCodeFailed
+		})
+	}
+	return err
+}
@@ -0,0 +1,60 @@
+package netlink
+
+import (
+	"runtime"
+	"syscall"
+	"testing"
+
+	"github.com/jsimonetti/rtnetlink"
+)
+
+// testNetns moves the test's thread into a fresh network namespace and dials
+// rtnetlink there. The thread stays locked, so it is thrown away with the test
+func testNetns(t *testing.T) *rtnetlink.Conn {
+	t.Helper()
+	runtime.LockOSThread()
+	if err := syscall.Unshare(syscall.CLONE_NEWNET); err != nil {
+		t.Skipf("cannot create a network namespace: %v", err)
+	}
+	conn, err := rtnetlink.Dial(nil)
+	if err != nil {
+		t.Skipf("rtnetlink unavailable: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestSetLinkUp(t *testing.T) {
+	conn := testNetns(t)
+	name := "xn-dummy0"
+	err := conn.Link.New(&rtnetlink.LinkMessage{
+		Family: syscall.AF_UNSPEC,
+		Attributes: &rtnetlink.LinkAttributes{
+			Name: name,
+			Info: &rtnetlink.LinkInfo{Kind: "dummy"},
+		},
+	})
+	if err != nil {
+		// No dummy driver; a fresh namespace's loopback starts down as well
+		t.Logf("cannot create a dummy link (%v), using lo", err)
+		name = "lo"
+	}
+
+	if err := SetLinkUp(conn, name); err != nil {
+		t.Fatalf("SetLinkUp(%s) = %v", name, err)
+	}
+
+	links, err := conn.Link.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, link := range links {
+		if link.Attributes != nil && link.Attributes.Name == name {
+			if link.Flags&syscall.IFF_UP == 0 {
+				t.Fatalf("%s flags = %#x, want IFF_UP", name, link.Flags)
+			}
+			return
+		}
+	}
+	t.Fatalf("%s disappeared", name)
+}
@@ -0,0 +1,93 @@
+package netlink
+
+import (
+	"fmt"
+	"net"
+	"sort"
+
+	"github.com/jsimonetti/rtnetlink"
+)
+
+// Neighbor is one peer from the kernel neighbor (ARP/NDP) table
+type Neighbor struct {
+	IP        string
+	MAC       string
+	Interface string
+	State     string // "reachable", "delay", "probe", "permanent" or "noarp"
+}
+
+// neighborStates names the entry states GetNeighbors reports; stale,
+// failed and incomplete entries are left out
+var neighborStates = []struct {
+	bit  uint16
+	name string
+}{
+	{nudReachable, "reachable"},
+	{nudPermanent, "permanent"},
+	{nudDelay, "delay"},
+	{nudProbe, "probe"},
+	{nudNoARP, "noarp"},
+}
+
+// ListNeighbors returns the live neighbor entries of ifaces, ordered by
+// interface then IP. Interfaces that don't exist are skipped
+func ListNeighbors(ifaces []string) ([]Neighbor, error) {
+	names := make(map[uint32]string, len(ifaces))
+	for _, name := range ifaces {
+		if iface, err := net.InterfaceByName(name); err == nil {
+			names[uint32(iface.Index)] = name
+		}
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	conn, err := rtnetlink.Dial(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial rtnetlink: %w", err)
+	}
+	defer conn.Close()
+
+	neighs, err := conn.Neigh.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list neighbors: %w", err)
+	}
+
+	var result []Neighbor
+	for _, n := range neighs {
+		name, ok := names[n.Index]
+		if !ok || n.Attributes == nil || n.Attributes.Address == nil || len(n.Attributes.LLAddress) == 0 {
+			continue
+		}
+		if n.Attributes.Address.IsMulticast() {
+			continue // Multicast groups (ff02::1 ...), not peers
+		}
+		state := neighborStateName(n.State)
+		if state == "" {
+			continue
+		}
+		result = append(result, Neighbor{
+			IP:        n.Attributes.Address.String(),
+			MAC:       net.HardwareAddr(n.Attributes.LLAddress).String(),
+			Interface: name,
+			State:     state,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Interface != result[j].Interface {
+			return result[i].Interface < result[j].Interface
+		}
+		return result[i].IP < result[j].IP
+	})
+	return result, nil
+}
+
+// neighborStateName returns the reported name of a NUD state ("" = filtered)
+func neighborStateName(nud uint16) string {
+	for _, s := range neighborStates {
+		if nud&s.bit != 0 {
+			return s.name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,92 @@
+package netlink
+
+import (
+	"errors"
+	"log"
+	"syscall"
+	"time"
+
+	"x-network/internal/state"
+)
+
+// Reconnect backoff after the event socket died
+const (
+	reconnectMinBackoff = 1 * time.Second
+	reconnectMaxBackoff = 30 * time.Second
+)
+
+// resync re-reads links and addresses, covering events that were never seen
+func (w *Watcher) resync() {
+	w.fetchInterfaces()
+	w.fetchAddresses()
+}
+
+// stopped reports whether Close was called
+func (w *Watcher) stopped() bool {
+	select {
+	case <-w.stopCh:
+		return true
+	default:
+		return false
+	}
+}
+
+// recover handles a Receive error and reports whether Run should go on
+// ENOBUFS means the kernel dropped events after a burst: the socket is fine,
+// but state may have missed changes. Anything else is treated as a dead socket
+func (w *Watcher) recover(err error) bool {
+	if w.stopped() {
+		return false
+	}
+	switch {
+	case errors.Is(err, syscall.ENOBUFS):
+		log.Printf("Netlink events dropped (receive buffer full), resyncing")
+		w.resync()
+		return true
+	case errors.Is(err, syscall.EINTR), errors.Is(err, syscall.EAGAIN):
+		return true
+	}
+
+	log.Printf("Netlink receive error: %v, reconnecting", err)
+	return w.reconnect()
+}
+
+// reconnect re-dials both connections with exponential backoff, then replays
+// the initial fetch. Returns false if the watcher was closed meanwhile
+func (w *Watcher) reconnect() bool {
+	backoff := reconnectMinBackoff
+	for {
+		select {
+		case <-w.stopCh:
+			return false
+		case <-time.After(backoff):
+		}
+
+		conn, rtConn, err := dial()
+		if err != nil {
+			backoff = min(backoff*2, reconnectMaxBackoff)
+			log.Printf("Netlink reconnect failed: %v (retrying in %s)", err, backoff)
+			continue
+		}
+
+		w.connMu.Lock()
+		if w.stopped() {
+			w.connMu.Unlock()
+			conn.Close()
+			rtConn.Close()
+			return false
+		}
+		oldConn, oldRtConn := w.conn, w.rtConn
+		w.conn, w.rtConn = conn, rtConn
+		w.connMu.Unlock()
+		oldConn.Close()
+		oldRtConn.Close()
+
+		w.stateMgr.Update(func(st *state.State) {
+			st.NetlinkReconnects++
+		})
+		log.Printf("Netlink reconnected, resyncing")
+		w.resync()
+		return true
+	}
+}
@@ -0,0 +1,101 @@
+package netlink
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+
+	"github.com/jsimonetti/rtnetlink"
+)
+
+// rtprotDHCP marks routes installed from a DHCP lease (RTPROT_DHCP)
+const rtprotDHCP = 16
+
+// AddAddress installs an IPv4 address with prefix on the interface
+// Existing identical addresses are replaced rather than duplicated
+func AddAddress(conn *rtnetlink.Conn, index uint32, ip net.IP, prefixLen uint8) error {
+	ip4 := ip.To4()
+	if ip4 == nil {
+		return fmt.Errorf("not an IPv4 address: %s", ip)
+	}
+
+	err := conn.Address.New(&rtnetlink.AddressMessage{
+		Family:       syscall.AF_INET,
+		PrefixLength: prefixLen,
+		Scope:        syscall.RT_SCOPE_UNIVERSE,
+		Index:        index,
+		Attributes: &rtnetlink.AddressAttributes{
+			Address:   ip4,
+			Local:     ip4,
+			Broadcast: broadcastAddr(ip4, prefixLen),
+		},
+	})
+	if err != nil && !isExist(err) {
+		return fmt.Errorf("failed to add address %s/%d: %w", ip4, prefixLen, err)
+	}
+	return nil
+}
+
+// DelAddress removes an IPv4 address from the interface
+func DelAddress(conn *rtnetlink.Conn, index uint32, ip net.IP, prefixLen uint8) error {
+	ip4 := ip.To4()
+	if ip4 == nil {
+		return fmt.Errorf("not an IPv4 address: %s", ip)
+	}
+
+	return conn.Address.Delete(&rtnetlink.AddressMessage{
+		Family:       syscall.AF_INET,
+		PrefixLength: prefixLen,
+		Index:        index,
+		Attributes: &rtnetlink.AddressAttributes{
+			Address: ip4,
+			Local:   ip4,
+		},
+	})
+}
+
+// ReplaceDefaultRoute installs (or replaces) an IPv4 default route via gw on the interface
+func ReplaceDefaultRoute(conn *rtnetlink.Conn, index uint32, gw net.IP, metric uint32) error {
+	return conn.Route.Replace(defaultRouteMessage(index, gw, metric))
+}
+
+// DelDefaultRoute removes the IPv4 default route via gw on the interface
+func DelDefaultRoute(conn *rtnetlink.Conn, index uint32, gw net.IP, metric uint32) error {
+	return conn.Route.Delete(defaultRouteMessage(index, gw, metric))
+}
+
+// defaultRouteMessage builds a 0.0.0.0/0 route message
+func defaultRouteMessage(index uint32, gw net.IP, metric uint32) *rtnetlink.RouteMessage {
+	return &rtnetlink.RouteMessage{
+		Family:   syscall.AF_INET,
+		Table:    syscall.RT_TABLE_MAIN,
+		Protocol: rtprotDHCP,
+		Scope:    syscall.RT_SCOPE_UNIVERSE,
+		Type:     syscall.RTN_UNICAST,
+		Attributes: rtnetlink.RouteAttributes{
+			Gateway:  gw.To4(),
+			OutIface: index,
+			Priority: metric,
+		},
+	}
+}
+
+// broadcastAddr computes the IPv4 broadcast address for ip/prefixLen
+func broadcastAddr(ip net.IP, prefixLen uint8) net.IP {
+	ip4 := ip.To4()
+	if ip4 == nil {
+		return nil
+	}
+	mask := net.CIDRMask(int(prefixLen), 32)
+	bcast := make(net.IP, net.IPv4len)
+	for i := range ip4 {
+		bcast[i] = ip4[i] | ^mask[i]
+	}
+	return bcast
+}
+
+// isExist reports whether a netlink error is EEXIST
+func isExist(err error) bool {
+	return errors.Is(err, syscall.EEXIST)
+}
@@ -0,0 +1,126 @@
+package netlink
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"x-network/internal/state"
+)
+
+func TestStartupHookDue(t *testing.T) {
+	ip := net.IPv4(192, 168, 1, 10)
+	base := state.State{IsStartup: true, InterfaceName: "wlan0", UsbInterfaceName: "usb0"}
+	tests := []struct {
+		name   string
+		change func(st *state.State)
+		iface  string
+		ip     net.IP
+		want   bool
+	}{
+		{"wifi uplink", nil, "wlan0", ip, true},
+		{"usb uplink", nil, "usb0", ip, true},
+		{"other interface", nil, "docker0", ip, false},
+		{"ipv6 only", nil, "wlan0", net.ParseIP("fe80::1"), false},
+		{"already triggered", func(st *state.State) { st.ConnectHookTriggered = true }, "wlan0", ip, false},
+		{"not starting up", func(st *state.State) { st.IsStartup = false }, "wlan0", ip, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := base
+			if tt.change != nil {
+				tt.change(&st)
+			}
+			if got := startupHookDue(&st, tt.iface, tt.ip); got != tt.want {
+				t.Errorf("startupHookDue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// startupWatcher returns a watcher whose connect hook fails fail times
+func startupWatcher(started time.Time, fail int) (*Watcher, *int) {
+	calls := 0
+	w := &Watcher{stateMgr: state.NewManager()}
+	w.connectHook = func(reason, ssid, iface, ip string) error {
+		calls++
+		if calls <= fail {
+			return errors.New("no internet yet")
+		}
+		return nil
+	}
+	w.stateMgr.Update(func(st *state.State) {
+		st.IsStartup = true
+		st.StartupTimestamp = started
+		st.ConnectHookTriggered = true // As set by the address handler
+	})
+	return w, &calls
+}
+
+func TestStartupHookRetriesOnceInWindow(t *testing.T) {
+	w, _ := startupWatcher(time.Now(), 2)
+
+	w.runStartupHook("cafe", "wlan0", "10.0.0.2")
+	st := w.stateMgr.Get()
+	if !st.IsStartup || st.ConnectHookTriggered || !st.StartupHookRetried {
+		t.Fatalf("first failure: IsStartup=%v triggered=%v retried=%v, want a retry armed",
+			st.IsStartup, st.ConnectHookTriggered, st.StartupHookRetried)
+	}
+
+	w.stateMgr.Update(func(st *state.State) { st.ConnectHookTriggered = true })
+	w.runStartupHook("home", "wlan0", "10.0.0.3")
+	if st := w.stateMgr.Get(); st.IsStartup {
+		t.Fatal("second failure still arms a retry")
+	}
+}
+
+func TestStartupHookSuccessEndsStartup(t *testing.T) {
+	w, calls := startupWatcher(time.Now(), 0)
+	w.runStartupHook("home", "wlan0", "10.0.0.2")
+	if st := w.stateMgr.Get(); st.IsStartup || *calls != 1 {
+		t.Fatalf("IsStartup=%v after a successful hook (%d calls)", st.IsStartup, *calls)
+	}
+}
+
+func TestStartupHookNoRetryAfterWindow(t *testing.T) {
+	w, _ := startupWatcher(time.Now().Add(-startupHookWindow-time.Second), 1)
+	w.runStartupHook("cafe", "wlan0", "10.0.0.2")
+	if st := w.stateMgr.Get(); st.IsStartup || st.StartupHookRetried {
+		t.Fatal("failure outside the startup window armed a retry")
+	}
+}
+
+func TestClaimStartupHook(t *testing.T) {
+	ip := net.IPv4(10, 0, 0, 2)
+	w := &Watcher{stateMgr: state.NewManager()}
+	w.stateMgr.Update(func(st *state.State) {
+		st.IsStartup = true
+		st.StartupTimestamp = time.Now()
+		st.InterfaceName = "wlan0"
+		st.ActiveSSID = "cafe"
+	})
+
+	if _, due := w.claimStartupHook("docker0", ip, time.Now()); due {
+		t.Fatal("address on a non-uplink claimed the startup hook")
+	}
+	if ssid, due := w.claimStartupHook("wlan0", ip, time.Now()); !due || ssid != "cafe" {
+		t.Fatalf("claimStartupHook() = %q, %v; want cafe, true", ssid, due)
+	}
+	if _, due := w.claimStartupHook("wlan0", ip, time.Now()); due {
+		t.Fatal("second address claimed the hook while it runs")
+	}
+
+	// A failure armed a retry, but the next address comes after the window
+	w.stateMgr.Update(func(st *state.State) {
+		st.StartupHookRetried = true
+		st.ConnectHookTriggered = false
+	})
+	late := time.Now().Add(startupHookWindow + time.Second)
+	if _, due := w.claimStartupHook("wlan0", ip, late); due {
+		t.Fatal("retry ran after the startup window")
+	}
+	if w.stateMgr.Get().IsStartup {
+		t.Fatal("expired retry left IsStartup set")
+	}
+}
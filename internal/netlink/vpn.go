@@ -0,0 +1,78 @@
+package netlink
+
+import (
+	"log"
+	"sort"
+	"strings"
+
+	"x-network/internal/state"
+
+	"github.com/jsimonetti/rtnetlink"
+)
+
+// tunnelKinds are IFLA_INFO_KIND values of VPN tunnel links
+var tunnelKinds = map[string]bool{
+	"wireguard": true,
+	"tun":       true,
+	"tap":       true,
+}
+
+// isTunnelLink reports whether a link is a VPN tunnel, from its rtnetlink
+// link kind or (for kinds the kernel doesn't report) a "wg" name prefix
+func isTunnelLink(msg *rtnetlink.LinkMessage) bool {
+	if msg.Attributes == nil {
+		return false
+	}
+	if msg.Attributes.Info != nil && tunnelKinds[msg.Attributes.Info.Kind] {
+		return true
+	}
+	return strings.HasPrefix(msg.Attributes.Name, "wg")
+}
+
+// isTunnelIndex reports whether ifindex belongs to a known tunnel link
+func (w *Watcher) isTunnelIndex(index uint32) bool {
+	_, ok := w.tunnels[index]
+	return ok
+}
+
+// updateTunnel tracks tunnel links and publishes VpnActive/VpnInterface
+// A tunnel counts as active while it is up; the physical link's state is left alone
+func (w *Watcher) updateTunnel(name string, index uint32, up, removed bool) {
+	if removed {
+		if _, ok := w.tunnels[index]; !ok {
+			return
+		}
+		delete(w.tunnels, index)
+	} else {
+		w.tunnels[index] = tunnel{name: name, up: up}
+	}
+
+	var active []string
+	for _, t := range w.tunnels {
+		if t.up {
+			active = append(active, t.name)
+		}
+	}
+	sort.Strings(active)
+
+	iface := ""
+	if len(active) > 0 {
+		iface = active[0]
+	}
+
+	st := w.stateMgr.Get()
+	if st.VpnActive == (iface != "") && st.VpnInterface == iface {
+		return
+	}
+	log.Printf("VPN active: %v (%s)", iface != "", name)
+	w.stateMgr.Update(func(st *state.State) {
+		st.VpnActive = iface != ""
+		st.VpnInterface = iface
+	})
+}
+
+// tunnel is one VPN tunnel link seen over rtnetlink
+type tunnel struct {
+	name string
+	up   bool
+}
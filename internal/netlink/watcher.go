@@ -5,8 +5,9 @@ import (
 	"log"
 	"net"
 	"os"
-	"os/exec"
+	"path/filepath"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -24,69 +25,164 @@ const (
 	RTM_DELADDR = syscall.RTM_DELADDR // 21
 )
 
+// linkUpKey is the lastLinkState entry of a link that is up with carrier
+const linkUpKey = "true:true"
+
+// startupHookWindow bounds how long after daemon start a failed startup hook may retry
+const startupHookWindow = 5 * time.Minute
+
+// DHCPRunner acquires and releases addresses on an interface (implemented by ipconfig.Manager)
+type DHCPRunner interface {
+	Start(iface string) error
+	Release(iface string) error
+}
+
 // Watcher watches netlink events
 type Watcher struct {
+	connMu        sync.RWMutex    // Guards conn and rtConn, replaced on reconnect
 	conn          *netlink.Conn   // Raw netlink connection for message type access (events)
 	rtConn        *rtnetlink.Conn // rtnetlink connection for List operations (fetching)
 	stateMgr      *state.Manager
+	dhcp          DHCPRunner
+	filter        *InterfaceFilter // Links never tracked (nil = only loopback)
 	stopCh        chan struct{}
+	gatewayProbe  chan struct{}     // Requests an immediate gateway probe (see gateway.go)
 	lastLinkState map[uint32]string // Track last state per interface to avoid log spam
+	lastCarrier   map[uint32]bool   // Last carrier per interface, for flap counting
+	flaps         map[string][]time.Time
+	wiredCarrier  map[uint32]bool            // Carrier per physical Ethernet port
+	tunnels       map[uint32]tunnel          // VPN tunnel links (see vpn.go)
+	addrs         map[uint32][]state.Address // Addresses per ifindex (see addresses.go)
+	connectHook   ConnectHook                // Run on first IPv4 after startup/resume (nil = none)
+	syncHook      func()                     // Run once after the initial fetch (nil = none)
 }
 
-// NewWatcher creates a new netlink watcher
-func NewWatcher(stateMgr *state.Manager) (*Watcher, error) {
+// NewWatcher creates a new netlink watcher tracking the links filter allows
+func NewWatcher(stateMgr *state.Manager, dhcp DHCPRunner, filter *InterfaceFilter) (*Watcher, error) {
+	conn, rtConn, err := dial()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Watcher{
+		conn:          conn,
+		rtConn:        rtConn,
+		stateMgr:      stateMgr,
+		dhcp:          dhcp,
+		filter:        filter,
+		stopCh:        make(chan struct{}),
+		gatewayProbe:  make(chan struct{}, 1),
+		lastLinkState: make(map[uint32]string),
+		lastCarrier:   make(map[uint32]bool),
+		flaps:         make(map[string][]time.Time),
+		wiredCarrier:  make(map[uint32]bool),
+		tunnels:       make(map[uint32]tunnel),
+		addrs:         make(map[uint32][]state.Address),
+	}, nil
+}
+
+// dial opens the event and the List connections
+func dial() (*netlink.Conn, *rtnetlink.Conn, error) {
 	// Raw netlink.Conn for event watching (to access Header.Type for RTM_DELLINK)
 	conn, err := netlink.Dial(syscall.NETLINK_ROUTE, &netlink.Config{
-		Groups: 0x1 | 0x10, // RTMGRP_LINK | RTMGRP_IPV4_IFADDR
+		Groups: 0x1 | 0x10 | 0x40 | rtmgrpIPv6IfAddr | rtmgrpIPv6Route, // RTMGRP_LINK | RTMGRP_IPV4_IFADDR | RTMGRP_IPV4_ROUTE | IPv6
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to dial netlink: %w", err)
+		return nil, nil, fmt.Errorf("failed to dial netlink: %w", err)
 	}
 
 	// rtnetlink.Conn for List operations (fetching interfaces, routes, addresses)
 	rtConn, err := rtnetlink.Dial(nil)
 	if err != nil {
 		conn.Close()
-		return nil, fmt.Errorf("failed to dial rtnetlink: %w", err)
+		return nil, nil, fmt.Errorf("failed to dial rtnetlink: %w", err)
 	}
-
-	return &Watcher{
-		conn:          conn,
-		rtConn:        rtConn,
-		stateMgr:      stateMgr,
-		stopCh:        make(chan struct{}),
-		lastLinkState: make(map[uint32]string),
-	}, nil
+	return conn, rtConn, nil
 }
 
-// Close closes the netlink connections
+// Close closes the netlink connections; a Receive in progress returns at once
+// and Run exits
 func (w *Watcher) Close() {
+	w.connMu.Lock()
+	defer w.connMu.Unlock()
+	select {
+	case <-w.stopCh:
+		return
+	default:
+	}
 	close(w.stopCh)
 	w.conn.Close()
 	w.rtConn.Close()
 }
 
-// Run starts watching netlink events
+// rt returns the current List connection (replaced by reconnect)
+func (w *Watcher) rt() *rtnetlink.Conn {
+	w.connMu.RLock()
+	defer w.connMu.RUnlock()
+	return w.rtConn
+}
+
+// receiveResult is one Receive call handed from the reader to Run
+type receiveResult struct {
+	msgs []netlink.Message
+	err  error
+}
+
+// Run starts watching netlink events. Close stops it promptly, even while
+// the reader is blocked in Receive
 func (w *Watcher) Run() {
 	// Initial fetch
-	w.fetchInterfaces()
-	w.fetchAddresses()
+	w.resync()
+	if w.syncHook != nil {
+		w.syncHook()
+	}
+
+	// Periodic gateway reachability while connected
+	go w.runGatewayProbe()
 
 	// Watch for events
+	results := make(chan receiveResult)
+	handled := make(chan struct{})
+	go w.receive(results, handled)
 	for {
 		select {
 		case <-w.stopCh:
 			return
-		default:
-			msgs, err := w.conn.Receive()
-			if err != nil {
-				log.Printf("Netlink receive error: %v", err)
-				continue
+		case r := <-results:
+			if r.err != nil && !w.recover(r.err) {
+				return
 			}
-
-			for _, msg := range msgs {
+			for _, msg := range r.msgs {
 				w.handleRawMessage(msg)
 			}
+			select {
+			case handled <- struct{}{}:
+			case <-w.stopCh:
+				return
+			}
+		}
+	}
+}
+
+// receive reads from the current event connection and hands each result to
+// Run, waiting until it was handled (and a dead socket replaced) before
+// reading again. The error of the socket closed by Close is dropped
+func (w *Watcher) receive(results chan<- receiveResult, handled <-chan struct{}) {
+	for {
+		w.connMu.RLock()
+		conn := w.conn
+		w.connMu.RUnlock()
+
+		msgs, err := conn.Receive()
+		select {
+		case results <- receiveResult{msgs, err}:
+		case <-w.stopCh:
+			return
+		}
+		select {
+		case <-handled:
+		case <-w.stopCh:
+			return
 		}
 	}
 }
@@ -106,6 +202,9 @@ func (w *Watcher) handleRawMessage(msg netlink.Message) {
 	case RTM_DELADDR:
 		// Address removed
 		w.handleAddressMessage(msg.Data, true)
+	case RTM_NEWROUTE:
+		// Default route may promote limited connectivity
+		w.handleRouteMessage(msg.Data)
 	}
 }
 
@@ -122,13 +221,20 @@ func (w *Watcher) handleLinkMessage(data []byte, isRemoved bool) {
 	ifaceName := msg.Attributes.Name
 	ifaceIndex := msg.Index
 
-	if ifaceName == "" || ifaceName == "lo" {
+	if ifaceName == "" || w.filter.Ignored(ifaceName) {
 		return
 	}
 
 	// Handle RTM_DELLINK - interface removed from system
 	if isRemoved {
 		log.Printf("RTM_DELLINK: Interface %s (idx=%d) removed", ifaceName, ifaceIndex)
+		w.updateCablePlugged(ifaceName, ifaceIndex, false, true)
+		w.updateTunnel(ifaceName, ifaceIndex, false, true)
+		delete(w.addrs, ifaceIndex)
+		if w.lastLinkState[ifaceIndex] == linkUpKey {
+			w.publishLinkEvent(ifaceName, false)
+		}
+		delete(w.lastLinkState, ifaceIndex)
 		w.stateMgr.Update(func(st *state.State) {
 			// Clear USB state if this was our tracked USB interface (match by ifindex!)
 			if st.UsbInterfaceIndex == ifaceIndex {
@@ -138,6 +244,16 @@ func (w *Watcher) handleLinkMessage(data []byte, isRemoved bool) {
 				st.UsbTetheringConnected = false
 				st.UsbInterfaceName = ""
 				st.UsbInterfaceIndex = 0
+				st.UsbIpAddressV6 = ""
+			}
+			if st.BtInterfaceIndex == ifaceIndex {
+				log.Printf("Bluetooth interface removed (ifindex=%d matched)", ifaceIndex)
+				st.BtTetheringConnected = false
+				st.BtInterfaceName = ""
+				st.BtInterfaceIndex = 0
+				if st.ConnectionType == "bluetooth" {
+					st.ConnectionType = ""
+				}
 			}
 		})
 		return
@@ -149,15 +265,46 @@ func (w *Watcher) handleLinkMessage(data []byte, isRemoved bool) {
 
 	// Log deduplication: only log when state actually changes
 	stateKey := fmt.Sprintf("%v:%v", isUp, hasCarrier)
-	if w.lastLinkState[ifaceIndex] != stateKey {
+	if prevKey := w.lastLinkState[ifaceIndex]; prevKey != stateKey {
 		log.Printf("RTM_NEWLINK: Interface %s (idx=%d): up=%v, carrier=%v", ifaceName, ifaceIndex, isUp, hasCarrier)
 		w.lastLinkState[ifaceIndex] = stateKey
+		// A link first seen down (or flapping carrier while down) isn't news
+		if wasUp := prevKey == linkUpKey; wasUp != (isUp && hasCarrier) {
+			w.publishLinkEvent(ifaceName, !wasUp)
+		}
 	}
 
+	// VPN tunnels never become the reported interface - WiFi/Ethernet stay the
+	// physical connection and the tunnel is published separately
+	if isTunnelLink(&msg) {
+		w.updateTunnel(ifaceName, ifaceIndex, isUp || msg.Flags&syscall.IFF_UP != 0, false)
+		return
+	}
+
+	prevCarrier := w.lastCarrier[ifaceIndex]
+	w.recordCarrier(ifaceName, ifaceIndex, hasCarrier)
+	w.updateCablePlugged(ifaceName, ifaceIndex, hasCarrier, false)
+	w.startWired(ifaceName, hasCarrier, prevCarrier)
+
 	// Check if this is a USB interface (via sysfs - kernel source of truth)
 	isUsb := isUsbInterface(ifaceName)
+	isBt := isBluetoothInterface(ifaceName)
 
 	w.stateMgr.Update(func(st *state.State) {
+		// Bluetooth PAN link - DHCP starts on carrier like USB, including links
+		// connected by other tools (the DHCP manager ignores a second start)
+		if isBt {
+			if st.BtInterfaceIndex != ifaceIndex && !isUp {
+				log.Printf("Bringing up Bluetooth interface %s", ifaceName)
+				go w.bringUpInterface(ifaceName)
+			}
+			if hasCarrier && !prevCarrier {
+				go w.runDHCPOnInterface(ifaceName)
+			}
+			st.BtInterfaceName = ifaceName
+			st.BtInterfaceIndex = ifaceIndex
+		}
+
 		// Handle USB interface
 		if isUsb {
 			// USB interface detected
@@ -171,40 +318,71 @@ func (w *Watcher) handleLinkMessage(data []byte, isRemoved bool) {
 					st.UsbTetheringAvailable = true
 					log.Printf("USB tethering available on %s (carrier up)", ifaceName)
 
-					// If interface is down but has carrier, bring it up
-					if !isUp {
-						log.Printf("Bringing up USB interface %s", ifaceName)
-						go bringUpInterface(ifaceName)
+					// Auto-connect off: RequestUsbNetwork is the only trigger
+					if !st.UsbAutoConnect {
+						log.Printf("USB auto-connect disabled, leaving %s alone", ifaceName)
+					} else {
+						// If interface is down but has carrier, bring it up
+						if !isUp {
+							log.Printf("Bringing up USB interface %s", ifaceName)
+							go w.bringUpInterface(ifaceName)
+						}
+
+						// Auto-start DHCP when carrier comes up
+						go w.runDHCPOnInterface(ifaceName)
 					}
-
-					// Auto-start DHCP when carrier comes up
-					go w.runDHCPOnInterface(ifaceName)
 				}
 			} else {
 				// No carrier = phone tethering not active (but interface still exists)
 				st.UsbTetheringAvailable = false
 				st.UsbTetheringConnected = false
+				st.UsbIpAddressV6 = ""
 				// NOTE: Don't clear UsbInterfaceDetected here - RTM_DELLINK handles that
 			}
 		}
 
 		// Update general interface info (non-USB)
 		// Do NOT touch WiFi ConnectionState here - IWD D-Bus is the source of truth
-		if !isUsb && isUp && (st.InterfaceName == ifaceName || st.InterfaceName == "") {
+		if !isUsb && !isBt && isUp && (st.InterfaceName == ifaceName || st.InterfaceName == "") {
 			st.InterfaceName = ifaceName
 			st.ConnectionType = getConnectionType(ifaceName)
 		}
 	})
 }
 
-// bringUpInterface brings up a network interface (requires sudo)
-func bringUpInterface(iface string) {
-	cmd := exec.Command("sudo", "ip", "link", "set", iface, "up")
-	if err := cmd.Run(); err != nil {
+// bringUpInterface brings up a network interface via rtnetlink
+func (w *Watcher) bringUpInterface(iface string) {
+	if err := bringUp(w.rt(), w.stateMgr, iface); err != nil {
 		log.Printf("Failed to bring up %s: %v", iface, err)
 	}
 }
 
+// publishLinkEvent records an interface going up or down for InterfaceChanged
+func (w *Watcher) publishLinkEvent(iface string, up bool) {
+	w.stateMgr.Update(func(st *state.State) {
+		st.LinkEventIface = iface
+		st.LinkEventUp = up
+		st.LinkEventSeq++
+	})
+}
+
+// ConnectHook runs the hooks for the first IPv4 address after startup or resume
+// reason is "startup" or "resume"; an error lets a startup hook retry once
+type ConnectHook func(reason, ssid, iface, ip string) error
+
+// SetConnectHook sets the hook run when the first IPv4 address arrives after
+// daemon start or resume; nil disables it
+func (w *Watcher) SetConnectHook(hook ConnectHook) {
+	w.connectHook = hook
+}
+
+// SetSyncHook sets a function run once the initial fetch has filled in the
+// interface, connection type and addresses, before any event is handled
+// Must be called before Run
+func (w *Watcher) SetSyncHook(hook func()) {
+	w.syncHook = hook
+}
+
 // handleAddressMessage handles IP address changes
 func (w *Watcher) handleAddressMessage(data []byte, isRemoved bool) {
 	// Parse raw data into AddressMessage
@@ -214,13 +392,8 @@ func (w *Watcher) handleAddressMessage(data []byte, isRemoved bool) {
 		return
 	}
 
-	// Ignore address removal events for now (we care about address adds)
-	if isRemoved {
-		return
-	}
-
 	// Get interface name via rtConn (List operation)
-	links, err := w.rtConn.Link.List()
+	links, err := w.rt().Link.List()
 	if err != nil {
 		return
 	}
@@ -233,7 +406,7 @@ func (w *Watcher) handleAddressMessage(data []byte, isRemoved bool) {
 		}
 	}
 
-	if ifaceName == "" || ifaceName == "lo" {
+	if ifaceName == "" || w.filter.Ignored(ifaceName) || w.isTunnelIndex(msg.Index) {
 		return
 	}
 
@@ -241,87 +414,176 @@ func (w *Watcher) handleAddressMessage(data []byte, isRemoved bool) {
 	ip := msg.Attributes.Address
 	ifaceIndex := msg.Index
 
-	log.Printf("Address change on %s: %s", ifaceName, ip)
-
-	// Check if this is a USB interface
+	// Check if this is a USB or Bluetooth tethering interface
 	isUsb := isUsbInterface(ifaceName)
+	isBt := isBluetoothInterface(ifaceName)
+
+	addrs := w.trackAddress(ifaceIndex, ip, msg.PrefixLength, isRemoved)
+	if isRemoved {
+		log.Printf("Address removed on %s: %s", ifaceName, ip)
+		w.stateMgr.Update(func(st *state.State) {
+			if ownsAddresses(st, ifaceName, isUsb, isBt) {
+				removeAddress(st, ifaceName, ip, addrs, isUsb || isBt)
+			}
+		})
+		return
+	}
+	if msg.Family == syscall.AF_INET6 {
+		w.stateMgr.Update(func(st *state.State) {
+			if ownsAddresses(st, ifaceName, isUsb, isBt) {
+				st.Addresses = addrs
+			}
+		})
+	}
+
+	if msg.Family == syscall.AF_INET6 {
+		w.handleAddressV6(ifaceName, ifaceIndex, ip)
+		return
+	}
+
+	log.Printf("Address change on %s: %s", ifaceName, ip)
 
 	w.stateMgr.Update(func(st *state.State) {
+		if ownsAddresses(st, ifaceName, isUsb, isBt) {
+			st.Addresses = addrs
+		}
+
 		// Handle USB interface address (IP + route = connected)
 		if isUsb && st.UsbInterfaceName == ifaceName {
 			st.IpAddress = ip.String()
+			st.IpPrefixLen = msg.PrefixLength
 			// Check for default route via this interface (Connected = IP + route)
-			if w.checkDefaultRouteViaInterface(ifaceIndex) {
+			// A link-local address (DHCP gave up) is never a connection
+			if !isLinkLocal(ip) && w.checkDefaultRouteViaInterface(ifaceIndex) {
 				st.UsbTetheringConnected = true
 				st.ConnectionType = "usb"
 				log.Printf("USB tethering connected on %s: %s", ifaceName, ip)
 			}
 		}
 
-		// Handle WiFi/Ethernet
-		if !isUsb && st.InterfaceName == ifaceName {
+		// Handle Bluetooth PAN address the same way
+		if isBt && st.BtInterfaceName == ifaceName {
 			st.IpAddress = ip.String()
-			// Mark as connected when IP is assigned
-			if st.ConnectionState == state.StateConnecting || st.ConnectionState == state.StateObtaining {
-				st.ConnectionState = state.StateConnected
+			st.IpPrefixLen = msg.PrefixLength
+			if !isLinkLocal(ip) && w.checkDefaultRouteViaInterface(ifaceIndex) {
+				st.BtTetheringConnected = true
+				st.ConnectionType = "bluetooth"
+				log.Printf("Bluetooth tethering connected on %s: %s", ifaceName, ip)
 			}
 		}
+
+		// Handle WiFi/Ethernet
+		if !isUsb && !isBt && st.InterfaceName == ifaceName {
+			st.IpAddress = ip.String()
+			st.IpPrefixLen = msg.PrefixLength
+			// Promotes to connected only with a routable address and default route
+			w.promoteConnected(st, ip, ifaceIndex)
+		}
 	})
+	w.triggerGatewayProbe()
 
-	// Trigger weather refresh after resume when IPv4 is assigned
-	// NOTE: Only weather is triggered here - it's time-sensitive and network-dependent
-	// Holidays are NOT triggered on resume - they use month-based refresh via timer
+	// Run the on-connect hook after resume when IPv4 is assigned
 	currentState := w.stateMgr.Get()
-	if currentState.WasResumed &&
-		!currentState.WeatherTriggered &&
+	if w.connectHook != nil &&
+		currentState.WasResumed &&
+		!currentState.ConnectHookTriggered &&
 		time.Since(currentState.ResumeTimestamp) < 60*time.Second &&
 		ip != nil && ip.To4() != nil {
 
-		log.Printf("Resume + IPv4 assigned: triggering x-fetch weather")
-		go exec.Command(
-			os.ExpandEnv("$HOME/.local/bin/x-fetch"),
-			"weather", "--reason=resume",
-		).Run()
+		log.Printf("Resume + IPv4 assigned: running on-connect hook")
+		go w.runConnectHook("resume", currentState.ActiveSSID, ifaceName, ip.String())
 
 		// Clear flags
 		w.stateMgr.Update(func(st *state.State) {
 			st.WasResumed = false
-			st.WeatherTriggered = true
+			st.ConnectHookTriggered = true
 		})
 	}
 
-	// Trigger weather refresh on startup when first IPv4 is assigned
-	// NOTE: Only weather is triggered here - holidays use month-based refresh
-	if currentState.IsStartup &&
-		!currentState.WeatherTriggered &&
-		ip != nil && ip.To4() != nil {
+	// Run the on-connect hook on startup when first IPv4 is assigned
+	if w.connectHook != nil && currentState.IsStartup {
+		if ssid, due := w.claimStartupHook(ifaceName, ip, time.Now()); due {
+			log.Printf("Startup + IPv4 assigned on %s: running on-connect hook", ifaceName)
+			go w.runStartupHook(ssid, ifaceName, ip.String())
+		}
+	}
 
-		log.Printf("Startup + IPv4 assigned: triggering x-fetch weather")
-		go exec.Command(
-			os.ExpandEnv("$HOME/.local/bin/x-fetch"),
-			"weather", "--reason=startup",
-		).Run()
+	// Try to get gateway
+	w.fetchGateway()
+}
 
-		// Clear startup flag
-		w.stateMgr.Update(func(st *state.State) {
+// runStartupHook runs the startup hook and decides whether a retry is allowed
+// A failed hook (e.g. first network is a captive portal) gets one more chance on the
+// next IPv4 assignment, as long as we're still inside the startup window
+func (w *Watcher) runStartupHook(ssid, iface, ip string) {
+	err := w.runConnectHook("startup", ssid, iface, ip)
+
+	w.stateMgr.Update(func(st *state.State) {
+		if err == nil {
 			st.IsStartup = false
-			st.WeatherTriggered = true
-		})
+			return
+		}
+
+		log.Printf("Startup on-connect hook failed: %v", err)
+		if !st.StartupHookRetried && withinStartupWindow(st, time.Now()) {
+			log.Printf("Startup hook will retry on next connectivity event")
+			st.StartupHookRetried = true
+			st.ConnectHookTriggered = false
+			return
+		}
+
+		// Retry spent or window expired - give up until next resume
+		st.IsStartup = false
+	})
+}
+
+// startupHookDue reports whether an IPv4 address on iface runs the startup hook:
+// once per startup (or once more after a failure), and only for an uplink, so
+// addresses on bridges or other unused links don't spend the attempt
+func startupHookDue(st *state.State, iface string, ip net.IP) bool {
+	if !st.IsStartup || st.ConnectHookTriggered || ip == nil || ip.To4() == nil || iface == "" {
+		return false
 	}
+	return iface == st.InterfaceName || iface == st.UsbInterfaceName || iface == st.BtInterfaceName
+}
 
-	// Try to get gateway
-	w.fetchGateway()
+// claimStartupHook decides whether an IPv4 address on iface runs the startup
+// hook and marks it triggered, so address events don't stack hooks while it
+// runs. An armed retry lapses once the startup window is over
+func (w *Watcher) claimStartupHook(iface string, ip net.IP, now time.Time) (ssid string, due bool) {
+	w.stateMgr.Update(func(st *state.State) {
+		if st.IsStartup && st.StartupHookRetried && !withinStartupWindow(st, now) {
+			log.Printf("Startup hook retry window expired")
+			st.IsStartup = false
+			return
+		}
+		if due = startupHookDue(st, iface, ip); due {
+			st.ConnectHookTriggered = true
+			ssid = st.ActiveSSID
+		}
+	})
+	return ssid, due
+}
+
+// withinStartupWindow reports whether a failed startup hook may still be retried
+func withinStartupWindow(st *state.State, now time.Time) bool {
+	return now.Sub(st.StartupTimestamp) < startupHookWindow
+}
+
+// runConnectHook runs the connect hook and waits for it
+func (w *Watcher) runConnectHook(reason, ssid, iface, ip string) error {
+	return w.connectHook(reason, ssid, iface, ip)
 }
 
 // fetchInterfaces fetches current interface states
 func (w *Watcher) fetchInterfaces() {
-	links, err := w.rtConn.Link.List()
+	links, err := w.rt().Link.List()
 	if err != nil {
 		return
 	}
 
 	for _, link := range links {
-		if link.Attributes.Name == "lo" {
+		if w.filter.Ignored(link.Attributes.Name) {
 			continue
 		}
 
@@ -329,6 +591,13 @@ func (w *Watcher) fetchInterfaces() {
 		isUp := link.Attributes.OperationalState == rtnetlink.OperStateUp
 		hasCarrier := link.Attributes.Carrier != nil && *link.Attributes.Carrier == 1
 
+		if isTunnelLink(&link) {
+			w.updateTunnel(ifaceName, link.Index, isUp || link.Flags&syscall.IFF_UP != 0, false)
+			continue
+		}
+		w.updateCablePlugged(ifaceName, link.Index, hasCarrier, false)
+		w.startWired(ifaceName, hasCarrier, false)
+
 		// Check for USB interfaces on startup
 		if isUsbInterface(ifaceName) {
 			w.stateMgr.Update(func(st *state.State) {
@@ -339,11 +608,14 @@ func (w *Watcher) fetchInterfaces() {
 				if hasCarrier {
 					st.UsbTetheringAvailable = true
 					log.Printf("USB tethering available on %s at startup (carrier up)", ifaceName)
+					if !st.UsbAutoConnect {
+						return // RequestUsbNetwork brings it up on demand
+					}
 
 					// If interface is down but has carrier, bring it up
 					if !isUp {
 						log.Printf("Bringing up USB interface %s at startup", ifaceName)
-						go bringUpInterface(ifaceName)
+						go w.bringUpInterface(ifaceName)
 					}
 
 					// Auto-start DHCP
@@ -352,6 +624,15 @@ func (w *Watcher) fetchInterfaces() {
 			})
 		}
 
+		// Bluetooth PAN links left up by a previous run
+		if isBluetoothInterface(ifaceName) {
+			w.stateMgr.Update(func(st *state.State) {
+				st.BtInterfaceName = ifaceName
+				st.BtInterfaceIndex = link.Index
+			})
+			continue
+		}
+
 		// Handle WiFi/Ethernet
 		if isUp && !isUsbInterface(ifaceName) {
 			w.stateMgr.Update(func(st *state.State) {
@@ -365,39 +646,54 @@ func (w *Watcher) fetchInterfaces() {
 
 // fetchAddresses fetches current IP addresses
 func (w *Watcher) fetchAddresses() {
-	addrs, err := w.rtConn.Address.List()
+	addrs, err := w.rt().Address.List()
 	if err != nil {
 		return
 	}
 
 	st := w.stateMgr.Get()
-	links, _ := w.rtConn.Link.List()
+	links, _ := w.rt().Link.List()
 
+	names := make(map[uint32]string, len(links))
+	for _, link := range links {
+		names[link.Index] = link.Attributes.Name
+	}
 	for _, addr := range addrs {
-		// Find matching link
-		for _, link := range links {
-			if link.Index == addr.Index && link.Attributes.Name == st.InterfaceName {
-				w.stateMgr.Update(func(s *state.State) {
-					if addr.Attributes.Address != nil {
-						s.IpAddress = addr.Attributes.Address.String()
-					}
-				})
-				break
-			}
+		name := names[addr.Index]
+		if name == "" || w.filter.Ignored(name) || w.isTunnelIndex(addr.Index) {
+			continue
+		}
+		w.trackAddress(addr.Index, addr.Attributes.Address, addr.PrefixLength, false)
+	}
+
+	// Publish the WiFi/Ethernet link's addresses; IpAddress is its first IPv4
+	for index, name := range names {
+		if name != st.InterfaceName {
+			continue
 		}
+		list := w.trackAddress(index, nil, 0, false)
+		w.stateMgr.Update(func(s *state.State) {
+			s.Addresses = list
+			if v4, prefixLen := primaryIPv4(list); v4 != "" {
+				s.IpAddress, s.IpPrefixLen = v4, prefixLen
+			}
+		})
+		break
 	}
 }
 
 // fetchGateway fetches default gateway
 func (w *Watcher) fetchGateway() {
-	routes, err := w.rtConn.Route.List()
+	routes, err := w.rt().Route.List()
 	if err != nil {
 		return
 	}
 
 	for _, route := range routes {
-		// Default route (0.0.0.0/0)
-		if route.Attributes.Dst == nil && route.Attributes.Gateway != nil {
+		// Default route (0.0.0.0/0) of the physical link - a VPN's own default
+		// route would hide the real gateway
+		if route.Family == syscall.AF_INET && route.Attributes.Dst == nil && route.Attributes.Gateway != nil &&
+			!w.isTunnelIndex(route.Attributes.OutIface) {
 			w.stateMgr.Update(func(st *state.State) {
 				st.Gateway = route.Attributes.Gateway.String()
 			})
@@ -406,12 +702,24 @@ func (w *Watcher) fetchGateway() {
 	}
 }
 
+// InterfaceType classifies an interface as "usb", "bluetooth", "wifi",
+// "ethernet" or "unknown" from sysfs
+func InterfaceType(iface string) string {
+	return getConnectionType(iface)
+}
+
+// sysClassNet is where the kernel lists network interfaces (a var for tests)
+var sysClassNet = "/sys/class/net"
+
 // getConnectionType determines type from interface using sysfs (fully dynamic)
 func getConnectionType(iface string) string {
 	// Check sysfs for USB first (most reliable)
 	if isUsbInterface(iface) {
 		return "usb"
 	}
+	if isBluetoothInterface(iface) {
+		return "bluetooth"
+	}
 	// Check sysfs for WiFi (kernel-standard: /sys/class/net/<iface>/wireless exists)
 	if isWifiInterface(iface) {
 		return "wifi"
@@ -426,7 +734,7 @@ func getConnectionType(iface string) string {
 // isUsbInterface checks if interface is USB via sysfs (kernel source of truth)
 // Checks /sys/class/net/<iface>/device/subsystem -> usb
 func isUsbInterface(name string) bool {
-	subsystemPath := "/sys/class/net/" + name + "/device/subsystem"
+	subsystemPath := filepath.Join(sysClassNet, name, "device", "subsystem")
 	target, err := os.Readlink(subsystemPath)
 	if err != nil {
 		return false
@@ -434,31 +742,48 @@ func isUsbInterface(name string) bool {
 	return strings.HasSuffix(target, "/usb")
 }
 
+// isBluetoothInterface checks if interface is a Bluetooth PAN (bnep) link via sysfs
+// bnep devices hang off the HCI adapter: /sys/class/net/<iface>/device/subsystem -> bluetooth
+func isBluetoothInterface(name string) bool {
+	target, err := os.Readlink(filepath.Join(sysClassNet, name, "device", "subsystem"))
+	if err != nil {
+		return false
+	}
+	return strings.HasSuffix(target, "/bluetooth")
+}
+
 // isWifiInterface checks if interface is WiFi via sysfs
 // Kernel creates /sys/class/net/<iface>/wireless for WiFi interfaces
 func isWifiInterface(name string) bool {
-	wirelessPath := "/sys/class/net/" + name + "/wireless"
+	wirelessPath := filepath.Join(sysClassNet, name, "wireless")
 	_, err := os.Stat(wirelessPath)
 	return err == nil
 }
 
 // isPhysicalInterface checks if interface has a device in sysfs (not virtual)
 func isPhysicalInterface(name string) bool {
-	devicePath := "/sys/class/net/" + name + "/device"
+	devicePath := filepath.Join(sysClassNet, name, "device")
 	_, err := os.Stat(devicePath)
 	return err == nil
 }
 
-// checkDefaultRouteViaInterface checks if there's a default route through the given interface
+// checkDefaultRouteViaInterface checks if there's an IPv4 or IPv6 default route
+// through the given interface
 func (w *Watcher) checkDefaultRouteViaInterface(ifaceIndex uint32) bool {
-	routes, err := w.rtConn.Route.List()
+	return w.hasDefaultRoute(ifaceIndex, syscall.AF_INET) || w.hasDefaultRoute(ifaceIndex, syscall.AF_INET6)
+}
+
+// hasDefaultRoute checks for a default route of one address family
+// (0.0.0.0/0 or ::/0) through the given interface
+func (w *Watcher) hasDefaultRoute(ifaceIndex uint32, family uint8) bool {
+	routes, err := w.rt().Route.List()
 	if err != nil {
 		return false
 	}
 
 	for _, route := range routes {
-		// Default route (0.0.0.0/0) via our interface
-		if route.Attributes.Dst == nil &&
+		if route.Family == family &&
+			route.DstLength == 0 &&
 			route.Attributes.Gateway != nil &&
 			route.Attributes.OutIface == ifaceIndex {
 			return true
@@ -467,12 +792,12 @@ func (w *Watcher) checkDefaultRouteViaInterface(ifaceIndex uint32) bool {
 	return false
 }
 
-// runDHCPOnInterface runs dhcpcd on the given interface asynchronously (requires sudo)
+// runDHCPOnInterface configures a tethering or wired interface asynchronously
+// (static profile or DHCP lease). Native client first, dhcpcd/dhclient fallback is handled by the DHCP manager
 func (w *Watcher) runDHCPOnInterface(iface string) {
 	go func() {
-		log.Printf("Starting DHCP on USB interface %s", iface)
-		cmd := exec.Command("sudo", "dhcpcd", "-4", "-q", iface)
-		if err := cmd.Run(); err != nil {
+		log.Printf("Starting DHCP on %s", iface)
+		if err := w.dhcp.Start(iface); err != nil {
 			log.Printf("DHCP failed on %s: %v", iface, err)
 			// Don't spam - DHCP failure handled by netlink (no IP = not connected)
 		}
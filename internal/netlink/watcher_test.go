@@ -0,0 +1,73 @@
+package netlink
+
+import (
+	"testing"
+	"time"
+
+	"x-network/internal/state"
+
+	"github.com/jsimonetti/rtnetlink"
+	"github.com/mdlayher/netlink"
+	"github.com/mdlayher/netlink/nltest"
+)
+
+// fakeDHCP is a DHCPRunner that does nothing
+type fakeDHCP struct{}
+
+func (fakeDHCP) Start(string) error   { return nil }
+func (fakeDHCP) Release(string) error { return nil }
+
+// testWatcher returns a watcher whose event socket returns events on every
+// Receive; dumps go to the kernel, read-only
+func testWatcher(t *testing.T, events []netlink.Message) *Watcher {
+	t.Helper()
+	rtConn, err := rtnetlink.Dial(nil)
+	if err != nil {
+		t.Skipf("rtnetlink unavailable: %v", err)
+	}
+	flow := func([]netlink.Message) ([]netlink.Message, error) { return events, nil }
+	return &Watcher{
+		conn:          nltest.Dial(flow),
+		rtConn:        rtConn,
+		stateMgr:      state.NewManager(),
+		dhcp:          fakeDHCP{},
+		stopCh:        make(chan struct{}),
+		gatewayProbe:  make(chan struct{}, 1),
+		lastLinkState: make(map[uint32]string),
+		lastCarrier:   make(map[uint32]bool),
+		flaps:         make(map[string][]time.Time),
+		wiredCarrier:  make(map[uint32]bool),
+		tunnels:       make(map[uint32]tunnel),
+		addrs:         make(map[uint32][]state.Address),
+	}
+}
+
+func TestCloseStopsRunWhileEventsFlow(t *testing.T) {
+	link, err := (&rtnetlink.LinkMessage{Index: 1, Attributes: &rtnetlink.LinkAttributes{Name: "lo"}}).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := make([]netlink.Message, 64)
+	for i := range events {
+		events[i] = netlink.Message{Header: netlink.Header{Type: RTM_NEWLINK}, Data: link}
+	}
+
+	w := testWatcher(t, events)
+	synced := make(chan struct{})
+	w.SetSyncHook(func() { close(synced) })
+
+	done := make(chan struct{})
+	go func() {
+		w.Run()
+		close(done)
+	}()
+	<-synced
+	time.Sleep(50 * time.Millisecond) // Let the event loop get busy
+
+	w.Close()
+	select {
+	case <-done:
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("Run still running 100ms after Close")
+	}
+}
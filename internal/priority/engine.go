@@ -0,0 +1,303 @@
+package priority
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	xnetlink "x-network/internal/netlink"
+	"x-network/internal/state"
+
+	"github.com/jsimonetti/rtnetlink"
+	"github.com/mdlayher/netlink"
+)
+
+const (
+	// baseMetric/metricStep give the link ranked i the metric base + i*step
+	baseMetric = 100
+	metricStep = 100
+
+	// settleDelay lets DHCP finish installing routes before re-ranking
+	settleDelay = time.Second
+)
+
+// DefaultOrder ranks link types when no order is configured
+var DefaultOrder = []string{"ethernet", "wifi", "usb", "bluetooth"}
+
+// link is one interface with an IPv4 default route
+type link struct {
+	index  uint32
+	name   string
+	kind   string
+	routes []rtnetlink.RouteMessage // Its default routes, lowest metric first
+}
+
+// Engine keeps exactly one link preferred for the default route
+// Links are ranked by type (see SetOrder); PrimaryPinned in state overrides the
+// ranking. Tunnels and other unranked links are never touched
+type Engine struct {
+	stateMgr *state.Manager
+	trigger  chan struct{}
+	stopCh   chan struct{}
+
+	mu    sync.Mutex
+	order []string
+
+	keyMu   sync.Mutex
+	lastKey string // Relevant state at the last trigger (see observe)
+}
+
+// NewEngine creates a priority engine with DefaultOrder
+func NewEngine(stateMgr *state.Manager) *Engine {
+	e := &Engine{
+		stateMgr: stateMgr,
+		trigger:  make(chan struct{}, 1),
+		stopCh:   make(chan struct{}),
+		order:    DefaultOrder,
+	}
+	stateMgr.AddListener(e.observe)
+	return e
+}
+
+// ParseOrder parses a comma-separated type list ("ethernet,wifi,usb,bluetooth")
+func ParseOrder(s string) ([]string, error) {
+	var order []string
+	seen := make(map[string]bool)
+	for _, kind := range strings.Split(s, ",") {
+		kind = strings.TrimSpace(kind)
+		switch kind {
+		case "ethernet", "wifi", "usb", "bluetooth":
+		default:
+			return nil, fmt.Errorf("unknown link type %q", kind)
+		}
+		if !seen[kind] {
+			seen[kind] = true
+			order = append(order, kind)
+		}
+	}
+	return order, nil
+}
+
+// SetOrder sets the type ranking, most preferred first
+func (e *Engine) SetOrder(order []string) {
+	e.mu.Lock()
+	e.order = order
+	e.mu.Unlock()
+	e.Trigger()
+}
+
+// Trigger requests a re-evaluation (non-blocking)
+func (e *Engine) Trigger() {
+	select {
+	case e.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Close stops the engine
+func (e *Engine) Close() {
+	close(e.stopCh)
+}
+
+// Run evaluates now, then whenever routes or link state change
+func (e *Engine) Run() {
+	go e.watchRoutes()
+
+	e.evaluate()
+	for {
+		select {
+		case <-e.stopCh:
+			return
+		case <-e.trigger:
+			time.Sleep(settleDelay)
+			e.evaluate()
+		}
+	}
+}
+
+// observe triggers on changes that can add, remove or re-rank links
+// Called on every state update, so only a change of these fields counts
+func (e *Engine) observe(st *state.State) {
+	key := fmt.Sprint(st.ConnectionState, st.UsbTetheringConnected, st.BtTetheringConnected,
+		st.EthernetCablePlugged, st.InterfaceName, st.UsbInterfaceName, st.BtInterfaceName,
+		st.PrimaryPinned, st.UsbFallbackActive)
+
+	e.keyMu.Lock()
+	changed := key != e.lastKey
+	e.lastKey = key
+	e.keyMu.Unlock()
+
+	if changed {
+		e.Trigger()
+	}
+}
+
+// evaluate ranks the links, fixes route metrics and publishes the primary
+func (e *Engine) evaluate() {
+	st := e.stateMgr.Get()
+
+	conn, err := rtnetlink.Dial(nil)
+	if err != nil {
+		log.Printf("Priority: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	links, err := e.rankedLinks(conn, st.PrimaryPinned)
+	if err != nil {
+		log.Printf("Priority: %v", err)
+		return
+	}
+
+	// The soft USB fallback owns the routes while it is active
+	if st.UsbFallbackActive {
+		sort.SliceStable(links, func(i, j int) bool {
+			return links[i].name == st.UsbInterfaceName && links[j].name != st.UsbInterfaceName
+		})
+	} else {
+		for i, l := range links {
+			if err := setMetric(conn, l, baseMetric+uint32(i)*metricStep); err != nil {
+				log.Printf("Priority: cannot set metric on %s: %v", l.name, err)
+			}
+		}
+	}
+
+	var kind, name string
+	if len(links) > 0 {
+		kind, name = links[0].kind, links[0].name
+	}
+	if st.PrimaryType == kind && st.PrimaryInterface == name {
+		return
+	}
+	log.Printf("Priority: primary connection %s (%s)", name, kind)
+	e.stateMgr.Update(func(st *state.State) {
+		st.PrimaryType = kind
+		st.PrimaryInterface = name
+		if kind != "" {
+			st.ConnectionType = kind
+		}
+	})
+}
+
+// rankedLinks lists links with an IPv4 default route, best first
+func (e *Engine) rankedLinks(conn *rtnetlink.Conn, pinned string) ([]link, error) {
+	routes, err := conn.Route.List()
+	if err != nil {
+		return nil, err
+	}
+
+	byIndex := make(map[uint32]*link)
+	for _, r := range routes {
+		if r.Family != syscall.AF_INET || r.DstLength != 0 || r.Attributes.Gateway == nil {
+			continue
+		}
+		if r.Table != syscall.RT_TABLE_MAIN && r.Attributes.Table != syscall.RT_TABLE_MAIN {
+			continue
+		}
+		l, ok := byIndex[r.Attributes.OutIface]
+		if !ok {
+			iface, err := net.InterfaceByIndex(int(r.Attributes.OutIface))
+			if err != nil {
+				continue
+			}
+			l = &link{index: r.Attributes.OutIface, name: iface.Name, kind: xnetlink.InterfaceType(iface.Name)}
+			byIndex[l.index] = l
+		}
+		l.routes = append(l.routes, r)
+	}
+
+	e.mu.Lock()
+	rank := make(map[string]int, len(e.order))
+	for i, kind := range e.order {
+		rank[kind] = i + 1
+	}
+	e.mu.Unlock()
+
+	var links []link
+	for _, l := range byIndex {
+		if l.name != pinned && rank[l.kind] == 0 {
+			continue // VPNs and unranked types keep their routes
+		}
+		sort.Slice(l.routes, func(i, j int) bool {
+			return l.routes[i].Attributes.Priority < l.routes[j].Attributes.Priority
+		})
+		links = append(links, *l)
+	}
+	sort.Slice(links, func(i, j int) bool {
+		a, b := links[i], links[j]
+		if (a.name == pinned) != (b.name == pinned) {
+			return a.name == pinned
+		}
+		if rank[a.kind] != rank[b.kind] {
+			return rank[a.kind] < rank[b.kind]
+		}
+		return a.name < b.name
+	})
+	return links, nil
+}
+
+// setMetric leaves l with a single default route at metric
+func setMetric(conn *rtnetlink.Conn, l link, metric uint32) error {
+	keep := -1
+	for i, r := range l.routes {
+		if r.Attributes.Priority == metric {
+			keep = i
+			break
+		}
+	}
+	if keep < 0 {
+		if err := xnetlink.ReplaceDefaultRoute(conn, l.index, l.routes[0].Attributes.Gateway, metric); err != nil {
+			return err
+		}
+	}
+	for i := range l.routes {
+		if i == keep {
+			continue
+		}
+		// ESRCH: the route went away with its lease meanwhile
+		if err := conn.Route.Delete(&l.routes[i]); err != nil && !errors.Is(err, syscall.ESRCH) {
+			return err
+		}
+	}
+	return nil
+}
+
+// watchRoutes triggers an evaluation on every IPv4 route change
+func (e *Engine) watchRoutes() {
+	conn, err := netlink.Dial(syscall.NETLINK_ROUTE, &netlink.Config{
+		Groups: 0x40, // RTMGRP_IPV4_ROUTE
+	})
+	if err != nil {
+		log.Printf("Priority: cannot watch routes: %v", err)
+		return
+	}
+	go func() {
+		<-e.stopCh
+		conn.Close()
+	}()
+
+	for {
+		msgs, err := conn.Receive()
+		if err != nil {
+			select {
+			case <-e.stopCh:
+				return
+			default:
+			}
+			log.Printf("Priority: route watch error: %v", err)
+			return
+		}
+		for _, msg := range msgs {
+			if msg.Header.Type == syscall.RTM_NEWROUTE || msg.Header.Type == syscall.RTM_DELROUTE {
+				e.Trigger()
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package probe
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+)
+
+// ErrNoPermission is returned when neither an unprivileged ping socket
+// (net.ipv4.ping_group_range) nor a raw ICMP socket can be opened
+var ErrNoPermission = errors.New("probe: no permission for ICMP socket")
+
+// pingSeq gives each echo a distinct sequence number
+var pingSeq atomic.Uint32
+
+// Ping sends a single ICMP echo to ip and returns the round-trip time
+// Uses an unprivileged ICMP datagram socket, falling back to a raw socket
+func Ping(ip net.IP, timeout time.Duration) (time.Duration, error) {
+	ip4 := ip.To4()
+	if ip4 == nil {
+		return 0, fmt.Errorf("probe: not an IPv4 address: %s", ip)
+	}
+
+	conn, unprivileged, err := listenICMP()
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	seq := int(pingSeq.Add(1) & 0xffff)
+	msg := icmp.Message{
+		Type: ipv4.ICMPTypeEcho,
+		Code: 0,
+		Body: &icmp.Echo{
+			ID:   os.Getpid() & 0xffff, // Kernel rewrites ID on unprivileged sockets
+			Seq:  seq,
+			Data: []byte("x-network"),
+		},
+	}
+	wire, err := msg.Marshal(nil)
+	if err != nil {
+		return 0, err
+	}
+
+	var dst net.Addr = &net.IPAddr{IP: ip4}
+	if unprivileged {
+		dst = &net.UDPAddr{IP: ip4}
+	}
+
+	start := time.Now()
+	if _, err := conn.WriteTo(wire, dst); err != nil {
+		return 0, err
+	}
+
+	conn.SetReadDeadline(start.Add(timeout))
+	buf := make([]byte, 1500)
+	for {
+		n, peer, err := conn.ReadFrom(buf)
+		if err != nil {
+			return 0, err
+		}
+		if !sameIP(peer, ip4) {
+			continue
+		}
+
+		reply, err := icmp.ParseMessage(1, buf[:n]) // 1 = ICMPv4
+		if err != nil || reply.Type != ipv4.ICMPTypeEchoReply {
+			continue
+		}
+		if echo, ok := reply.Body.(*icmp.Echo); ok && echo.Seq == seq {
+			return time.Since(start), nil
+		}
+	}
+}
+
+// listenICMP opens an ICMP socket, reporting whether it is the unprivileged kind
+func listenICMP() (*icmp.PacketConn, bool, error) {
+	conn, err := icmp.ListenPacket("udp4", "0.0.0.0")
+	if err == nil {
+		return conn, true, nil
+	}
+
+	conn, rawErr := icmp.ListenPacket("ip4:icmp", "0.0.0.0")
+	if rawErr == nil {
+		return conn, false, nil
+	}
+
+	if errors.Is(err, os.ErrPermission) || errors.Is(rawErr, os.ErrPermission) {
+		return nil, false, fmt.Errorf("%w: %v", ErrNoPermission, rawErr)
+	}
+	return nil, false, rawErr
+}
+
+// sameIP compares a peer address against ip
+func sameIP(addr net.Addr, ip net.IP) bool {
+	switch a := addr.(type) {
+	case *net.UDPAddr:
+		return a.IP.Equal(ip)
+	case *net.IPAddr:
+		return a.IP.Equal(ip)
+	}
+	return false
+}
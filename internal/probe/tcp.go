@@ -0,0 +1,41 @@
+package probe
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// TCPPorts are tried in turn by TCPPing (DNS, HTTPS)
+var TCPPorts = []int{53, 443}
+
+// TCPPing measures the TCP handshake time to ip, for hosts ICMP can't reach
+// A refused connection still proves the host answered, so it counts as a reply
+func TCPPing(ip net.IP, timeout time.Duration) (time.Duration, error) {
+	var lastErr error
+	for _, port := range TCPPorts {
+		start := time.Now()
+		conn, err := net.DialTimeout("tcp", net.JoinHostPort(ip.String(), strconv.Itoa(port)), timeout)
+		rtt := time.Since(start)
+		if err == nil {
+			conn.Close()
+			return rtt, nil
+		}
+		if errors.Is(err, syscall.ECONNREFUSED) {
+			return rtt, nil
+		}
+		lastErr = err
+	}
+	return 0, lastErr
+}
+
+// RoundTrip pings ip, falling back to TCPPing when ICMP sockets aren't permitted
+func RoundTrip(ip net.IP, timeout time.Duration) (time.Duration, error) {
+	rtt, err := Ping(ip, timeout)
+	if errors.Is(err, ErrNoPermission) {
+		return TCPPing(ip, timeout)
+	}
+	return rtt, err
+}
@@ -0,0 +1,231 @@
+package quality
+
+import (
+	"log"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"x-network/internal/probe"
+	"x-network/internal/state"
+)
+
+const (
+	// DefaultInterval is how often the gateway and internet target are probed
+	DefaultInterval = 10 * time.Second
+	// DefaultWindow is how many probe rounds latency and loss are averaged over
+	DefaultWindow = 6
+	// DefaultTarget is the internet host probed next to the gateway
+	DefaultTarget = "1.1.1.1"
+
+	// DefaultLatencyThreshold and DefaultLossThreshold mark the link degraded
+	DefaultLatencyThreshold = 200 * time.Millisecond
+	DefaultLossThreshold    = 10 // Percent
+
+	probeTimeout = 2 * time.Second
+)
+
+// Thresholds decide when the link counts as degraded
+type Thresholds struct {
+	Latency     time.Duration // Internet (or gateway) latency above this is degraded
+	LossPercent int           // Packet loss at or above this is degraded
+}
+
+// sample is one probe round
+type sample struct {
+	gateway  time.Duration // 0 = no reply
+	internet time.Duration
+	sent     int // Probes sent this round (1 without a gateway)
+	lost     int
+}
+
+// Monitor probes latency and loss while connected and publishes the rolling
+// result in state. It pauses in airplane mode and while disconnected
+type Monitor struct {
+	stateMgr *state.Manager
+	interval time.Duration
+	window   int
+	target   net.IP
+
+	mu         sync.Mutex
+	thresholds Thresholds
+	samples    []sample // Oldest first, at most window entries
+
+	stopCh  chan struct{}
+	running atomic.Bool
+}
+
+// NewMonitor creates a link quality monitor probing every interval and
+// averaging over window rounds (zero values pick the defaults)
+func NewMonitor(stateMgr *state.Manager, interval time.Duration, window int, target string) *Monitor {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	if window <= 0 {
+		window = DefaultWindow
+	}
+	ip := net.ParseIP(target)
+	if ip == nil {
+		ip = net.ParseIP(DefaultTarget)
+	}
+	return &Monitor{
+		stateMgr:   stateMgr,
+		interval:   interval,
+		window:     window,
+		target:     ip,
+		thresholds: Thresholds{DefaultLatencyThreshold, DefaultLossThreshold},
+		stopCh:     make(chan struct{}),
+	}
+}
+
+// SetThresholds changes the degraded thresholds; applied from the next round
+func (m *Monitor) SetThresholds(t Thresholds) {
+	m.mu.Lock()
+	m.thresholds = t
+	m.mu.Unlock()
+}
+
+// Run starts the probe loop
+func (m *Monitor) Run() {
+	if !m.running.CompareAndSwap(false, true) {
+		return
+	}
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.stopCh:
+			return
+		case <-ticker.C:
+			m.round()
+		}
+	}
+}
+
+// Stop stops the probe loop
+func (m *Monitor) Stop() {
+	if m.running.CompareAndSwap(true, false) {
+		close(m.stopCh)
+	}
+}
+
+// active reports whether probing makes sense: some link is connected and
+// the radios aren't switched off. Wired links count once they have an address
+func active(st *state.State) bool {
+	if st.AirplaneMode {
+		return false
+	}
+	wired := st.ConnectionType == "ethernet" && st.IpAddress != ""
+	return st.ConnectionState == state.StateConnected || st.UsbTetheringConnected || st.BtTetheringConnected || wired
+}
+
+// round probes once and publishes the updated window
+func (m *Monitor) round() {
+	st := m.stateMgr.Get()
+	if !active(&st) {
+		m.pause(&st)
+		return
+	}
+
+	var s sample
+	if gw := net.ParseIP(st.Gateway); gw != nil {
+		s.sent++
+		if rtt, err := probe.RoundTrip(gw, probeTimeout); err == nil {
+			s.gateway = atLeastOne(rtt)
+		} else {
+			s.lost++
+		}
+	}
+	s.sent++
+	if rtt, err := probe.RoundTrip(m.target, probeTimeout); err == nil {
+		s.internet = atLeastOne(rtt)
+	} else {
+		s.lost++
+	}
+
+	m.mu.Lock()
+	m.samples = append(m.samples, s)
+	if len(m.samples) > m.window {
+		m.samples = m.samples[len(m.samples)-m.window:]
+	}
+	gwMs, inetMs, loss := summarize(m.samples)
+	quality := state.LinkQualityGood
+	if over(inetMs, m.thresholds.Latency) || over(gwMs, m.thresholds.Latency) || int(loss) >= m.thresholds.LossPercent {
+		quality = state.LinkQualityDegraded
+	}
+	m.mu.Unlock()
+
+	m.stateMgr.Update(func(st *state.State) {
+		if !active(st) {
+			return // Disconnected while probing - pause clears on the next round
+		}
+		if st.LinkQuality != quality {
+			log.Printf("Link quality %s: gateway %d ms, internet %d ms, loss %d%%", quality, gwMs, inetMs, loss)
+		}
+		st.GatewayLatencyMs = gwMs
+		st.InternetLatencyMs = inetMs
+		st.PacketLossPercent = loss
+		st.LinkQuality = quality
+	})
+}
+
+// pause drops the window and clears the published values once
+func (m *Monitor) pause(st *state.State) {
+	m.mu.Lock()
+	m.samples = nil
+	m.mu.Unlock()
+
+	if st.LinkQuality == "" && st.GatewayLatencyMs == 0 && st.InternetLatencyMs == 0 && st.PacketLossPercent == 0 {
+		return
+	}
+	m.stateMgr.Update(func(st *state.State) {
+		st.GatewayLatencyMs = 0
+		st.InternetLatencyMs = 0
+		st.PacketLossPercent = 0
+		st.LinkQuality = ""
+	})
+}
+
+// summarize averages the replies per target and the loss over all probes
+func summarize(samples []sample) (gatewayMs, internetMs uint32, lossPercent uint8) {
+	var gwSum, inetSum time.Duration
+	var gwN, inetN, sent, lost int
+	for _, s := range samples {
+		if s.gateway > 0 {
+			gwSum += s.gateway
+			gwN++
+		}
+		if s.internet > 0 {
+			inetSum += s.internet
+			inetN++
+		}
+		sent += s.sent
+		lost += s.lost
+	}
+	if gwN > 0 {
+		gatewayMs = uint32((gwSum / time.Duration(gwN)).Milliseconds())
+	}
+	if inetN > 0 {
+		internetMs = uint32((inetSum / time.Duration(inetN)).Milliseconds())
+	}
+	if sent > 0 {
+		lossPercent = uint8(lost * 100 / sent)
+	}
+	return gatewayMs, internetMs, lossPercent
+}
+
+// over reports whether a latency in ms exceeds threshold (0 = no threshold)
+func over(ms uint32, threshold time.Duration) bool {
+	return threshold > 0 && time.Duration(ms)*time.Millisecond > threshold
+}
+
+// atLeastOne keeps a reply distinguishable from "no reply" in a sample
+func atLeastOne(d time.Duration) time.Duration {
+	if d <= 0 {
+		return time.Nanosecond
+	}
+	return d
+}
@@ -0,0 +1,175 @@
+package rfkill
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+
+	"x-network/internal/state"
+)
+
+// DevicePath is the kernel's rfkill event interface
+const DevicePath = "/dev/rfkill"
+
+// Radio types (enum rfkill_type)
+const (
+	TypeAll       uint8 = 0
+	TypeWLAN      uint8 = 1
+	TypeBluetooth uint8 = 2
+)
+
+// Event operations (enum rfkill_operation)
+const (
+	OpAdd       uint8 = 0
+	OpDel       uint8 = 1
+	OpChange    uint8 = 2
+	OpChangeAll uint8 = 3
+)
+
+// eventSize is the original struct rfkill_event; newer kernels append fields
+// but truncate reads to the size asked for
+const eventSize = 8
+
+// Event is one struct rfkill_event
+type Event struct {
+	Index uint32
+	Type  uint8
+	Op    uint8
+	Soft  bool
+	Hard  bool
+}
+
+// Device is the block state of one radio
+type Device struct {
+	Index uint32
+	Type  uint8
+	Soft  bool
+	Hard  bool
+}
+
+// Blocked reports whether the radio is off for either reason
+func (d Device) Blocked() bool {
+	return d.Soft || d.Hard
+}
+
+// ParseType maps a radio name to its rfkill type
+// Accepts rfkill(8) names: "wlan"/"wifi", "bluetooth", "all"
+func ParseType(name string) (uint8, error) {
+	switch name {
+	case "all":
+		return TypeAll, nil
+	case "wlan", "wifi":
+		return TypeWLAN, nil
+	case "bluetooth":
+		return TypeBluetooth, nil
+	}
+	return 0, fmt.Errorf("unknown radio type %q", name)
+}
+
+func decode(buf []byte) Event {
+	return Event{
+		Index: binary.NativeEndian.Uint32(buf[0:4]),
+		Type:  buf[4],
+		Op:    buf[5],
+		Soft:  buf[6] != 0,
+		Hard:  buf[7] != 0,
+	}
+}
+
+func encode(ev Event) []byte {
+	buf := make([]byte, eventSize)
+	binary.NativeEndian.PutUint32(buf[0:4], ev.Index)
+	buf[4] = ev.Type
+	buf[5] = ev.Op
+	if ev.Soft {
+		buf[6] = 1
+	}
+	if ev.Hard {
+		buf[7] = 1
+	}
+	return buf
+}
+
+// readEvent reads one event from an rfkill fd
+func readEvent(f *os.File) (Event, error) {
+	buf := make([]byte, eventSize)
+	n, err := f.Read(buf)
+	if err != nil {
+		return Event{}, err
+	}
+	if n < eventSize {
+		return Event{}, io.ErrUnexpectedEOF
+	}
+	return decode(buf), nil
+}
+
+// Devices returns the current state of every radio
+// On open the kernel queues one OpAdd per existing device; reading until the
+// queue is empty gives a snapshot without waiting for changes
+func Devices() ([]Device, error) {
+	fd, err := syscall.Open(DevicePath, syscall.O_RDONLY|syscall.O_NONBLOCK|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer syscall.Close(fd)
+
+	var devices []Device
+	buf := make([]byte, eventSize)
+	for {
+		n, err := syscall.Read(fd, buf)
+		if errors.Is(err, syscall.EAGAIN) {
+			return devices, nil
+		}
+		if err != nil {
+			return devices, err
+		}
+		if n < eventSize {
+			return devices, io.ErrUnexpectedEOF
+		}
+		if ev := decode(buf); ev.Op == OpAdd {
+			devices = append(devices, Device{Index: ev.Index, Type: ev.Type, Soft: ev.Soft, Hard: ev.Hard})
+		}
+	}
+}
+
+// SetBlocked soft-blocks or unblocks every radio of typ (TypeAll for all)
+// Hard blocks (hardware switch) cannot be lifted from software
+func SetBlocked(typ uint8, blocked bool) error {
+	f, err := os.OpenFile(DevicePath, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(encode(Event{Type: typ, Op: OpChangeAll, Soft: blocked}))
+	return err
+}
+
+// BlockOf combines the block state of every radio of typ
+func BlockOf(devices []Device, typ uint8) state.RadioBlock {
+	var b state.RadioBlock
+	for _, d := range devices {
+		if d.Type == typ {
+			b.Soft = b.Soft || d.Soft
+			b.Hard = b.Hard || d.Hard
+		}
+	}
+	return b
+}
+
+// AirplaneMode reports whether every radio is blocked
+// No radios at all is not airplane mode
+func AirplaneMode(devices []Device) bool {
+	if len(devices) == 0 {
+		return false
+	}
+	for _, d := range devices {
+		if !d.Blocked() {
+			return false
+		}
+	}
+	return true
+}
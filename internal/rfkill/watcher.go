@@ -0,0 +1,112 @@
+package rfkill
+
+import (
+	"errors"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"syscall"
+
+	"x-network/internal/state"
+)
+
+// Watcher mirrors radio block state into AirplaneMode, WifiBlocked and BluetoothBlocked
+type Watcher struct {
+	stateMgr *state.Manager
+	file     *os.File // Non-blocking rfkill fd wrapped for the runtime poller
+
+	mu      sync.Mutex
+	devices map[uint32]Device
+}
+
+// NewWatcher opens /dev/rfkill and publishes the current block state before
+// returning, so AirplaneMode is correct before the D-Bus service comes up
+func NewWatcher(stateMgr *state.Manager) (*Watcher, error) {
+	fd, err := syscall.Open(DevicePath, syscall.O_RDONLY|syscall.O_NONBLOCK|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Watcher{
+		stateMgr: stateMgr,
+		file:     os.NewFile(uintptr(fd), DevicePath),
+		devices:  make(map[uint32]Device),
+	}
+
+	// Drain the OpAdd burst the kernel queues on open
+	buf := make([]byte, eventSize)
+	for {
+		n, err := syscall.Read(fd, buf)
+		if err != nil || n < eventSize {
+			break
+		}
+		w.apply(decode(buf))
+	}
+	w.publish()
+
+	return w, nil
+}
+
+// Close stops the watcher
+func (w *Watcher) Close() {
+	w.file.Close() // Unblocks Run's pending read
+}
+
+// Run applies block changes until Close
+func (w *Watcher) Run() {
+	for {
+		ev, err := readEvent(w.file)
+		if err != nil {
+			if !errors.Is(err, os.ErrClosed) {
+				log.Printf("rfkill: read failed: %v", err)
+			}
+			return
+		}
+		w.apply(ev)
+		w.publish()
+	}
+}
+
+// Devices returns the tracked radios ordered by index
+func (w *Watcher) Devices() []Device {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	devices := make([]Device, 0, len(w.devices))
+	for _, d := range w.devices {
+		devices = append(devices, d)
+	}
+	sort.Slice(devices, func(i, j int) bool { return devices[i].Index < devices[j].Index })
+	return devices
+}
+
+func (w *Watcher) apply(ev Event) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	switch ev.Op {
+	case OpAdd, OpChange:
+		w.devices[ev.Index] = Device{Index: ev.Index, Type: ev.Type, Soft: ev.Soft, Hard: ev.Hard}
+	case OpDel:
+		delete(w.devices, ev.Index)
+	}
+}
+
+func (w *Watcher) publish() {
+	devices := w.Devices()
+	airplane := AirplaneMode(devices)
+	wifi := BlockOf(devices, TypeWLAN)
+	bt := BlockOf(devices, TypeBluetooth)
+
+	cur := w.stateMgr.Get()
+	if cur.AirplaneMode == airplane && cur.WifiBlocked == wifi && cur.BluetoothBlocked == bt {
+		return
+	}
+	log.Printf("rfkill: airplane mode %v, wifi %s, bluetooth %s", airplane, wifi, bt)
+	w.stateMgr.Update(func(st *state.State) {
+		st.AirplaneMode = airplane
+		st.WifiBlocked = wifi
+		st.BluetoothBlocked = bt
+	})
+}
@@ -0,0 +1,94 @@
+package settings
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Settings are daemon toggles changed over D-Bus that survive restarts
+// Pointer fields distinguish "never set" from false so defaults can change
+type Settings struct {
+	UsbAutoConnect  *bool            `json:"usb_autoconnect,omitempty"`
+	NetworkPriority map[string]int32 `json:"network_priority,omitempty"` // By SSID, higher first (IWD has no such key)
+	BlockedNetworks []string         `json:"blocked_networks,omitempty"` // SSIDs never auto-joined nor one-tap connected
+	ScanMode        string           `json:"scan_mode,omitempty"`        // SetScanParams mode ("" = active)
+	ScanDwellMs     uint32           `json:"scan_dwell_ms,omitempty"`    // SetScanParams dwell (0 = driver default)
+}
+
+// UsbAutoConnectOr returns UsbAutoConnect, or def when it was never set
+func (s Settings) UsbAutoConnectOr(def bool) bool {
+	if s.UsbAutoConnect == nil {
+		return def
+	}
+	return *s.UsbAutoConnect
+}
+
+// Store persists Settings as JSON
+type Store struct {
+	path     string
+	mu       sync.Mutex
+	settings Settings
+}
+
+// DefaultPath returns ~/.config/x-network/settings.json
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = "/etc"
+	}
+	return filepath.Join(dir, "x-network", "settings.json")
+}
+
+// NewStore loads settings from path (a missing file means all defaults)
+// On a parse error the returned store is still usable, with defaults
+func NewStore(path string) (*Store, error) {
+	s := &Store{path: path}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(data, &s.settings); err != nil {
+		s.settings = Settings{}
+		return s, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Get returns a copy of the current settings
+func (s *Store) Get() Settings {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.settings
+}
+
+// Update applies fn and writes the result
+func (s *Store) Update(fn func(*Settings)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(&s.settings)
+	return s.save()
+}
+
+// save writes settings atomically (caller holds mu)
+func (s *Store) save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s.settings, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
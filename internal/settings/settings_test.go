@@ -0,0 +1,33 @@
+package settings
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestScanParamsSurviveReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json")
+	s, err := NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if set := s.Get(); set.ScanMode != "" || set.ScanDwellMs != 0 {
+		t.Fatalf("fresh store has scan params %q/%d", set.ScanMode, set.ScanDwellMs)
+	}
+
+	err = s.Update(func(set *Settings) {
+		set.ScanMode = "passive"
+		set.ScanDwellMs = 120
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if set := reloaded.Get(); set.ScanMode != "passive" || set.ScanDwellMs != 120 {
+		t.Errorf("reloaded scan params = %q/%d, want passive/120", set.ScanMode, set.ScanDwellMs)
+	}
+}
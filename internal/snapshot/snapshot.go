@@ -0,0 +1,298 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"x-network/internal/iwd"
+	"x-network/internal/state"
+)
+
+const (
+	// formatVersion is bumped when the file layout changes; other versions are ignored
+	formatVersion = 1
+
+	// maxAge discards snapshots of a daemon that stayed down longer than this
+	maxAge = 24 * time.Hour
+
+	// saveDelay coalesces bursts of changes into one write
+	saveDelay = 2 * time.Second
+
+	bootIDPath = "/proc/sys/kernel/random/boot_id"
+)
+
+// Snapshot is the session context that outlives a daemon restart
+type Snapshot struct {
+	Version int       `json:"version"`
+	BootID  string    `json:"boot_id"` // A reboot invalidates the connection part
+	SavedAt time.Time `json:"saved_at"`
+
+	// Connection at save time (empty while not connected)
+	ActiveSSID     string    `json:"active_ssid,omitempty"`
+	ConnectionType string    `json:"connection_type,omitempty"`
+	ConnectedSince time.Time `json:"connected_since"`
+
+	// Captive portal check of ActiveSSID ("" CaptiveSSID = not checked yet)
+	CaptiveSSID     string `json:"captive_ssid,omitempty"`
+	CaptiveDetected bool   `json:"captive_detected,omitempty"`
+	CaptiveURL      string `json:"captive_url,omitempty"`
+	CaptiveEndpoint string `json:"captive_endpoint,omitempty"`
+	CaptiveStage    string `json:"captive_stage,omitempty"`
+
+	History []iwd.Attempt `json:"history,omitempty"` // Newest first
+}
+
+// DefaultPath returns $XDG_STATE_HOME/x-network/state.json
+// (~/.local/state when XDG_STATE_HOME is unset)
+func DefaultPath() string {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return filepath.Join("/var/lib", "x-network", "state.json")
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "x-network", "state.json")
+}
+
+// Store saves snapshots on significant state changes and restores the last
+// one at startup
+type Store struct {
+	path     string
+	stateMgr *state.Manager
+	iwd      *iwd.Client   // nil without IWD: nothing to reconcile
+	history  []iwd.Attempt // Restored history carried over while iwd is nil
+
+	keyMu   sync.Mutex
+	lastSeq uint64 // Seq of the last state observed
+	lastKey string // significantKey of the last state that queued a save
+
+	writeMu sync.Mutex // Serializes writes
+	kick    chan struct{}
+	stopCh  chan struct{}
+}
+
+// NewStore creates a snapshot store writing to path
+func NewStore(path string, stateMgr *state.Manager, iwdClient *iwd.Client) *Store {
+	return &Store{
+		path:     path,
+		stateMgr: stateMgr,
+		iwd:      iwdClient,
+		kick:     make(chan struct{}, 1),
+		stopCh:   make(chan struct{}),
+	}
+}
+
+// Restore reads the last snapshot and reconciles it with the current state.
+// The connection context (connected-since, captive result) only comes back
+// when IWD still reports the same connected network in the same boot; the
+// startup hook is skipped then. Corrupt, foreign or stale files are ignored
+// Call it after the netlink watcher's initial fetch: the connection type is
+// only compared once something has set it
+func (s *Store) Restore() {
+	snap, err := load(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	if err != nil {
+		log.Printf("Warning: ignoring state snapshot: %v", err)
+		return
+	}
+	if s.iwd == nil {
+		s.history = snap.History
+		return
+	}
+	s.iwd.RestoreHistory(snap.History)
+
+	if snap.ActiveSSID == "" {
+		return
+	}
+	restored := false
+	s.stateMgr.Update(func(st *state.State) {
+		if !sameConnection(st, snap) {
+			return
+		}
+		if !snap.ConnectedSince.IsZero() {
+			st.ConnectedSince = snap.ConnectedSince
+		}
+		if snap.CaptiveSSID == st.ActiveSSID {
+			st.CaptivePortalDetected = snap.CaptiveDetected
+			st.CaptivePortalURL = snap.CaptiveURL
+			st.CaptivePortalEndpoint = snap.CaptiveEndpoint
+			st.CaptivePortalStage = snap.CaptiveStage
+			st.LastCaptiveCheckSSID = snap.CaptiveSSID
+		}
+		st.IsStartup = false // Same session as before the restart - not a fresh connection
+		restored = true
+	})
+	if restored {
+		log.Printf("Restored session on %s (connected since %s)", snap.ActiveSSID, snap.ConnectedSince.Format(time.RFC3339))
+	} else {
+		log.Printf("State snapshot of %s is out of date, discarding it", snap.ActiveSSID)
+	}
+}
+
+// sameConnection reports whether st is still the connection snap recorded
+func sameConnection(st *state.State, snap Snapshot) bool {
+	if st.ConnectionState != state.StateConnected || st.ActiveSSID != snap.ActiveSSID {
+		return false
+	}
+	// Unset until the netlink watcher has seen the link
+	return st.ConnectionType == "" || snap.ConnectionType == "" || st.ConnectionType == snap.ConnectionType
+}
+
+// load reads and validates a snapshot
+func load(path string) (Snapshot, error) {
+	var snap Snapshot
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return snap, err
+	}
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return Snapshot{}, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if snap.Version != formatVersion {
+		return Snapshot{}, fmt.Errorf("%s: unsupported version %d", path, snap.Version)
+	}
+
+	now := time.Now()
+	switch {
+	case snap.BootID == "" || snap.BootID != bootID():
+		return Snapshot{}, fmt.Errorf("%s: saved before the last reboot", path)
+	case snap.SavedAt.After(now) || now.Sub(snap.SavedAt) > maxAge:
+		return Snapshot{}, fmt.Errorf("%s: stale (saved %s)", path, snap.SavedAt.Format(time.RFC3339))
+	case snap.ConnectedSince.After(snap.SavedAt):
+		snap.ConnectedSince = time.Time{}
+	}
+	if len(snap.History) > iwd.HistorySize {
+		snap.History = snap.History[:iwd.HistorySize]
+	}
+	return snap, nil
+}
+
+// Attach saves a snapshot shortly after every significant state change
+func (s *Store) Attach() {
+	st := s.stateMgr.Get()
+	s.lastSeq, s.lastKey = st.Seq, significantKey(st)
+	go s.run()
+	s.stateMgr.AddListener(s.observe)
+}
+
+// observe queues a save when something worth restoring changed
+func (s *Store) observe(st *state.State) {
+	key := significantKey(*st)
+	s.keyMu.Lock()
+	if st.Seq <= s.lastSeq {
+		s.keyMu.Unlock()
+		return // Older than a state already seen
+	}
+	s.lastSeq = st.Seq
+	changed := key != s.lastKey
+	s.lastKey = key
+	s.keyMu.Unlock()
+
+	if changed {
+		select {
+		case s.kick <- struct{}{}:
+		default: // A save is already queued
+		}
+	}
+}
+
+// significantKey covers the fields a snapshot holds; the connection history
+// only changes together with ConnectionState
+func significantKey(st state.State) string {
+	return fmt.Sprintf("%s|%s|%s|%d|%d", st.ConnectionState, st.ActiveSSID, st.ConnectionType,
+		st.ConnectedSince.UnixNano(), st.CaptiveCheckSeq)
+}
+
+// run writes queued snapshots until Close
+func (s *Store) run() {
+	for {
+		select {
+		case <-s.stopCh:
+			return
+		case <-s.kick:
+		}
+		select {
+		case <-s.stopCh:
+			return
+		case <-time.After(saveDelay):
+		}
+		if err := s.Save(); err != nil {
+			log.Printf("Warning: failed to save state snapshot: %v", err)
+		}
+	}
+}
+
+// Close stops the background writer and saves a final snapshot
+func (s *Store) Close() {
+	select {
+	case <-s.stopCh:
+		return
+	default:
+		close(s.stopCh)
+	}
+	if err := s.Save(); err != nil {
+		log.Printf("Warning: failed to save state snapshot: %v", err)
+	}
+}
+
+// Save writes the current state atomically
+func (s *Store) Save() error {
+	st := s.stateMgr.Get()
+	snap := Snapshot{
+		Version: formatVersion,
+		BootID:  bootID(),
+		SavedAt: time.Now(),
+	}
+	if st.ConnectionState == state.StateConnected {
+		snap.ActiveSSID = st.ActiveSSID
+		snap.ConnectionType = st.ConnectionType
+		snap.ConnectedSince = st.ConnectedSince
+		if st.LastCaptiveCheckSSID != "" && st.LastCaptiveCheckSSID == st.ActiveSSID {
+			snap.CaptiveSSID = st.LastCaptiveCheckSSID
+			snap.CaptiveDetected = st.CaptivePortalDetected
+			snap.CaptiveURL = st.CaptivePortalURL
+			snap.CaptiveEndpoint = st.CaptivePortalEndpoint
+			snap.CaptiveStage = st.CaptivePortalStage
+		}
+	}
+	snap.History = s.history
+	if s.iwd != nil {
+		snap.History = s.iwd.ConnectionHistory(0)
+	}
+
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// bootID identifies the running boot ("" if the kernel doesn't say)
+func bootID() string {
+	data, err := os.ReadFile(bootIDPath)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
@@ -0,0 +1,29 @@
+package snapshot
+
+import (
+	"testing"
+
+	"x-network/internal/state"
+)
+
+func TestSameConnection(t *testing.T) {
+	snap := Snapshot{ActiveSSID: "Cafe", ConnectionType: "wifi"}
+	tests := []struct {
+		name  string
+		st    state.State
+		match bool
+	}{
+		{"same", state.State{ConnectionState: state.StateConnected, ActiveSSID: "Cafe", ConnectionType: "wifi"}, true},
+		{"type not seen yet", state.State{ConnectionState: state.StateConnected, ActiveSSID: "Cafe"}, true},
+		{"other type", state.State{ConnectionState: state.StateConnected, ActiveSSID: "Cafe", ConnectionType: "ethernet"}, false},
+		{"other network", state.State{ConnectionState: state.StateConnected, ActiveSSID: "Home", ConnectionType: "wifi"}, false},
+		{"not connected", state.State{ConnectionState: state.StateDisconnected, ActiveSSID: "Cafe", ConnectionType: "wifi"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sameConnection(&tt.st, snap); got != tt.match {
+				t.Errorf("sameConnection() = %v, want %v", got, tt.match)
+			}
+		})
+	}
+}
@@ -1,10 +1,16 @@
 package state
 
 import (
+	"net"
+	"strconv"
 	"sync"
 	"time"
 )
 
+// sessionResumeWindow is how long a dropped WiFi connection may take to come
+// back to the same network without restarting ConnectedSince (roam bounces)
+const sessionResumeWindow = 5 * time.Second
+
 // ConnectionState represents WiFi connection state
 type ConnectionState string
 
@@ -16,16 +22,110 @@ const (
 	StateFailed       ConnectionState = "failed"
 )
 
+// Connectivity levels
+const (
+	ConnectivityNone    = "none"    // No usable address
+	ConnectivityLimited = "limited" // Local network only: no default route, or no probe answered
+	ConnectivityPortal  = "portal"  // Behind a captive portal
+	ConnectivityFull    = "full"    // Probes answered as expected
+)
+
+// HotspotStateChanged reasons
+const (
+	HotspotReasonStarted   = "started"
+	HotspotReasonStopped   = "stopped"    // StopHotspot
+	HotspotReasonAPStopped = "ap-stopped" // AP went down without StopHotspot (IWD restart, mode change)
+	HotspotReasonFailed    = "failed"     // Start call failed
+	HotspotReasonTimeout   = "timeout"    // No clients for the requested timeout
+)
+
+// Link quality levels (see the quality package)
+const (
+	LinkQualityGood     = "good"
+	LinkQualityDegraded = "degraded" // Latency or loss over the configured thresholds
+)
+
+// DisconnectReason values - only causes we know for certain, "" otherwise
+const (
+	DisconnectReasonLocal      = "local_choice" // Disconnect requested through the daemon
+	DisconnectReasonRadioOff   = "radio_off"    // WiFi powered off or rfkill-blocked
+	DisconnectReasonIWDStopped = "iwd_stopped"  // IWD left the bus
+)
+
+// Error codes for LastErrorCode and the Error signal
+const (
+	ErrCodeAuthFailed    = "auth_failed"    // The network rejected the credentials
+	ErrCodeConnectFailed = "connect_failed" // The attempt failed without a specific cause
+	ErrCodeOutOfRange    = "out_of_range"
+	ErrCodeTimeout       = "timeout"
+	ErrCodeUnknown       = "unknown"
+
+	ErrCodeIWDUnavailable  = "iwd_unavailable"
+	ErrCodeInitializing    = "initializing" // IWD is (re)starting - retry shortly
+	ErrCodeNetworkNotFound = "network_not_found"
+	ErrCodeDHCPFailed      = "dhcp_failed"
+	ErrCodeScanFailed      = "scan_failed"
+	ErrCodeHotspotFailed   = "hotspot_failed"
+	ErrCodeRfkillFailed    = "rfkill_failed"
+	ErrCodeHardBlocked     = "hard_blocked" // Radio disabled by a hardware switch
+	ErrCodeFailed          = "failed"       // Generic operation failure
+
+	// Translated from IWD D-Bus error names (see iwd.ClassifyConnectError)
+	ErrCodeInvalidFormat = "invalid_format" // Passphrase rejected before trying (length/charset)
+	ErrCodeBusy          = "busy"
+	ErrCodeNoAgent       = "no_agent" // IWD needed a passphrase and no agent answered
+	ErrCodeNotSupported  = "not_supported"
+	ErrCodeInProgress    = "in_progress" // Already connecting
+	ErrCodeAborted       = "aborted"
+	ErrCodeNotConnected  = "not_connected"
+	ErrCodeBSSIDMismatch = "bssid_mismatch" // Pinned connect associated to another BSS
+)
+
 // Network represents a WiFi network
 type Network struct {
 	SSID       string
 	Security   string // "open", "psk", "sae", "8021x"
 	SignalDBm  int16  // Raw RSSI in dBm
 	Signal     uint8  // Derived percentage 0-100
+	SignalBars uint8  // Derived bars 0-4
 	Connected  bool
 	Saved      bool
 	Frequency  uint32 // MHz
 	ObjectPath string // IWD D-Bus path
+	BSSCount   int    // Access points advertising this network (merged entries sum theirs)
+}
+
+// RadioBlock is the rfkill state of one radio type
+type RadioBlock struct {
+	Soft bool // Blocked from software (rfkill, airplane mode)
+	Hard bool // Blocked by a hardware switch
+}
+
+// String returns "hard", "soft" or "none" (hard wins when both are set)
+func (b RadioBlock) String() string {
+	switch {
+	case b.Hard:
+		return "hard"
+	case b.Soft:
+		return "soft"
+	}
+	return "none"
+}
+
+// KnownNetwork is a saved network profile
+type KnownNetwork struct {
+	SSID          string
+	Security      string // "open", "psk", "8021x"
+	Hidden        bool
+	AutoConnect   bool
+	LastConnected time.Time // Zero if never connected
+	Priority      int32     // Higher is preferred (SetNetworkPriority, default 0)
+}
+
+// Address is one address of an interface
+type Address struct {
+	CIDR   string // Address with prefix length, e.g. "192.168.1.42/24"
+	Family string // "inet" or "inet6"
 }
 
 // State holds all network state
@@ -33,74 +133,180 @@ type State struct {
 	// WiFi state
 	WifiEnabled     bool
 	WifiScanning    bool
+	LastScanTime    time.Time // Completion time of the last successful scan
+	ScanMode        string    // "active" or "passive" ("" = active)
+	ScanDwellMs     uint32    // Per-channel dwell, 0 = driver default
 	ConnectionState ConnectionState
+	Connectivity    string // See Connectivity* constants
+	HasInternet     bool   // InternetProbeURL answered 204 over the default route
+	AgentRegistered bool   // Our IWD agent is registered (false = password prompts won't work)
+
+	// Probe result per interface with a default route ("wlan0": "portal", "usb0": "full")
+	// Replaced wholesale on update so snapshots never share it
+	InterfaceConnectivity map[string]string
 
 	// Active connection
-	ActiveSSID     string
-	ConnectingSSID string // Set during connection attempt, cleared on success/failure
-	ActiveSecurity string
-	SignalRSSI     int16
-	SignalStrength uint8
-	Frequency      uint32
+	ActiveSSID         string
+	ConnectedSince     time.Time // When ConnectionState last became connected (zero while not connected)
+	ConnectingSSID     string    // Set during connection attempt, cleared on success/failure
+	ActiveSecurity     string
+	SecurityDowngraded bool // WPA3-capable network joined over WPA2 (transition mode)
+	SignalRSSI         int16
+	SignalStrength     uint8
+	SignalBars         uint8  // 0-4, see DBmToBars
+	Frequency          uint32 // MHz, from StationDiagnostic (0 when not connected)
+	Channel            uint32 // From Frequency, see FrequencyToChannel
+	ChannelWidth       uint32 // MHz (20/40/80/160/320), 0 when IWD doesn't report it
+	WifiGeneration     string // "Wi-Fi 4" ... "Wi-Fi 6E" from the PHY mode, "" if unknown
 
 	// Network info
-	InterfaceName string
-	MacAddress    string
-	IpAddress     string
-	Gateway       string
+	LinkFlaps        map[string][]time.Time // Carrier transitions per interface (rolling window)
+	LinkEventIface   string                 // Last interface whose up/carrier state changed
+	LinkEventUp      bool                   // Its new state: operationally up with carrier
+	LinkEventSeq     uint64                 // Bumped on every transition (drives InterfaceChanged)
+	InterfaceName    string
+	MacAddress       string
+	IpAddress        string    // Primary IPv4 of the active interface
+	IpPrefixLen      uint8     // Prefix length of IpAddress (0 = unknown)
+	Addresses        []Address // Every address of the active interface, IPv4 first
+	Gateway          string
+	GatewayReachable bool // Gateway answers ping/ARP (local link is healthy)
+
+	// Carrier on a physical Ethernet port - true even when DHCP hasn't
+	// produced an address ("cable connected, no IP")
+	EthernetCablePlugged bool
+
+	// DNS (resolved, resolv.conf or DHCP lease)
+	DnsServers    []string
+	SearchDomains []string
 
 	// Traffic (bytes/sec)
 	TrafficIn  uint64
 	TrafficOut uint64
 
+	// Link quality from the probe loop (zero while paused or disabled)
+	GatewayLatencyMs  uint32
+	InternetLatencyMs uint32
+	PacketLossPercent uint8
+	LinkQuality       string // LinkQuality* ("" = not measured)
+
 	// Network lists
-	Networks      []Network
-	SavedNetworks []string
+	Networks        []Network
+	SavedNetworks   []string       // Names only - derived from KnownNetworks
+	KnownNetworks   []KnownNetwork // Saved profiles with details
+	BlockedNetworks []string       // Blacklisted SSIDs (BlacklistNetwork), sorted
 
 	// Features
 	AirplaneMode          bool
-	CaptivePortalDetected  bool
-	CaptivePortalURL       string
-	LastCaptiveCheckSSID   string // Guard: last SSID checked for captive portal (reset on disconnect)
-	HotspotActive          bool
-	HotspotSSID           string
+	WifiBlocked           RadioBlock
+	BluetoothBlocked      RadioBlock
+	CaptivePortalDetected bool
+	CaptivePortalURL      string
+	LastCaptiveCheckSSID  string    // Guard: last SSID checked for captive portal (reset on disconnect)
+	CaptiveCheckSeq       uint64    // Bumped on every completed check (drives CaptivePortalStatus)
+	CaptivePortalEndpoint string    // Probe endpoint that triggered detection
+	CaptivePortalStage    string    // "http" or "https" stage that triggered detection
+	HotspotActive         bool      // From AccessPoint.Started, not from our own calls
+	HotspotSSID           string    // From AccessPoint.Name (kept after stop for the signal)
+	HotspotClients        uint32    // Stations associated to the AP
+	HotspotReason         string    // Why the hotspot last changed (see HotspotReason* constants)
+	HotspotEventSeq       uint64    // Bumped on every start/stop/failure (drives HotspotStateChanged)
+	HotspotExpiresAt      time.Time // Auto-off time if no client joins (zero = no timeout)
+	HotspotSince          time.Time // When the running hotspot started (zero when off)
+
+	// Hotspot credentials for HotspotQRString - never logged or dumped
+	HotspotPassword string `json:"-"`
+	HotspotSecurity string `json:"-"`
 
 	// Connection type
-	ConnectionType string // "wifi", "ethernet", "usb"
+	ConnectionType string // "wifi", "ethernet", "usb", "bluetooth"
+
+	// Default route ownership (see internal/priority)
+	PrimaryType      string // Type of the link owning the default route ("" = none)
+	PrimaryInterface string // Its interface name
+	PrimaryPinned    string // Interface pinned by SetPrimaryConnection ("" = rank by type)
 
 	// USB Tethering state
-	UsbInterfaceDetected  bool   // USB interface exists
-	UsbTetheringAvailable bool   // Phone ready (carrier up)
-	UsbTetheringConnected bool   // IP + route (actually usable)
-	UsbInterfaceName      string // e.g., "enp0s26u1u2"
-	UsbInterfaceIndex     uint32 // ifindex - stable identifier
+	UsbInterfaceDetected  bool      // USB interface exists
+	UsbTetheringAvailable bool      // Phone ready (carrier up)
+	UsbTetheringConnected bool      // IP + route (actually usable), IPv4 or IPv6
+	UsbIpAddressV6        string    // Global IPv6 address on the USB link (SLAAC or DHCPv6)
+	UsbInterfaceName      string    // e.g., "enp0s26u1u2"
+	UsbInterfaceIndex     uint32    // ifindex - stable identifier
+	UsbFallbackActive     bool      // Default route moved to USB because WiFi has no internet
+	UsbAutoConnect        bool      // Bring up USB links and run DHCP on carrier (false = only RequestUsbNetwork)
+	UsbConnectedSince     time.Time // When UsbTetheringConnected last became true (zero while not connected)
+
+	// VPN tunnel (WireGuard, tun/tap) - reported alongside the physical connection
+	VpnActive    bool   // A tunnel link is up
+	VpnInterface string // e.g., "wg0" ("" = none)
+
+	// Bluetooth PAN tethering
+	BtTetheringAvailable bool   // A paired device offers the NAP profile
+	BtTetheringConnected bool   // IP + route over the bnep interface
+	BtDeviceName         string // Phone we tether through (BlueZ alias)
+	BtInterfaceName      string // e.g., "bnep0"
+	BtInterfaceIndex     uint32 // ifindex - stable identifier
+
+	// DHCP lease (native client only - empty when an external client is used)
+	DhcpInterface   string
+	DhcpServer      string
+	DhcpLeaseExpiry time.Time
+	DhcpDNS         []string
+
+	// Why the last established WiFi connection dropped ("" = unknown, see DisconnectReason*)
+	DisconnectReason string
+
+	// Watcher health, visible in GetState
+	NetlinkReconnects uint32 // Times the netlink watcher re-dialed a dead socket
 
 	// Error reporting
-	LastError string // Last error message for UI feedback
+	LastError     string // Last error message for UI feedback
+	LastErrorCode string // Machine-readable reason (see ErrCode* constants)
+
+	// Resume tracking for the on-connect hook (internal, not exposed via D-Bus)
+	WasResumed           bool      `json:"-"` // Set by PrepareForSleep(false)
+	ResumeTimestamp      time.Time `json:"-"` // When resume happened
+	ConnectHookTriggered bool      `json:"-"` // Dedup: prevent double trigger
 
-	// Resume tracking for weather refresh (internal, not exposed via D-Bus)
-	WasResumed       bool      // Set by PrepareForSleep(false)
-	ResumeTimestamp  time.Time // When resume happened
-	WeatherTriggered bool      // Dedup: prevent double trigger
+	// Startup tracking - run the on-connect hook on first network connection at boot
+	IsStartup          bool      `json:"-"` // Set true at daemon start, cleared after the hook succeeds (or retry spent)
+	StartupTimestamp   time.Time `json:"-"` // When the daemon started (bounds the retry window)
+	StartupHookRetried bool      `json:"-"` // Dedup: only one retry if the first startup hook fails
 
-	// Startup tracking - trigger weather on first network connection at boot
-	IsStartup bool // Set true at daemon start, cleared after first weather trigger
+	// Bumped by every Update; observers receive states in Seq order
+	Seq uint64 `json:"-"`
+
+	// Last WiFi session, kept briefly after a drop so a bounce can resume it
+	lostSince time.Time
+	lostSSID  string
+	lostAt    time.Time
 }
 
 // Manager manages state with thread-safe access
 type Manager struct {
-	mu       sync.RWMutex
-	state    State
-	onChange func(*State) // Callback when state changes
+	mu        sync.RWMutex
+	state     State
+	onChange  func(*State)   // Callback when state changes
+	listeners []func(*State) // Additional observers (AddListener)
+
+	// Notifications go out one update at a time, in Seq order
+	notifyMu   sync.Mutex
+	notifyCond *sync.Cond
+	delivered  uint64 // Seq of the last update whose observers have returned
 }
 
 // NewManager creates a new state manager
 func NewManager() *Manager {
-	return &Manager{
+	m := &Manager{
 		state: State{
 			ConnectionState: StateDisconnected,
+			Connectivity:    ConnectivityNone,
+			UsbAutoConnect:  true,
 		},
 	}
+	m.notifyCond = sync.NewCond(&m.notifyMu)
+	return m
 }
 
 // SetOnChange sets the callback for state changes
@@ -110,6 +316,14 @@ func (m *Manager) SetOnChange(fn func(*State)) {
 	m.mu.Unlock()
 }
 
+// AddListener registers an extra observer called after every update
+// Listeners get a copy, in update order, and must not block or call Update
+func (m *Manager) AddListener(fn func(*State)) {
+	m.mu.Lock()
+	m.listeners = append(m.listeners, fn)
+	m.mu.Unlock()
+}
+
 // Get returns a copy of current state
 func (m *Manager) Get() State {
 	m.mu.RLock()
@@ -118,16 +332,93 @@ func (m *Manager) Get() State {
 }
 
 // Update atomically updates state and triggers callback
+// Observers run outside the state lock, but never concurrently and never out
+// of order: an update waits until the observers of the one before it returned
 func (m *Manager) Update(fn func(*State)) {
 	m.mu.Lock()
+	prev := m.state
 	fn(&m.state)
+	m.state.stampSessions(&prev, time.Now())
+	m.state.Seq++
 	stateCopy := m.state
 	onChange := m.onChange
+	listeners := m.listeners
 	m.mu.Unlock()
 
+	m.notifyMu.Lock()
+	for m.delivered != stateCopy.Seq-1 {
+		m.notifyCond.Wait()
+	}
 	if onChange != nil {
 		onChange(&stateCopy)
 	}
+	for _, fn := range listeners {
+		fn(&stateCopy)
+	}
+	m.delivered = stateCopy.Seq
+	m.notifyCond.Broadcast()
+	m.notifyMu.Unlock()
+}
+
+// stampSessions keeps ConnectedSince, UsbConnectedSince and HotspotSince in
+// step with the transitions made by an update
+func (s *State) stampSessions(prev *State, now time.Time) {
+	wasConnected := prev.ConnectionState == StateConnected
+	isConnected := s.ConnectionState == StateConnected
+	switch {
+	case isConnected && !wasConnected:
+		if s.lostSSID != "" && s.lostSSID == s.ActiveSSID && now.Sub(s.lostAt) < sessionResumeWindow {
+			s.ConnectedSince = s.lostSince
+		} else {
+			s.ConnectedSince = now
+		}
+		s.lostSSID = ""
+	case wasConnected && !isConnected:
+		s.lostSince = prev.ConnectedSince
+		s.lostSSID = prev.ActiveSSID
+		s.lostAt = now
+		s.ConnectedSince = time.Time{}
+	}
+
+	if s.UsbTetheringConnected && !prev.UsbTetheringConnected {
+		s.UsbConnectedSince = now
+	} else if !s.UsbTetheringConnected {
+		s.UsbConnectedSince = time.Time{}
+	}
+
+	if s.HotspotActive && !prev.HotspotActive {
+		s.HotspotSince = now
+	} else if !s.HotspotActive {
+		s.HotspotSince = time.Time{}
+	}
+}
+
+// SetCaptiveResult records a captive portal check and keeps Connectivity in step
+func (s *State) SetCaptiveResult(detected bool, url, endpoint, stage string) {
+	s.CaptivePortalDetected = detected
+	s.CaptivePortalURL = url
+	s.CaptivePortalEndpoint = endpoint
+	s.CaptivePortalStage = stage
+	s.CaptiveCheckSeq++
+	if detected && s.Connectivity == ConnectivityFull {
+		s.Connectivity = ConnectivityPortal
+	} else if !detected && s.Connectivity == ConnectivityPortal {
+		s.Connectivity = ConnectivityFull
+	}
+}
+
+// SetHotspotState records a hotspot start, stop or failure
+func (s *State) SetHotspotState(active bool, ssid, reason string) {
+	s.HotspotActive = active
+	s.HotspotSSID = ssid
+	s.HotspotReason = reason
+	s.HotspotEventSeq++
+	if !active {
+		s.HotspotClients = 0
+		s.HotspotExpiresAt = time.Time{}
+		s.HotspotPassword = ""
+		s.HotspotSecurity = ""
+	}
 }
 
 // Helper: Convert dBm to percentage
@@ -142,6 +433,46 @@ func DBmToPercent(dBm int16) uint8 {
 	return uint8(2 * (int(dBm) + 100))
 }
 
+// Helper: Convert dBm to signal bars (0-4)
+// Thresholds follow the common -55/-66/-77/-88 dBm steps; 0 dBm means unknown
+func DBmToBars(dBm int16) uint8 {
+	switch {
+	case dBm == 0 || dBm < -88:
+		return 0
+	case dBm < -77:
+		return 1
+	case dBm < -66:
+		return 2
+	case dBm < -55:
+		return 3
+	}
+	return 4
+}
+
+// Helper: Format an address with its prefix length, e.g. "192.168.1.42/24"
+// ("" without an address, the bare address when the prefix is unknown)
+func IPCidr(ip string, prefixLen uint8) string {
+	if ip == "" || prefixLen == 0 {
+		return ip
+	}
+	return ip + "/" + strconv.Itoa(int(prefixLen))
+}
+
+// Helper: IPv4 broadcast address of ip/prefixLen ("" for IPv6, unknown
+// prefixes and /31 and /32, which have no broadcast address)
+func IPv4Broadcast(ip string, prefixLen uint8) string {
+	ip4 := net.ParseIP(ip).To4()
+	if ip4 == nil || prefixLen == 0 || prefixLen > 30 {
+		return ""
+	}
+	mask := net.CIDRMask(int(prefixLen), 32)
+	bcast := make(net.IP, net.IPv4len)
+	for i := range ip4 {
+		bcast[i] = ip4[i] | ^mask[i]
+	}
+	return bcast.String()
+}
+
 // Helper: Get band from frequency
 func FrequencyToBand(freq uint32) string {
 	if freq >= 2400 && freq < 2500 {
@@ -155,3 +486,40 @@ func FrequencyToBand(freq uint32) string {
 	}
 	return "unknown"
 }
+
+// Helper: Get channel number from frequency (0 if not a WiFi channel)
+func FrequencyToChannel(freq uint32) uint32 {
+	switch {
+	case freq == 2484:
+		return 14
+	case freq >= 2412 && freq <= 2472:
+		return (freq - 2407) / 5
+	case freq == 5935:
+		return 2 // 6 GHz channel 2 sits below the regular 6 GHz raster
+	case freq >= 5955 && freq <= 7115:
+		return (freq - 5950) / 5
+	case freq >= 5000 && freq < 5950:
+		return (freq - 5000) / 5
+	}
+	return 0
+}
+
+// Helper: Get display label from IWD security type
+// Combined types ("psk+sae") are transition-mode networks
+func SecurityLabel(security string) string {
+	switch security {
+	case "open":
+		return "Open"
+	case "wep":
+		return "WEP"
+	case "psk":
+		return "WPA2"
+	case "sae":
+		return "WPA3"
+	case "8021x":
+		return "Enterprise"
+	case "psk+sae", "sae+psk":
+		return "WPA2/WPA3"
+	}
+	return security
+}
@@ -0,0 +1,55 @@
+package state
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestUpdateNotifiesInOrder(t *testing.T) {
+	m := NewManager()
+
+	var mu sync.Mutex
+	var seen []uint64
+	var running, overlapped bool
+	record := func(st *State) {
+		mu.Lock()
+		if running {
+			overlapped = true
+		}
+		running = true
+		seen = append(seen, st.Seq)
+		mu.Unlock()
+
+		m.Get() // Observers may read the state while being notified
+
+		mu.Lock()
+		running = false
+		mu.Unlock()
+	}
+	m.AddListener(record)
+
+	const writers, updates = 8, 200
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < updates; j++ {
+				m.Update(func(st *State) { st.NetlinkReconnects++ })
+			}
+		}()
+	}
+	wg.Wait()
+
+	if overlapped {
+		t.Error("listener ran concurrently with itself")
+	}
+	if len(seen) != writers*updates {
+		t.Fatalf("listener saw %d updates, want %d", len(seen), writers*updates)
+	}
+	for i, seq := range seen {
+		if seq != uint64(i+1) {
+			t.Fatalf("update %d delivered with Seq %d", i+1, seq)
+		}
+	}
+}
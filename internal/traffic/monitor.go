@@ -9,18 +9,23 @@ import (
 	"sync/atomic"
 	"time"
 
+	"x-network/internal/netlink"
 	"x-network/internal/state"
 )
 
 const (
-	sysClassNet    = "/sys/class/net"
-	updateInterval = 1 * time.Second
-	minDeltaBytes  = 100 // Only emit if delta > 100 bytes
+	sysClassNet   = "/sys/class/net"
+	minDeltaBytes = 100 // Only emit if delta > 100 bytes
+
+	// DefaultInterval is how often the counters are sampled
+	DefaultInterval = 1 * time.Second
 )
 
 // Monitor monitors network traffic
 type Monitor struct {
 	stateMgr *state.Manager
+	interval time.Duration
+	filter   *netlink.InterfaceFilter // Links never sampled (nil = only loopback)
 	stopCh   chan struct{}
 	running  atomic.Bool
 
@@ -29,10 +34,16 @@ type Monitor struct {
 	idleEmitted bool // Track if we've emitted 0,0 to avoid repeated emissions
 }
 
-// NewMonitor creates a new traffic monitor
-func NewMonitor(stateMgr *state.Manager) *Monitor {
+// NewMonitor creates a traffic monitor sampling every interval (0 = DefaultInterval)
+// over the links filter allows
+func NewMonitor(stateMgr *state.Manager, interval time.Duration, filter *netlink.InterfaceFilter) *Monitor {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
 	return &Monitor{
 		stateMgr: stateMgr,
+		interval: interval,
+		filter:   filter,
 		stopCh:   make(chan struct{}),
 	}
 }
@@ -43,7 +54,7 @@ func (m *Monitor) Run() {
 		return
 	}
 
-	ticker := time.NewTicker(updateInterval)
+	ticker := time.NewTicker(m.interval)
 	defer ticker.Stop()
 
 	for {
@@ -73,6 +84,8 @@ func (m *Monitor) sample() {
 	// If WiFi not connected and USB tethering is active, use USB interface
 	if (iface == "" || st.ConnectionState != state.StateConnected) && st.UsbTetheringConnected && st.UsbInterfaceName != "" {
 		iface = st.UsbInterfaceName
+	} else if (iface == "" || st.ConnectionState != state.StateConnected) && st.BtTetheringConnected && st.BtInterfaceName != "" {
+		iface = st.BtInterfaceName
 	}
 
 	if iface == "" {
@@ -98,9 +111,11 @@ func (m *Monitor) sample() {
 
 	// Only update if significant traffic (delta > threshold)
 	if deltaRx > minDeltaBytes || deltaTx > minDeltaBytes {
+		// Published as bytes/sec whatever the sampling interval
+		perSec := func(b uint64) uint64 { return b * uint64(time.Second) / uint64(m.interval) }
 		m.stateMgr.Update(func(s *state.State) {
-			s.TrafficIn = deltaRx
-			s.TrafficOut = deltaTx
+			s.TrafficIn = perSec(deltaRx)
+			s.TrafficOut = perSec(deltaTx)
 			s.InterfaceName = iface
 		})
 		m.idleEmitted = false // Reset so we can emit zero once when idle
@@ -133,7 +148,7 @@ func (m *Monitor) findActiveInterface() string {
 
 	for _, entry := range entries {
 		name := entry.Name()
-		if name == "lo" {
+		if m.filter.Ignored(name) {
 			continue
 		}
 
@@ -13,6 +13,7 @@ echo "→ Removing files..."
 sudo rm -rf /usr/lib/x-network
 sudo rm -f /etc/dbus-1/session.d/org.xshell.Network.conf
 sudo rm -f /usr/share/dbus-1/services/org.xshell.Network.service
+sudo rm -f /usr/share/polkit-1/actions/org.xshell.network.policy
 rm -f ~/.config/systemd/user/x-network.service
 
 echo "→ Reloading..."